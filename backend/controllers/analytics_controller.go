@@ -0,0 +1,130 @@
+// backend/controllers/analytics_controller.go
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsController expone las métricas de ventas del dashboard (admin)
+type AnalyticsController struct {
+	analyticsService services.SalesAnalyticsService
+}
+
+// NewAnalyticsController crea una nueva instancia del controlador de analítica
+func NewAnalyticsController(analyticsService services.SalesAnalyticsService) *AnalyticsController {
+	return &AnalyticsController{analyticsService: analyticsService}
+}
+
+// AdminSalesSummary retorna revenue, órdenes, ticket promedio y el reparto
+// local / Cargo Expreso, comparados con el período anterior (admin)
+// GET /api/v1/admin/analytics/summary?from=2026-01-01&to=2026-01-31
+// Sin fechas usa los últimos 30 días (hora de Guatemala).
+func (ac *AnalyticsController) AdminSalesSummary(c *gin.Context) {
+	period, ok := analyticsPeriodFromQuery(c)
+	if !ok {
+		return
+	}
+
+	summary, err := ac.analyticsService.Summary(period)
+	if err != nil {
+		respondAnalyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// AdminSalesTimeSeries retorna órdenes y revenue por día, semana o mes (admin)
+// GET /api/v1/admin/analytics/sales?from=2026-01-01&to=2026-03-31&granularity=day|week|month
+func (ac *AnalyticsController) AdminSalesTimeSeries(c *gin.Context) {
+	period, ok := analyticsPeriodFromQuery(c)
+	if !ok {
+		return
+	}
+
+	granularity := services.Granularity(c.DefaultQuery("granularity", string(services.GranularityDay)))
+	points, err := ac.analyticsService.TimeSeries(period, granularity)
+	if err != nil {
+		respondAnalyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period":      period,
+		"granularity": granularity,
+		"data":        points,
+	})
+}
+
+// AdminTopProducts retorna los productos más vendidos (admin)
+// GET /api/v1/admin/analytics/top-products?from=...&to=...&sort=units|revenue&limit=10
+func (ac *AnalyticsController) AdminTopProducts(c *gin.Context) {
+	period, ok := analyticsPeriodFromQuery(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validación: limit debe ser un número"})
+		return
+	}
+
+	products, err := ac.analyticsService.TopProducts(period, c.Query("sort"), limit)
+	if err != nil {
+		respondAnalyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period": period,
+		"data":   products,
+	})
+}
+
+// AdminSalesByRegion retorna las ventas por departamento o municipio (admin)
+// GET /api/v1/admin/analytics/regions?from=...&to=...&group_by=department|municipality
+func (ac *AnalyticsController) AdminSalesByRegion(c *gin.Context) {
+	period, ok := analyticsPeriodFromQuery(c)
+	if !ok {
+		return
+	}
+
+	regions, err := ac.analyticsService.SalesByRegion(period, c.Query("group_by"))
+	if err != nil {
+		respondAnalyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period": period,
+		"data":   regions,
+	})
+}
+
+// analyticsPeriodFromQuery lee from/to; responde 400 y retorna false si son inválidos
+func analyticsPeriodFromQuery(c *gin.Context) (services.AnalyticsPeriod, bool) {
+	period, err := services.ParseAnalyticsPeriod(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return period, false
+	}
+	return period, true
+}
+
+// respondAnalyticsError responde 400 a errores de validación y 500 al resto
+func respondAnalyticsError(c *gin.Context, err error) {
+	if strings.Contains(err.Error(), "validación") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Error en analítica de ventas: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Error calculando métricas"})
+}
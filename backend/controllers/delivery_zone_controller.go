@@ -0,0 +1,248 @@
+// backend/controllers/delivery_zone_controller.go
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeliveryZoneController expone la administración de zonas de entrega por
+// coordenadas y el reporte de pines fuera del municipio declarado (admin)
+type DeliveryZoneController struct {
+	zoneService services.DeliveryZoneService
+}
+
+// NewDeliveryZoneController crea una nueva instancia del controlador de zonas
+func NewDeliveryZoneController(zoneService services.DeliveryZoneService) *DeliveryZoneController {
+	return &DeliveryZoneController{zoneService: zoneService}
+}
+
+// AdminGetDeliveryZones lista las zonas (admin)
+// GET /api/v1/admin/delivery-zones?include_inactive=true
+func (zc *DeliveryZoneController) AdminGetDeliveryZones(c *gin.Context) {
+	zones, err := zc.zoneService.List(c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  zones,
+		"count": len(zones),
+	})
+}
+
+// AdminGetDeliveryZone retorna una zona (admin)
+// GET /api/v1/admin/delivery-zones/:id
+func (zc *DeliveryZoneController) AdminGetDeliveryZone(c *gin.Context) {
+	id, ok := deliveryZoneIDParam(c)
+	if !ok {
+		return
+	}
+
+	zone, err := zc.zoneService.Get(id)
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": zone})
+}
+
+// AdminCreateDeliveryZone crea una zona (admin)
+// POST /api/v1/admin/delivery-zones
+// Body: {"name": "...", "municipality": "Chiantla", "method": "local_delivery", "cost": 0, "geometry": {"type": "Polygon", "coordinates": [...]}}
+func (zc *DeliveryZoneController) AdminCreateDeliveryZone(c *gin.Context) {
+	var input services.DeliveryZoneDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	zone, err := zc.zoneService.Create(input)
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": zone})
+}
+
+// AdminUpdateDeliveryZone reemplaza una zona (admin)
+// PUT /api/v1/admin/delivery-zones/:id
+func (zc *DeliveryZoneController) AdminUpdateDeliveryZone(c *gin.Context) {
+	id, ok := deliveryZoneIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input services.DeliveryZoneDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	zone, err := zc.zoneService.Update(id, input)
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": zone})
+}
+
+// AdminDeleteDeliveryZone borra una zona (admin)
+// DELETE /api/v1/admin/delivery-zones/:id
+func (zc *DeliveryZoneController) AdminDeleteDeliveryZone(c *gin.Context) {
+	id, ok := deliveryZoneIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := zc.zoneService.Delete(id); err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Zona eliminada"})
+}
+
+// AdminImportDeliveryZones crea o actualiza zonas desde un FeatureCollection (admin)
+// POST /api/v1/admin/delivery-zones/import
+// Body: FeatureCollection GeoJSON; las properties de cada Feature son las de
+// AdminCreateDeliveryZone. Las zonas se actualizan por nombre.
+func (zc *DeliveryZoneController) AdminImportDeliveryZones(c *gin.Context) {
+	var collection services.DeliveryZoneCollection
+	if err := c.ShouldBindJSON(&collection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "GeoJSON inválido: " + err.Error()})
+		return
+	}
+
+	result, err := zc.zoneService.Import(collection)
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// AdminExportDeliveryZones descarga todas las zonas como FeatureCollection (admin)
+// GET /api/v1/admin/delivery-zones/export
+func (zc *DeliveryZoneController) AdminExportDeliveryZones(c *gin.Context) {
+	collection, err := zc.zoneService.Export()
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="delivery-zones.geojson"`)
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, collection)
+}
+
+// AdminLocateDeliveryZone indica en qué zona cae un punto (admin)
+// GET /api/v1/admin/delivery-zones/locate?lat=15.3197&lng=-91.4710
+func (zc *DeliveryZoneController) AdminLocateDeliveryZone(c *gin.Context) {
+	point, ok := geoPointQuery(c)
+	if !ok {
+		return
+	}
+	if point == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat y lng son requeridos"})
+		return
+	}
+
+	location, err := zc.zoneService.Locate(*point)
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": location})
+}
+
+// AdminGetLocationMismatches lista órdenes cuyo pin cae fuera del municipio declarado (admin)
+// GET /api/v1/admin/orders/location-mismatches?from=2026-01-01&status=paid&limit=100
+// Acepta los mismos filtros que GET /admin/orders. Solo se verifican los
+// municipios que tienen zonas de entrega.
+func (zc *DeliveryZoneController) AdminGetLocationMismatches(c *gin.Context) {
+	filter, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 500"})
+			return
+		}
+	}
+
+	report, err := zc.zoneService.LocationMismatches(filter, limit)
+	if err != nil {
+		c.JSON(deliveryZoneErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      report.Mismatches,
+		"count":     len(report.Mismatches),
+		"checked":   report.Checked,
+		"truncated": report.Truncated,
+	})
+}
+
+// geoPointQuery lee lat/lng opcionales del query string; responde 400 y
+// retorna false si son inválidas
+func geoPointQuery(c *gin.Context) (*services.GeoPoint, bool) {
+	rawLat, rawLng := c.Query("lat"), c.Query("lng")
+	if rawLat == "" && rawLng == "" {
+		return nil, true
+	}
+
+	lat, errLat := strconv.ParseFloat(rawLat, 64)
+	lng, errLng := strconv.ParseFloat(rawLng, 64)
+	if errLat != nil || errLng != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat y lng deben ser números"})
+		return nil, false
+	}
+
+	point, err := services.NewGeoPoint(&lat, &lng)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return point, true
+}
+
+// deliveryZoneIDParam lee :id; responde 400 y retorna false si no es un número
+func deliveryZoneIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de zona inválido"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// deliveryZoneErrorStatus mapea errores del servicio de zonas a códigos HTTP
+func deliveryZoneErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "no encontrada"):
+		return http.StatusNotFound
+	case strings.Contains(msg, "validación"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
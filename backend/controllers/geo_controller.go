@@ -0,0 +1,52 @@
+// backend/controllers/geo_controller.go
+package controllers
+
+import (
+	"net/http"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geoCacheControl: el catálogo solo cambia con un despliegue
+const geoCacheControl = "public, max-age=86400"
+
+// GeoController expone el catálogo de departamentos y municipios de Guatemala
+// para los selectores del checkout
+type GeoController struct{}
+
+// NewGeoController crea una nueva instancia del controlador geográfico
+func NewGeoController() *GeoController {
+	return &GeoController{}
+}
+
+// GetDepartments retorna los 22 departamentos
+// GET /api/v1/geo/departments
+func (gc *GeoController) GetDepartments(c *gin.Context) {
+	departments := services.GeoDepartments()
+
+	c.Header("Cache-Control", geoCacheControl)
+	c.JSON(http.StatusOK, gin.H{
+		"data":  departments,
+		"count": len(departments),
+	})
+}
+
+// GetMunicipalities retorna los municipios de un departamento
+// GET /api/v1/geo/departments/:code/municipalities
+// :code acepta el código (GT-13), el número (13) o el nombre del departamento.
+func (gc *GeoController) GetMunicipalities(c *gin.Context) {
+	department, ok := services.FindDepartment(c.Param("code"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Departamento no encontrado: " + c.Param("code")})
+		return
+	}
+
+	c.Header("Cache-Control", geoCacheControl)
+	c.JSON(http.StatusOK, gin.H{
+		"department": department,
+		"data":       department.Municipalities,
+		"count":      len(department.Municipalities),
+	})
+}
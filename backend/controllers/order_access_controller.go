@@ -0,0 +1,110 @@
+// backend/controllers/order_access_controller.go
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OrderAccessController maneja la consulta de órdenes de invitados con enlaces mágicos
+type OrderAccessController struct {
+	accessService services.OrderAccessService
+}
+
+// NewOrderAccessController crea una nueva instancia del controlador de consulta de órdenes
+func NewOrderAccessController(accessService services.OrderAccessService) *OrderAccessController {
+	return &OrderAccessController{accessService: accessService}
+}
+
+// RequestOrderLink - Solicitud de enlace de consulta de un invitado
+// POST /api/v1/orders/lookup
+// Body: {"email": "...", "order_number": "..."}
+// Siempre responde 202 con el mismo mensaje, exista o no la orden, para no
+// revelar qué órdenes o emails existen.
+func (ac *OrderAccessController) RequestOrderLink(c *gin.Context) {
+	var input services.OrderLookupDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if err := ac.accessService.RequestLink(input); err != nil {
+		log.Printf("Error al enviar enlace de consulta para orden %q: %v", input.OrderNumber, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo enviar el enlace, intenta de nuevo"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Si los datos coinciden con un pedido, recibirás un enlace en tu correo",
+	})
+}
+
+// GetOrderByLink - Vista de solo lectura de la orden del enlace
+// GET /api/v1/orders/access/:token
+func (ac *OrderAccessController) GetOrderByLink(c *gin.Context) {
+	view, err := ac.accessService.ViewOrder(c.Param("token"))
+	if err != nil {
+		c.JSON(orderAccessErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": view,
+	})
+}
+
+// RevokeOrderLink - El cliente invalida su enlace (ej: lo compartió por error)
+// DELETE /api/v1/orders/access/:token
+func (ac *OrderAccessController) RevokeOrderLink(c *gin.Context) {
+	if err := ac.accessService.RevokeLink(c.Param("token")); err != nil {
+		c.JSON(orderAccessErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Enlace revocado",
+	})
+}
+
+// AdminRevokeOrderLinks revoca todos los enlaces de consulta activos de una orden (admin)
+// POST /api/v1/admin/orders/:id/revoke-links
+func (ac *OrderAccessController) AdminRevokeOrderLinks(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return
+	}
+
+	revoked, err := ac.accessService.RevokeOrderLinks(orderID)
+	if err != nil {
+		log.Printf("Error revocando enlaces de la orden %s: %v", orderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando enlaces"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Enlaces revocados",
+		"revoked": revoked,
+	})
+}
+
+// orderAccessErrorStatus traduce los errores de OrderAccessService a códigos HTTP
+func orderAccessErrorStatus(err error) int {
+	errMsg := err.Error()
+	switch {
+	case strings.Contains(errMsg, "vencido"), strings.Contains(errMsg, "revocado"):
+		return http.StatusGone
+	case strings.Contains(errMsg, "inválido"):
+		return http.StatusUnauthorized
+	case strings.Contains(errMsg, "no encontrad"):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,49 @@
+// backend/controllers/order_bulk_controller.go
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderBulkController maneja las operaciones en lote sobre órdenes (admin)
+type OrderBulkController struct {
+	bulkService services.OrderBulkService
+}
+
+// NewOrderBulkController crea una nueva instancia del controlador de operaciones en lote
+func NewOrderBulkController(bulkService services.OrderBulkService) *OrderBulkController {
+	return &OrderBulkController{bulkService: bulkService}
+}
+
+// AdminBulkOrders aplica una acción a varias órdenes (admin)
+// POST /api/v1/admin/orders/bulk
+// Body: {"order_ids": ["...", "..."], "action": "update_status" | "generate_guides" | "packing_slips" | "cancel", "status": "shipped", "reason": "..."}
+// Responde 200 con un resultado por orden aunque algunas fallen.
+func (bc *OrderBulkController) AdminBulkOrders(c *gin.Context) {
+	var input services.BulkOrderActionDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	result, err := bc.bulkService.Run(input, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Error en operación en lote '%s': %v", input.Action, err)
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "validación") {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
@@ -2,11 +2,17 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
 	"moda-organica/backend/services"
 
 	"github.com/gin-gonic/gin"
@@ -16,175 +22,24 @@ import (
 
 // OrderController maneja las operaciones relacionadas con órdenes
 type OrderController struct {
-	DB *gorm.DB
+	DB                  *gorm.DB
+	refundService       services.RefundService
+	cancellationService services.OrderCancellationService
+	editService         services.OrderEditService
+	reconciler          *services.OrderReconciler
 }
 
 // NewOrderController crea una nueva instancia del controlador de órdenes
-func NewOrderController(db *gorm.DB) *OrderController {
+func NewOrderController(db *gorm.DB, gateways *services.PaymentGateways, reconciler *services.OrderReconciler, cancellationService services.OrderCancellationService, editService services.OrderEditService) *OrderController {
 	return &OrderController{
-		DB: db,
+		DB:                  db,
+		refundService:       services.NewRefundService(db, gateways),
+		cancellationService: cancellationService,
+		editService:         editService,
+		reconciler:          reconciler,
 	}
 }
 
-// CreateOrderInput estructura para el binding del JSON del frontend
-// Representa los datos que envía el cliente para crear una orden
-type CreateOrderInput struct {
-	// CustomerEmail: Email del cliente (requerido)
-	CustomerEmail string `json:"customer_email" binding:"required,email"`
-
-	// CustomerName: Nombre completo del cliente (requerido)
-	CustomerName string `json:"customer_name" binding:"required"`
-
-	// CustomerPhone: Teléfono del cliente (requerido)
-	CustomerPhone string `json:"customer_phone" binding:"required"`
-
-	// ShippingAddress: Dirección de envío (requerida)
-	ShippingAddress string `json:"shipping_address" binding:"required"`
-
-	// ShippingMunicipality: Municipio de envío para calcular el costo (requerido)
-	ShippingMunicipality string `json:"shipping_municipality" binding:"required"`
-
-	// Items: Carrito del cliente - slice de items a comprar
-	Items []models.CartItem `json:"items" binding:"required,min=1"`
-}
-
-// CreateOrder maneja POST /api/orders
-// Crea una nueva orden a partir de los datos del cliente
-func (oc *OrderController) CreateOrder(c *gin.Context) {
-	log.Printf("POST /api/orders - Crear nueva orden")
-
-	// Parsear y validar el input JSON
-	var input CreateOrderInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Printf("Error al parsear CreateOrderInput: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Bad Request",
-			"message": "Datos inválidos: " + err.Error(),
-		})
-		return
-	}
-
-	// Inicializar subtotal y slice de items de la orden
-	subtotal := 0.0
-	orderItems := []models.OrderItem{}
-
-	// Iterar sobre los items del carrito del cliente
-	for _, item := range input.Items {
-		// Consultar el producto en la BD para obtener el precio actual
-		var product models.Product
-		if err := oc.DB.First(&product, "id = ?", item.ProductID).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				log.Printf("Producto no encontrado: %d", item.ProductID)
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "Not Found",
-					"message": "Producto no encontrado: ID " + string(rune(item.ProductID)),
-				})
-				return
-			}
-			log.Printf("Error al consultar producto: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Internal Server Error",
-				"message": "Error al consultar el producto",
-			})
-			return
-		}
-
-		// Validar que haya stock disponible
-		if product.Stock < item.Quantity {
-			log.Printf("Stock insuficiente para producto %d. Disponible: %d, Solicitado: %d",
-				item.ProductID, product.Stock, item.Quantity)
-			c.JSON(http.StatusConflict, gin.H{
-				"error":   "Conflict",
-				"message": "Stock insuficiente para " + product.Name,
-			})
-			return
-		}
-
-		// Usar el precio de la BD (no del frontend) para mayor seguridad
-		itemSubtotal := product.Price * float64(item.Quantity)
-		subtotal += itemSubtotal
-
-		// Crear el OrderItem con datos del producto de la BD
-		orderItem := models.OrderItem{
-			ID:          uuid.New(),
-			ProductID:   product.ID,
-			ProductName: product.Name, // Snapshot del nombre
-			Quantity:    item.Quantity,
-			Price:       product.Price, // Snapshot del precio
-		}
-
-		orderItems = append(orderItems, orderItem)
-	}
-
-	// Calcular costo de envío según municipio
-	shippingCost := services.CalculateShippingCost(input.ShippingMunicipality)
-
-	// Calcular total
-	total := subtotal + shippingCost
-
-	// Crear la orden principal
-	order := models.Order{
-		ID:                   uuid.New(),
-		Status:               models.StatusPending,
-		CustomerEmail:        input.CustomerEmail,
-		CustomerName:         input.CustomerName,
-		CustomerPhone:        input.CustomerPhone,
-		ShippingAddress:      input.ShippingAddress,
-		ShippingMunicipality: input.ShippingMunicipality,
-		Subtotal:             subtotal,
-		ShippingCost:         shippingCost,
-		Total:                total,
-		OrderItems:           orderItems,
-	}
-
-	// Usar transacción para garantizar integridad de datos
-	err := oc.DB.Transaction(func(tx *gorm.DB) error {
-		// Crear la orden
-		if err := tx.Create(&order).Error; err != nil {
-			log.Printf("Error al crear orden: %v", err)
-			return err
-		}
-
-		// Asignar el ID de la orden a cada item
-		for i := range orderItems {
-			orderItems[i].OrderID = order.ID
-		}
-
-		// Crear los items de la orden
-		if err := tx.Create(&orderItems).Error; err != nil {
-			log.Printf("Error al crear items de orden: %v", err)
-			return err
-		}
-
-		// Reducir stock de los productos
-		for _, item := range orderItems {
-			if err := tx.Model(&models.Product{}).
-				Where("id = ?", item.ProductID).
-				Update("stock", gorm.Expr("stock - ?", item.Quantity)).Error; err != nil {
-				log.Printf("Error al reducir stock: %v", err)
-				return err
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		log.Printf("Error en transacción de creación de orden: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Internal Server Error",
-			"message": "Error al crear la orden",
-		})
-		return
-	}
-
-	// Respuesta exitosa: 201 Created con la orden completa
-	log.Printf("Orden creada exitosamente: %s", order.ID)
-	c.JSON(http.StatusCreated, gin.H{
-		"data": order,
-	})
-}
-
 // GetUserOrders - Obtener pedidos del usuario autenticado
 func (oc *OrderController) GetUserOrders(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -248,7 +103,8 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 	orderID := c.Param("id")
 
 	var input struct {
-		Status string `json:"status" binding:"required,oneof=pending paid shipped delivered cancelled"`
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -256,15 +112,61 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	status := models.OrderStatus(input.Status)
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Estado inválido: " + input.Status,
+			"valid_statuses": models.AllOrderStatuses(),
+		})
+		return
+	}
+
+	// Cancelar tiene efectos (stock, sesión de pago, guía): se delega al servicio
+	if status == models.StatusCancelled {
+		id, err := uuid.Parse(orderID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+			return
+		}
+		result, err := oc.cancellationService.CancelByAdmin(id, input.Reason, c.GetString("user_id"))
+		if err != nil {
+			c.JSON(cancellationErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Pedido cancelado",
+			"order":   result.Order,
+		})
+		return
+	}
+
+	// Pago y reembolsos tienen sus propios flujos (paid_at, ledger, stock)
+	if err := models.ValidateManualStatus(status); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
 	var order models.Order
-	if err := oc.DB.First(&order, orderID).Error; err != nil {
+	if err := oc.DB.First(&order, "id = ?", orderID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido no encontrado"})
 		return
 	}
 
-	order.Status = models.OrderStatus(input.Status)
-
-	if err := oc.DB.Save(&order).Error; err != nil {
+	err := oc.DB.Transaction(func(tx *gorm.DB) error {
+		return order.TransitionTo(tx, status, models.StatusChange{
+			ActorType: models.ActorAdmin,
+			ActorID:   c.GetString("user_id"),
+			Reason:    input.Reason,
+		})
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "transición de estado no permitida") {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":               err.Error(),
+				"allowed_transitions": order.Status.AllowedTransitions(),
+			})
+			return
+		}
 		log.Printf("Error actualizando estado del pedido: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando pedido"})
 		return
@@ -277,26 +179,76 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 	})
 }
 
-// ===== MÉTODOS DE ADMINISTRACIÓN (Para panel de admin) =====
+// CancelOrder - Cancelación de un pedido por el cliente
+// POST /api/v1/orders/:id/cancel
+// Body: {"token": "...", "reason": "..."}
+// Con cuenta se identifica por JWT; como invitado, con el token del enlace de consulta.
+// Si la orden ya estaba pagada se reembolsa el total.
+func (oc *OrderController) CancelOrder(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return
+	}
 
-// AdminGetOrders obtiene todas las órdenes con filtros (admin)
-// GET /api/v1/admin/orders?status=pending&limit=50&offset=0
-func (oc *OrderController) AdminGetOrders(c *gin.Context) {
-	var orders []models.Order
+	// El body es opcional para clientes autenticados
+	var input services.CancelOrderDTO
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
 
-	query := oc.DB.Preload("OrderItems.Product")
+	result, err := oc.cancellationService.CancelByCustomer(orderID, input, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Error al cancelar orden %s: %v", orderID, err)
+		c.JSON(cancellationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
-	// Filtro por status
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pedido cancelado",
+		"data":    result,
+	})
+}
+
+// cancellationErrorStatus traduce los errores de OrderCancellationService a códigos HTTP
+func cancellationErrorStatus(err error) int {
+	errMsg := err.Error()
+	switch {
+	case strings.Contains(errMsg, "orden no encontrada"):
+		return http.StatusNotFound
+	case strings.Contains(errMsg, "no autorizado"):
+		return http.StatusForbidden
+	case strings.Contains(errMsg, "no se puede cancelar"), strings.Contains(errMsg, "transición de estado no permitida"),
+		strings.Contains(errMsg, "cambió de estado"), strings.Contains(errMsg, "reembolso pendiente"):
+		return http.StatusConflict
+	case strings.Contains(errMsg, "Stripe"), strings.Contains(errMsg, "pasarela"):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
 	}
+}
 
-	// Filtro por municipio
-	if municipality := c.Query("municipality"); municipality != "" {
-		query = query.Where("shipping_municipality = ?", municipality)
+// ===== MÉTODOS DE ADMINISTRACIÓN (Para panel de admin) =====
+
+// AdminGetOrders obtiene las órdenes con filtros y paginación por cursor (admin)
+// GET /api/v1/admin/orders?status=pending&municipality=...&order_number=000123&from=2026-01-01&to=2026-01-31
+//
+//	&min_total=100&max_total=500&delivery_type=home_delivery&shipping_method=cargo_expreso
+//	&requires_courier=true&has_tracking=false&q=maria&limit=50&cursor=...
+//
+// Las órdenes vienen de la más reciente a la más antigua. Para la página siguiente
+// se envía el next_cursor de la respuesta; total cuenta las órdenes que cumplen
+// los filtros (sin importar la página).
+func (oc *OrderController) AdminGetOrders(c *gin.Context) {
+	var orders []models.Order
+
+	filter, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Limit y Offset
 	limit := 50
 	if l := c.Query("limit"); l != "" {
 		if parsed, _ := strconv.Atoi(l); parsed > 0 && parsed <= 500 {
@@ -304,37 +256,116 @@ func (oc *OrderController) AdminGetOrders(c *gin.Context) {
 		}
 	}
 
-	offset := 0
-	if o := c.Query("offset"); o != "" {
-		if parsed, _ := strconv.Atoi(o); parsed >= 0 {
-			offset = parsed
+	query := filter.Apply(oc.DB.Model(&models.Order{}).Preload("OrderItems"))
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := services.ParseOrderCursor(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		query = cursor.After(query)
 	}
 
-	// Contar total
+	// Contar total con los mismos filtros
 	var totalCount int64
-	oc.DB.Model(&models.Order{}).Count(&totalCount)
+	if err := filter.Apply(oc.DB.Model(&models.Order{})).Count(&totalCount).Error; err != nil {
+		log.Printf("Error contando órdenes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo órdenes"})
+		return
+	}
 
-	// Obtener órdenes
-	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&orders).Error; err != nil {
+	// Obtener una orden de más para saber si hay otra página
+	if err := query.Order("orders.created_at DESC, orders.id DESC").Limit(limit + 1).Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo órdenes"})
 		return
 	}
 
+	hasMore := len(orders) > limit
+	nextCursor := ""
+	if hasMore {
+		orders = orders[:limit]
+		nextCursor = services.NewOrderCursor(&orders[len(orders)-1]).Encode()
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
-		"count":  len(orders),
-		"total":  totalCount,
+		"orders":      orders,
+		"count":       len(orders),
+		"total":       totalCount,
+		"has_more":    hasMore,
+		"next_cursor": nextCursor,
 	})
 }
 
+// orderFilterFromQuery lee los filtros comunes de los listados de órdenes
+// (GET /admin/orders y la exportación)
+func orderFilterFromQuery(c *gin.Context) (services.OrderFilter, error) {
+	filter := services.OrderFilter{
+		Status:         models.OrderStatus(c.Query("status")),
+		Municipality:   c.Query("municipality"),
+		OrderNumber:    c.Query("order_number"),
+		DeliveryType:   c.Query("delivery_type"),
+		ShippingMethod: c.Query("shipping_method"),
+		Search:         strings.TrimSpace(c.Query("q")),
+	}
+
+	var err error
+	if filter.From, filter.To, err = services.ParseOrderDateRange(c.Query("from"), c.Query("to")); err != nil {
+		return filter, err
+	}
+	if filter.MinTotal, err = moneyQuery(c, "min_total"); err != nil {
+		return filter, err
+	}
+	if filter.MaxTotal, err = moneyQuery(c, "max_total"); err != nil {
+		return filter, err
+	}
+	if filter.RequiresCourier, err = boolQuery(c, "requires_courier"); err != nil {
+		return filter, err
+	}
+	if filter.HasTracking, err = boolQuery(c, "has_tracking"); err != nil {
+		return filter, err
+	}
+	return filter, filter.Validate()
+}
+
+// moneyQuery lee un monto opcional del query string (nil si no viene)
+func moneyQuery(c *gin.Context, key string) (*models.Money, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	amount, err := models.ParseMoney(raw)
+	if err != nil {
+		return nil, fmt.Errorf("validación: %s debe ser un monto", key)
+	}
+	return &amount, nil
+}
+
+// boolQuery lee un booleano opcional del query string (nil si no viene)
+func boolQuery(c *gin.Context, key string) (*bool, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("validación: %s debe ser true o false", key)
+	}
+	return &value, nil
+}
+
 // AdminGetOrderByID obtiene una orden específica (admin)
 // GET /api/v1/admin/orders/:id
+// :id puede ser el UUID o el número de orden (MO-2026-000123).
 func (oc *OrderController) AdminGetOrderByID(c *gin.Context) {
-	orderID := c.Param("id")
+	condition, ref := models.OrderRefCondition(c.Param("id"))
 
 	var order models.Order
-	if err := oc.DB.Preload("OrderItems.Product").First(&order, orderID).Error; err != nil {
+	if err := oc.DB.Preload("OrderItems").
+		Preload("Refunds.Items").
+		Preload("StatusEvents", models.PreloadStatusEvents).
+		Preload("Edits", models.PreloadOrderEdits).
+		Preload("ShipmentEvents", models.PreloadShipmentEvents).
+		First(&order, condition, ref).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Orden no encontrada"})
 		return
 	}
@@ -342,17 +373,75 @@ func (oc *OrderController) AdminGetOrderByID(c *gin.Context) {
 	c.JSON(http.StatusOK, order)
 }
 
+// AdminEditOrder corrige una orden antes de enviarla (admin)
+// PATCH /api/v1/admin/orders/:id
+// Body: {"reason": "...", "items": [{"product_id": 12, "quantity": 2}], "shipping_address": "...", "delivery_type": "pickup_at_branch", "pickup_branch": "..."}
+// Cada item fija la cantidad final del producto (0 lo quita). Los totales se
+// recalculan; si la orden ya estaba pagada la diferencia queda en balance_due.
+// "package": {"type": "caja_mediana", "weight_kg": 2.5} fija el paquete de la
+// guía (no se recalcula al editar items); {"auto": true} vuelve al calculado.
+func (oc *OrderController) AdminEditOrder(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return
+	}
+
+	var input services.EditOrderDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	result, err := oc.editService.EditOrder(orderID, input, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Error al editar orden %s: %v", orderID, err)
+		var shortage *repositories.InsufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": shortage.Error(),
+				"items": shortage.Items,
+			})
+			return
+		}
+		c.JSON(orderEditErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Orden actualizada",
+		"data":    result,
+	})
+}
+
+// orderEditErrorStatus traduce los errores de OrderEditService a códigos HTTP
+func orderEditErrorStatus(err error) int {
+	errMsg := err.Error()
+	switch {
+	case strings.Contains(errMsg, "no encontrad"):
+		return http.StatusNotFound
+	case strings.Contains(errMsg, "validación"):
+		return http.StatusBadRequest
+	case strings.Contains(errMsg, "no se puede"):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // AdminGetOrdersStats obtiene estadísticas para el dashboard
 // GET /api/v1/admin/orders/stats
 func (oc *OrderController) AdminGetOrdersStats(c *gin.Context) {
 	type StatsResponse struct {
-		TotalOrders      int64   `json:"total_orders"`
-		PendingOrders    int64   `json:"pending_orders"`
-		ProcessingOrders int64   `json:"processing_orders"`
-		ShippedOrders    int64   `json:"shipped_orders"`
-		DeliveredOrders  int64   `json:"delivered_orders"`
-		CancelledOrders  int64   `json:"cancelled_orders"`
-		TotalRevenue     float64 `json:"total_revenue"`
+		TotalOrders      int64        `json:"total_orders"`
+		PendingOrders    int64        `json:"pending_orders"`
+		ProcessingOrders int64        `json:"processing_orders"`
+		ShippedOrders    int64        `json:"shipped_orders"`
+		DeliveredOrders  int64        `json:"delivered_orders"`
+		CancelledOrders  int64        `json:"cancelled_orders"`
+		RefundedOrders   int64        `json:"refunded_orders"`
+		TotalRevenue     models.Money `json:"total_revenue"`
+		TotalRefunded    models.Money `json:"total_refunded"`
 	}
 
 	var stats StatsResponse
@@ -361,17 +450,24 @@ func (oc *OrderController) AdminGetOrdersStats(c *gin.Context) {
 	oc.DB.Model(&models.Order{}).Count(&stats.TotalOrders)
 
 	// Por status
-	oc.DB.Model(&models.Order{}).Where("status = ?", "pending").Count(&stats.PendingOrders)
-	oc.DB.Model(&models.Order{}).Where("status = ?", "processing").Count(&stats.ProcessingOrders)
-	oc.DB.Model(&models.Order{}).Where("status = ?", "shipped").Count(&stats.ShippedOrders)
-	oc.DB.Model(&models.Order{}).Where("status = ?", "delivered").Count(&stats.DeliveredOrders)
-	oc.DB.Model(&models.Order{}).Where("status = ?", "cancelled").Count(&stats.CancelledOrders)
-
-	// Revenue total
+	oc.DB.Model(&models.Order{}).Where("status = ?", models.StatusPending).Count(&stats.PendingOrders)
+	oc.DB.Model(&models.Order{}).Where("status = ?", models.StatusProcessing).Count(&stats.ProcessingOrders)
+	oc.DB.Model(&models.Order{}).Where("status = ?", models.StatusShipped).Count(&stats.ShippedOrders)
+	oc.DB.Model(&models.Order{}).Where("status = ?", models.StatusDelivered).Count(&stats.DeliveredOrders)
+	oc.DB.Model(&models.Order{}).Where("status = ?", models.StatusCancelled).Count(&stats.CancelledOrders)
+	oc.DB.Model(&models.Order{}).Where("status IN ?", []models.OrderStatus{models.StatusPartiallyRefunded, models.StatusRefunded}).Count(&stats.RefundedOrders)
+
+	// Revenue total: órdenes cobradas, neto de reembolsos (igual que la analítica de ventas)
 	oc.DB.Model(&models.Order{}).
-		Where("status IN ?", []string{"shipped", "delivered"}).
-		Select("COALESCE(SUM(total), 0)").
-		Scan(&stats.TotalRevenue)
+		Where(models.RevenueCondition).
+		Select("COALESCE(SUM(total - refunded_amount), 0)").
+		Row().Scan(&stats.TotalRevenue)
+
+	// Total reembolsado (confirmado por la pasarela)
+	oc.DB.Model(&models.Refund{}).
+		Where("status = ?", models.RefundSucceeded).
+		Select("COALESCE(SUM(amount), 0)").
+		Row().Scan(&stats.TotalRefunded)
 
 	c.JSON(http.StatusOK, stats)
 }
@@ -403,3 +499,71 @@ func (oc *OrderController) AdminGetOrdersMap(c *gin.Context) {
 		"count":  len(orders),
 	})
 }
+
+// AdminCreateRefund emite un reembolso total o parcial sobre una orden (admin)
+// POST /api/v1/admin/orders/:id/refunds
+// Body: {"reason": "...", "items": [{"order_item_id": "...", "quantity": 1}], "include_shipping": false, "restock": true}
+// Sin items se reembolsa todo lo pendiente de la orden, incluido el envío.
+func (oc *OrderController) AdminCreateRefund(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return
+	}
+
+	var input services.CreateRefundDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	adminID := c.GetString("user_id")
+	refund, err := oc.refundService.RefundOrder(orderID, input, adminID)
+	if err != nil {
+		log.Printf("Error al reembolsar orden %s: %v", orderID, err)
+		statusCode := http.StatusInternalServerError
+		errMsg := err.Error()
+		if strings.Contains(errMsg, "orden no encontrada") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(errMsg, "no se puede reembolsar") {
+			statusCode = http.StatusConflict
+		} else if strings.Contains(errMsg, "validación") {
+			statusCode = http.StatusBadRequest
+		} else if strings.Contains(errMsg, "Stripe") || strings.Contains(errMsg, "pasarela") {
+			statusCode = http.StatusBadGateway
+		}
+		c.JSON(statusCode, gin.H{"error": errMsg})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": refund,
+	})
+}
+
+// AdminExpirePendingOrders ejecuta manualmente el reconciliador de órdenes pendientes (admin)
+// POST /api/v1/admin/orders/expire-pending?older_than=2h
+// Sin older_than se usa el TTL configurado (PENDING_ORDER_TTL).
+func (oc *OrderController) AdminExpirePendingOrders(c *gin.Context) {
+	olderThan := oc.reconciler.TTL()
+	if raw := c.Query("older_than"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than inválido (ej: 30m, 2h, 24h)"})
+			return
+		}
+		olderThan = parsed
+	}
+
+	log.Printf("Admin %s ejecutó el reconciliador de órdenes pendientes (older_than=%s)", c.GetString("user_id"), olderThan)
+
+	result, err := oc.reconciler.ExpireStaleOrders(olderThan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
@@ -0,0 +1,87 @@
+// backend/controllers/order_export_controller.go
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderExportController maneja la exportación de órdenes para contabilidad (admin)
+type OrderExportController struct {
+	exportService services.OrderExportService
+}
+
+// NewOrderExportController crea una nueva instancia del controlador de exportación
+func NewOrderExportController(exportService services.OrderExportService) *OrderExportController {
+	return &OrderExportController{exportService: exportService}
+}
+
+// AdminExportOrders descarga las órdenes en CSV o XLSX (admin)
+// GET /api/v1/admin/orders/export?format=csv|xlsx&rows=order|item&status=...&municipality=...&order_number=...&from=2026-01-01&to=2026-01-31
+// Acepta los mismos filtros que GET /admin/orders. El archivo se envía por
+// partes mientras se lee de la BD. Si la BD falla antes del primer lote se
+// responde 500; si falla a mitad del archivo se corta la conexión para que el
+// cliente vea la descarga fallida y no un archivo truncado.
+func (ec *OrderExportController) AdminExportOrders(c *gin.Context) {
+	filter, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request := services.OrderExportRequest{
+		Filter: filter,
+		Format: services.ExportFormat(c.DefaultQuery("format", "csv")),
+		Rows:   services.ExportRows(c.Query("rows")),
+	}
+	// Validar antes de escribir: después del primer byte ya no se puede responder 400
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Los encabezados del archivo y el 200 se envían con el primer byte
+	filename := fmt.Sprintf("ordenes-%s.%s", time.Now().Format("20060102"), request.Format)
+	out := &exportResponseWriter{ResponseWriter: c.Writer, start: func() {
+		c.Header("Content-Type", request.Format.ContentType())
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+	}}
+
+	if _, err := ec.exportService.Export(request, out); err != nil {
+		log.Printf("Error exportando órdenes (%s): %v", request.Format, err)
+		if !out.started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exportando órdenes"})
+			return
+		}
+		// La respuesta ya empezó: cortar la conexión (ver middleware.Recovery)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportResponseWriter llama a start antes del primer Write, para fijar los
+// encabezados del archivo solo cuando ya hay datos que enviar.
+type exportResponseWriter struct {
+	gin.ResponseWriter
+	start   func()
+	started bool
+}
+
+func (w *exportResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.start()
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *exportResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -1,29 +1,52 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"moda-organica/backend/db"
+	"moda-organica/backend/middleware"
 	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
 	"moda-organica/backend/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v78"
-	"github.com/stripe/stripe-go/v78/checkout/session"
+	"github.com/stripe/stripe-go/v78/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// PaymentController maneja las operaciones de pago con Stripe
+// PaymentController maneja el checkout delegando el cobro a la pasarela
+// de la forma de pago elegida (PaymentGateway), recibe los webhooks de Stripe
 // y genera guías de envío con Cargo Expreso cuando aplica
 type PaymentController struct {
 	cargoExpresoService services.CargoExpresoService
+	gateways            *services.PaymentGateways
+	orderService        services.OrderService
+	refunds             services.RefundService
+	pendingOrderTTL     time.Duration
 }
 
 // NewPaymentController crea una instancia con dependencias inyectadas
-func NewPaymentController() *PaymentController {
+// Las órdenes se crean con el mismo pipeline que POST /api/v1/orders
+// refunds devuelve los pagos que llegan con la orden ya cancelada
+// pendingOrderTTL es el vencimiento de la sesión de pago (el mismo TTL del reconciliador)
+func NewPaymentController(orderService services.OrderService, gateways *services.PaymentGateways, cargo services.CargoExpresoService, refunds services.RefundService, pendingOrderTTL time.Duration) *PaymentController {
 	return &PaymentController{
-		cargoExpresoService: services.NewCargoExpresoService(),
+		cargoExpresoService: cargo,
+		gateways:            gateways,
+		orderService:        orderService,
+		refunds:             refunds,
+		pendingOrderTTL:     pendingOrderTTL,
 	}
 }
 
@@ -52,19 +75,24 @@ type CreateCheckoutSessionInput struct {
 	DeliveryLat *float64 `json:"delivery_lat"` // Latitud
 	DeliveryLng *float64 `json:"delivery_lng"` // Longitud
 
+	// Forma de pago: 'card' (default) | 'cash_on_delivery' (solo entregas locales)
+	PaymentMethod string `json:"payment_method" binding:"omitempty,oneof=card cash_on_delivery"`
+
 	// Items del carrito
+	// Name y Price son los que vio el cliente; el servidor los recalcula desde la BD
 	Items []struct {
-		ProductID uint    `json:"product_id" binding:"required"`
-		Name      string  `json:"name" binding:"required"`
-		Price     float64 `json:"price" binding:"required,gt=0"`
-		Quantity  int     `json:"quantity" binding:"required,gt=0"`
-		ImageURL  string  `json:"image_url"`
+		ProductID uint         `json:"product_id" binding:"required"`
+		Name      string       `json:"name" binding:"required"`
+		Price     models.Money `json:"price"` // Debe ser > 0 (validado en el handler)
+		Quantity  int          `json:"quantity" binding:"required,gt=0"`
+		ImageURL  string       `json:"image_url"`
 	} `json:"items" binding:"required,min=1"`
 
-	// Costos
-	Subtotal     float64 `json:"subtotal" binding:"required,gt=0"`
-	ShippingCost float64 `json:"shipping_cost" binding:"gte=0"`
-	Total        float64 `json:"total" binding:"required,gt=0"`
+	// Costos mostrados al cliente (solo se usan para detectar diferencias)
+	// Los montos se validan en el handler: binding no soporta comparaciones sobre Money
+	Subtotal     models.Money `json:"subtotal"`
+	ShippingCost models.Money `json:"shipping_cost"`
+	Total        models.Money `json:"total"`
 }
 
 /**
@@ -74,10 +102,13 @@ type CreateCheckoutSessionInput struct {
  *
  * Flow:
  * 1. Validar input
- * 2. Crear orden pendiente en DB (status: 'pending')
- * 3. Crear line items para Stripe
- * 4. Crear Checkout Session con metadata de la orden
- * 5. Retornar URL de checkout y order_id
+ * 2. Crear la orden con OrderService.CreateOrder: recalcula precios, envío y total
+ *    desde la BD (409 si difieren de lo mostrado), reserva stock y la guarda como 'pending'
+ * 3. Iniciar el cobro con la PaymentGateway de la forma de pago elegida
+ * 4. Retornar URL de checkout y order_id
+ *
+ * Con tarjeta, la orden pasa a 'paid' cuando llega el webhook checkout.session.completed.
+ * Con pago contra entrega, la orden queda 'pending' hasta que se cobra al entregar.
  */
 func (ctrl *PaymentController) CreateCheckoutSession(c *gin.Context) {
 	var input CreateCheckoutSessionInput
@@ -89,6 +120,12 @@ func (ctrl *PaymentController) CreateCheckoutSession(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateCheckoutAmounts(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
 
 	// 1.1 Validación adicional: si es pickup, no requiere dirección pero sí sucursal
 	if input.DeliveryType == "pickup_at_branch" {
@@ -108,253 +145,519 @@ func (ctrl *PaymentController) CreateCheckoutSession(c *gin.Context) {
 		}
 	}
 
-	// 2. Crear orden pendiente en DB
-	// Calcular costo de envío
-	shippingCost := services.CalculateShippingCost(input.ShippingAddress.Municipality)
-	requiresCourier := services.RequiresCargoExpreso(input.ShippingAddress.Municipality)
-	shippingMethod := getShippingMethod(requiresCourier)
+	// 1.2 El municipio debe pertenecer al departamento elegido
+	department, municipality, err := services.ResolveLocation(input.ShippingAddress.Department, input.ShippingAddress.Municipality)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	input.ShippingAddress.Department = department.Code
+	input.ShippingAddress.Municipality = municipality.Name
 
-	order := models.Order{
-		// Guest checkout (user_id = null)
-		UserID: nil,
+	pin, err := services.NewGeoPoint(input.DeliveryLat, input.DeliveryLng)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	destination := services.ShippingDestination{
+		Department:   department.Code,
+		Municipality: municipality.Name,
+		DeliveryType: input.DeliveryType,
+		Pin:          pin,
+	}
 
-		// Datos del cliente
-		CustomerEmail: input.CustomerEmail,
-		CustomerName:  input.CustomerName,
-		CustomerPhone: input.CustomerPhone,
+	// 1.3 La forma de pago debe aplicar al destino (con pin, según la zona de entrega)
+	paymentMethod := services.PaymentMethod(input.PaymentMethod)
+	if paymentMethod == "" {
+		paymentMethod = services.PaymentMethodCard
+	}
+	if !ctrl.gateways.IsAvailableFor(paymentMethod, destination) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "Forma de pago no disponible para " + input.ShippingAddress.Municipality,
+			"payment_methods": ctrl.gateways.AvailableFor(destination),
+		})
+		return
+	}
+	gateway, err := ctrl.gateways.Get(paymentMethod)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-		// Dirección de envío
+	// 2. Crear la orden con el pipeline de órdenes: recalcula precios, envío y
+	// total desde la BD (nunca confiamos en los montos del cliente), rechaza si
+	// difieren de lo mostrado, reserva stock y guarda la orden como 'pending'.
+	dto := services.CreateOrderDTO{
+		CustomerEmail:        input.CustomerEmail,
+		CustomerName:         input.CustomerName,
+		CustomerPhone:        input.CustomerPhone,
 		ShippingDepartment:   input.ShippingAddress.Department,
 		ShippingMunicipality: input.ShippingAddress.Municipality,
 		ShippingAddress:      input.ShippingAddress.Address,
-
-		// NUEVO: Tipo de entrega y opciones
-		DeliveryType:  input.DeliveryType,
-		PickupBranch:  input.PickupBranch,
-		DeliveryNotes: input.DeliveryNotes,
-
-		// NUEVO: Coordenadas de geolocalización (para entrega local)
-		DeliveryLat: input.DeliveryLat,
-		DeliveryLng: input.DeliveryLng,
-
-		// Totales
-		Subtotal:     input.Subtotal,
-		ShippingCost: shippingCost,
-		Total:        input.Subtotal + shippingCost,
-
-		// Información de envío
-		ShippingMethod:  shippingMethod,
-		RequiresCourier: requiresCourier,
-
-		// Estado inicial
-		Status: "pending", // Cambiará a 'paid' cuando Stripe confirme
+		DeliveryType:         input.DeliveryType,
+		PickupBranch:         input.PickupBranch,
+		DeliveryNotes:        input.DeliveryNotes,
+		DeliveryLat:          input.DeliveryLat,
+		DeliveryLng:          input.DeliveryLng,
+		PaymentMethod:        paymentMethod,
+		ClientPrices: &services.ClientPrices{
+			Items:        map[uint]models.Money{},
+			Subtotal:     input.Subtotal,
+			ShippingCost: input.ShippingCost,
+			Total:        input.Total,
+		},
 	}
-
-	// Crear orden en DB
-	if err := db.GormDB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error creando orden en base de datos",
-		})
-		return
+	for _, item := range input.Items {
+		dto.Items = append(dto.Items, services.OrderItemDTO{ProductID: item.ProductID, Quantity: item.Quantity})
+		dto.ClientPrices.Items[item.ProductID] = item.Price
 	}
 
-	// Crear OrderItems
-	for _, item := range input.Items {
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-		}
-		if err := db.GormDB.Create(&orderItem).Error; err != nil {
-			// Rollback de orden si falla
-			db.GormDB.Delete(&order)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Error creando items de orden",
+	result, err := ctrl.orderService.CreateOrder(dto)
+	if err != nil {
+		var mismatch *services.PriceMismatchError
+		if errors.As(err, &mismatch) {
+			log.Printf("Checkout rechazado para %s: %d diferencias de precio", input.CustomerEmail, len(mismatch.Discrepancies))
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Los precios del carrito cambiaron. Revisa el resumen antes de pagar.",
+				"discrepancies": mismatch.Discrepancies,
+				"quote":         mismatch.Quote,
 			})
 			return
 		}
-	}
 
-	// 3. Configurar Stripe API Key
-	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
+		var shortage *repositories.InsufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": shortage.Error(),
+				"items": shortage.Items,
+			})
+			return
+		}
 
-	// 4. Crear line items para Stripe
-	var lineItems []*stripe.CheckoutSessionLineItemParams
-	for _, item := range input.Items {
-		// Stripe maneja precios en centavos (multiply by 100)
-		priceInCents := int64(item.Price * 100)
-
-		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
-			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-				Currency: stripe.String("gtq"), // Quetzales guatemaltecos
-				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-					Name:        stripe.String(item.Name),
-					Description: stripe.String(fmt.Sprintf("Producto ID: %d", item.ProductID)),
-					Images:      []*string{stripe.String(item.ImageURL)},
-				},
-				UnitAmount: stripe.Int64(priceInCents),
-			},
-			Quantity: stripe.Int64(int64(item.Quantity)),
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "producto no encontrado") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "stock insuficiente") {
+			statusCode = http.StatusConflict
+		} else if strings.Contains(err.Error(), "validación") || strings.Contains(err.Error(), "sin items") {
+			statusCode = http.StatusBadRequest
+		}
+		if statusCode == http.StatusInternalServerError {
+			log.Printf("Error creando orden de checkout: %v", err)
+		}
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
 		})
+		return
 	}
+	order := result.Order
+	quote := result.Quote
 
-	// Agregar shipping como line item si es mayor a 0
-	if input.ShippingCost > 0 {
-		shippingInCents := int64(input.ShippingCost * 100)
-		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
-			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-				Currency: stripe.String("gtq"),
-				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-					Name:        stripe.String("Envío a " + input.ShippingAddress.Municipality),
-					Description: stripe.String("Costo de envío"),
-				},
-				UnitAmount: stripe.Int64(shippingInCents),
-			},
-			Quantity: stripe.Int64(1),
-		})
-	}
+	// La orden ya está guardada con su stock reservado: si algo falla desde
+	// aquí, un reintento con la misma Idempotency-Key recibe este mismo error
+	// en lugar de crear otra orden.
+	middleware.MarkIdempotentPersisted(c)
 
-	// 5. URLs de éxito y cancelación
+	// 3. URLs de éxito y cancelación
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:5173" // Default para desarrollo
 	}
 
 	successURL := fmt.Sprintf("%s/checkout/success?session_id={CHECKOUT_SESSION_ID}", frontendURL)
-	cancelURL := fmt.Sprintf("%s/checkout/cancel", frontendURL)
-
-	// 6. Crear Checkout Session
-	params := &stripe.CheckoutSessionParams{
-		PaymentMethodTypes: stripe.StringSlice([]string{
-			"card", // Tarjetas de crédito/débito
-		}),
-		LineItems:  lineItems,
-		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
-		SuccessURL: stripe.String(successURL),
-		CancelURL:  stripe.String(cancelURL),
-
-		// Metadata para vincular con nuestra orden
-		Metadata: map[string]string{
-			"order_id":       fmt.Sprintf("%d", order.ID),
-			"customer_email": input.CustomerEmail,
-			"customer_name":  input.CustomerName,
-		},
-
-		// Información del cliente
-		CustomerEmail: stripe.String(input.CustomerEmail),
-
-		// Permitir códigos promocionales (opcional)
-		AllowPromotionCodes: stripe.Bool(false),
-
-		// Configuración de facturación
-		BillingAddressCollection: stripe.String("auto"),
+	if paymentMethod == services.PaymentMethodCashOnDelivery {
+		successURL = fmt.Sprintf("%s/checkout/success?order_id=%s", frontendURL, order.ID)
 	}
+	cancelURL := fmt.Sprintf("%s/checkout/cancel", frontendURL)
 
-	sess, err := session.New(params)
+	// 4. Iniciar el cobro con la pasarela de la forma de pago elegida
+	checkout, err := gateway.CreateCheckout(services.CheckoutRequest{
+		Order:      order,
+		Quote:      quote,
+		SuccessURL: successURL,
+		CancelURL:  cancelURL,
+		ExpiresAt:  order.CreatedAt.Add(ctrl.pendingOrderTTL),
+	})
 	if err != nil {
-		// Si falla Stripe, no eliminar la orden (queda como pending)
+		// Si falla la pasarela, no eliminar la orden (queda como pending)
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error creando sesión de pago con Stripe: " + err.Error(),
+			"error": "Error iniciando el pago: " + err.Error(),
 		})
 		return
 	}
 
-	// 7. Actualizar orden con Stripe Session ID
-	order.PaymentIntentID = sess.ID
-	db.GormDB.Save(&order)
-
-	// 8. SI REQUIERE CARGO EXPRESO: Generar guía de envío
-	// NOTA: En producción con webhook, esto se ejecutará después del pago
-	// Por ahora se genera inmediatamente (en modo mock)
-	if requiresCourier {
-		go func() {
-			// Goroutine para no bloquear la respuesta HTTP
-			ctrl.generateCargoExpresoGuide(&order)
-		}()
+	// 5. Actualizar orden con el ID de sesión de la pasarela (si aplica)
+	// El PaymentIntentID se completa cuando llega el webhook checkout.session.completed
+	if checkout.SessionID != "" {
+		order.StripeSessionID = checkout.SessionID
+		db.GormDB.Model(order).Update("stripe_session_id", checkout.SessionID)
 	}
 
-	// 9. Retornar URL de checkout
+	// 6. La guía de Cargo Expreso se genera cuando Stripe confirma el pago
+	// (ver StripeWebhook → handleCheckoutSessionCompleted). El pago contra
+	// entrega solo aplica a entregas locales, que no requieren courier.
+
+	// 7. Retornar URL de checkout
 	c.JSON(http.StatusOK, gin.H{
-		"checkout_url":     sess.URL,
-		"session_id":       sess.ID,
+		"checkout_url":     checkout.RedirectURL,
+		"session_id":       checkout.SessionID,
+		"payment_method":   paymentMethod,
 		"order_id":         order.ID,
-		"requires_courier": requiresCourier,
-		"shipping_method":  shippingMethod,
-		"shipping_cost":    shippingCost,
+		"order_number":     order.OrderNumber,
+		"requires_courier": order.RequiresCourier,
+		"shipping_method":  order.ShippingMethod,
+		"delivery_zone":    quote.Shipping.Zone,
+		"shipping_cost":    quote.ShippingCost,
+		"subtotal":         quote.Subtotal,
+		"total":            quote.Total,
 	})
 }
 
 // generateCargoExpresoGuide genera una guía de envío con Cargo Expreso
 // Se ejecuta en una goroutine para no bloquear la respuesta HTTP
 func (ctrl *PaymentController) generateCargoExpresoGuide(order *models.Order) {
-	// Datos del remitente (Moda Orgánica) desde .env
-	senderName := os.Getenv("CARGO_EXPRESO_SENDER_NAME")
-	senderPhone := os.Getenv("CARGO_EXPRESO_SENDER_PHONE")
-	senderAddress := os.Getenv("CARGO_EXPRESO_SENDER_ADDRESS")
-	senderCity := os.Getenv("CARGO_EXPRESO_SENDER_CITY")
-
-	// Validar que tenemos datos del remitente
-	if senderName == "" || senderPhone == "" || senderAddress == "" {
-		fmt.Printf("Advertencia: Datos del remitente de Cargo Expreso incompletos en .env\n")
+	response, err := services.GenerateOrderGuide(db.GormDB, ctrl.cargoExpresoService, order, models.StatusChange{
+		ActorType: models.ActorSystem,
+		ActorID:   "cargo_expreso",
+	})
+	if err != nil {
+		log.Printf("Error generando guía de Cargo Expreso: %v", err)
 		return
 	}
 
-	// Preparar request para Cargo Expreso
-	request := services.CargoExpresoGuideRequest{
-		// Remitente (Moda Orgánica)
-		SenderName:    senderName,
-		SenderPhone:   senderPhone,
-		SenderAddress: senderAddress,
-		SenderCity:    senderCity,
+	log.Printf("Guía generada: %s para orden %s", response.TrackingNumber, order.Reference())
+}
+
+// validateCheckoutAmounts verifica que los montos enviados por el cliente sean válidos
+func validateCheckoutAmounts(input *CreateCheckoutSessionInput) error {
+	for _, item := range input.Items {
+		if !item.Price.IsPositive() {
+			return fmt.Errorf("price del producto %d debe ser mayor a 0", item.ProductID)
+		}
+	}
+	if !input.Subtotal.IsPositive() {
+		return fmt.Errorf("subtotal debe ser mayor a 0")
+	}
+	if input.ShippingCost.IsNegative() {
+		return fmt.Errorf("shipping_cost no puede ser negativo")
+	}
+	if !input.Total.IsPositive() {
+		return fmt.Errorf("total debe ser mayor a 0")
+	}
+	return nil
+}
 
-		// Destinatario (Cliente)
-		RecipientName:    order.CustomerName,
-		RecipientPhone:   order.CustomerPhone,
-		RecipientAddress: order.ShippingAddress, // Vacío si es pickup
-		RecipientCity:    order.ShippingMunicipality,
+// ============================================
+// WEBHOOK DE STRIPE
+// ============================================
 
-		// Datos del envío
-		OrderID:       order.ID.String(), // Convertir UUID a string
-		PackageType:   "caja_pequena",
-		Weight:        1.0, // Default 1 libra (ajustar según productos)
-		DeclaredValue: order.Total,
-		Notes:         fmt.Sprintf("Orden numero %s - Moda Organica", order.ID.String()),
+// maxWebhookBodyBytes limita el tamaño del payload aceptado en el webhook
+const maxWebhookBodyBytes = int64(65536)
 
-		// NUEVO: Tipo de entrega
-		DeliveryType: order.DeliveryType,
-		PickupBranch: order.PickupBranch,
+/**
+ * StripeWebhook - Recibe los eventos de Stripe y actualiza el estado de las órdenes
+ *
+ * POST /api/v1/payments/webhook
+ *
+ * Eventos manejados:
+ * - checkout.session.completed      → orden 'pending' pasa a 'paid'
+ * - checkout.session.expired        → orden 'pending' pasa a 'cancelled'
+ * - payment_intent.payment_failed   → se registra; la orden sigue 'pending' para que
+ *                                     el cliente reintente el pago en la misma sesión
+ *                                     (si no paga, la cancela checkout.session.expired
+ *                                     o el reconciliador)
+ *
+ * Cada evento procesado se guarda en la tabla stripe_events, de modo que
+ * las entregas repetidas del mismo evento se ignoran.
+ */
+func (ctrl *PaymentController) StripeWebhook(c *gin.Context) {
+	// 1. Leer el payload crudo (la firma se calcula sobre los bytes exactos)
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		log.Printf("Error leyendo payload del webhook de Stripe: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Error leyendo el payload"})
+		return
 	}
 
-	// Llamar servicio de Cargo Expreso (mock o real según .env)
-	response, err := ctrl.cargoExpresoService.CreateGuide(request)
+	// 2. Verificar la firma de Stripe
+	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		log.Println("Error: STRIPE_WEBHOOK_SECRET no configurado")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "STRIPE_WEBHOOK_SECRET no configurado"})
+		return
+	}
+
+	event, err := webhook.ConstructEvent(payload, c.GetHeader("Stripe-Signature"), webhookSecret)
 	if err != nil {
-		fmt.Printf("Error generando guia de Cargo Expreso: %v\n", err)
+		log.Printf("Firma de webhook de Stripe inválida: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Firma inválida"})
 		return
 	}
 
-	if !response.Success {
-		fmt.Printf("Cargo Expreso fallo: %s\n", response.ErrorMessage)
+	// 3. Procesar el evento dentro de una transacción junto con su registro,
+	// así un fallo deja el evento sin marcar y Stripe lo reintentará
+	duplicate := false
+	var affectedOrderID *uuid.UUID
+	err = db.GormDB.Transaction(func(tx *gorm.DB) error {
+		record := models.StripeEvent{
+			EventID: event.ID,
+			Type:    string(event.Type),
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return fmt.Errorf("error registrando evento: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		orderID, err := ctrl.handleStripeEvent(tx, event)
+		if err != nil {
+			return err
+		}
+
+		affectedOrderID = orderID
+		if orderID != nil {
+			return tx.Model(&record).Update("order_id", orderID).Error
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Error procesando evento de Stripe %s (%s): %v", event.ID, event.Type, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error procesando evento"})
 		return
 	}
 
-	// Actualizar orden con tracking number
-	order.ShippingTracking = response.TrackingNumber
-	order.CargoExpresoGuideURL = response.GuideURL
-	order.Status = "processing" // Cambiar de pending a processing
+	if duplicate {
+		log.Printf("Evento de Stripe %s ya procesado, se ignora", event.ID)
+	}
+
+	// 4. Con el pago confirmado (y la transacción ya guardada), generar la guía
+	// de Cargo Expreso, o reembolsar el pago si la orden ya estaba cancelada.
+	// Goroutines para no bloquear la respuesta al webhook
+	if event.Type == stripe.EventTypeCheckoutSessionCompleted && affectedOrderID != nil {
+		var order models.Order
+		if err := db.GormDB.First(&order, "id = ?", affectedOrderID).Error; err == nil {
+			switch {
+			case order.Status == models.StatusPaid && order.RequiresCourier && order.ShippingTracking == "":
+				go ctrl.generateCargoExpresoGuide(&order)
+			case order.Status == models.StatusCancelled:
+				go ctrl.refundLatePayments(db.GormDB, order.ID)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// handleStripeEvent despacha el evento según su tipo.
+// Retorna el ID de la orden afectada (si aplica).
+func (ctrl *PaymentController) handleStripeEvent(tx *gorm.DB, event stripe.Event) (*uuid.UUID, error) {
+	switch event.Type {
+	case stripe.EventTypeCheckoutSessionCompleted:
+		var sess stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &sess); err != nil {
+			return nil, fmt.Errorf("error parseando checkout session: %w", err)
+		}
+		return ctrl.handleCheckoutSessionCompleted(tx, &sess)
+
+	case stripe.EventTypeCheckoutSessionExpired:
+		var sess stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &sess); err != nil {
+			return nil, fmt.Errorf("error parseando checkout session: %w", err)
+		}
+		return cancelPendingOrder(tx, sess.Metadata["order_id"], "sesión de Stripe expirada")
+
+	case stripe.EventTypePaymentIntentPaymentFailed:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &intent); err != nil {
+			return nil, fmt.Errorf("error parseando payment intent: %w", err)
+		}
+		return handlePaymentFailed(tx, &intent)
+
+	default:
+		log.Printf("Evento de Stripe no manejado: %s", event.Type)
+		return nil, nil
+	}
+}
+
+// handleCheckoutSessionCompleted marca la orden como pagada.
+func (ctrl *PaymentController) handleCheckoutSessionCompleted(tx *gorm.DB, sess *stripe.CheckoutSession) (*uuid.UUID, error) {
+	order, err := findOrderByMetadata(tx, sess.Metadata["order_id"])
+	if err != nil || order == nil {
+		return nil, err
+	}
+
+	if sess.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+		log.Printf("Sesión %s completada sin pago confirmado (%s), orden %s sigue pendiente",
+			sess.ID, sess.PaymentStatus, order.ID)
+		return &order.ID, nil
+	}
+
+	// Pago que llegó con la orden ya cancelada (ej: venció mientras el cliente
+	// pagaba): su stock ya se liberó, así que el pago se reembolsa completo
+	// después de guardar el evento (ver refundLatePayments)
+	if order.Status == models.StatusCancelled {
+		paymentIntentID := ""
+		if sess.PaymentIntent != nil {
+			paymentIntentID = sess.PaymentIntent.ID
+		}
+		if _, err := services.ReserveLatePaymentRefund(tx, order, models.NewMoney(sess.AmountTotal), paymentIntentID, sess.ID); err != nil {
+			return nil, err
+		}
+		return &order.ID, nil
+	}
+
+	if !order.Status.CanTransitionTo(models.StatusPaid) {
+		log.Printf("Orden %s en estado '%s', no se marca como pagada", order.ID, order.Status)
+		return &order.ID, nil
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"paid_at":           now,
+		"stripe_session_id": sess.ID,
+	}
+	if sess.PaymentIntent != nil {
+		updates["payment_intent_id"] = sess.PaymentIntent.ID
+	}
+
+	if err := order.TransitionTo(tx, models.StatusPaid, models.StatusChange{
+		ActorType: models.ActorStripeWebhook,
+		ActorID:   sess.ID,
+		Reason:    "pago confirmado (checkout.session.completed)",
+		Fields:    updates,
+	}); err != nil {
+		return nil, fmt.Errorf("error marcando orden como pagada: %w", err)
+	}
+	log.Printf("Orden %s marcada como pagada (sesión %s)", order.ID, sess.ID)
+
+	return &order.ID, nil
+}
 
-	if err := db.GormDB.Save(order).Error; err != nil {
-		fmt.Printf("Error actualizando orden con tracking: %v\n", err)
+// refundLatePayments emite los reembolsos reservados por pagos que llegaron
+// con la orden ya cancelada. Si la pasarela falla, el reembolso queda
+// pendiente y el reconciliador lo reintenta.
+func (ctrl *PaymentController) refundLatePayments(gormDB *gorm.DB, orderID uuid.UUID) {
+	var refundIDs []uuid.UUID
+	if err := gormDB.Model(&models.Refund{}).
+		Where("order_id = ? AND status = ?", orderID, models.RefundPending).
+		Pluck("id", &refundIDs).Error; err != nil {
+		log.Printf("Error buscando reembolsos pendientes de la orden cancelada %s: %v", orderID, err)
 		return
 	}
 
-	fmt.Printf("Guia generada: %s para orden numero %d\n", response.TrackingNumber, order.ID)
+	for _, refundID := range refundIDs {
+		refund, err := ctrl.refunds.CompletePendingRefund(refundID, models.StatusChange{
+			ActorType: models.ActorStripeWebhook,
+		})
+		if err != nil {
+			log.Printf("Reembolso %s del pago recibido con la orden %s cancelada: %v", refundID, orderID, err)
+			continue
+		}
+		log.Printf("Pago recibido con la orden %s cancelada reembolsado: %s (%s)", orderID, refund.ProviderRefundID, refund.Amount)
+	}
+}
+
+// handlePaymentFailed registra un intento de pago rechazado. La orden no se
+// cancela: Stripe Checkout permite reintentar con otra tarjeta en la misma
+// sesión, y si el cliente no paga la orden se cancela al expirar la sesión
+// (checkout.session.expired) o con el reconciliador de órdenes pendientes.
+func handlePaymentFailed(tx *gorm.DB, intent *stripe.PaymentIntent) (*uuid.UUID, error) {
+	order, err := findOrderByMetadata(tx, intent.Metadata["order_id"])
+	if err != nil || order == nil {
+		return nil, err
+	}
+
+	reason := "sin detalle"
+	if intent.LastPaymentError != nil && intent.LastPaymentError.Msg != "" {
+		reason = intent.LastPaymentError.Msg
+	}
+	log.Printf("Pago rechazado para orden %s (intent %s): %s; la orden sigue en '%s' para reintentar",
+		order.ID, intent.ID, reason, order.Status)
+	return &order.ID, nil
+}
+
+// cancelPendingOrder cancela la orden indicada si aún está pendiente de pago.
+func cancelPendingOrder(tx *gorm.DB, rawOrderID, reason string) (*uuid.UUID, error) {
+	order, err := findOrderByMetadata(tx, rawOrderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+
+	if order.Status != models.StatusPending {
+		log.Printf("Orden %s en estado '%s', no se cancela (%s)", order.ID, order.Status, reason)
+		return &order.ID, nil
+	}
+
+	released, err := services.ReleaseOrderStock(tx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := order.TransitionTo(tx, models.StatusCancelled, models.StatusChange{
+		ActorType: models.ActorStripeWebhook,
+		Reason:    reason,
+	}); err != nil {
+		return nil, fmt.Errorf("error cancelando orden: %w", err)
+	}
+
+	log.Printf("Orden %s cancelada: %s (%d unidades regresadas al inventario)", order.ID, reason, released)
+	return &order.ID, nil
+}
+
+// findOrderByMetadata obtiene la orden a partir del order_id guardado en la metadata de Stripe.
+// Retorna nil (sin error) si la metadata no corresponde a ninguna orden.
+func findOrderByMetadata(tx *gorm.DB, rawOrderID string) (*models.Order, error) {
+	orderID, err := uuid.Parse(rawOrderID)
+	if err != nil {
+		log.Printf("Evento de Stripe sin order_id válido en metadata: %q", rawOrderID)
+		return nil, nil
+	}
+
+	var order models.Order
+	if err := tx.First(&order, "id = ?", orderID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Printf("Orden %s del evento de Stripe no encontrada", orderID)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo orden: %w", err)
+	}
+
+	return &order, nil
 }
 
-// getShippingMethod determina el método de envío según si requiere courier
-func getShippingMethod(requiresCourier bool) string {
-	if requiresCourier {
-		return "cargo_expreso"
+// GetPaymentMethods retorna las formas de pago disponibles para un destino
+// GET /api/v1/payments/methods?municipality=Chiantla&department=GT-13&lat=15.35&lng=-91.45
+// Con lat/lng y zonas de entrega configuradas, el pago contra entrega depende
+// de la zona donde cae el pin.
+func (ctrl *PaymentController) GetPaymentMethods(c *gin.Context) {
+	municipality := c.Query("municipality")
+	if municipality == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "municipality es requerido"})
+		return
+	}
+	pin, ok := geoPointQuery(c)
+	if !ok {
+		return
 	}
-	return "local_delivery"
+
+	c.JSON(http.StatusOK, gin.H{
+		"municipality": municipality,
+		"payment_methods": ctrl.gateways.AvailableFor(services.ShippingDestination{
+			Department:   c.Query("department"),
+			Municipality: municipality,
+			DeliveryType: c.DefaultQuery("delivery_type", "home_delivery"),
+			Pin:          pin,
+		}),
+	})
 }
@@ -0,0 +1,88 @@
+// backend/controllers/payment_controller_test.go
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/services"
+	"moda-organica/backend/testsupport"
+
+	"github.com/stripe/stripe-go/v78"
+	"gorm.io/gorm"
+)
+
+// TestCheckoutCompletedForCancelledOrder simula un pago que Stripe confirma
+// cuando la orden ya se canceló (venció mientras el cliente pagaba): el pago
+// se reembolsa completo y la orden sigue cancelada, sin contar como venta.
+//
+// Uso: TEST_DATABASE_URL=... go test ./controllers -run CancelledOrder
+func TestCheckoutCompletedForCancelledOrder(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+
+	fake := services.NewFakePaymentGateway()
+	gateways := services.NewPaymentGateways(fake)
+	ctrl := NewPaymentController(nil, gateways, nil, services.NewRefundService(gormDB, gateways), time.Hour)
+
+	order := models.Order{
+		Status:               models.StatusCancelled,
+		PaymentMethod:        string(services.PaymentMethodCard),
+		CustomerEmail:        "pago-tardio@example.com",
+		CustomerName:         "Prueba Pago Tardío",
+		CustomerPhone:        "55555555",
+		ShippingDepartment:   "GT-13",
+		ShippingMunicipality: "Huehuetenango",
+		ShippingAddress:      "4a calle 5-10 zona 1",
+		Subtotal:             models.NewMoney(50000),
+		ShippingCost:         models.NewMoney(3500),
+		Total:                models.NewMoney(53500),
+	}
+	if err := gormDB.Create(&order).Error; err != nil {
+		t.Fatalf("Error creando orden de prueba: %v", err)
+	}
+	t.Cleanup(func() {
+		gormDB.Where("order_id = ?", order.ID).Delete(&models.Refund{})
+		gormDB.Where("order_id = ?", order.ID).Delete(&models.OrderStatusEvent{})
+		gormDB.Delete(&models.Order{}, "id = ?", order.ID)
+	})
+
+	sess := &stripe.CheckoutSession{
+		ID:            "cs_test_late_" + order.ID.String()[:8],
+		PaymentStatus: stripe.CheckoutSessionPaymentStatusPaid,
+		AmountTotal:   53500,
+		PaymentIntent: &stripe.PaymentIntent{ID: "pi_test_late"},
+		Metadata:      map[string]string{"order_id": order.ID.String()},
+	}
+
+	if err := gormDB.Transaction(func(tx *gorm.DB) error {
+		_, err := ctrl.handleCheckoutSessionCompleted(tx, sess)
+		return err
+	}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	ctrl.refundLatePayments(gormDB, order.ID)
+
+	if len(fake.Refunds) != 1 {
+		t.Fatalf("reembolsos en la pasarela = %d, se esperaba 1", len(fake.Refunds))
+	}
+	if request := fake.Refunds[0]; request.PaymentIntentID != "pi_test_late" || request.Amount.Cents() != 53500 {
+		t.Errorf("reembolso = %s de %s, se esperaba Q535.00 de pi_test_late", request.Amount, request.PaymentIntentID)
+	}
+
+	var refunds []models.Refund
+	if err := gormDB.Where("order_id = ?", order.ID).Find(&refunds).Error; err != nil {
+		t.Fatalf("Error consultando reembolsos: %v", err)
+	}
+	if len(refunds) != 1 || refunds[0].Status != models.RefundSucceeded || refunds[0].ProviderRefundID == "" {
+		t.Fatalf("reembolsos = %+v, se esperaba uno 'succeeded' con el ID de la pasarela", refunds)
+	}
+
+	var reloaded models.Order
+	if err := gormDB.First(&reloaded, "id = ?", order.ID).Error; err != nil {
+		t.Fatalf("Error consultando orden: %v", err)
+	}
+	if reloaded.Status != models.StatusCancelled || reloaded.PaidAt != nil {
+		t.Errorf("orden = %s (paid_at %v), se esperaba cancelada sin paid_at", reloaded.Status, reloaded.PaidAt)
+	}
+}
@@ -27,14 +27,14 @@ func NewProductController() *ProductController {
 
 // GetProducts maneja GET /api/v1/products
 func (pc *ProductController) GetProducts(c *gin.Context) {
-	var products []models.Product
 	data, _, err := db.Client.From("products").Select("*", "", false).Execute()
 	if err != nil {
 		log.Printf("Error al consultar productos: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener los productos"})
 		return
 	}
-	if err := json.Unmarshal(data, &products); err != nil {
+	products, err := decodeProducts(data)
+	if err != nil {
 		log.Printf("Error al decodificar JSON de productos: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la respuesta de la base de datos"})
 		return
@@ -45,14 +45,14 @@ func (pc *ProductController) GetProducts(c *gin.Context) {
 // GetProductByID maneja GET /api/v1/products/:id
 func (pc *ProductController) GetProductByID(c *gin.Context) {
 	id := c.Param("id")
-	var product models.Product
 	data, _, err := db.Client.From("products").Select("*", "", false).Eq("id", id).Single().Execute()
 	if err != nil {
 		log.Printf("Error al consultar producto por ID %s: %v", id, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Producto no encontrado"})
 		return
 	}
-	if err := json.Unmarshal(data, &product); err != nil {
+	product, err := decodeProduct(data)
+	if err != nil {
 		log.Printf("Error al decodificar JSON de producto ID %s: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la respuesta de la base de datos"})
 		return
@@ -62,12 +62,41 @@ func (pc *ProductController) GetProductByID(c *gin.Context) {
 
 // --- Estructura para Crear/Actualizar Productos ---
 type ProductInput struct {
-	Name        *string  `json:"name"`
-	Description *string  `json:"description"`
-	Price       *float64 `json:"price"`
-	Stock       *int     `json:"stock"`
-	ImageURL    *string  `json:"image_url"`
-	CategoryID  *int     `json:"category_id"`
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Price       *models.Money `json:"price"`
+	Stock       *int          `json:"stock"`
+	ImageURL    *string       `json:"image_url"`
+	CategoryID  *int          `json:"category_id"`
+	// Empaque: peso en kg y dimensiones en cm del producto empacado
+	WeightKg *float64 `json:"weight_kg"`
+	LengthCm *float64 `json:"length_cm"`
+	WidthCm  *float64 `json:"width_cm"`
+	HeightCm *float64 `json:"height_cm"`
+}
+
+// packagingData retorna los campos de empaque enviados, o error si alguno es negativo
+func (input ProductInput) packagingData() (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	fields := []struct {
+		column string
+		value  *float64
+	}{
+		{"weight_kg", input.WeightKg},
+		{"length_cm", input.LengthCm},
+		{"width_cm", input.WidthCm},
+		{"height_cm", input.HeightCm},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		if *field.value < 0 {
+			return nil, fmt.Errorf("%s no puede ser negativo", field.column)
+		}
+		data[field.column] = *field.value
+	}
+	return data, nil
 }
 
 // Helper para obtener valor o string vacío si es nil
@@ -85,17 +114,28 @@ func (pc *ProductController) CreateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo de la petición inválido o faltan campos requeridos (name, price, stock)"})
 		return
 	}
+	if *input.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El stock no puede ser negativo"})
+		return
+	}
 
 	newProductData := map[string]interface{}{
 		"name":        *input.Name,
 		"description": getStringOrDefault(input.Description),
-		"price":       *input.Price,
+		"price":       input.Price.Cents(),
+		"currency":    input.Price.CurrencyCode(),
 		"stock":       *input.Stock,
 		"image_url":   getStringOrDefault(input.ImageURL),
 		"category_id": input.CategoryID,
 	}
-	var createdProduct models.Product
-
+	packaging, err := input.packagingData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for column, value := range packaging {
+		newProductData[column] = value
+	}
 	// --- CORRECCIÓN INSERT: Usar Execute() y Unmarshal ---
 	// 1. Insertar datos, pidiendo 'representation' en el segundo argumento
 	// El tercer argumento (count) se deja vacío ""
@@ -106,7 +146,8 @@ func (pc *ProductController) CreateProduct(c *gin.Context) {
 		return
 	}
 	// 2. Decodificar el resultado
-	if err := json.Unmarshal(insertResultBytes, &createdProduct); err != nil {
+	createdProduct, err := decodeProduct(insertResultBytes)
+	if err != nil {
 		log.Printf("Error al decodificar producto creado: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la respuesta de la base de datos"})
 		return
@@ -158,9 +199,14 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 		needsEmbeddingUpdate = true
 	}
 	if input.Price != nil { /* ... */
-		updateData["price"] = *input.Price
+		updateData["price"] = input.Price.Cents()
+		updateData["currency"] = input.Price.CurrencyCode()
 	}
 	if input.Stock != nil { /* ... */
+		if *input.Stock < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El stock no puede ser negativo"})
+			return
+		}
 		updateData["stock"] = *input.Stock
 	}
 	if input.ImageURL != nil { /* ... */
@@ -169,14 +215,20 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 	if input.CategoryID != nil { /* ... */
 		updateData["category_id"] = *input.CategoryID
 	}
+	packaging, err := input.packagingData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for column, value := range packaging {
+		updateData[column] = value
+	}
 
 	if len(updateData) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No se proporcionaron campos para actualizar"})
 		return
 	}
 
-	var updatedProduct models.Product
-
 	// --- CORRECCIÓN UPDATE: Arreglar argumentos y usar Execute + Unmarshal ---
 	// 1. Llamar a Update con los argumentos correctos: (datos, returning, count)
 	//    Pedimos 'representation' en el segundo argumento. Count vacío "".
@@ -187,7 +239,8 @@ func (pc *ProductController) UpdateProduct(c *gin.Context) {
 		return
 	}
 	// 2. Decodificar el resultado
-	if err := json.Unmarshal(updateResultBytes, &updatedProduct); err != nil {
+	updatedProduct, err := decodeProduct(updateResultBytes)
+	if err != nil {
 		log.Printf("Error al decodificar producto actualizado ID %s: %v", idStr, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar la respuesta de la base de datos"})
 		return
@@ -237,8 +290,6 @@ func (pc *ProductController) SemanticSearchProducts(c *gin.Context) {
 		return
 	}
 
-	var searchResults []models.Product
-
 	// 2. Parámetros para la función SQL (¡Aquí usamos queryEmbedding!)
 	rpcParams := map[string]interface{}{
 		"query_embedding": queryEmbedding, // <-- CORREGIDO: Usar la variable
@@ -277,10 +328,63 @@ func (pc *ProductController) SemanticSearchProducts(c *gin.Context) {
 	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 { /* ... manejo error ... */
 		return
 	}
-	if err := json.Unmarshal(responseBody, &searchResults); err != nil { /* ... manejo error ... */
+	searchResults, err := decodeProducts(responseBody)
+	if err != nil { /* ... manejo error ... */
 		return
 	}
 	// --- FIN Llamada HTTP Manual ---
 
 	c.JSON(http.StatusOK, searchResults)
 }
+
+// ============================================================================
+// DECODIFICACIÓN DE FILAS DE SUPABASE
+// ============================================================================
+
+// productRow es una fila de products tal como la devuelve PostgREST. La columna
+// price viene en centavos (BIGINT), mientras que el JSON de models.Product la
+// interpreta en quetzales, así que se lee aparte y se convierte.
+type productRow struct {
+	models.Product
+	Price json.Number `json:"price"`
+}
+
+// toProduct retorna el producto con el precio en centavos y la moneda de la fila.
+func (row productRow) toProduct() (models.Product, error) {
+	product := row.Product
+	if row.Price != "" {
+		price, err := models.ParseCents(row.Price.String())
+		if err != nil {
+			return models.Product{}, err
+		}
+		product.Price = price
+	}
+	models.ApplyCurrency(product.Currency, &product.Price)
+	return product, nil
+}
+
+// decodeProduct decodifica una fila de products.
+func decodeProduct(data []byte) (models.Product, error) {
+	var row productRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return models.Product{}, err
+	}
+	return row.toProduct()
+}
+
+// decodeProducts decodifica una lista de filas de products.
+func decodeProducts(data []byte) ([]models.Product, error) {
+	var rows []productRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	products := make([]models.Product, 0, len(rows))
+	for _, row := range rows {
+		product, err := row.toProduct()
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
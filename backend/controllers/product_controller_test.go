@@ -0,0 +1,44 @@
+// backend/controllers/product_controller_test.go
+package controllers
+
+import "testing"
+
+// TestDecodeProduct verifica que el precio de las filas de Supabase se lea en
+// centavos (columna BIGINT) y tome la moneda de la fila.
+func TestDecodeProduct(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantCents    int64
+		wantCurrency string
+		wantErr      bool
+	}{
+		{"centavos", `{"id":1,"name":"Collar","price":3500,"currency":"GTQ"}`, 3500, "GTQ", false},
+		{"sin moneda", `{"id":2,"name":"Pulsera","price":1500}`, 1500, "GTQ", false},
+		{"otra moneda", `{"id":3,"name":"Tobillera","price":999,"currency":"USD"}`, 999, "USD", false},
+		{"sin precio", `{"id":4,"name":"Lápiz"}`, 0, "GTQ", false},
+		{"precio inválido", `{"id":5,"price":"Q35"}`, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, err := decodeProduct([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error, se obtuvo %+v", product)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if product.Price.Cents() != tt.wantCents || product.Price.Currency != tt.wantCurrency {
+				t.Errorf("precio = %d %s, se esperaba %d %s", product.Price.Cents(), product.Price.Currency, tt.wantCents, tt.wantCurrency)
+			}
+		})
+	}
+
+	products, err := decodeProducts([]byte(`[{"id":1,"price":3500},{"id":2,"price":1500}]`))
+	if err != nil || len(products) != 2 || products[1].Price.Cents() != 1500 {
+		t.Errorf("decodeProducts = %+v (%v)", products, err)
+	}
+}
@@ -0,0 +1,228 @@
+// backend/controllers/return_controller.go
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReturnController maneja las devoluciones y cambios (RMA)
+type ReturnController struct {
+	returnService services.ReturnService
+}
+
+// NewReturnController crea una nueva instancia del controlador de devoluciones
+func NewReturnController(returnService services.ReturnService) *ReturnController {
+	return &ReturnController{returnService: returnService}
+}
+
+// CreateReturn - Solicitud de devolución del cliente
+// POST /api/v1/orders/:id/returns
+// Body: {"token": "...", "notes": "...", "items": [{"order_item_id": "...", "quantity": 1, "reason": "size_too_small", "exchange_product_id": 12}]}
+// Con cuenta se identifica por JWT; como invitado, con el token del enlace de consulta.
+func (rc *ReturnController) CreateReturn(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return
+	}
+
+	var input services.CreateReturnDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	request, err := rc.returnService.RequestReturn(orderID, input, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Error al solicitar devolución de la orden %s: %v", orderID, err)
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": request,
+	})
+}
+
+// AdminGetReturns lista las devoluciones (admin)
+// GET /api/v1/admin/returns?status=requested&order_id=...&limit=50&offset=0
+func (rc *ReturnController) AdminGetReturns(c *gin.Context) {
+	filter := services.ReturnFilter{
+		Status: models.ReturnStatus(c.Query("status")),
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado de devolución inválido: " + string(filter.Status)})
+		return
+	}
+	if raw := c.Query("order_id"); raw != "" {
+		orderID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order_id debe ser un UUID válido"})
+			return
+		}
+		filter.OrderID = &orderID
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	returns, total, err := rc.returnService.ListReturns(filter)
+	if err != nil {
+		log.Printf("Error obteniendo devoluciones: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo devoluciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  returns,
+		"total": total,
+	})
+}
+
+// AdminGetReturnByID obtiene una devolución (admin)
+// GET /api/v1/admin/returns/:id
+func (rc *ReturnController) AdminGetReturnByID(c *gin.Context) {
+	returnID, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	request, err := rc.returnService.GetReturn(returnID)
+	if err != nil {
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": request,
+	})
+}
+
+// AdminApproveReturn aprueba la devolución y genera la guía de retorno (admin)
+// POST /api/v1/admin/returns/:id/approve
+// Body: {"notes": "..."}
+func (rc *ReturnController) AdminApproveReturn(c *gin.Context) {
+	rc.applyAdminAction(c, func(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error) {
+		return rc.returnService.ApproveReturn(returnID, notes, adminID)
+	})
+}
+
+// AdminRejectReturn rechaza la devolución (admin)
+// POST /api/v1/admin/returns/:id/reject
+// Body: {"notes": "motivo del rechazo"}
+func (rc *ReturnController) AdminRejectReturn(c *gin.Context) {
+	rc.applyAdminAction(c, func(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error) {
+		return rc.returnService.RejectReturn(returnID, notes, adminID)
+	})
+}
+
+// AdminReceiveReturn marca las prendas como recibidas y repone el stock (admin)
+// POST /api/v1/admin/returns/:id/receive
+// Body: {"notes": "estado de las prendas"}
+func (rc *ReturnController) AdminReceiveReturn(c *gin.Context) {
+	rc.applyAdminAction(c, func(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error) {
+		return rc.returnService.ReceiveReturn(returnID, notes, adminID)
+	})
+}
+
+// AdminResolveReturn reembolsa o cambia las prendas recibidas (admin)
+// POST /api/v1/admin/returns/:id/resolve
+// Body: {"resolution": "refund" | "exchange", "notes": "..."}
+func (rc *ReturnController) AdminResolveReturn(c *gin.Context) {
+	returnID, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	var input services.ResolveReturnDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	request, err := rc.returnService.ResolveReturn(returnID, input, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Error al resolver devolución %s: %v", returnID, err)
+		var shortage *repositories.InsufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": shortage.Error(),
+				"items": shortage.Items,
+			})
+			return
+		}
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": request,
+	})
+}
+
+// applyAdminAction ejecuta una acción de admin que solo recibe notas opcionales
+func (rc *ReturnController) applyAdminAction(c *gin.Context, action func(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error)) {
+	returnID, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	request, err := action(returnID, input.Notes, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Error actualizando devolución %s: %v", returnID, err)
+		c.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": request,
+	})
+}
+
+// parseReturnID lee el UUID de la devolución; responde 400 si es inválido
+func parseReturnID(c *gin.Context) (uuid.UUID, bool) {
+	returnID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return uuid.Nil, false
+	}
+	return returnID, true
+}
+
+// returnErrorStatus traduce los errores de ReturnService a códigos HTTP
+func returnErrorStatus(err error) int {
+	errMsg := err.Error()
+	switch {
+	case strings.Contains(errMsg, "no encontrad"):
+		return http.StatusNotFound
+	case strings.Contains(errMsg, "no autorizado"):
+		return http.StatusForbidden
+	case strings.Contains(errMsg, "validación"):
+		return http.StatusBadRequest
+	case strings.Contains(errMsg, "no permitida"), strings.Contains(errMsg, "no se puede"), strings.Contains(errMsg, "stock insuficiente"):
+		return http.StatusConflict
+	case strings.Contains(errMsg, "guía de retorno"), strings.Contains(errMsg, "Stripe"), strings.Contains(errMsg, "pasarela"):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,67 @@
+// backend/controllers/shipment_tracking_controller.go
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ShipmentTrackingController permite al admin forzar la consulta del rastreo
+// de Cargo Expreso (el poller corre solo en segundo plano)
+type ShipmentTrackingController struct {
+	poller *services.TrackingPoller
+}
+
+// NewShipmentTrackingController crea una nueva instancia del controlador de rastreo
+func NewShipmentTrackingController(poller *services.TrackingPoller) *ShipmentTrackingController {
+	return &ShipmentTrackingController{poller: poller}
+}
+
+// AdminPollTracking ejecuta manualmente el poller de rastreo (admin)
+// POST /api/v1/admin/orders/poll-tracking
+// Consulta las guías cuya próxima consulta ya venció, como en cada corrida automática.
+func (tc *ShipmentTrackingController) AdminPollTracking(c *gin.Context) {
+	log.Printf("Admin %s ejecutó el poller de rastreo", c.GetString("user_id"))
+
+	result, err := tc.poller.PollDue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// AdminPollOrderTracking consulta ya el rastreo de la guía de una orden (admin)
+// POST /api/v1/admin/orders/:id/poll-tracking
+func (tc *ShipmentTrackingController) AdminPollOrderTracking(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID debe ser un UUID válido"})
+		return
+	}
+
+	result, err := tc.poller.PollOrder(orderID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "no encontrada"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "validación"):
+			statusCode = http.StatusBadRequest
+		case strings.Contains(err.Error(), "consultar rastreo"):
+			// Falló la consulta a Cargo Expreso (n8n)
+			statusCode = http.StatusBadGateway
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
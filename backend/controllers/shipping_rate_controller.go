@@ -0,0 +1,180 @@
+// backend/controllers/shipping_rate_controller.go
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShippingRateController maneja la tabla de tarifas de envío (admin)
+type ShippingRateController struct {
+	rateService services.ShippingRateService
+}
+
+// NewShippingRateController crea una nueva instancia del controlador de tarifas
+func NewShippingRateController(rateService services.ShippingRateService) *ShippingRateController {
+	return &ShippingRateController{rateService: rateService}
+}
+
+// AdminGetShippingRates lista las tarifas (admin)
+// GET /api/v1/admin/shipping-rates?department=GT-13&municipality=Chiantla&include_inactive=true
+func (rc *ShippingRateController) AdminGetShippingRates(c *gin.Context) {
+	filter := services.ShippingRateListFilter{
+		Department:      c.Query("department"),
+		Municipality:    c.Query("municipality"),
+		IncludeInactive: c.Query("include_inactive") == "true",
+	}
+
+	rates, err := rc.rateService.List(filter)
+	if err != nil {
+		log.Printf("Error listando tarifas de envío: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo tarifas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  rates,
+		"count": len(rates),
+	})
+}
+
+// AdminGetShippingRate obtiene una tarifa (admin)
+// GET /api/v1/admin/shipping-rates/:id
+func (rc *ShippingRateController) AdminGetShippingRate(c *gin.Context) {
+	id, ok := shippingRateIDParam(c)
+	if !ok {
+		return
+	}
+
+	rate, err := rc.rateService.Get(id)
+	if err != nil {
+		c.JSON(shippingRateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rate})
+}
+
+// AdminCreateShippingRate crea una tarifa (admin)
+// POST /api/v1/admin/shipping-rates
+// Body: {"name": "Quetzaltenango 0-2 kg", "department": "GT-09", "method": "cargo_expreso", "max_weight_kg": 2, "base_cost": 30, "pickup_surcharge": 0, "home_delivery_surcharge": 10}
+func (rc *ShippingRateController) AdminCreateShippingRate(c *gin.Context) {
+	var input services.ShippingRateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	rate, err := rc.rateService.Create(input)
+	if err != nil {
+		log.Printf("Error creando tarifa de envío: %v", err)
+		c.JSON(shippingRateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Tarifa de envío %d creada por %s", rate.ID, c.GetString("user_id"))
+	c.JSON(http.StatusCreated, gin.H{"data": rate})
+}
+
+// AdminUpdateShippingRate reemplaza una tarifa (admin)
+// PUT /api/v1/admin/shipping-rates/:id
+func (rc *ShippingRateController) AdminUpdateShippingRate(c *gin.Context) {
+	id, ok := shippingRateIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input services.ShippingRateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	rate, err := rc.rateService.Update(id, input)
+	if err != nil {
+		log.Printf("Error actualizando tarifa de envío %d: %v", id, err)
+		c.JSON(shippingRateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Tarifa de envío %d actualizada por %s", rate.ID, c.GetString("user_id"))
+	c.JSON(http.StatusOK, gin.H{"data": rate})
+}
+
+// AdminDeleteShippingRate elimina una tarifa (admin)
+// DELETE /api/v1/admin/shipping-rates/:id
+func (rc *ShippingRateController) AdminDeleteShippingRate(c *gin.Context) {
+	id, ok := shippingRateIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := rc.rateService.Delete(id); err != nil {
+		log.Printf("Error eliminando tarifa de envío %d: %v", id, err)
+		c.JSON(shippingRateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Tarifa de envío %d eliminada por %s", id, c.GetString("user_id"))
+	c.JSON(http.StatusOK, gin.H{"message": "Tarifa eliminada"})
+}
+
+// AdminQuoteShipping cotiza un destino con las tarifas vigentes (admin)
+// GET /api/v1/admin/shipping-rates/quote?municipality=Chiantla&department=GT-13&delivery_type=pickup_at_branch&weight_kg=1.5&lat=15.35&lng=-91.45
+func (rc *ShippingRateController) AdminQuoteShipping(c *gin.Context) {
+	pin, ok := geoPointQuery(c)
+	if !ok {
+		return
+	}
+	dest := services.ShippingDestination{
+		Department:   c.Query("department"),
+		Municipality: c.Query("municipality"),
+		DeliveryType: c.DefaultQuery("delivery_type", "home_delivery"),
+		Pin:          pin,
+	}
+	if raw := c.Query("weight_kg"); raw != "" {
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil || weight < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weight_kg debe ser un número positivo"})
+			return
+		}
+		dest.WeightKg = weight
+	}
+
+	quote, err := rc.rateService.Quote(dest)
+	if err != nil {
+		c.JSON(shippingRateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": quote})
+}
+
+// shippingRateIDParam lee :id; responde 400 y retorna false si no es un número
+func shippingRateIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de tarifa inválido"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// shippingRateErrorStatus mapea errores del servicio de tarifas a códigos HTTP
+func shippingRateErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "no encontrada"):
+		return http.StatusNotFound
+	case strings.Contains(msg, "validación"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,12 +2,13 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
 	"moda-organica/backend/services"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +20,9 @@ import (
 type OrderHandler struct {
 	orderService services.OrderService
 	validator    *validator.Validate
+
+	// cancellationService: Cancelación con efectos (solo en rutas admin).
+	cancellationService services.OrderCancellationService
 }
 
 // NewOrderHandler crea una nueva instancia del handler de órdenes.
@@ -44,14 +48,6 @@ type successResponse struct {
 	Data interface{} `json:"data"`
 }
 
-// paginatedResponse estructura para respuestas paginadas.
-type paginatedResponse struct {
-	Data  interface{} `json:"data"`
-	Total int64       `json:"total"`
-	Page  int         `json:"page"`
-	Size  int         `json:"size"`
-}
-
 // respondWithError responde con un error JSON.
 func (h *OrderHandler) respondWithError(c *gin.Context, statusCode int, message string) {
 	log.Printf("Error Response: %d - %s", statusCode, message)
@@ -66,16 +62,6 @@ func (h *OrderHandler) respondWithSuccess(c *gin.Context, statusCode int, data i
 	c.JSON(statusCode, successResponse{Data: data})
 }
 
-// respondWithPaginated responde con datos paginados.
-func (h *OrderHandler) respondWithPaginated(c *gin.Context, statusCode int, data interface{}, total int64, page int, size int) {
-	c.JSON(statusCode, paginatedResponse{
-		Data:  data,
-		Total: total,
-		Page:  page,
-		Size:  size,
-	})
-}
-
 // ============================================================================
 // Validators
 // ============================================================================
@@ -110,17 +96,29 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Crear orden a través del servicio
-	order, err := h.orderService.CreateOrder(dto)
+	// Crear orden a través del pipeline de órdenes
+	result, err := h.orderService.CreateOrder(dto)
 	if err != nil {
 		log.Printf("Error al crear orden: %v", err)
+
+		// Stock insuficiente: indicar qué productos no alcanzan
+		var shortage *repositories.InsufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   http.StatusText(http.StatusConflict),
+				"message": err.Error(),
+				"items":   shortage.Items,
+			})
+			return
+		}
+
 		// Diferenciación de errores usando Contains para mayor flexibilidad
 		statusCode := http.StatusInternalServerError
 		errMsg := err.Error()
 
 		if strings.Contains(errMsg, "no se puede crear una orden sin items") {
 			statusCode = http.StatusBadRequest
-		} else if strings.Contains(errMsg, "stock insuficiente") {
+		} else if strings.Contains(errMsg, "stock insuficiente") || strings.Contains(errMsg, "precios del carrito cambiaron") {
 			statusCode = http.StatusConflict
 		} else if strings.Contains(errMsg, "producto no encontrado") {
 			statusCode = http.StatusNotFound
@@ -131,113 +129,8 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Orden creada exitosamente: %s", order.ID)
-	h.respondWithSuccess(c, http.StatusCreated, order)
-}
-
-// ============================================================================
-// GET /api/orders/:id - Obtener orden por ID
-// ============================================================================
-
-// GetOrderByID maneja GET /api/orders/:id.
-// Obtiene una orden específica por su ID UUID.
-func (h *OrderHandler) GetOrderByID(c *gin.Context) {
-	idParam := c.Param("id")
-	log.Printf("GET /api/orders/:id - Obtener orden: %s", idParam)
-
-	// Parsear UUID
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		log.Printf("ID UUID inválido: %s", idParam)
-		h.respondWithError(c, http.StatusBadRequest, "ID debe ser un UUID válido")
-		return
-	}
-
-	// Obtener orden del servicio
-	order, err := h.orderService.GetOrderByID(id)
-	if err != nil {
-		log.Printf("Error al obtener orden: %v", err)
-		h.respondWithError(c, http.StatusNotFound, err.Error())
-		return
-	}
-
-	log.Printf("Orden obtenida exitosamente: %s", id)
-	h.respondWithSuccess(c, http.StatusOK, order)
-}
-
-// ============================================================================
-// GET /api/orders/user/:userId - Obtener órdenes de usuario
-// ============================================================================
-
-// GetUserOrders maneja GET /api/orders/user/:userId.
-// Obtiene todas las órdenes de un usuario específico.
-func (h *OrderHandler) GetUserOrders(c *gin.Context) {
-	userIDParam := c.Param("userId")
-	log.Printf("GET /api/orders/user/:userId - Obtener órdenes del usuario: %s", userIDParam)
-
-	// Parsear UUID del usuario
-	userID, err := uuid.Parse(userIDParam)
-	if err != nil {
-		log.Printf("User ID UUID inválido: %s", userIDParam)
-		h.respondWithError(c, http.StatusBadRequest, "User ID debe ser un UUID válido")
-		return
-	}
-
-	// Obtener órdenes del servicio
-	orders, err := h.orderService.GetUserOrders(userID)
-	if err != nil {
-		log.Printf("Error al obtener órdenes del usuario: %v", err)
-		h.respondWithError(c, http.StatusInternalServerError, "Error al obtener órdenes")
-		return
-	}
-
-	// Si no hay órdenes, retornar lista vacía
-	if orders == nil {
-		orders = []models.Order{}
-	}
-
-	log.Printf("Órdenes del usuario obtenidas exitosamente: %s (%d órdenes)", userID, len(orders))
-	h.respondWithSuccess(c, http.StatusOK, orders)
-}
-
-// ============================================================================
-// GET /api/orders - Listar todas (admin)
-// ============================================================================
-
-// GetAllOrders maneja GET /api/orders.
-// Obtiene todas las órdenes con paginación.
-// Requiere permisos de administrador (middleware).
-func (h *OrderHandler) GetAllOrders(c *gin.Context) {
-	log.Printf("GET /api/orders - Listar todas las órdenes")
-
-	// Parsear parámetros de paginación con valores por defecto
-	page := 1
-	pageSize := 10
-
-	if pageParam := c.Query("page"); pageParam != "" {
-		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
-			page = p
-		} else {
-			log.Printf("Página inválida: %s, usando default: 1", pageParam)
-		}
-	}
-
-	if sizeParam := c.Query("pageSize"); sizeParam != "" {
-		if s, err := strconv.Atoi(sizeParam); err == nil && s > 0 && s <= 100 {
-			pageSize = s
-		} else {
-			log.Printf("Page size inválido: %s, usando default: 10", sizeParam)
-		}
-	}
-
-	log.Printf("Paginación: page=%d, pageSize=%d", page, pageSize)
-
-	// Obtener órdenes del repositorio a través del servicio
-	// Nota: El servicio actual no expone GetAll, pero podríamos agregarlo
-	// Por ahora usamos GetUserOrders con lógica adaptada
-	// TODO: Agregar método GetAllOrders al servicio
-
-	h.respondWithError(c, http.StatusNotImplemented, "Endpoint no implementado en el servicio")
+	log.Printf("Orden creada exitosamente: %s", result.Order.ID)
+	h.respondWithSuccess(c, http.StatusCreated, result.Order)
 }
 
 // ============================================================================
@@ -246,7 +139,8 @@ func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 
 // updateStatusRequest estructura para el body del request de actualización de estado.
 type updateStatusRequest struct {
-	Status string `json:"status" binding:"required,oneof=pending paid shipped cancelled"`
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
 }
 
 // UpdateOrderStatus maneja PUT /api/orders/:id/status.
@@ -274,12 +168,43 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	// Convertir string a OrderStatus
 	status := models.OrderStatus(req.Status)
 
+	// Cancelar tiene efectos (stock, sesión de pago, guía): se delega al servicio de cancelación
+	if status == models.StatusCancelled && h.cancellationService != nil {
+		result, err := h.cancellationService.CancelByAdmin(id, req.Reason, c.GetString("user_id"))
+		if err != nil {
+			log.Printf("Error al cancelar orden: %v", err)
+			statusCode := http.StatusInternalServerError
+			if strings.Contains(err.Error(), "orden no encontrada") {
+				statusCode = http.StatusNotFound
+			} else if strings.Contains(err.Error(), "no se puede cancelar") || strings.Contains(err.Error(), "transición de estado no permitida") {
+				statusCode = http.StatusConflict
+			} else if strings.Contains(err.Error(), "anular guía") || strings.Contains(err.Error(), "Stripe") {
+				statusCode = http.StatusBadGateway
+			}
+			h.respondWithError(c, statusCode, err.Error())
+			return
+		}
+
+		log.Printf("Orden %s cancelada por admin", id)
+		h.respondWithSuccess(c, http.StatusOK, result.Order)
+		return
+	}
+
 	// Actualizar estado a través del servicio
-	if err := h.orderService.UpdateOrderStatus(id, status); err != nil {
+	change := models.StatusChange{
+		ActorType: models.ActorAdmin,
+		ActorID:   c.GetString("user_id"),
+		Reason:    req.Reason,
+	}
+	if err := h.orderService.UpdateOrderStatus(id, status, change); err != nil {
 		log.Printf("Error al actualizar estado de orden: %v", err)
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "orden no encontrada" {
+		if strings.Contains(err.Error(), "orden no encontrada") {
 			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "estado de orden inválido") {
+			statusCode = http.StatusBadRequest
+		} else if strings.Contains(err.Error(), "transición de estado no permitida") {
+			statusCode = http.StatusConflict
 		}
 		h.respondWithError(c, statusCode, err.Error())
 		return
@@ -326,7 +251,7 @@ func (h *OrderHandler) CalculateShipping(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Costo de envío calculado para %s: %.2f", req.Municipality, cost)
+	log.Printf("Costo de envío calculado para %s: %s", req.Municipality, cost)
 
 	response := gin.H{
 		"municipality": req.Municipality,
@@ -339,29 +264,29 @@ func (h *OrderHandler) CalculateShipping(c *gin.Context) {
 // Router Setup
 // ============================================================================
 
-// RegisterRoutes registra todas las rutas de órdenes en el router de Gin.
-func RegisterOrderRoutes(router *gin.Engine, orderService services.OrderService) {
+// RegisterOrderRoutes registra las rutas públicas de órdenes en el grupo /api/v1.
+// middlewares se aplican a la creación de órdenes (ej: Idempotency-Key).
+func RegisterOrderRoutes(api *gin.RouterGroup, orderService services.OrderService, middlewares ...gin.HandlerFunc) {
 	handler := NewOrderHandler(orderService)
 
 	// Grupo de rutas de órdenes
-	orders := router.Group("/api/orders")
+	orders := api.Group("/orders")
 	{
-		// POST /api/orders - Crear orden
-		orders.POST("", handler.CreateOrder)
-
-		// GET /api/orders/:id - Obtener orden por ID
-		orders.GET("/:id", handler.GetOrderByID)
-
-		// GET /api/orders/user/:userId - Obtener órdenes de usuario
-		orders.GET("/user/:userId", handler.GetUserOrders)
+		// POST /api/v1/orders - Crear orden
+		orders.POST("", append(middlewares, handler.CreateOrder)...)
 
-		// PUT /api/orders/:id/status - Actualizar estado
-		orders.PUT("/:id/status", handler.UpdateOrderStatus)
-
-		// POST /api/orders/calculate-shipping - Calcular envío
+		// POST /api/v1/orders/calculate-shipping - Calcular envío
 		orders.POST("/calculate-shipping", handler.CalculateShipping)
-
-		// GET /api/orders - Listar todas (admin)
-		// orders.GET("", handler.GetAllOrders) // TODO: Implementar con middleware auth
 	}
 }
+
+// RegisterAdminOrderRoutes registra las rutas de órdenes que requieren rol admin.
+// admin debe ser un grupo protegido con AdminAuthMiddleware.
+// cancellationService aplica los efectos de cancelar (stock, sesión de pago, guía).
+func RegisterAdminOrderRoutes(admin *gin.RouterGroup, orderService services.OrderService, cancellationService services.OrderCancellationService) {
+	handler := NewOrderHandler(orderService)
+	handler.cancellationService = cancellationService
+
+	// PUT /api/v1/admin/orders/:id/status - Actualizar estado (valida la máquina de estados)
+	admin.PUT("/orders/:id/status", handler.UpdateOrderStatus)
+}
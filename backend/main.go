@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -10,8 +11,11 @@ import (
 
 	"moda-organica/backend/controllers"
 	"moda-organica/backend/db"
+	"moda-organica/backend/handlers"
 	"moda-organica/backend/middleware"
 	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
+	"moda-organica/backend/services"
 )
 
 func main() {
@@ -38,18 +42,55 @@ func main() {
 
 	// Migrar los modelos
 	if gormDB != nil {
-		gormDB.AutoMigrate(&models.Product{}, &models.Order{}, &models.OrderItem{})
-		log.Println("Modelos migrados exitosamente")
+		// La restricción chk_products_stock_non_negative no se puede crear si hay
+		// productos con stock negativo (sobreventas anteriores): se dejan en 0
+		if gormDB.Migrator().HasTable(&models.Product{}) {
+			result := gormDB.Model(&models.Product{}).Where("stock < 0").Update("stock", 0)
+			if result.Error != nil {
+				log.Printf("No se pudo corregir stock negativo: %v", result.Error)
+			} else if result.RowsAffected > 0 {
+				log.Printf("Productos con stock negativo corregidos a 0: %d", result.RowsAffected)
+			}
+		}
+
+		if err := models.Migrate(gormDB); err != nil {
+			log.Printf("Error al migrar modelos: %v", err)
+		} else {
+			log.Println("Modelos migrados exitosamente")
+
+			// Órdenes anteriores a order_number: se numeran en orden de creación
+			if numbered, err := models.BackfillOrderNumbers(gormDB); err != nil {
+				log.Printf("No se pudieron numerar órdenes existentes: %v", err)
+			} else if numbered > 0 {
+				log.Printf("Órdenes existentes numeradas: %d", numbered)
+			}
+
+			// Órdenes contra entrega ya entregadas: cuentan como venta desde que se cobraron
+			if collected, err := models.BackfillCashOnDeliveryPaidAt(gormDB); err != nil {
+				log.Printf("No se pudo registrar el cobro de órdenes contra entrega: %v", err)
+			} else if collected > 0 {
+				log.Printf("Órdenes contra entrega marcadas como cobradas: %d", collected)
+			}
+
+			// Tabla de tarifas de envío: arranca con las tarifas que antes estaban en el código
+			if seeded, err := models.SeedShippingRates(gormDB); err != nil {
+				log.Printf("No se pudieron cargar las tarifas de envío por defecto: %v", err)
+			} else if seeded > 0 {
+				log.Printf("Tarifas de envío por defecto creadas: %d", seeded)
+			}
+		}
 	}
 
-	// Crea una instancia del router Gin
-	router := gin.Default()
+	// Crea una instancia del router Gin (logger y recuperación de pánicos;
+	// middleware.Recovery deja que las descargas fallidas corten la conexión)
+	router := gin.New()
+	router.Use(gin.Logger(), middleware.Recovery())
 
 	// Configuración CORS
 	config := cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:4173", "http://127.0.0.1:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", middleware.IdempotencyKeyHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -67,17 +108,97 @@ func main() {
 	// Instancia el controlador de productos
 	pc := controllers.NewProductController()
 
+	// Pipeline único de creación de órdenes (POST /orders y checkout)
+	orderService := services.NewOrderService(repositories.NewOrderRepository(gormDB), repositories.NewProductRepository(gormDB))
+
+	// Clientes externos compartidos: una sola instancia de Cargo Expreso y de las
+	// pasarelas de pago (elegidas según STRIPE_MOCK) para todos los servicios
+	cargo := services.NewCargoExpresoService()
+	gateways := services.NewPaymentGatewaysFromEnv()
+
+	// Tiempo que una orden puede quedar pendiente de pago: vence la sesión de
+	// pago y el reconciliador cancela la orden
+	pendingOrderTTL := services.PendingOrderTTLFromEnv()
+
 	// Instancia el controlador de pedidos
 	var orderController *controllers.OrderController
+	var cancellationService services.OrderCancellationService
+	var returnController *controllers.ReturnController
+	var orderAccessController *controllers.OrderAccessController
+	var orderBulkController *controllers.OrderBulkController
+	var orderExportController *controllers.OrderExportController
+	var analyticsController *controllers.AnalyticsController
+	var shippingRateController *controllers.ShippingRateController
+	var deliveryZoneController *controllers.DeliveryZoneController
+	var shipmentTrackingController *controllers.ShipmentTrackingController
+	var idempotencyStore *services.IdempotencyStore
 	if gormDB != nil {
-		orderController = controllers.NewOrderController(gormDB)
+		// Tarifas de envío desde la BD (con caché): un cambio de tarifa no requiere redeploy
+		shippingRateStore := services.NewShippingRateStoreFromEnv(gormDB)
+		services.SetShippingRateStore(shippingRateStore)
+		shippingRateController = controllers.NewShippingRateController(services.NewShippingRateService(gormDB, shippingRateStore))
+
+		// Zonas de entrega por coordenadas (se cargan con el store de tarifas)
+		deliveryZoneService := services.NewDeliveryZoneService(gormDB, shippingRateStore)
+		deliveryZoneController = controllers.NewDeliveryZoneController(deliveryZoneService)
+		if path := os.Getenv("DELIVERY_ZONES_GEOJSON"); path != "" {
+			if seeded, err := services.SeedDeliveryZones(gormDB, deliveryZoneService, path); err != nil {
+				log.Printf("No se pudieron cargar las zonas de entrega de %s: %v", path, err)
+			} else if seeded > 0 {
+				log.Printf("Zonas de entrega creadas desde %s: %d", path, seeded)
+			}
+		}
+
+		// Idempotency-Key: evita órdenes duplicadas por doble clic o reintentos
+		idempotencyStore = services.NewIdempotencyStoreFromEnv(gormDB)
+		go idempotencyStore.Start(make(chan struct{}))
+
+		// Reconciliador: cancela órdenes pendientes vencidas y libera su stock
+		reconciler := services.NewOrderReconcilerFromEnv(gormDB, gateways, pendingOrderTTL)
+		go reconciler.Start(make(chan struct{}))
+
+		// Enlaces de consulta firmados: los invitados los usan para ver, cancelar o devolver su orden
+		orderAccessService := services.NewOrderAccessServiceFromEnv(gormDB, services.NewEmailService())
+
+		// Cancelación de órdenes (cliente y admin): stock, sesión de pago o reembolso, guía
+		cancellationService = services.NewOrderCancellationServiceFromEnv(gormDB, gateways, cargo, orderAccessService)
+
+		// Edición de órdenes por el admin antes del envío
+		editService := services.NewOrderEditService(gormDB, gateways, cargo)
+
+		orderController = controllers.NewOrderController(gormDB, gateways, reconciler, cancellationService, editService)
+
+		// Rastreo de guías: guarda los eventos de Cargo Expreso y pasa las órdenes a shipped/delivered
+		trackingPoller := services.NewTrackingPollerFromEnv(gormDB, cargo)
+		go trackingPoller.Start(make(chan struct{}))
+		shipmentTrackingController = controllers.NewShipmentTrackingController(trackingPoller)
+
+		// Devoluciones y cambios (RMA)
+		returnController = controllers.NewReturnController(
+			services.NewReturnServiceFromEnv(gormDB, gateways, cargo, orderAccessService),
+		)
+
+		// Operaciones en lote del admin (cambio de estado, guías, hojas de empaque, cancelación)
+		orderBulkController = controllers.NewOrderBulkController(
+			services.NewOrderBulkServiceFromEnv(gormDB, orderService, cancellationService, cargo),
+		)
+
+		// Exportación de órdenes a CSV/XLSX para contabilidad
+		orderExportController = controllers.NewOrderExportController(services.NewOrderExportService(gormDB))
+
+		// Métricas de ventas del dashboard
+		analyticsController = controllers.NewAnalyticsController(services.NewSalesAnalyticsService(gormDB))
+
+		// Consulta de órdenes de invitados con enlaces firmados enviados por email
+		orderAccessController = controllers.NewOrderAccessController(orderAccessService)
 		log.Println("OrderController inicializado exitosamente")
 	} else {
 		log.Println("Advertencia: GORM no está disponible, OrderController no inicializado")
 	}
 
 	// Instancia el controlador de pagos con inyección de dependencias
-	paymentController := controllers.NewPaymentController()
+	paymentController := controllers.NewPaymentController(orderService, gateways, cargo, services.NewRefundService(gormDB, gateways), pendingOrderTTL)
+	geoController := controllers.NewGeoController()
 
 	// Define las rutas de la API v1
 	apiV1 := router.Group("/api/v1")
@@ -93,16 +214,42 @@ func main() {
 		}
 
 		// Rutas para pedidos
-		if orderController != nil {
-			apiV1.POST("/orders", orderController.CreateOrder)
+		if gormDB != nil {
+			handlers.RegisterOrderRoutes(apiV1, orderService, middleware.IdempotencyMiddleware(idempotencyStore))
 			log.Println("Endpoint POST /api/v1/orders registrado exitosamente")
+
+			// Cancelación por el cliente (JWT opcional; los invitados confirman con su email)
+			apiV1.POST("/orders/:id/cancel", middleware.OptionalCustomerAuth(), orderController.CancelOrder)
+			log.Println("Endpoint POST /api/v1/orders/:id/cancel registrado exitosamente")
+
+			// Solicitud de devolución (misma identificación que la cancelación)
+			apiV1.POST("/orders/:id/returns", middleware.OptionalCustomerAuth(), returnController.CreateReturn)
+			log.Println("Endpoint POST /api/v1/orders/:id/returns registrado exitosamente")
+
+			// Consulta de órdenes de invitados (enlace mágico por email)
+			apiV1.POST("/orders/lookup", orderAccessController.RequestOrderLink)
+			apiV1.GET("/orders/access/:token", orderAccessController.GetOrderByLink)
+			apiV1.DELETE("/orders/access/:token", orderAccessController.RevokeOrderLink)
+			log.Println("Endpoints de consulta de órdenes por enlace registrados exitosamente")
+		}
+
+		// Catálogo de departamentos y municipios (selectores del checkout)
+		geo := apiV1.Group("/geo")
+		{
+			geo.GET("/departments", geoController.GetDepartments)
+			geo.GET("/departments/:code/municipalities", geoController.GetMunicipalities)
 		}
 
 		// Rutas para pagos con Stripe
 		payments := apiV1.Group("/payments")
 		{
-			payments.POST("/create-checkout-session", paymentController.CreateCheckoutSession)
+			payments.GET("/methods", paymentController.GetPaymentMethods)
+			payments.POST("/create-checkout-session", middleware.IdempotencyMiddleware(idempotencyStore), paymentController.CreateCheckoutSession)
 			log.Println("Endpoint POST /api/v1/payments/create-checkout-session registrado exitosamente")
+
+			// Webhook de Stripe (verificado por firma, no requiere autenticación)
+			payments.POST("/webhook", paymentController.StripeWebhook)
+			log.Println("Endpoint POST /api/v1/payments/webhook registrado exitosamente")
 		}
 	}
 
@@ -112,10 +259,53 @@ func main() {
 	{
 		// Gestión de Órdenes
 		admin.GET("/orders", orderController.AdminGetOrders)
+		admin.GET("/orders/export", orderExportController.AdminExportOrders)
 		admin.GET("/orders/:id", orderController.AdminGetOrderByID)
+		admin.PATCH("/orders/:id", orderController.AdminEditOrder)
 		admin.GET("/orders/stats", orderController.AdminGetOrdersStats)
 		admin.GET("/orders/map", orderController.AdminGetOrdersMap)
+		admin.POST("/orders/:id/refunds", orderController.AdminCreateRefund)
+		admin.POST("/orders/expire-pending", orderController.AdminExpirePendingOrders)
+		admin.POST("/orders/poll-tracking", shipmentTrackingController.AdminPollTracking)
+		admin.POST("/orders/:id/poll-tracking", shipmentTrackingController.AdminPollOrderTracking)
+		admin.POST("/orders/bulk", orderBulkController.AdminBulkOrders)
+		admin.POST("/orders/:id/revoke-links", orderAccessController.AdminRevokeOrderLinks)
+		handlers.RegisterAdminOrderRoutes(admin, orderService, cancellationService)
 		log.Println("Rutas de administración de órdenes registradas exitosamente")
+
+		// Gestión de Devoluciones (RMA)
+		admin.GET("/returns", returnController.AdminGetReturns)
+		admin.GET("/returns/:id", returnController.AdminGetReturnByID)
+		admin.POST("/returns/:id/approve", returnController.AdminApproveReturn)
+		admin.POST("/returns/:id/reject", returnController.AdminRejectReturn)
+		admin.POST("/returns/:id/receive", returnController.AdminReceiveReturn)
+		admin.POST("/returns/:id/resolve", returnController.AdminResolveReturn)
+		log.Println("Rutas de administración de devoluciones registradas exitosamente")
+
+		// Tarifas de envío
+		admin.GET("/shipping-rates", shippingRateController.AdminGetShippingRates)
+		admin.GET("/shipping-rates/quote", shippingRateController.AdminQuoteShipping)
+		admin.GET("/shipping-rates/:id", shippingRateController.AdminGetShippingRate)
+		admin.POST("/shipping-rates", shippingRateController.AdminCreateShippingRate)
+		admin.PUT("/shipping-rates/:id", shippingRateController.AdminUpdateShippingRate)
+		admin.DELETE("/shipping-rates/:id", shippingRateController.AdminDeleteShippingRate)
+
+		// Zonas de entrega por coordenadas (GeoJSON)
+		admin.GET("/delivery-zones", deliveryZoneController.AdminGetDeliveryZones)
+		admin.GET("/delivery-zones/export", deliveryZoneController.AdminExportDeliveryZones)
+		admin.GET("/delivery-zones/locate", deliveryZoneController.AdminLocateDeliveryZone)
+		admin.GET("/delivery-zones/:id", deliveryZoneController.AdminGetDeliveryZone)
+		admin.POST("/delivery-zones", deliveryZoneController.AdminCreateDeliveryZone)
+		admin.POST("/delivery-zones/import", deliveryZoneController.AdminImportDeliveryZones)
+		admin.PUT("/delivery-zones/:id", deliveryZoneController.AdminUpdateDeliveryZone)
+		admin.DELETE("/delivery-zones/:id", deliveryZoneController.AdminDeleteDeliveryZone)
+		admin.GET("/orders/location-mismatches", deliveryZoneController.AdminGetLocationMismatches)
+
+		// Analítica de ventas (dashboard)
+		admin.GET("/analytics/summary", analyticsController.AdminSalesSummary)
+		admin.GET("/analytics/sales", analyticsController.AdminSalesTimeSeries)
+		admin.GET("/analytics/top-products", analyticsController.AdminTopProducts)
+		admin.GET("/analytics/regions", analyticsController.AdminSalesByRegion)
 	}
 
 	// Inicia el servidor
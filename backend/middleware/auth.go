@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // AuthRequired middleware para rutas que requieren autenticación
@@ -70,3 +73,62 @@ func AdminRequired() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalCustomerAuth valida el JWT de Supabase si la solicitud lo trae y guarda
+// el user_id (claim "sub") en el contexto. Sin header Authorization la solicitud
+// continúa como invitado; un token inválido se rechaza con 401.
+func OptionalCustomerAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Formato de token inválido. Usa: Authorization: Bearer <token>",
+			})
+			c.Abort()
+			return
+		}
+
+		jwtSecret := os.Getenv("SUPABASE_JWT_SECRET")
+		if jwtSecret == "" {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "SUPABASE_JWT_SECRET no configurado",
+			})
+			c.Abort()
+			return
+		}
+
+		token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
+			}
+			return []byte(jwtSecret), nil
+		})
+		if err != nil || !token.Valid {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token inválido o expirado",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Claims inválidos",
+			})
+			c.Abort()
+			return
+		}
+
+		userID, _ := claims["sub"].(string)
+		c.Set("user_id", userID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"moda-organica/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader es el header con el que el cliente identifica un intento.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayHeader marca las respuestas devueltas desde el store.
+	idempotentReplayHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength limita el tamaño del header.
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyPersistedKey marca en el contexto que el handler ya guardó
+	// cambios (ver MarkIdempotentPersisted).
+	idempotencyPersistedKey = "idempotency_persisted"
+)
+
+// MarkIdempotentPersisted indica que la solicitud ya guardó cambios que no se
+// revierten (ej: la orden con su stock reservado). Si después responde 5xx, la
+// respuesta se guarda para la key en lugar de liberarla: un reintento no debe
+// crear otra orden.
+func MarkIdempotentPersisted(c *gin.Context) {
+	c.Set(idempotencyPersistedKey, true)
+}
+
+// responseRecorder copia el body de la respuesta mientras se escribe al cliente
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware hace idempotentes las solicitudes que traen Idempotency-Key.
+// - Primera solicitud: se procesa y se guarda la respuesta junto al hash del body.
+// - Reintento con el mismo body: se devuelve la respuesta original.
+// - Misma key con otro body: 409 Conflict.
+// - Reintento mientras la original sigue en proceso: 409 Conflict.
+// - Reintento cuando venció la reserva de la original (IDEMPOTENCY_LOCK_TIMEOUT): se procesa.
+// Las respuestas 5xx no se guardan, para que el cliente pueda reintentar, salvo
+// que el handler ya haya guardado cambios (MarkIdempotentPersisted).
+// Sin el header, la solicitud se procesa normalmente.
+func IdempotencyMiddleware(store *services.IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || store == nil {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key no puede superar 255 caracteres",
+			})
+			c.Abort()
+			return
+		}
+
+		// Leer el body para calcular el hash y restaurarlo para el handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "No se pudo leer el body de la solicitud",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+		endpoint := c.Request.Method + " " + c.FullPath()
+
+		existing, err := store.Claim(key, endpoint, requestHash)
+		if err != nil {
+			log.Printf("Error de idempotencia (%s): %v", endpoint, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error al verificar Idempotency-Key",
+			})
+			c.Abort()
+			return
+		}
+
+		if existing != nil {
+			if existing.RequestHash != requestHash {
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "Idempotency-Key ya fue usada con una solicitud diferente",
+				})
+				c.Abort()
+				return
+			}
+			if !existing.Completed {
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "La solicitud original con esta Idempotency-Key aún se está procesando",
+				})
+				c.Abort()
+				return
+			}
+
+			// Devolver la respuesta original
+			c.Header(idempotentReplayHeader, "true")
+			c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError && !c.GetBool(idempotencyPersistedKey) {
+			if err := store.Release(key, endpoint); err != nil {
+				log.Printf("No se pudo liberar Idempotency-Key %s: %v", key, err)
+			}
+			return
+		}
+
+		if err := store.Complete(key, endpoint, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("No se pudo guardar respuesta para Idempotency-Key %s: %v", key, err)
+		}
+	}
+}
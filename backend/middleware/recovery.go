@@ -0,0 +1,24 @@
+// backend/middleware/recovery.go
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery reemplaza a gin.Recovery: registra el pánico y responde 500.
+// http.ErrAbortHandler se deja pasar a net/http, que corta la conexión sin
+// terminar la respuesta: así una descarga que falla a mitad del archivo (ej:
+// la exportación de órdenes) se ve como fallida y no como un archivo completo.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err interface{}) {
+		if err == http.ErrAbortHandler {
+			panic(err)
+		}
+		log.Printf("[Recovery] pánico en %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, err, debug.Stack())
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Recovery())
+	router.GET("/panic", func(c *gin.Context) {
+		panic("error inesperado")
+	})
+	router.GET("/partial", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("numero,total\n")
+		c.Writer.Flush()
+		panic(http.ErrAbortHandler)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	t.Run("un pánico responde 500", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/panic")
+		if err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("status = %d, se esperaba 500", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrAbortHandler corta la descarga", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/partial")
+		if err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, se esperaba 200", resp.StatusCode)
+		}
+		if _, err := io.ReadAll(resp.Body); err == nil {
+			t.Fatal("se esperaba un error al leer la respuesta cortada, el cliente la recibió completa")
+		}
+	})
+}
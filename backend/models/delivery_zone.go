@@ -0,0 +1,81 @@
+// backend/models/delivery_zone.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ZoneGeometry es la geometría GeoJSON de una zona de entrega: "Polygon" o
+// "MultiPolygon". Las coordenadas van en orden GeoJSON: [longitud, latitud].
+// Se guarda tal cual; services la interpreta y valida.
+type ZoneGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// DeliveryZone es un polígono de entrega. Si el pin de entrega de una orden
+// (DeliveryLat/DeliveryLng) cae dentro de la zona, el envío usa su método y
+// costo en lugar de la tabla de tarifas por municipio.
+type DeliveryZone struct {
+	// ID: Identificador único de la zona, clave primaria.
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Name: Nombre único de la zona (ej: "Huehuetenango - casco urbano").
+	// Se usa para actualizar zonas al importar GeoJSON.
+	Name string `json:"name" gorm:"type:varchar(100);not null;uniqueIndex"`
+
+	// Department / Municipality: Municipio que cubre la zona (código de
+	// departamento y nombre oficial). Sirve para detectar órdenes cuyo pin no
+	// coincide con el municipio declarado. Vacío = zona sin municipio.
+	Department   string `json:"department" gorm:"type:varchar(50);index"`
+	Municipality string `json:"municipality" gorm:"type:varchar(100);index"`
+
+	// Method: 'local_delivery' | 'cargo_expreso'. Define si la orden requiere courier.
+	Method string `json:"method" gorm:"type:varchar(50);not null"`
+
+	// Cost: Costo del envío dentro de la zona.
+	Cost Money `json:"cost" gorm:"type:bigint;not null"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
+
+	// Geometry: Polígono GeoJSON de la zona.
+	Geometry ZoneGeometry `json:"geometry" gorm:"type:jsonb;serializer:json;not null"`
+
+	// Active: Permite desactivar una zona sin borrarla.
+	Active bool `json:"active" gorm:"default:true;index"`
+
+	// --- Timestamps ---
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime:milli"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo DeliveryZone.
+func (DeliveryZone) TableName() string {
+	return "delivery_zones"
+}
+
+// BeforeSave es un hook de GORM que guarda en currency la moneda de su costo.
+// Falla si los montos son de monedas distintas.
+func (z *DeliveryZone) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: z.Currency}, z.Cost)
+	if err != nil {
+		return err
+	}
+	z.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (z *DeliveryZone) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(z.Currency, &z.Cost)
+	return nil
+}
+
+// RequiresCourier indica si los envíos dentro de la zona van por Cargo Expreso.
+func (z *DeliveryZone) RequiresCourier() bool {
+	return z.Method == ShippingMethodCargoExpreso
+}
@@ -0,0 +1,50 @@
+// backend/models/idempotency_key.go
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey guarda la respuesta de una solicitud enviada con el header
+// Idempotency-Key. Si el cliente reintenta (doble clic, red móvil inestable),
+// se devuelve la respuesta original en lugar de crear otra orden.
+type IdempotencyKey struct {
+	// Key: Valor del header Idempotency-Key enviado por el cliente.
+	Key string `json:"key" gorm:"type:varchar(255);primaryKey"`
+
+	// Endpoint: Método y ruta de la solicitud (ej: "POST /api/v1/orders").
+	// La misma key puede usarse en endpoints distintos sin conflicto.
+	Endpoint string `json:"endpoint" gorm:"type:varchar(255);primaryKey"`
+
+	// RequestHash: SHA-256 del body de la solicitud original.
+	// Si un reintento trae otro body con la misma key, se rechaza con 409.
+	RequestHash string `json:"request_hash" gorm:"type:varchar(64);not null"`
+
+	// Completed: false mientras la solicitud original se está procesando.
+	Completed bool `json:"completed" gorm:"default:false"`
+
+	// LockedUntil: Vencimiento de la reserva de una solicitud en proceso. Si el
+	// proceso murió sin completar ni liberar la key, un reintento con el mismo
+	// body la retoma después de este momento.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+
+	// StatusCode: Código HTTP de la respuesta original.
+	StatusCode int `json:"status_code"`
+
+	// ContentType: Content-Type de la respuesta original.
+	ContentType string `json:"content_type" gorm:"type:varchar(100)"`
+
+	// ResponseBody: Body de la respuesta original.
+	ResponseBody []byte `json:"-" gorm:"type:bytea"`
+
+	// CreatedAt: Timestamp automático de creación del registro.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+
+	// ExpiresAt: A partir de este momento la key se puede reutilizar y se elimina.
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo IdempotencyKey.
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
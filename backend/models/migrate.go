@@ -0,0 +1,108 @@
+// backend/models/migrate.go
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// AllModels retorna todos los modelos persistidos, en el orden en que se migran.
+// main.go y las pruebas contra la base de datos migran esta misma lista, así una
+// tabla nueva no se queda fuera de alguna de las dos.
+func AllModels() []interface{} {
+	return []interface{}{
+		&Product{},
+		&Order{},
+		&OrderItem{},
+		&StripeEvent{},
+		&Refund{},
+		&RefundItem{},
+		&IdempotencyKey{},
+		&OrderStatusEvent{},
+		&ReturnRequest{},
+		&ReturnItem{},
+		&OrderAccessToken{},
+		&OrderNumberCounter{},
+		&OrderEdit{},
+		&ShippingRate{},
+		&DeliveryZone{},
+		&ShipmentEvent{},
+	}
+}
+
+// moneyColumns lista las columnas de montos por modelo. Antes se guardaban como
+// decimal(10,2) en quetzales; ahora son BIGINT de centavos (ver Money).
+var moneyColumns = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&Product{}, []string{"price"}},
+	{&Order{}, []string{"subtotal", "shipping_cost", "total", "refunded_amount", "balance_due"}},
+	{&OrderItem{}, []string{"price"}},
+	{&Refund{}, []string{"amount", "shipping_amount"}},
+	{&RefundItem{}, []string{"amount"}},
+	{&OrderEdit{}, []string{"previous_total", "new_total", "balance_change"}},
+	{&ShippingRate{}, []string{"base_cost", "home_delivery_surcharge", "pickup_surcharge"}},
+	{&DeliveryZone{}, []string{"cost"}},
+}
+
+// Migrate migra todos los modelos (AllModels). Antes convierte a centavos las
+// columnas de montos que siguen en decimal: AutoMigrate solo cambiaría el tipo
+// y Q40.50 quedaría como 41 centavos.
+func Migrate(db *gorm.DB) error {
+	if err := convertMoneyColumns(db); err != nil {
+		return err
+	}
+	return db.AutoMigrate(AllModels()...)
+}
+
+// convertMoneyColumns pasa cada columna decimal de montos a BIGINT de centavos
+// en la misma sentencia, así un fallo no deja la columna a medio convertir.
+func convertMoneyColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, table := range moneyColumns {
+		if !migrator.HasTable(table.model) {
+			continue
+		}
+		columnTypes, err := migrator.ColumnTypes(table.model)
+		if err != nil {
+			return fmt.Errorf("error al leer columnas: %w", err)
+		}
+
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(table.model); err != nil {
+			return fmt.Errorf("error al leer modelo: %w", err)
+		}
+
+		for _, columnType := range columnTypes {
+			if !containsColumn(table.columns, columnType.Name()) || !isDecimalType(columnType.DatabaseTypeName()) {
+				continue
+			}
+			sql := fmt.Sprintf("ALTER TABLE %q ALTER COLUMN %q TYPE bigint USING ROUND(%q * 100)::bigint",
+				stmt.Schema.Table, columnType.Name(), columnType.Name())
+			if err := db.Exec(sql).Error; err != nil {
+				return fmt.Errorf("error al convertir %s.%s a centavos: %w", stmt.Schema.Table, columnType.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func isDecimalType(databaseType string) bool {
+	switch strings.ToLower(databaseType) {
+	case "numeric", "decimal":
+		return true
+	}
+	return false
+}
+
+func containsColumn(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+// backend/models/money.go
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// DefaultCurrency es la moneda de la tienda: quetzales guatemaltecos (ISO 4217).
+const DefaultCurrency = "GTQ"
+
+// Money representa un monto de dinero como un entero de centavos más el código
+// de moneda. Usar enteros evita los errores de redondeo de float64 (ej: Q19.99 * 3)
+// y garantiza que los totales cuadren exactamente con los pagos de Stripe,
+// que también maneja montos en centavos.
+//
+// Representaciones:
+//   - JSON: número decimal en quetzales con dos decimales (ej: 40.50), compatible
+//     con el formato que ya consume el frontend.
+//   - SQL: BIGINT de centavos. La moneda se guarda en la columna currency de
+//     cada tabla; el modelo la asigna a sus montos al leerlos (AfterFind) y la
+//     toma de ellos al guardarlos (BeforeSave), ver ApplyCurrency y CommonCurrency.
+type Money struct {
+	// Amount: Monto en centavos (Q40.50 = 4050).
+	Amount int64
+
+	// Currency: Código ISO 4217 de la moneda (ej: "GTQ").
+	Currency string
+}
+
+// ErrCurrencyMismatch indica una operación entre montos de monedas distintas.
+var ErrCurrencyMismatch = errors.New("operación entre monedas distintas")
+
+// NewMoney crea un monto en quetzales a partir de centavos.
+func NewMoney(cents int64) Money {
+	return Money{Amount: cents, Currency: DefaultCurrency}
+}
+
+// ParseMoney interpreta un monto decimal en quetzales (ej: "40.5", "36", "1e2").
+// Los montos con más de dos decimales se redondean al centavo más cercano.
+func ParseMoney(s string) (Money, error) {
+	return parseScaled(s, 100)
+}
+
+// ParseCents interpreta un monto en centavos (ej: "4050"), como lo devuelven
+// las columnas BIGINT y sus agregados (SUM retorna NUMERIC, AVG con decimales).
+// Las fracciones de centavo se redondean.
+func ParseCents(s string) (Money, error) {
+	return parseScaled(s, 1)
+}
+
+// parseScaled interpreta un número decimal, lo multiplica por scale y lo
+// redondea a centavos (mitad lejos de cero) sin pasar por float64.
+func parseScaled(s string, scale int64) (Money, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("monto inválido: %q", s)
+	}
+
+	r.Mul(r, big.NewRat(scale, 1))
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+
+	if !quo.IsInt64() {
+		return Money{}, fmt.Errorf("monto fuera de rango: %q", s)
+	}
+	return NewMoney(quo.Int64()), nil
+}
+
+// Cents retorna el monto en centavos (formato que espera Stripe).
+func (m Money) Cents() int64 {
+	return m.Amount
+}
+
+// Float64 retorna el monto en quetzales. Solo para mostrar o registrar en logs;
+// nunca debe usarse para hacer cálculos.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / 100
+}
+
+// CurrencyCode retorna el código de moneda, usando DefaultCurrency si está vacío.
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// Add suma dos montos de la misma moneda. Retorna ErrCurrencyMismatch si
+// las monedas son distintas.
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.mergeCurrency(other)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: currency}, nil
+}
+
+// Sub resta dos montos de la misma moneda. Retorna ErrCurrencyMismatch si
+// las monedas son distintas.
+func (m Money) Sub(other Money) (Money, error) {
+	currency, err := m.mergeCurrency(other)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: currency}, nil
+}
+
+// Mul multiplica el monto por una cantidad entera (precio unitario * unidades).
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.CurrencyCode()}
+}
+
+// IsZero indica si el monto es cero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative indica si el monto es menor a cero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// IsPositive indica si el monto es mayor a cero.
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Equal compara monto y moneda.
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.CurrencyCode() == other.CurrencyCode()
+}
+
+// GreaterThan indica si el monto es mayor que otro de la misma moneda.
+func (m Money) GreaterThan(other Money) bool {
+	return m.Amount > other.Amount
+}
+
+// Decimal retorna el monto en quetzales con dos decimales (ej: "40.50").
+func (m Money) Decimal() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// String retorna el monto con símbolo de moneda (ej: "Q40.50").
+func (m Money) String() string {
+	if m.CurrencyCode() == DefaultCurrency {
+		return "Q" + m.Decimal()
+	}
+	return m.Decimal() + " " + m.CurrencyCode()
+}
+
+// mergeCurrency retorna la moneda común de ambos montos. Un monto sin moneda
+// (ej: un agregado leído de la BD) toma la del otro.
+func (m Money) mergeCurrency(other Money) (string, error) {
+	switch {
+	case m.Currency == "":
+		return other.CurrencyCode(), nil
+	case other.Currency == "", m.Currency == other.Currency:
+		return m.Currency, nil
+	default:
+		return "", fmt.Errorf("%w: %s y %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+}
+
+// CommonCurrency retorna la moneda de los montos (DefaultCurrency si ninguno
+// la indica). Los modelos la usan para llenar su columna currency al guardar.
+func CommonCurrency(amounts ...Money) (string, error) {
+	currency := ""
+	for _, amount := range amounts {
+		switch {
+		case amount.Currency == "", amount.Currency == currency:
+		case currency == "":
+			currency = amount.Currency
+		default:
+			return "", fmt.Errorf("%w: %s y %s", ErrCurrencyMismatch, currency, amount.Currency)
+		}
+	}
+	if currency == "" {
+		return DefaultCurrency, nil
+	}
+	return currency, nil
+}
+
+// ApplyCurrency asigna a los montos la moneda de la columna currency de su
+// fila. Si la fila no tiene moneda (ej: consulta con columnas parciales) los
+// montos quedan en DefaultCurrency.
+func ApplyCurrency(currency string, amounts ...*Money) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	for _, amount := range amounts {
+		amount.Currency = currency
+	}
+}
+
+// ============================================================================
+// JSON
+// ============================================================================
+
+// MarshalJSON serializa el monto como número decimal en quetzales (ej: 40.50).
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON acepta un número (40.5) o un string ("40.50") en quetzales.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		*m = Money{}
+		return nil
+	}
+
+	if strings.HasPrefix(raw, `"`) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw = s
+	}
+
+	parsed, err := ParseMoney(raw)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ============================================================================
+// SQL
+// ============================================================================
+
+// GormDataType define el tipo de columna usado por GORM al migrar.
+func (Money) GormDataType() string {
+	return "bigint"
+}
+
+// Value implementa driver.Valuer. Escribe el monto en centavos.
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+// Scan implementa sql.Scanner. Lee centavos de columnas BIGINT y de sus
+// agregados (SUM y AVG retornan NUMERIC) sin pasar por float64. La moneda
+// queda vacía: la asigna el modelo con su columna currency (ApplyCurrency).
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case int64:
+		*m = Money{Amount: v}
+		return nil
+	case []byte:
+		return m.scanCents(string(v))
+	case string:
+		return m.scanCents(v)
+	case float64:
+		*m = Money{Amount: int64(math.Round(v))}
+		return nil
+	default:
+		return fmt.Errorf("tipo incompatible para Money: %T", value)
+	}
+}
+
+// scanCents interpreta un monto en centavos leído como texto.
+func (m *Money) scanCents(raw string) error {
+	parsed, err := ParseCents(raw)
+	if err != nil {
+		return err
+	}
+	*m = Money{Amount: parsed.Amount}
+	return nil
+}
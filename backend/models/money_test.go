@@ -0,0 +1,194 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"40.50", 4050, false},
+		{"40.5", 4050, false},
+		{"36", 3600, false},
+		{" 19.99 ", 1999, false},
+		{"1e2", 10000, false},
+		{"0.005", 1, false},
+		{"0.004", 0, false},
+		{"-0.005", -1, false},
+		{"-12.345", -1235, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error, se obtuvo %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Errorf("ParseMoney(%q) = %d %s, se esperaba %d %s", tt.in, got.Amount, got.Currency, tt.want, DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    Money
+		wantErr bool
+	}{
+		{"bigint", int64(4050), Money{Amount: 4050}, false},
+		{"numeric de SUM", []byte("123456"), Money{Amount: 123456}, false},
+		{"numeric de AVG", "1234.5", Money{Amount: 1235}, false},
+		{"float", float64(99.4), Money{Amount: 99}, false},
+		{"null", nil, Money{}, false},
+		{"texto inválido", []byte("Q40"), Money{}, true},
+		{"tipo incompatible", true, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.Scan(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error, se obtuvo %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %+v, se esperaba %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyValue(t *testing.T) {
+	value, err := NewMoney(4050).Value()
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if value != int64(4050) {
+		t.Errorf("Value() = %#v, se esperaba int64(4050)", value)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		money Money
+		json  string
+	}{
+		{NewMoney(4050), "40.50"},
+		{NewMoney(5), "0.05"},
+		{NewMoney(-1999), "-19.99"},
+		{NewMoney(0), "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			data, err := tt.money.MarshalJSON()
+			if err != nil || string(data) != tt.json {
+				t.Fatalf("MarshalJSON = %s (%v), se esperaba %s", data, err, tt.json)
+			}
+			var got Money
+			if err := got.UnmarshalJSON([]byte(`"` + tt.json + `"`)); err != nil || !got.Equal(tt.money) {
+				t.Errorf("UnmarshalJSON(%q) = %s (%v), se esperaba %s", tt.json, got, err, tt.money)
+			}
+		})
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := Money{Amount: 1000, Currency: "USD"}
+	tests := []struct {
+		name    string
+		op      func() (Money, error)
+		want    Money
+		wantErr error
+	}{
+		{"suma", func() (Money, error) { return NewMoney(1999).Add(NewMoney(1)) }, NewMoney(2000), nil},
+		{"resta negativa", func() (Money, error) { return NewMoney(500).Sub(NewMoney(750)) }, NewMoney(-250), nil},
+		{"suma con monto sin moneda", func() (Money, error) { return Money{Amount: 100}.Add(usd) }, Money{Amount: 1100, Currency: "USD"}, nil},
+		{"resta de monto sin moneda", func() (Money, error) { return usd.Sub(Money{Amount: 100}) }, Money{Amount: 900, Currency: "USD"}, nil},
+		{"suma de monedas distintas", func() (Money, error) { return NewMoney(100).Add(usd) }, Money{}, ErrCurrencyMismatch},
+		{"resta de monedas distintas", func() (Money, error) { return usd.Sub(NewMoney(100)) }, Money{}, ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("se esperaba %v, se obtuvo %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resultado = %+v, se esperaba %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := NewMoney(1999).Mul(3); got != NewMoney(5997) {
+		t.Errorf("Mul(3) = %+v, se esperaba Q59.97", got)
+	}
+}
+
+func TestCommonCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		amounts []Money
+		want    string
+		wantErr bool
+	}{
+		{"sin montos", nil, DefaultCurrency, false},
+		{"montos sin moneda", []Money{{Amount: 1}, {Amount: 2}}, DefaultCurrency, false},
+		{"misma moneda", []Money{{Currency: "USD"}, {Amount: 5}, {Currency: "USD"}}, "USD", false},
+		{"monedas distintas", []Money{NewMoney(1), {Currency: "USD"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CommonCurrency(tt.amounts...)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCurrencyMismatch) {
+					t.Fatalf("se esperaba ErrCurrencyMismatch, se obtuvo %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("CommonCurrency = %q (%v), se esperaba %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderCalculateTotalRejectsMixedCurrencies(t *testing.T) {
+	order := Order{Subtotal: NewMoney(10000), ShippingCost: Money{Amount: 500, Currency: "USD"}}
+	if err := order.CalculateTotal(); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("se esperaba ErrCurrencyMismatch, se obtuvo %v", err)
+	}
+
+	order.ShippingCost = NewMoney(3500)
+	if err := order.CalculateTotal(); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if order.Total != NewMoney(13500) {
+		t.Errorf("Total = %s, se esperaba Q135.00", order.Total)
+	}
+}
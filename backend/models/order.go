@@ -14,22 +14,42 @@ import (
 // El uso de un tipo personalizado (enum) mejora la legibilidad y previene errores.
 type OrderStatus string
 
+// Las transiciones permitidas entre estados están definidas en order_status.go.
 const (
-	StatusPending   OrderStatus = "pending"   // El pedido ha sido recibido, pendiente de pago/procesamiento.
-	StatusPaid      OrderStatus = "paid"      // El pago ha sido confirmado.
-	StatusShipped   OrderStatus = "shipped"   // El pedido ha sido enviado.
-	StatusCancelled OrderStatus = "cancelled" // El pedido ha sido cancelado.
+	StatusPending    OrderStatus = "pending"    // El pedido ha sido recibido, pendiente de pago/procesamiento.
+	StatusPaid       OrderStatus = "paid"       // El pago ha sido confirmado.
+	StatusProcessing OrderStatus = "processing" // El pedido se está preparando (guía generada o en empaque).
+	StatusShipped    OrderStatus = "shipped"    // El pedido ha sido enviado.
+	StatusDelivered  OrderStatus = "delivered"  // El pedido fue entregado al cliente.
+	StatusCancelled  OrderStatus = "cancelled"  // El pedido ha sido cancelado.
+
+	StatusPartiallyRefunded OrderStatus = "partially_refunded" // Se reembolsó parte del pedido.
+	StatusRefunded          OrderStatus = "refunded"           // Se reembolsó el pedido completo.
 )
 
+// RevenueCondition es la condición SQL de las órdenes que cuentan como venta:
+// las cobradas. No se usa el estado porque 'processing' también incluye
+// órdenes contra entrega aún sin cobrar. La comparten las estadísticas de
+// órdenes y la analítica de ventas.
+const RevenueCondition = "paid_at IS NOT NULL"
+
+// cashOnDeliveryPaymentMethod es Order.PaymentMethod de las órdenes contra
+// entrega: se cobran al entregarlas (ver TransitionTo).
+const cashOnDeliveryPaymentMethod = "cash_on_delivery"
+
 // Order representa la cabecera de un pedido de un cliente en el e-commerce de joyería.
 type Order struct {
 	// ID: Identificador único de la orden (UUID), clave primaria.
-	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid();index:idx_orders_created_at_id,priority:2"`
+
+	// OrderNumber: Número legible de la orden, consecutivo por año (ej: MO-2026-000123).
+	// Es el que ve el cliente; se asigna al crear la orden (ver NextOrderNumber).
+	OrderNumber string `json:"order_number" gorm:"type:varchar(20);uniqueIndex"`
 
 	// UserID: Identificador opcional del usuario registrado (Foreign Key opcional).
 	UserID *uuid.UUID `json:"user_id" gorm:"type:uuid;index;omitempty"`
 
-	// Status: Estado actual del pedido (Pendiente, Pagado, Enviado, Cancelado).
+	// Status: Estado actual del pedido. Solo debe cambiarse con TransitionTo.
 	Status OrderStatus `json:"status" gorm:"type:varchar(20);default:'pending'"`
 
 	// --- Información del Cliente ---
@@ -89,7 +109,7 @@ type Order struct {
 	IsSpecialDeliveryZone bool `json:"is_special_delivery_zone" gorm:"default:false"`
 
 	// ShippingCost: Costo de envío calculado según la zona de envío.
-	ShippingCost float64 `json:"shipping_cost" gorm:"type:decimal(10,2);default:0"`
+	ShippingCost Money `json:"shipping_cost" gorm:"type:bigint;default:0"`
 
 	// --- Envío y Tracking (Cargo Expreso) ---
 	// ShippingMethod: Método de envío utilizado ('local' o 'cargo_expreso').
@@ -106,29 +126,95 @@ type Order struct {
 	// Se obtiene del API de Cargo Expreso tras crear la guía.
 	CargoExpresoGuideURL string `json:"cargo_expreso_guide_url" gorm:"type:text;omitempty"`
 
+	// TrackingNextCheckAt: Próxima consulta del rastreo de la guía (ver services.TrackingPoller).
+	// Se aleja mientras la guía no tenga movimiento. Nil = consultar en la siguiente corrida.
+	TrackingNextCheckAt *time.Time `json:"tracking_next_check_at,omitempty" gorm:"index"`
+
+	// TrackingLastEventAt: Momento del último evento de rastreo reportado por la transportista.
+	TrackingLastEventAt *time.Time `json:"tracking_last_event_at,omitempty"`
+
 	// RequiresCourier: Flag indicando si este envío requiere Cargo Expreso.
 	// true = necesita courier (resto de Guatemala)
 	// false = entrega local (Huehuetenango, Chiantla)
 	RequiresCourier bool `json:"requires_courier" gorm:"default:false"`
 
+	// --- Paquete (Cargo Expreso) ---
+	// PackageType: Tipo de paquete para la guía ('sobre' | 'caja_pequeña' | 'caja_mediana').
+	// Lo calcula services.PackItems con el peso y las dimensiones de los productos.
+	// Vacío en órdenes anteriores al calculador (se calcula al generar la guía).
+	PackageType string `json:"package_type" gorm:"type:varchar(20)"`
+
+	// PackageWeightKg: Peso total del paquete en kg. Define la tarifa por peso
+	// y el peso de la guía (que Cargo Expreso recibe en libras).
+	PackageWeightKg float64 `json:"package_weight_kg" gorm:"type:decimal(8,3);default:0"`
+
+	// PackageOverridden: true si un admin fijó el paquete a mano; editar los
+	// items ya no lo recalcula.
+	PackageOverridden bool `json:"package_overridden" gorm:"default:false"`
+
 	// --- Totales ---
 	// Subtotal: Suma de los precios de todos los OrderItems.
-	Subtotal float64 `json:"subtotal" gorm:"type:decimal(10,2);default:0"`
+	Subtotal Money `json:"subtotal" gorm:"type:bigint;default:0"`
 
 	// Total: Subtotal + ShippingCost (total a pagar).
-	Total float64 `json:"total" gorm:"type:decimal(10,2);default:0"`
+	Total Money `json:"total" gorm:"type:bigint;default:0"`
+
+	// RefundedAmount: Suma de todos los reembolsos emitidos sobre la orden.
+	RefundedAmount Money `json:"refunded_amount" gorm:"type:bigint;default:0"`
+
+	// Currency: Moneda de todos los montos de la orden (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);default:'GTQ'"`
 
 	// --- Pago ---
+	// PaymentMethod: Forma de pago elegida por el cliente.
+	// Valores: 'card' (Stripe) | 'cash_on_delivery' (contra entrega, solo entregas locales)
+	PaymentMethod string `json:"payment_method" gorm:"type:varchar(30);default:'card'"`
+
 	// PaymentIntentID: Identificador del intent de pago de Stripe (opcional).
 	PaymentIntentID string `json:"payment_intent_id" gorm:"omitempty"`
 
+	// StripeSessionID: Identificador de la Checkout Session de Stripe.
+	// Se usa para asociar los webhooks de Stripe con la orden.
+	StripeSessionID string `json:"stripe_session_id" gorm:"type:varchar(255);index"`
+
+	// PaidAt: Momento en que se cobró la orden: Stripe confirmó el pago o se
+	// entregó una orden contra entrega (nil si no se ha cobrado).
+	PaidAt *time.Time `json:"paid_at"`
+
+	// BalanceDue: Saldo pendiente con el cliente por ediciones posteriores al pago.
+	// Positivo: el cliente debe pagarlo. Negativo: se le debe reembolsar.
+	BalanceDue Money `json:"balance_due" gorm:"type:bigint;default:0"`
+
+	// --- Inventario ---
+	// StockReserved: Indica si al crear la orden se descontó el stock de sus productos.
+	// Si la orden se cancela con stock reservado, las unidades deben regresar al inventario.
+	StockReserved bool `json:"stock_reserved" gorm:"default:false"`
+
+	// --- Devoluciones ---
+	// ReplacesOrderID: Orden original cuando esta es una orden de reemplazo
+	// creada por un cambio (ver ReturnRequest).
+	ReplacesOrderID *uuid.UUID `json:"replaces_order_id,omitempty" gorm:"type:uuid;index"`
+
 	// --- Relaciones ---
 	// OrderItems: Artículos del pedido (relación uno-a-muchos).
 	OrderItems []OrderItem `json:"order_items" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
 
+	// Refunds: Reembolsos emitidos sobre la orden (relación uno-a-muchos).
+	Refunds []Refund `json:"refunds,omitempty" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+
+	// StatusEvents: Historial de cambios de estado (timeline del pedido).
+	StatusEvents []OrderStatusEvent `json:"status_events,omitempty" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+
+	// Edits: Bitácora de ediciones hechas por un admin.
+	Edits []OrderEdit `json:"edits,omitempty" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+
+	// ShipmentEvents: Eventos de rastreo de la guía de Cargo Expreso.
+	ShipmentEvents []ShipmentEvent `json:"shipment_events,omitempty" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+
 	// --- Timestamps ---
 	// CreatedAt: Timestamp automático de creación del registro.
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+	// Índice (created_at, id): paginación por cursor del listado del admin y la exportación.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli;index:idx_orders_created_at_id,priority:1"`
 
 	// UpdatedAt: Timestamp automático de última actualización.
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime:milli"`
@@ -165,7 +251,10 @@ type OrderItem struct {
 	// Price: Snapshot (fotografía) del precio unitario en el momento de la compra.
 	// Se almacena aquí para mantener un registro histórico exacto del precio pagado,
 	// permitiendo auditoría y reportes precisos incluso si el precio del producto cambia.
-	Price float64 `json:"price"`
+	Price Money `json:"price" gorm:"type:bigint"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
 
 	// --- Timestamps ---
 	// CreatedAt: Timestamp automático de creación del item en la orden.
@@ -184,23 +273,81 @@ func (Order) TableName() string {
 	return "orders"
 }
 
+// BeforeSave es un hook de GORM que guarda en currency la moneda de sus montos.
+// Falla si los montos son de monedas distintas.
+func (o *Order) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: o.Currency}, o.Subtotal, o.ShippingCost, o.Total, o.RefundedAmount, o.BalanceDue)
+	if err != nil {
+		return err
+	}
+	o.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (o *Order) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(o.Currency, &o.Subtotal, &o.ShippingCost, &o.Total, &o.RefundedAmount, &o.BalanceDue)
+	return nil
+}
+
 // BeforeCreate es un hook de GORM que se ejecuta antes de insertar un registro.
-// Genera un UUID automático si no existe.
+// Genera un UUID automático si no existe y asigna el número de orden
+// dentro de la misma transacción del INSERT.
 func (o *Order) BeforeCreate(tx *gorm.DB) error {
 	if o.ID == uuid.Nil {
 		o.ID = uuid.New()
 	}
+	if o.OrderNumber == "" {
+		number, err := NextOrderNumber(tx, orderNumberYear(o))
+		if err != nil {
+			return err
+		}
+		o.OrderNumber = number
+	}
 	return nil
 }
 
+// Reference retorna el número legible de la orden, o el UUID si aún no tiene.
+func (o *Order) Reference() string {
+	if o.OrderNumber != "" {
+		return o.OrderNumber
+	}
+	return o.ID.String()
+}
+
+// AfterCreate es un hook de GORM que registra el estado inicial en el timeline.
+func (o *Order) AfterCreate(tx *gorm.DB) error {
+	status := o.Status
+	if status == "" {
+		status = StatusPending
+	}
+
+	change := StatusChange{
+		ActorType: ActorCustomer,
+		Reason:    "orden creada",
+	}
+	if o.ReplacesOrderID != nil {
+		change = StatusChange{
+			ActorType: ActorSystem,
+			Reason:    fmt.Sprintf("orden de reemplazo por cambio de la orden %s", o.ReplacesOrderID),
+		}
+	}
+	return RecordStatusEvent(tx, o.ID, "", status, change)
+}
+
 // ============================================================================
 // MÉTODOS DE NEGOCIO
 // ============================================================================
 
 // CalculateTotal calcula el total del pedido como la suma del subtotal y el costo de envío.
 // Este método debe llamarse después de actualizar el subtotal o el costo de envío.
-func (o *Order) CalculateTotal() {
-	o.Total = o.Subtotal + o.ShippingCost
+func (o *Order) CalculateTotal() error {
+	total, err := o.Subtotal.Add(o.ShippingCost)
+	if err != nil {
+		return err
+	}
+	o.Total = total
+	return nil
 }
 
 // ============================================================================
@@ -239,7 +386,7 @@ func (o *Order) Validate() error {
 	}
 
 	// Validar que el total sea mayor o igual a cero
-	if o.Total < 0 {
+	if o.Total.IsNegative() {
 		return fmt.Errorf("total no puede ser negativo")
 	}
 
@@ -255,6 +402,23 @@ func (OrderItem) TableName() string {
 	return "order_items"
 }
 
+// BeforeSave es un hook de GORM que guarda en currency la moneda de su precio.
+// Falla si los montos son de monedas distintas.
+func (oi *OrderItem) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: oi.Currency}, oi.Price)
+	if err != nil {
+		return err
+	}
+	oi.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (oi *OrderItem) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(oi.Currency, &oi.Price)
+	return nil
+}
+
 // BeforeCreate es un hook de GORM que se ejecuta antes de insertar un registro de OrderItem.
 // Genera un UUID automático si no existe.
 func (oi *OrderItem) BeforeCreate(tx *gorm.DB) error {
@@ -265,9 +429,9 @@ func (oi *OrderItem) BeforeCreate(tx *gorm.DB) error {
 }
 
 // GetSubtotal calcula el subtotal de este item multiplicando el precio unitario
-// por la cantidad. Retorna el resultado como float64.
-func (oi *OrderItem) GetSubtotal() float64 {
-	return oi.Price * float64(oi.Quantity)
+// por la cantidad. El cálculo es exacto porque se hace en centavos.
+func (oi *OrderItem) GetSubtotal() Money {
+	return oi.Price.Mul(oi.Quantity)
 }
 
 // Validate realiza todas las validaciones necesarias del item del pedido.
@@ -279,8 +443,8 @@ func (oi *OrderItem) Validate() error {
 	}
 
 	// Validar que el precio no sea negativo
-	if oi.Price < 0 {
-		return fmt.Errorf("price no puede ser negativo, recibido: %.2f", oi.Price)
+	if oi.Price.IsNegative() {
+		return fmt.Errorf("price no puede ser negativo, recibido: %s", oi.Price)
 	}
 
 	// Validar que el ProductName no esté vacío
@@ -0,0 +1,53 @@
+// backend/models/order_access_token.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderAccessToken registra cada enlace de consulta de orden enviado por email.
+// El enlace lleva un token firmado con HMAC que contiene el ID de este registro;
+// revocar el registro invalida el enlace aunque su firma siga siendo válida.
+type OrderAccessToken struct {
+	// ID: Identificador del token (UUID), incluido en el payload firmado.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// OrderID: Única orden que permite consultar el token (Foreign Key).
+	OrderID uuid.UUID `json:"order_id" gorm:"type:uuid;index;not null"`
+
+	// Email: Dirección a la que se envió el enlace.
+	Email string `json:"email" gorm:"type:varchar(255);not null"`
+
+	// ExpiresAt: A partir de este momento el enlace deja de funcionar.
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+
+	// RevokedAt: Momento en que se revocó el enlace (nil si sigue activo).
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// LastUsedAt: Última vez que se abrió el enlace.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// CreatedAt: Timestamp automático de creación del registro.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo OrderAccessToken.
+func (OrderAccessToken) TableName() string {
+	return "order_access_tokens"
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (t *OrderAccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive indica si el token no ha vencido ni sido revocado.
+func (t *OrderAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
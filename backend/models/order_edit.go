@@ -0,0 +1,95 @@
+// backend/models/order_edit.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderEdit registra una edición de la orden hecha por un admin (bitácora).
+// Las filas nunca se modifican: junto con el timeline de estados forman el
+// historial completo de la orden.
+type OrderEdit struct {
+	// ID: Identificador único de la edición (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// OrderID: Orden editada (Foreign Key).
+	OrderID uuid.UUID `json:"order_id" gorm:"type:uuid;index;not null"`
+
+	// EditedBy: user_id del admin que hizo la edición.
+	EditedBy string `json:"edited_by" gorm:"type:varchar(255)"`
+
+	// Reason: Motivo de la edición (ej: "la clienta llamó para cambiar la talla").
+	Reason string `json:"reason" gorm:"type:text"`
+
+	// Changes: Detalle de los campos e items modificados.
+	Changes []OrderEditChange `json:"changes" gorm:"type:jsonb;serializer:json"`
+
+	// PreviousTotal: Total de la orden antes de la edición.
+	PreviousTotal Money `json:"previous_total" gorm:"type:bigint"`
+
+	// NewTotal: Total de la orden después de la edición.
+	NewTotal Money `json:"new_total" gorm:"type:bigint"`
+
+	// BalanceChange: Diferencia que queda pendiente con el cliente por esta edición.
+	// Positivo: el cliente debe pagarla. Negativo: se le debe reembolsar.
+	// Es 0 si la orden aún no se había cobrado (el nuevo total es el que se cobra).
+	BalanceChange Money `json:"balance_change" gorm:"type:bigint;default:0"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
+
+	// CreatedAt: Momento de la edición.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli;index"`
+}
+
+// OrderEditChange describe un cambio puntual dentro de una edición.
+type OrderEditChange struct {
+	// Field: Campo modificado ("shipping_address", "delivery_type", "item", ...).
+	Field string `json:"field"`
+
+	// ProductID: Producto afectado cuando Field es "item".
+	ProductID uint `json:"product_id,omitempty"`
+
+	// From / To: Valor anterior y nuevo (cantidades como texto en los items).
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo OrderEdit.
+func (OrderEdit) TableName() string {
+	return "order_edits"
+}
+
+// BeforeSave es un hook de GORM que guarda en currency la moneda de sus montos.
+// Falla si los montos son de monedas distintas.
+func (e *OrderEdit) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: e.Currency}, e.PreviousTotal, e.NewTotal, e.BalanceChange)
+	if err != nil {
+		return err
+	}
+	e.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (e *OrderEdit) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(e.Currency, &e.PreviousTotal, &e.NewTotal, &e.BalanceChange)
+	return nil
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (e *OrderEdit) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// PreloadOrderEdits ordena la bitácora de ediciones de la más antigua a la más reciente.
+// Uso: db.Preload("Edits", models.PreloadOrderEdits).
+func PreloadOrderEdits(db *gorm.DB) *gorm.DB {
+	return db.Order("order_edits.created_at ASC")
+}
@@ -0,0 +1,98 @@
+// backend/models/order_number.go
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderNumberPrefix es el prefijo de los números de orden legibles (MO-2026-000123).
+const OrderNumberPrefix = "MO"
+
+// OrderNumberCounter guarda el último consecutivo asignado en cada año.
+// Se usa una tabla contador en lugar de una secuencia de Postgres porque las
+// secuencias dejan huecos cuando la transacción se revierte; el contador se
+// actualiza en la misma transacción que crea la orden y se revierte con ella.
+type OrderNumberCounter struct {
+	// Year: Año del consecutivo, clave primaria.
+	Year int `json:"year" gorm:"primaryKey;autoIncrement:false"`
+
+	// LastValue: Último consecutivo asignado en el año.
+	LastValue int64 `json:"last_value" gorm:"not null;default:0"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo OrderNumberCounter.
+func (OrderNumberCounter) TableName() string {
+	return "order_number_counters"
+}
+
+// FormatOrderNumber construye el número de orden: MO-<año>-<consecutivo de 6 dígitos>.
+func FormatOrderNumber(year int, seq int64) string {
+	return fmt.Sprintf("%s-%d-%06d", OrderNumberPrefix, year, seq)
+}
+
+// NextOrderNumber incrementa el contador del año y retorna el número de orden.
+// El UPSERT bloquea la fila del año hasta que termina la transacción, así que
+// dos órdenes concurrentes nunca reciben el mismo consecutivo, y si la
+// transacción se revierte el consecutivo se libera (no quedan huecos).
+func NextOrderNumber(tx *gorm.DB, year int) (string, error) {
+	var seq int64
+	err := tx.Raw(`INSERT INTO order_number_counters (year, last_value) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_value = order_number_counters.last_value + 1
+		RETURNING last_value`, year).Scan(&seq).Error
+	if err != nil {
+		return "", fmt.Errorf("error al asignar número de orden: %w", err)
+	}
+	return FormatOrderNumber(year, seq), nil
+}
+
+// BackfillOrderNumbers asigna número a las órdenes creadas antes de que
+// existiera order_number, en orden de creación y por año de creación.
+// Retorna cuántas órdenes se numeraron.
+func BackfillOrderNumbers(db *gorm.DB) (int, error) {
+	var orders []Order
+	if err := db.Select("id", "created_at").
+		Where("order_number IS NULL OR order_number = ''").
+		Order("created_at ASC").
+		Find(&orders).Error; err != nil {
+		return 0, fmt.Errorf("error al obtener órdenes sin número: %w", err)
+	}
+
+	for i, order := range orders {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			number, err := NextOrderNumber(tx, orderNumberYear(&order))
+			if err != nil {
+				return err
+			}
+			return tx.Model(&Order{}).Where("id = ?", order.ID).Update("order_number", number).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("error al numerar orden %s: %w", order.ID, err)
+		}
+	}
+	return len(orders), nil
+}
+
+// OrderRefCondition traduce la referencia que escribe un cliente o admin
+// (UUID o número de orden, sin importar mayúsculas) a la condición de búsqueda
+// y su valor: db.First(&order, condition, value).
+func OrderRefCondition(ref string) (string, string) {
+	ref = strings.TrimSpace(ref)
+	if _, err := uuid.Parse(ref); err == nil {
+		return "id = ?", ref
+	}
+	return "order_number = ?", strings.ToUpper(ref)
+}
+
+// orderNumberYear es el año con el que se numera una orden nueva, en hora de
+// Guatemala: una orden del 31 de diciembre a las 20:00 sigue siendo de ese año.
+func orderNumberYear(o *Order) int {
+	if !o.CreatedAt.IsZero() {
+		return o.CreatedAt.In(StoreLocation).Year()
+	}
+	return time.Now().In(StoreLocation).Year()
+}
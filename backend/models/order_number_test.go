@@ -0,0 +1,25 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderNumberYearUsesGuatemalaTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		want      int
+	}{
+		{"31 de diciembre 20:00 en Guatemala (2:00 UTC del 1 de enero)", time.Date(2027, 1, 1, 2, 0, 0, 0, time.UTC), 2026},
+		{"1 de enero 00:30 en Guatemala", time.Date(2027, 1, 1, 6, 30, 0, 0, time.UTC), 2027},
+		{"mediados de año", time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC), 2026},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderNumberYear(&Order{CreatedAt: tt.createdAt}); got != tt.want {
+				t.Fatalf("orderNumberYear = %d, se esperaba %d", got, tt.want)
+			}
+		})
+	}
+}
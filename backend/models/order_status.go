@@ -0,0 +1,193 @@
+// backend/models/order_status.go
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// MÁQUINA DE ESTADOS DEL PEDIDO
+// ============================================================================
+//
+// Flujo normal: pending -> paid -> processing -> shipped -> delivered.
+// - pending -> processing aplica a pago contra entrega, que se cobra al entregar.
+// - Una orden pagada no se cancela directamente: se reembolsa. La tabla permite
+//   processing -> cancelled por las órdenes contra entrega sin cobrar; el
+//   bloqueo de las pagadas está en OrderCancellationService (isOrderPaid).
+// - paid, partially_refunded y refunded no se asignan a mano (ver
+//   ValidateManualStatus): salen del pago y de los reembolsos.
+// - cancelled y refunded son estados finales.
+
+// orderTransitions define, para cada estado, los estados a los que puede pasar.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	StatusPending:           {StatusPaid, StatusProcessing, StatusCancelled},
+	StatusPaid:              {StatusProcessing, StatusShipped, StatusDelivered, StatusPartiallyRefunded, StatusRefunded},
+	StatusProcessing:        {StatusShipped, StatusDelivered, StatusCancelled, StatusPartiallyRefunded, StatusRefunded},
+	StatusShipped:           {StatusDelivered, StatusPartiallyRefunded, StatusRefunded},
+	StatusDelivered:         {StatusPartiallyRefunded, StatusRefunded},
+	StatusPartiallyRefunded: {StatusProcessing, StatusShipped, StatusDelivered, StatusPartiallyRefunded, StatusRefunded},
+	StatusCancelled:         {},
+	StatusRefunded:          {},
+}
+
+// AllOrderStatuses retorna todos los estados válidos de un pedido.
+func AllOrderStatuses() []OrderStatus {
+	return []OrderStatus{
+		StatusPending,
+		StatusPaid,
+		StatusProcessing,
+		StatusShipped,
+		StatusDelivered,
+		StatusCancelled,
+		StatusPartiallyRefunded,
+		StatusRefunded,
+	}
+}
+
+// IsValid indica si el estado pertenece a la máquina de estados.
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// IsTerminal indica si desde este estado ya no se puede pasar a ningún otro.
+func (s OrderStatus) IsTerminal() bool {
+	return s.IsValid() && len(orderTransitions[s]) == 0
+}
+
+// AllowedTransitions retorna los estados a los que se puede pasar desde s.
+func (s OrderStatus) AllowedTransitions() []OrderStatus {
+	return append([]OrderStatus(nil), orderTransitions[s]...)
+}
+
+// CanTransitionTo indica si el cambio de s a next está permitido.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStatusTransition retorna un error si el cambio de from a to no está permitido.
+func ValidateStatusTransition(from, to OrderStatus) error {
+	if !to.IsValid() {
+		return fmt.Errorf("estado de orden inválido: %s", to)
+	}
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("transición de estado no permitida: %s -> %s", from, to)
+	}
+	return nil
+}
+
+// systemStatuses son los estados que solo asignan el pago o los reembolsos, con
+// sus efectos (paid_at, ledger de reembolsos, refunded_amount, stock).
+var systemStatuses = map[OrderStatus]string{
+	StatusPaid:              "solo lo asigna la confirmación del pago",
+	StatusPartiallyRefunded: "solo se asigna al reembolsar (POST /api/v1/admin/orders/:id/refunds)",
+	StatusRefunded:          "solo se asigna al reembolsar (POST /api/v1/admin/orders/:id/refunds)",
+}
+
+// ValidateManualStatus retorna un error si el admin no puede asignar el estado
+// directamente (cambio de estado individual o en lote).
+func ValidateManualStatus(to OrderStatus) error {
+	if !to.IsValid() {
+		return fmt.Errorf("estado de orden inválido: %s", to)
+	}
+	if reason, ok := systemStatuses[to]; ok {
+		return fmt.Errorf("transición de estado no permitida: '%s' %s", to, reason)
+	}
+	return nil
+}
+
+// TransitionTo cambia el estado de la orden validando la máquina de estados y
+// registra el cambio en order_status_events. Debe llamarse dentro de una
+// transacción para que el estado y el evento se guarden juntos.
+// Al entregar una orden contra entrega sin cobrar se registra paid_at.
+// El UPDATE se condiciona al estado leído, de modo que si otro proceso cambió
+// la orden mientras tanto, no se sobrescribe y se retorna un error.
+func (o *Order) TransitionTo(tx *gorm.DB, next OrderStatus, change StatusChange) error {
+	if err := ValidateStatusTransition(o.Status, next); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"status": next}
+	for column, value := range change.Fields {
+		updates[column] = value
+	}
+
+	// Contra entrega: la orden se cobra al entregarla
+	var collectedAt *time.Time
+	if next == StatusDelivered && o.PaymentMethod == cashOnDeliveryPaymentMethod && o.PaidAt == nil {
+		if _, ok := updates["paid_at"]; !ok {
+			now := time.Now()
+			collectedAt = &now
+			updates["paid_at"] = now
+		}
+	}
+
+	result := tx.Model(&Order{}).
+		Where("id = ? AND status = ?", o.ID, o.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("error al actualizar estado de la orden: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("transición de estado no permitida: la orden %s ya no está en estado %s", o.ID, o.Status)
+	}
+
+	if err := RecordStatusEvent(tx, o.ID, o.Status, next, change); err != nil {
+		return err
+	}
+
+	o.Status = next
+	if collectedAt != nil {
+		o.PaidAt = collectedAt
+	}
+	return nil
+}
+
+// BackfillCashOnDeliveryPaidAt marca como cobradas las órdenes contra entrega
+// entregadas antes de que TransitionTo registrara paid_at al entregarlas. Se
+// usa la fecha del evento de entrega (o la última actualización si no hay).
+// Retorna cuántas órdenes se actualizaron.
+func BackfillCashOnDeliveryPaidAt(db *gorm.DB) (int64, error) {
+	result := db.Exec(`
+		UPDATE orders SET paid_at = COALESCE(
+			(SELECT MAX(e.created_at) FROM order_status_events e
+			  WHERE e.order_id = orders.id AND e.to_status = ?),
+			orders.updated_at)
+		WHERE payment_method = ? AND paid_at IS NULL
+		  AND (status = ? OR EXISTS (SELECT 1 FROM order_status_events e
+			  WHERE e.order_id = orders.id AND e.to_status = ?))`,
+		StatusDelivered, cashOnDeliveryPaymentMethod, StatusDelivered, StatusDelivered)
+	if result.Error != nil {
+		return 0, fmt.Errorf("error al registrar cobro de órdenes contra entrega: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RecordStatusEvent agrega un evento al timeline de la orden.
+func RecordStatusEvent(tx *gorm.DB, orderID uuid.UUID, from, to OrderStatus, change StatusChange) error {
+	event := OrderStatusEvent{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorType:  change.ActorType,
+		ActorID:    change.ActorID,
+		Reason:     change.Reason,
+	}
+	if event.ActorType == "" {
+		event.ActorType = ActorSystem
+	}
+
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("error al registrar cambio de estado: %w", err)
+	}
+	return nil
+}
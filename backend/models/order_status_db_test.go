@@ -0,0 +1,108 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/testsupport"
+
+	"gorm.io/gorm"
+)
+
+// TestTransitionTo verifica contra Postgres que TransitionTo guarde el estado
+// y su evento juntos, registre el cobro de contra entrega al entregar y no
+// sobrescriba una orden que otro proceso ya cambió.
+//
+// Uso: TEST_DATABASE_URL=... go test ./models -run TestTransitionTo
+func TestTransitionTo(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+
+	newOrder := func(t *testing.T, status models.OrderStatus, paymentMethod string) *models.Order {
+		t.Helper()
+		order := &models.Order{
+			Status:               status,
+			PaymentMethod:        paymentMethod,
+			CustomerEmail:        "transiciones@example.com",
+			CustomerName:         "Prueba Transiciones",
+			CustomerPhone:        "55555555",
+			ShippingDepartment:   "GT-13",
+			ShippingMunicipality: "Huehuetenango",
+			ShippingAddress:      "4a calle 5-10 zona 1",
+			Subtotal:             models.NewMoney(10000),
+			ShippingCost:         models.NewMoney(0),
+			Total:                models.NewMoney(10000),
+		}
+		if err := gormDB.Create(order).Error; err != nil {
+			t.Fatalf("Error creando orden de prueba: %v", err)
+		}
+		t.Cleanup(func() {
+			gormDB.Where("order_id = ?", order.ID).Delete(&models.OrderStatusEvent{})
+			gormDB.Delete(&models.Order{}, "id = ?", order.ID)
+		})
+		return order
+	}
+
+	tests := []struct {
+		name          string
+		status        models.OrderStatus
+		paymentMethod string
+		next          models.OrderStatus
+		staleStatus   models.OrderStatus // estado que otro proceso asignó antes
+		wantErr       string
+		wantPaidAt    bool
+	}{
+		{"pendiente a cancelada", models.StatusPending, "card", models.StatusCancelled, "", "", false},
+		{"contra entrega entregada registra el cobro", models.StatusShipped, "cash_on_delivery", models.StatusDelivered, "", "", true},
+		{"transición no permitida", models.StatusShipped, "card", models.StatusPending, "", "no permitida", false},
+		{"otro proceso cambió la orden", models.StatusPending, "card", models.StatusProcessing, models.StatusCancelled, "ya no está en estado", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := newOrder(t, tt.status, tt.paymentMethod)
+			if tt.staleStatus != "" {
+				if err := gormDB.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", tt.staleStatus).Error; err != nil {
+					t.Fatalf("Error cambiando estado: %v", err)
+				}
+			}
+
+			err := gormDB.Transaction(func(tx *gorm.DB) error {
+				return order.TransitionTo(tx, tt.next, models.StatusChange{ActorType: models.ActorAdmin, ActorID: "test", Reason: tt.name})
+			})
+
+			var events []models.OrderStatusEvent
+			if err := gormDB.Where("order_id = ?", order.ID).Find(&events).Error; err != nil {
+				t.Fatalf("Error consultando eventos: %v", err)
+			}
+			var reloaded models.Order
+			if err := gormDB.First(&reloaded, "id = ?", order.ID).Error; err != nil {
+				t.Fatalf("Error consultando orden: %v", err)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("se esperaba error con %q, se obtuvo %v", tt.wantErr, err)
+				}
+				if len(events) != 0 {
+					t.Errorf("eventos = %d, no se esperaba ninguno", len(events))
+				}
+				if tt.staleStatus != "" && reloaded.Status != tt.staleStatus {
+					t.Errorf("estado = %s, no se debía sobrescribir %s", reloaded.Status, tt.staleStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if reloaded.Status != tt.next || order.Status != tt.next {
+				t.Errorf("estado = %s (en memoria %s), se esperaba %s", reloaded.Status, order.Status, tt.next)
+			}
+			if len(events) != 1 || events[0].FromStatus != tt.status || events[0].ToStatus != tt.next || events[0].ActorType != models.ActorAdmin {
+				t.Errorf("eventos = %+v, se esperaba uno %s -> %s del admin", events, tt.status, tt.next)
+			}
+			if (reloaded.PaidAt != nil) != tt.wantPaidAt {
+				t.Errorf("paid_at = %v, se esperaba registrado: %v", reloaded.PaidAt, tt.wantPaidAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// backend/models/order_status_event.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StatusActor identifica quién realizó un cambio de estado.
+type StatusActor string
+
+const (
+	ActorAdmin         StatusActor = "admin"          // Un administrador desde el panel.
+	ActorCustomer      StatusActor = "customer"       // El cliente (ej: cancelación).
+	ActorSystem        StatusActor = "system"         // Un proceso interno (reconciliador, generación de guías).
+	ActorStripeWebhook StatusActor = "stripe_webhook" // Un evento de Stripe.
+	ActorCarrier       StatusActor = "carrier"        // La transportista (tracking de Cargo Expreso).
+)
+
+// OrderStatusEvent registra un cambio de estado de una orden.
+// Las filas nunca se modifican: forman el timeline del pedido.
+type OrderStatusEvent struct {
+	// ID: Identificador único del evento (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// OrderID: Orden que cambió de estado (Foreign Key).
+	OrderID uuid.UUID `json:"order_id" gorm:"type:uuid;index;not null"`
+
+	// FromStatus: Estado anterior (vacío en el evento de creación).
+	FromStatus OrderStatus `json:"from_status" gorm:"type:varchar(20)"`
+
+	// ToStatus: Estado nuevo.
+	ToStatus OrderStatus `json:"to_status" gorm:"type:varchar(20);not null"`
+
+	// ActorType: Quién hizo el cambio ('admin', 'customer', 'system', 'stripe_webhook', 'carrier').
+	ActorType StatusActor `json:"actor_type" gorm:"type:varchar(20);not null"`
+
+	// ActorID: user_id del admin, ID del evento de Stripe, nombre del proceso, etc.
+	ActorID string `json:"actor_id,omitempty" gorm:"type:varchar(255)"`
+
+	// Reason: Motivo del cambio.
+	Reason string `json:"reason,omitempty" gorm:"type:text"`
+
+	// CreatedAt: Momento del cambio.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli;index"`
+}
+
+// StatusChange describe quién hace un cambio de estado y por qué.
+type StatusChange struct {
+	// ActorType: Quién hace el cambio.
+	ActorType StatusActor
+
+	// ActorID: Identificador del actor (opcional).
+	ActorID string
+
+	// Reason: Motivo del cambio (opcional).
+	Reason string
+
+	// Fields: Otras columnas de la orden a actualizar en el mismo UPDATE (ej: paid_at).
+	Fields map[string]interface{}
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo OrderStatusEvent.
+func (OrderStatusEvent) TableName() string {
+	return "order_status_events"
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (e *OrderStatusEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// PreloadStatusEvents ordena el timeline del más antiguo al más reciente.
+// Uso: db.Preload("StatusEvents", models.PreloadStatusEvents).
+func PreloadStatusEvents(db *gorm.DB) *gorm.DB {
+	return db.Order("order_status_events.created_at ASC")
+}
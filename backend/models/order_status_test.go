@@ -0,0 +1,117 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateManualStatus(t *testing.T) {
+	tests := []struct {
+		status  OrderStatus
+		wantErr string
+	}{
+		{StatusProcessing, ""},
+		{StatusShipped, ""},
+		{StatusDelivered, ""},
+		{StatusCancelled, ""},
+		{StatusPaid, "confirmación del pago"},
+		{StatusPartiallyRefunded, "al reembolsar"},
+		{StatusRefunded, "al reembolsar"},
+		{OrderStatus("lost"), "estado de orden inválido"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			err := ValidateManualStatus(tt.status)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("se esperaba error con %q, se obtuvo %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOrderTransitions(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{StatusPending, StatusPaid, true},
+		{StatusPending, StatusProcessing, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusShipped, false},
+		{StatusPending, StatusRefunded, false},
+		{StatusPaid, StatusProcessing, true},
+		{StatusPaid, StatusRefunded, true},
+		{StatusPaid, StatusCancelled, false},
+		{StatusPaid, StatusPending, false},
+		{StatusProcessing, StatusShipped, true},
+		{StatusProcessing, StatusCancelled, true},
+		{StatusProcessing, StatusPaid, false},
+		{StatusShipped, StatusDelivered, true},
+		{StatusShipped, StatusProcessing, false},
+		{StatusShipped, StatusCancelled, false},
+		{StatusDelivered, StatusPartiallyRefunded, true},
+		{StatusDelivered, StatusShipped, false},
+		{StatusPartiallyRefunded, StatusPartiallyRefunded, true},
+		{StatusPartiallyRefunded, StatusRefunded, true},
+		{StatusPartiallyRefunded, StatusCancelled, false},
+		{StatusCancelled, StatusPending, false},
+		{StatusCancelled, StatusPaid, false},
+		{StatusRefunded, StatusPartiallyRefunded, false},
+		{StatusRefunded, StatusDelivered, false},
+		{OrderStatus("lost"), StatusPending, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("CanTransitionTo = %v, se esperaba %v", got, tt.want)
+			}
+			err := ValidateStatusTransition(tt.from, tt.to)
+			if tt.want != (err == nil) {
+				t.Errorf("ValidateStatusTransition = %v, se esperaba permitido: %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderTransitionsTableIsComplete(t *testing.T) {
+	for _, status := range AllOrderStatuses() {
+		if !status.IsValid() {
+			t.Errorf("el estado %s no está en la máquina de estados", status)
+		}
+		for _, next := range status.AllowedTransitions() {
+			if !next.IsValid() {
+				t.Errorf("%s -> %s apunta a un estado inválido", status, next)
+			}
+		}
+	}
+	if len(orderTransitions) != len(AllOrderStatuses()) {
+		t.Errorf("orderTransitions tiene %d estados, AllOrderStatuses %d", len(orderTransitions), len(AllOrderStatuses()))
+	}
+
+	for _, status := range []OrderStatus{StatusCancelled, StatusRefunded} {
+		if !status.IsTerminal() {
+			t.Errorf("%s debe ser final", status)
+		}
+	}
+	if StatusDelivered.IsTerminal() || OrderStatus("lost").IsTerminal() {
+		t.Error("delivered y los estados inválidos no son finales")
+	}
+}
+
+func TestTransitionToRejectsInvalidTransitionWithoutWriting(t *testing.T) {
+	order := &Order{Status: StatusCancelled}
+	// tx nil: una transición inválida no debe llegar a la base de datos
+	err := order.TransitionTo(nil, StatusPaid, StatusChange{ActorType: ActorAdmin})
+	if err == nil || !strings.Contains(err.Error(), "transición de estado no permitida") {
+		t.Fatalf("se esperaba transición no permitida, se obtuvo %v", err)
+	}
+	if order.Status != StatusCancelled {
+		t.Errorf("el estado cambió a %s", order.Status)
+	}
+}
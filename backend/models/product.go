@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // StringArray tipo personalizado para manejar arrays de strings en PostgreSQL JSONB
@@ -52,11 +54,15 @@ type Product struct {
 	// Descripción detallada, puede incluir materiales, dimensiones, etc.
 	Description string `json:"description"`
 
-	// Precio del producto. Usamos float64 para la precisión requerida en valores monetarios.
-	Price float64 `json:"price"`
+	// Precio del producto en centavos (ver Money). En JSON se expone en quetzales (ej: 149.99).
+	Price Money `json:"price" gorm:"type:bigint"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
 
 	// Cantidad de unidades disponibles en inventario.
-	Stock int `json:"stock"`
+	// La restricción CHECK impide que una reserva concurrente lo deje en negativo.
+	Stock int `json:"stock" gorm:"check:chk_products_stock_non_negative,stock >= 0"`
 
 	// URL de la imagen principal del producto alojada en Supabase Storage o similar.
 	// Esta es la primera imagen que se muestra en las tarjetas de producto.
@@ -70,6 +76,16 @@ type Product struct {
 	// - Productos con variantes: ["7.jpg", "7.1.jpg", "7.2.jpg", ...]
 	Images StringArray `json:"images" gorm:"type:jsonb;default:'[]'"`
 
+	// --- Empaque (Cargo Expreso) ---
+	// Peso y dimensiones del producto ya empacado (con bolsa o caja individual).
+	// Los usa el calculador de empaque (services.PackItems) para elegir el tipo de
+	// paquete y el peso que se envían a Cargo Expreso y a la tabla de tarifas.
+	// 0 = desconocido: se usa un peso por defecto y no se verifica el tamaño.
+	WeightKg float64 `json:"weight_kg" gorm:"type:decimal(8,3);default:0"`
+	LengthCm float64 `json:"length_cm" gorm:"type:decimal(8,2);default:0"`
+	WidthCm  float64 `json:"width_cm" gorm:"type:decimal(8,2);default:0"`
+	HeightCm float64 `json:"height_cm" gorm:"type:decimal(8,2);default:0"`
+
 	// --- Campo Clave para Búsqueda por IA ---
 	// Embedding es el vector numérico que representa las características semánticas
 	// de la imagen y/o descripción del producto, generado por un modelo como CLIP.
@@ -86,3 +102,20 @@ type Product struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// BeforeSave es un hook de GORM que guarda en currency la moneda de su precio.
+// Falla si los montos son de monedas distintas.
+func (p *Product) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: p.Currency}, p.Price)
+	if err != nil {
+		return err
+	}
+	p.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (p *Product) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(p.Currency, &p.Price)
+	return nil
+}
@@ -0,0 +1,151 @@
+// backend/models/refund.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefundStatus indica si la pasarela de pago ya confirmó el reembolso.
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"   // Reservado en el libro, falta la confirmación de la pasarela.
+	RefundSucceeded RefundStatus = "succeeded" // Emitido por la pasarela y aplicado a la orden.
+)
+
+// Refund representa un reembolso (total o parcial) emitido sobre una orden.
+// Funciona como libro de reembolsos: la fila se crea 'pending' antes de llamar
+// a la pasarela (su ID es la idempotency key) y pasa a 'succeeded' cuando la
+// pasarela lo confirma y se aplica a la orden. Las filas 'pending' reservan
+// sus unidades y su monto para que no se reembolsen dos veces.
+type Refund struct {
+	// ID: Identificador único del reembolso (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// OrderID: Orden reembolsada (Foreign Key).
+	OrderID uuid.UUID `json:"order_id" gorm:"type:uuid;index;not null"`
+
+	// Amount: Monto reembolsado en quetzales.
+	Amount Money `json:"amount" gorm:"type:bigint;not null"`
+
+	// ShippingAmount: Parte del monto que corresponde al costo de envío.
+	ShippingAmount Money `json:"shipping_amount" gorm:"type:bigint;default:0"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
+
+	// Status: 'pending' hasta que la pasarela confirma el reembolso, luego 'succeeded'.
+	Status RefundStatus `json:"status" gorm:"type:varchar(20);not null;default:'succeeded';index"`
+
+	// Reason: Motivo del reembolso indicado por el admin.
+	Reason string `json:"reason" gorm:"type:text"`
+
+	// Provider: Pasarela que procesó el reembolso ('stripe', 'cash_on_delivery', 'fake').
+	Provider string `json:"provider" gorm:"type:varchar(50)"`
+
+	// ProviderRefundID: Identificador del reembolso en la pasarela (ej: "re_3N...").
+	ProviderRefundID string `json:"provider_refund_id" gorm:"type:varchar(255);index"`
+
+	// Restocked: Indica si las unidades reembolsadas regresaron al inventario.
+	Restocked bool `json:"restocked" gorm:"default:false"`
+
+	// CreatedBy: user_id del admin que emitió el reembolso.
+	CreatedBy string `json:"created_by" gorm:"type:varchar(255)"`
+
+	// ReturnRequestID: Devolución que se resuelve con este reembolso (si aplica).
+	// Al confirmarse el reembolso, la devolución pasa a 'refunded'.
+	ReturnRequestID *uuid.UUID `json:"return_request_id,omitempty" gorm:"type:uuid;index"`
+
+	// Items: Detalle de unidades reembolsadas (vacío si solo se reembolsó envío).
+	Items []RefundItem `json:"items" gorm:"foreignKey:RefundID;constraint:OnDelete:CASCADE"`
+
+	// CreatedAt: Timestamp automático de creación del registro.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+}
+
+// RefundItem representa las unidades de un OrderItem incluidas en un reembolso.
+type RefundItem struct {
+	// ID: Identificador único (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// RefundID: Reembolso al que pertenece (Foreign Key).
+	RefundID uuid.UUID `json:"refund_id" gorm:"type:uuid;index"`
+
+	// OrderItemID: Item de la orden reembolsado.
+	OrderItemID uuid.UUID `json:"order_item_id" gorm:"type:uuid;index"`
+
+	// ProductID: Producto reembolsado (para restock y reportes).
+	ProductID uint `json:"product_id" gorm:"index"`
+
+	// Quantity: Unidades reembolsadas.
+	Quantity int `json:"quantity"`
+
+	// Amount: Monto reembolsado por estas unidades (precio snapshot * cantidad).
+	Amount Money `json:"amount" gorm:"type:bigint"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo Refund.
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+// BeforeSave es un hook de GORM que guarda en currency la moneda de sus montos.
+// Falla si los montos son de monedas distintas.
+func (r *Refund) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: r.Currency}, r.Amount, r.ShippingAmount)
+	if err != nil {
+		return err
+	}
+	r.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (r *Refund) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(r.Currency, &r.Amount, &r.ShippingAmount)
+	return nil
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo RefundItem.
+func (RefundItem) TableName() string {
+	return "refund_items"
+}
+
+// BeforeSave es un hook de GORM que guarda en currency la moneda de su monto.
+// Falla si los montos son de monedas distintas.
+func (ri *RefundItem) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: ri.Currency}, ri.Amount)
+	if err != nil {
+		return err
+	}
+	ri.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (ri *RefundItem) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(ri.Currency, &ri.Amount)
+	return nil
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (ri *RefundItem) BeforeCreate(tx *gorm.DB) error {
+	if ri.ID == uuid.Nil {
+		ri.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// backend/models/return_request.go
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReturnStatus define los estados de una solicitud de devolución (RMA).
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested" // El cliente solicitó la devolución.
+	ReturnApproved  ReturnStatus = "approved"  // El admin la aprobó y se generó la guía de retorno.
+	ReturnReceived  ReturnStatus = "received"  // Las prendas llegaron a la tienda y regresaron al inventario.
+	ReturnRefunded  ReturnStatus = "refunded"  // Se reembolsaron las prendas devueltas.
+	ReturnExchanged ReturnStatus = "exchanged" // Se creó una orden de reemplazo (cambio de talla, etc.).
+	ReturnRejected  ReturnStatus = "rejected"  // El admin rechazó la devolución.
+)
+
+// returnTransitions define, para cada estado, los estados a los que puede pasar.
+// refunded, exchanged y rejected son estados finales.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived, ReturnRejected},
+	ReturnReceived:  {ReturnRefunded, ReturnExchanged},
+	ReturnRefunded:  {},
+	ReturnExchanged: {},
+	ReturnRejected:  {},
+}
+
+// IsValid indica si el estado pertenece al flujo de devoluciones.
+func (s ReturnStatus) IsValid() bool {
+	_, ok := returnTransitions[s]
+	return ok
+}
+
+// CanTransitionTo indica si el cambio de s a next está permitido.
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateReturnTransition retorna un error si el cambio de from a to no está permitido.
+func ValidateReturnTransition(from, to ReturnStatus) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("transición de devolución no permitida: %s -> %s", from, to)
+	}
+	return nil
+}
+
+// ReturnReason es el motivo de devolución de una prenda.
+type ReturnReason string
+
+const (
+	ReasonSizeTooSmall   ReturnReason = "size_too_small"   // La talla quedó pequeña.
+	ReasonSizeTooLarge   ReturnReason = "size_too_large"   // La talla quedó grande.
+	ReasonDefective      ReturnReason = "defective"        // Defecto de fabricación o daño.
+	ReasonNotAsDescribed ReturnReason = "not_as_described" // No coincide con la descripción o fotos.
+	ReasonChangedMind    ReturnReason = "changed_mind"     // El cliente ya no la quiere.
+	ReasonOther          ReturnReason = "other"            // Otro motivo (ver notas).
+)
+
+// IsValid indica si el motivo es uno de los conocidos.
+func (r ReturnReason) IsValid() bool {
+	switch r {
+	case ReasonSizeTooSmall, ReasonSizeTooLarge, ReasonDefective, ReasonNotAsDescribed, ReasonChangedMind, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// ReturnRequest representa una solicitud de devolución o cambio sobre una orden.
+type ReturnRequest struct {
+	// ID: Identificador único de la devolución (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// OrderID: Orden original (Foreign Key).
+	OrderID uuid.UUID `json:"order_id" gorm:"type:uuid;index;not null"`
+
+	// Status: Estado de la devolución.
+	Status ReturnStatus `json:"status" gorm:"type:varchar(20);default:'requested';index"`
+
+	// CustomerNotes: Comentarios del cliente al solicitar la devolución.
+	CustomerNotes string `json:"customer_notes,omitempty" gorm:"type:text"`
+
+	// AdminNotes: Comentarios del admin (motivo de rechazo, estado de las prendas, etc.).
+	AdminNotes string `json:"admin_notes,omitempty" gorm:"type:text"`
+
+	// RequestedBy: user_id o email de quien solicitó la devolución.
+	RequestedBy string `json:"requested_by" gorm:"type:varchar(255)"`
+
+	// ReturnTracking: Número de guía de retorno de Cargo Expreso.
+	ReturnTracking string `json:"return_tracking,omitempty" gorm:"type:varchar(100)"`
+
+	// ReturnGuideURL: URL del PDF de la guía de retorno.
+	ReturnGuideURL string `json:"return_guide_url,omitempty" gorm:"type:text"`
+
+	// RefundID: Reembolso emitido al resolver como 'refunded'.
+	RefundID *uuid.UUID `json:"refund_id,omitempty" gorm:"type:uuid"`
+
+	// ReplacementOrderID: Orden de reemplazo creada al resolver como 'exchanged'.
+	ReplacementOrderID *uuid.UUID `json:"replacement_order_id,omitempty" gorm:"type:uuid"`
+
+	// Items: Prendas incluidas en la devolución.
+	Items []ReturnItem `json:"items" gorm:"foreignKey:ReturnRequestID;constraint:OnDelete:CASCADE"`
+
+	// ReceivedAt: Momento en que las prendas llegaron a la tienda.
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+
+	// ResolvedAt: Momento en que se reembolsó, se cambió o se rechazó.
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	// CreatedAt: Timestamp automático de creación del registro.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+
+	// UpdatedAt: Timestamp automático de última actualización.
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime:milli"`
+}
+
+// ReturnItem representa las unidades de un OrderItem incluidas en una devolución.
+type ReturnItem struct {
+	// ID: Identificador único (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// ReturnRequestID: Devolución a la que pertenece (Foreign Key).
+	ReturnRequestID uuid.UUID `json:"return_request_id" gorm:"type:uuid;index"`
+
+	// OrderItemID: Item de la orden que se devuelve.
+	OrderItemID uuid.UUID `json:"order_item_id" gorm:"type:uuid;index"`
+
+	// ProductID: Producto devuelto (para restock).
+	ProductID uint `json:"product_id" gorm:"index"`
+
+	// ProductName: Snapshot del nombre del producto.
+	ProductName string `json:"product_name"`
+
+	// Quantity: Unidades devueltas.
+	Quantity int `json:"quantity"`
+
+	// Reason: Motivo de la devolución de esta prenda.
+	Reason ReturnReason `json:"reason" gorm:"type:varchar(30)"`
+
+	// ExchangeProductID: Producto que el cliente quiere a cambio (ej: otra talla).
+	// Vacío si prefiere reembolso.
+	ExchangeProductID *uint `json:"exchange_product_id,omitempty"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo ReturnRequest.
+func (ReturnRequest) TableName() string {
+	return "return_requests"
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (r *ReturnRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.Status == "" {
+		r.Status = ReturnRequested
+	}
+	return nil
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo ReturnItem.
+func (ReturnItem) TableName() string {
+	return "return_items"
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (ri *ReturnItem) BeforeCreate(tx *gorm.DB) error {
+	if ri.ID == uuid.Nil {
+		ri.ID = uuid.New()
+	}
+	return nil
+}
+
+// TransitionTo cambia el estado de la devolución validando el flujo. Igual que
+// Order.TransitionTo, el UPDATE se condiciona al estado leído para no
+// sobrescribir un cambio concurrente. fields se guardan en el mismo UPDATE.
+func (r *ReturnRequest) TransitionTo(tx *gorm.DB, next ReturnStatus, fields map[string]interface{}) error {
+	if err := ValidateReturnTransition(r.Status, next); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"status": next}
+	for column, value := range fields {
+		updates[column] = value
+	}
+
+	result := tx.Model(&ReturnRequest{}).
+		Where("id = ? AND status = ?", r.ID, r.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("error al actualizar devolución: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("transición de devolución no permitida: la devolución %s ya no está en estado %s", r.ID, r.Status)
+	}
+
+	r.Status = next
+	return nil
+}
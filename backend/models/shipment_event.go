@@ -0,0 +1,136 @@
+// backend/models/shipment_event.go
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShipmentStatus es el estado normalizado de una guía de Cargo Expreso.
+type ShipmentStatus string
+
+const (
+	ShipmentPending        ShipmentStatus = "pending"          // Guía creada, la transportista aún no recibe el paquete.
+	ShipmentPickedUp       ShipmentStatus = "picked_up"        // Recibido en la agencia de origen.
+	ShipmentInTransit      ShipmentStatus = "in_transit"       // En camino entre agencias.
+	ShipmentOutForDelivery ShipmentStatus = "out_for_delivery" // En ruta de entrega a domicilio.
+	ShipmentReadyForPickup ShipmentStatus = "ready_for_pickup" // Disponible en la sucursal de destino.
+	ShipmentDelivered      ShipmentStatus = "delivered"        // Entregado al cliente.
+	ShipmentException      ShipmentStatus = "exception"        // Incidencia (dirección errónea, rechazo, devolución).
+)
+
+// shipmentStatusAliases traduce los estados que puede reportar la transportista
+// (en español o inglés) al estado normalizado.
+var shipmentStatusAliases = map[string]ShipmentStatus{
+	"pending":                ShipmentPending,
+	"pendiente":              ShipmentPending,
+	"guia generada":          ShipmentPending,
+	"picked_up":              ShipmentPickedUp,
+	"recibido":               ShipmentPickedUp,
+	"recibido en agencia":    ShipmentPickedUp,
+	"recolectado":            ShipmentPickedUp,
+	"in_transit":             ShipmentInTransit,
+	"en transito":            ShipmentInTransit,
+	"en_transito":            ShipmentInTransit,
+	"out_for_delivery":       ShipmentOutForDelivery,
+	"en ruta":                ShipmentOutForDelivery,
+	"en ruta de entrega":     ShipmentOutForDelivery,
+	"ready_for_pickup":       ShipmentReadyForPickup,
+	"disponible en sucursal": ShipmentReadyForPickup,
+	"delivered":              ShipmentDelivered,
+	"entregado":              ShipmentDelivered,
+	"exception":              ShipmentException,
+	"incidencia":             ShipmentException,
+	"devuelto":               ShipmentException,
+}
+
+// ParseShipmentStatus normaliza el estado reportado por la transportista.
+// Retorna false si no se reconoce.
+func ParseShipmentStatus(raw string) (ShipmentStatus, bool) {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	key = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u").Replace(key)
+	status, ok := shipmentStatusAliases[key]
+	return status, ok
+}
+
+// Progress ordena los estados de la guía: un estado con más progreso reemplaza
+// a uno con menos. Las incidencias no avanzan la orden (-1).
+func (s ShipmentStatus) Progress() int {
+	switch s {
+	case ShipmentPending:
+		return 0
+	case ShipmentPickedUp:
+		return 1
+	case ShipmentInTransit:
+		return 2
+	case ShipmentOutForDelivery, ShipmentReadyForPickup:
+		return 3
+	case ShipmentDelivered:
+		return 4
+	}
+	return -1
+}
+
+// OrderStatus retorna el estado que corresponde a la orden según su guía:
+// 'shipped' cuando la transportista ya tiene el paquete y 'delivered' al
+// entregarlo. Vacío si la guía no mueve la orden.
+func (s ShipmentStatus) OrderStatus() OrderStatus {
+	switch {
+	case s == ShipmentDelivered:
+		return StatusDelivered
+	case s.Progress() >= 1:
+		return StatusShipped
+	}
+	return ""
+}
+
+// ShipmentEvent es un evento de rastreo de la guía de una orden, tal como lo
+// reportó la transportista. Las filas nunca se modifican; el mismo evento
+// consultado dos veces no se duplica (índice único por guía, estado y momento).
+type ShipmentEvent struct {
+	// ID: Identificador único del evento (UUID), clave primaria.
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+
+	// OrderID: Orden de la guía (Foreign Key).
+	OrderID uuid.UUID `json:"order_id" gorm:"type:uuid;index;not null"`
+
+	// TrackingNumber: Guía de Cargo Expreso (una orden puede tener varias si se anuló alguna).
+	TrackingNumber string `json:"tracking_number" gorm:"type:varchar(100);not null;uniqueIndex:idx_shipment_events_unique,priority:1"`
+
+	// Status: Estado normalizado (ver ShipmentStatus).
+	Status ShipmentStatus `json:"status" gorm:"type:varchar(30);not null;uniqueIndex:idx_shipment_events_unique,priority:3"`
+
+	// Description: Texto del evento según la transportista (ej: "Recibido en agencia").
+	Description string `json:"description,omitempty" gorm:"type:text"`
+
+	// Location: Agencia o ciudad del evento.
+	Location string `json:"location,omitempty" gorm:"type:varchar(255)"`
+
+	// OccurredAt: Momento del evento según la transportista.
+	OccurredAt time.Time `json:"occurred_at" gorm:"not null;uniqueIndex:idx_shipment_events_unique,priority:2"`
+
+	// CreatedAt: Momento en que se registró (al consultar el rastreo).
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo ShipmentEvent.
+func (ShipmentEvent) TableName() string {
+	return "shipment_events"
+}
+
+// BeforeCreate es un hook de GORM que genera un UUID automático si no existe.
+func (e *ShipmentEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// PreloadShipmentEvents ordena el rastreo del evento más antiguo al más reciente.
+// Uso: db.Preload("ShipmentEvents", models.PreloadShipmentEvents).
+func PreloadShipmentEvents(db *gorm.DB) *gorm.DB {
+	return db.Order("shipment_events.occurred_at ASC")
+}
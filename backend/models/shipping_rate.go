@@ -0,0 +1,134 @@
+// backend/models/shipping_rate.go
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Métodos de envío de una tarifa (se copian a Order.ShippingMethod).
+const (
+	ShippingMethodLocal        = "local_delivery" // Entrega local propia
+	ShippingMethodCargoExpreso = "cargo_expreso"  // Envío por Cargo Expreso
+)
+
+// ShippingRate es una regla de la tabla de tarifas de envío. Para un destino se
+// usa la regla vigente más específica: municipio > departamento > todo el país.
+type ShippingRate struct {
+	// ID: Identificador único de la tarifa, clave primaria.
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Name: Nombre descriptivo para el admin (ej: "Huehuetenango - entrega local").
+	Name string `json:"name" gorm:"type:varchar(100);not null"`
+
+	// Department: ID del departamento (ej: GT-13). Vacío = aplica a todo el país.
+	Department string `json:"department" gorm:"type:varchar(50);index"`
+
+	// Municipality: Nombre oficial del municipio al que aplica. Vacío = todo el
+	// departamento. Se compara completo, sin tildes ni mayúsculas.
+	Municipality string `json:"municipality" gorm:"type:varchar(100);index"`
+
+	// Method: 'local_delivery' | 'cargo_expreso'. Define si la orden requiere courier.
+	Method string `json:"method" gorm:"type:varchar(50);not null"`
+
+	// MinWeightKg / MaxWeightKg: Rango de peso del paquete [min, max).
+	// MaxWeightKg = 0 significa sin límite.
+	MinWeightKg float64 `json:"min_weight_kg" gorm:"default:0"`
+	MaxWeightKg float64 `json:"max_weight_kg" gorm:"default:0"`
+
+	// BaseCost: Costo del envío.
+	BaseCost Money `json:"base_cost" gorm:"type:bigint;not null"`
+
+	// HomeDeliverySurcharge / PickupSurcharge: Recargo según el tipo de entrega
+	// ('home_delivery' o 'pickup_at_branch').
+	HomeDeliverySurcharge Money `json:"home_delivery_surcharge" gorm:"type:bigint;default:0"`
+	PickupSurcharge       Money `json:"pickup_surcharge" gorm:"type:bigint;default:0"`
+
+	// Currency: Moneda de los montos de la fila (ISO 4217).
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'GTQ'"`
+
+	// ValidFrom / ValidUntil: Vigencia de la tarifa. ValidUntil es exclusivo;
+	// nil = sin fecha de fin.
+	ValidFrom  time.Time  `json:"valid_from" gorm:"not null"`
+	ValidUntil *time.Time `json:"valid_until"`
+
+	// Active: Permite desactivar una tarifa sin borrarla.
+	Active bool `json:"active" gorm:"default:true;index"`
+
+	// --- Timestamps ---
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime:milli"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime:milli"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo ShippingRate.
+func (ShippingRate) TableName() string {
+	return "shipping_rates"
+}
+
+// BeforeSave es un hook de GORM que guarda en currency la moneda de sus montos.
+// Falla si los montos son de monedas distintas.
+func (r *ShippingRate) BeforeSave(tx *gorm.DB) error {
+	currency, err := CommonCurrency(Money{Currency: r.Currency}, r.BaseCost, r.HomeDeliverySurcharge, r.PickupSurcharge)
+	if err != nil {
+		return err
+	}
+	r.Currency = currency
+	return nil
+}
+
+// AfterFind es un hook de GORM que asigna a los montos la moneda de la fila.
+func (r *ShippingRate) AfterFind(tx *gorm.DB) error {
+	ApplyCurrency(r.Currency, &r.BaseCost, &r.HomeDeliverySurcharge, &r.PickupSurcharge)
+	return nil
+}
+
+// RequiresCourier indica si los envíos con esta tarifa van por Cargo Expreso.
+func (r *ShippingRate) RequiresCourier() bool {
+	return r.Method == ShippingMethodCargoExpreso
+}
+
+// IsValidAt indica si la tarifa está activa y vigente en el momento t.
+func (r *ShippingRate) IsValidAt(t time.Time) bool {
+	if !r.Active || t.Before(r.ValidFrom) {
+		return false
+	}
+	return r.ValidUntil == nil || t.Before(*r.ValidUntil)
+}
+
+// CoversWeight indica si el peso cae en el rango de la tarifa.
+func (r *ShippingRate) CoversWeight(weightKg float64) bool {
+	if weightKg < r.MinWeightKg {
+		return false
+	}
+	return r.MaxWeightKg == 0 || weightKg < r.MaxWeightKg
+}
+
+// DefaultShippingRates son las tarifas con las que arranca la tabla: entrega
+// local gratis en Huehuetenango y Chiantla, Cargo Expreso a Q36 al resto del país.
+func DefaultShippingRates() []ShippingRate {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []ShippingRate{
+		{Name: "Huehuetenango - entrega local", Department: "GT-13", Municipality: "Huehuetenango", Method: ShippingMethodLocal, BaseCost: NewMoney(0), ValidFrom: since, Active: true},
+		{Name: "Chiantla - entrega local", Department: "GT-13", Municipality: "Chiantla", Method: ShippingMethodLocal, BaseCost: NewMoney(0), ValidFrom: since, Active: true},
+		{Name: "Cargo Expreso nacional", Method: ShippingMethodCargoExpreso, BaseCost: NewMoney(3600), ValidFrom: since, Active: true},
+	}
+}
+
+// SeedShippingRates carga DefaultShippingRates si la tabla está vacía.
+// Retorna la cantidad de tarifas creadas.
+func SeedShippingRates(db *gorm.DB) (int, error) {
+	var count int64
+	if err := db.Model(&ShippingRate{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	rates := DefaultShippingRates()
+	if err := db.Create(&rates).Error; err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
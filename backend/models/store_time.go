@@ -0,0 +1,17 @@
+// backend/models/store_time.go
+package models
+
+import "time"
+
+// StoreTimeZone es la zona horaria de la tienda: los días, meses y años de
+// órdenes y reportes se cuentan en Guatemala, no en UTC.
+const StoreTimeZone = "America/Guatemala"
+
+// StoreLocation es StoreTimeZone cargada. Guatemala no tiene horario de
+// verano, así que si el sistema no trae la base de zonas se usa UTC-6 fijo.
+var StoreLocation = func() *time.Location {
+	if loc, err := time.LoadLocation(StoreTimeZone); err == nil {
+		return loc
+	}
+	return time.FixedZone("CST", -6*60*60)
+}()
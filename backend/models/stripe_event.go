@@ -0,0 +1,30 @@
+// backend/models/stripe_event.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StripeEvent registra cada evento de webhook de Stripe que ya fue procesado.
+// Stripe puede entregar el mismo evento varias veces (reintentos, redes lentas),
+// por lo que guardamos su ID para ignorar las entregas repetidas.
+type StripeEvent struct {
+	// EventID: Identificador del evento en Stripe (ej: "evt_1Nv..."), clave primaria.
+	EventID string `json:"event_id" gorm:"type:varchar(255);primaryKey"`
+
+	// Type: Tipo de evento (ej: "checkout.session.completed").
+	Type string `json:"type" gorm:"type:varchar(100);index"`
+
+	// OrderID: Orden afectada por el evento (si se pudo asociar).
+	OrderID *uuid.UUID `json:"order_id" gorm:"type:uuid;index"`
+
+	// ProcessedAt: Momento en que el evento fue procesado por el backend.
+	ProcessedAt time.Time `json:"processed_at" gorm:"autoCreateTime:milli"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos para el modelo StripeEvent.
+func (StripeEvent) TableName() string {
+	return "stripe_events"
+}
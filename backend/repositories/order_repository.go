@@ -4,6 +4,7 @@ package repositories
 import (
 	"fmt"
 	"log"
+	"sort"
 
 	"moda-organica/backend/models"
 
@@ -18,6 +19,10 @@ type OrderRepository interface {
 	// Retorna error si la creación falla.
 	Create(order *models.Order) error
 
+	// CreateWithStockReservation inserta la orden y descuenta el stock de sus items
+	// en una sola transacción. Retorna error si algún producto no tiene stock suficiente.
+	CreateWithStockReservation(order *models.Order) error
+
 	// GetByID obtiene una orden por su ID (UUID).
 	// Incluye el preload automático de OrderItems.
 	// Retorna error si la orden no existe o la consulta falla.
@@ -37,7 +42,8 @@ type OrderRepository interface {
 
 	// UpdateStatus actualiza solo el estado de una orden.
 	// Esto es más eficiente que actualizar toda la orden solo por el estado.
-	UpdateStatus(orderID uuid.UUID, status models.OrderStatus) error
+	// El cambio queda registrado en el timeline (order_status_events).
+	UpdateStatus(orderID uuid.UUID, status models.OrderStatus, change models.StatusChange) error
 
 	// Delete elimina una orden por su ID.
 	// Retorna error si la eliminación falla.
@@ -63,6 +69,107 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 // Create inserta una nueva orden en la base de datos.
 // Valida que la orden tenga items antes de crearla.
 func (r *orderRepository) Create(order *models.Order) error {
+	if err := validateNewOrder(order); err != nil {
+		return err
+	}
+
+	// Crear la orden; GORM inserta los OrderItems en la misma transacción
+	if err := r.db.Create(order).Error; err != nil {
+		log.Printf("Error al crear orden: %v", err)
+		return fmt.Errorf("error al crear orden: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWithStockReservation inserta la orden y descuenta del inventario las
+// unidades de cada item en una sola transacción (ver ReserveStock). Si algún
+// producto no tiene stock suficiente no se guarda nada y se retorna
+// *InsufficientStockError.
+func (r *orderRepository) CreateWithStockReservation(order *models.Order) error {
+	if err := validateNewOrder(order); err != nil {
+		return err
+	}
+
+	quantities := map[uint]int{}
+	for _, item := range order.OrderItems {
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := ReserveStock(tx, quantities); err != nil {
+			log.Printf("Orden %s rechazada: %v", order.ID, err)
+			return err
+		}
+
+		order.StockReserved = true
+		if err := tx.Create(order).Error; err != nil {
+			log.Printf("Error al crear orden: %v", err)
+			return fmt.Errorf("error al crear orden: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ReserveStock descuenta del inventario las unidades indicadas por producto.
+// Debe llamarse dentro de una transacción: si algún producto no tiene stock
+// suficiente se retorna *InsufficientStockError con todos los faltantes y el
+// llamador debe revertir la transacción.
+//
+// El descuento es un UPDATE condicional (stock >= cantidad) que PostgreSQL
+// ejecuta con la fila bloqueada, de modo que dos compras simultáneas de la
+// última unidad no pueden tener éxito ambas.
+func ReserveStock(tx *gorm.DB, quantities map[uint]int) error {
+	// Ordenar por ID: todas las transacciones bloquean las filas en el
+	// mismo orden y no se producen deadlocks
+	productIDs := make([]uint, 0, len(quantities))
+	for productID := range quantities {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	var shortages []StockShortage
+	for _, productID := range productIDs {
+		quantity := quantities[productID]
+
+		result := tx.Model(&models.Product{}).
+			Where("id = ? AND stock >= ?", productID, quantity).
+			Update("stock", gorm.Expr("stock - ?", quantity))
+		if result.Error != nil {
+			log.Printf("Error al reservar stock: %v", result.Error)
+			return fmt.Errorf("error al actualizar stock: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			continue
+		}
+
+		// No se descontó: el producto no existe o no alcanza el stock
+		var product models.Product
+		if err := tx.Select("id", "name", "stock").
+			Where("id = ?", productID).
+			First(&product).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("producto no encontrado: ID %d", productID)
+			}
+			return fmt.Errorf("error al obtener producto: %w", err)
+		}
+		shortages = append(shortages, StockShortage{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Requested: quantity,
+			Available: product.Stock,
+		})
+	}
+
+	if len(shortages) > 0 {
+		return &InsufficientStockError{Items: shortages}
+	}
+	return nil
+}
+
+// validateNewOrder valida la orden y sus items antes de insertarlos.
+func validateNewOrder(order *models.Order) error {
 	// Validar que la orden tenga items
 	if len(order.OrderItems) == 0 {
 		return fmt.Errorf("no se puede crear una orden sin items")
@@ -73,40 +180,10 @@ func (r *orderRepository) Create(order *models.Order) error {
 		return fmt.Errorf("validación de orden fallida: %w", err)
 	}
 
-	// Usar una transacción para asegurar la integridad de los datos
-	tx := r.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			log.Printf("Panic en Create Order: %v", r)
-		}
-	}()
-
-	// Crear la orden
-	if err := tx.Create(order).Error; err != nil {
-		tx.Rollback()
-		log.Printf("Error al crear orden: %v", err)
-		return fmt.Errorf("error al crear orden: %w", err)
-	}
-
-	// Crear los items de la orden
 	for _, item := range order.OrderItems {
 		if err := item.Validate(); err != nil {
-			tx.Rollback()
 			return fmt.Errorf("validación de item fallida: %w", err)
 		}
-
-		if err := tx.Create(&item).Error; err != nil {
-			tx.Rollback()
-			log.Printf("Error al crear item de orden: %v", err)
-			return fmt.Errorf("error al crear item de orden: %w", err)
-		}
-	}
-
-	// Confirmar la transacción
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("Error al confirmar transacción Create Order: %v", err)
-		return fmt.Errorf("error al confirmar transacción: %w", err)
 	}
 
 	return nil
@@ -117,12 +194,13 @@ func (r *orderRepository) Create(order *models.Order) error {
 // ============================================================================
 
 // GetByID obtiene una orden por su ID.
-// Carga automáticamente los OrderItems asociados (preload).
+// Carga automáticamente los OrderItems y el timeline de estados (preload).
 func (r *orderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
 	var order models.Order
 
 	if err := r.db.
 		Preload("OrderItems").
+		Preload("StatusEvents", models.PreloadStatusEvents).
 		Where("id = ?", id).
 		First(&order).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -143,6 +221,7 @@ func (r *orderRepository) GetByUserID(userID uuid.UUID) ([]models.Order, error)
 
 	if err := r.db.
 		Preload("OrderItems").
+		Preload("StatusEvents", models.PreloadStatusEvents).
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Find(&orders).Error; err != nil {
@@ -205,7 +284,7 @@ func (r *orderRepository) Update(order *models.Order) error {
 
 	// Verificar que la orden existe
 	var existing models.Order
-	if err := r.db.Select("id").Where("id = ?", order.ID).First(&existing).Error; err != nil {
+	if err := r.db.Select("id", "status").Where("id = ?", order.ID).First(&existing).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			log.Printf("Orden no encontrada para actualizar: %s", order.ID)
 			return fmt.Errorf("orden no encontrada: %s", order.ID)
@@ -214,6 +293,13 @@ func (r *orderRepository) Update(order *models.Order) error {
 		return fmt.Errorf("error al verificar orden: %w", err)
 	}
 
+	// Si cambia el estado, la transición debe estar permitida
+	if order.Status != "" && order.Status != existing.Status {
+		if err := models.ValidateStatusTransition(existing.Status, order.Status); err != nil {
+			return err
+		}
+	}
+
 	// Usar una transacción para actualizar
 	tx := r.db.Begin()
 	defer func() {
@@ -232,6 +318,17 @@ func (r *orderRepository) Update(order *models.Order) error {
 		return fmt.Errorf("error al actualizar orden: %w", err)
 	}
 
+	// Registrar el cambio de estado en el timeline
+	if order.Status != "" && order.Status != existing.Status {
+		if err := models.RecordStatusEvent(tx, order.ID, existing.Status, order.Status, models.StatusChange{
+			ActorType: models.ActorSystem,
+			Reason:    "actualización de orden",
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	// Confirmar la transacción
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Error al confirmar transacción Update Order: %v", err)
@@ -243,10 +340,11 @@ func (r *orderRepository) Update(order *models.Order) error {
 
 // UpdateStatus actualiza solo el estado de una orden.
 // Esta función es más eficiente que Update cuando solo necesitas cambiar el estado.
-func (r *orderRepository) UpdateStatus(orderID uuid.UUID, status models.OrderStatus) error {
+// Rechaza las transiciones que no permite la máquina de estados del pedido.
+func (r *orderRepository) UpdateStatus(orderID uuid.UUID, status models.OrderStatus, change models.StatusChange) error {
 	// Validar que la orden existe
 	var order models.Order
-	if err := r.db.Select("id").Where("id = ?", orderID).First(&order).Error; err != nil {
+	if err := r.db.Select("id", "status").Where("id = ?", orderID).First(&order).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			log.Printf("Orden no encontrada para actualizar estado: %s", orderID)
 			return fmt.Errorf("orden no encontrada: %s", orderID)
@@ -255,12 +353,12 @@ func (r *orderRepository) UpdateStatus(orderID uuid.UUID, status models.OrderSta
 		return fmt.Errorf("error al verificar orden: %w", err)
 	}
 
-	// Actualizar solo el estado
-	if err := r.db.Model(&models.Order{}).
-		Where("id = ?", orderID).
-		Update("status", status).Error; err != nil {
+	// Actualizar solo el estado, validando la transición y registrando el evento
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		return order.TransitionTo(tx, status, change)
+	}); err != nil {
 		log.Printf("Error al actualizar estado de orden: %v", err)
-		return fmt.Errorf("error al actualizar estado: %w", err)
+		return err
 	}
 
 	return nil
@@ -0,0 +1,187 @@
+// backend/repositories/order_repository_test.go
+package repositories
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/testsupport"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// createTestProduct crea un producto con el stock indicado y lo elimina, junto
+// con las órdenes creadas contra él, al terminar la prueba.
+func createTestProduct(t *testing.T, gormDB *gorm.DB, name string, stock int) models.Product {
+	t.Helper()
+
+	product := models.Product{
+		SKU:   "REPO-TEST-" + uuid.NewString()[:8],
+		Name:  name,
+		Price: models.NewMoney(10000),
+		Stock: stock,
+	}
+	if err := gormDB.Create(&product).Error; err != nil {
+		t.Fatalf("Error creando producto de prueba: %v", err)
+	}
+
+	t.Cleanup(func() {
+		var orderIDs []uuid.UUID
+		gormDB.Model(&models.OrderItem{}).Where("product_id = ?", product.ID).Distinct().Pluck("order_id", &orderIDs)
+		if len(orderIDs) > 0 {
+			gormDB.Where("order_id IN ?", orderIDs).Delete(&models.OrderStatusEvent{})
+			gormDB.Where("order_id IN ?", orderIDs).Delete(&models.OrderItem{})
+			gormDB.Where("id IN ?", orderIDs).Delete(&models.Order{})
+		}
+		gormDB.Delete(&models.Product{}, product.ID)
+	})
+	return product
+}
+
+// newTestOrder arma una orden pendiente con una línea por producto.
+func newTestOrder(lines map[*models.Product]int) *models.Order {
+	order := &models.Order{
+		Status:               models.StatusPending,
+		CustomerEmail:        "repo-test@example.com",
+		CustomerName:         "Prueba Repositorio",
+		CustomerPhone:        "55555555",
+		ShippingDepartment:   "GT-13",
+		ShippingMunicipality: "Huehuetenango",
+		ShippingAddress:      "4a calle 5-10 zona 1",
+		Subtotal:             models.NewMoney(0),
+		ShippingCost:         models.NewMoney(0),
+		Total:                models.NewMoney(0),
+	}
+	for product, quantity := range lines {
+		order.OrderItems = append(order.OrderItems, models.OrderItem{
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Quantity:    quantity,
+			Price:       product.Price,
+		})
+		order.Subtotal, _ = order.Subtotal.Add(product.Price.Mul(quantity))
+	}
+	order.Total = order.Subtotal
+	return order
+}
+
+func productStock(t *testing.T, gormDB *gorm.DB, productID uint) int {
+	t.Helper()
+	var stock int
+	if err := gormDB.Model(&models.Product{}).Select("stock").Where("id = ?", productID).Scan(&stock).Error; err != nil {
+		t.Fatalf("Error leyendo stock: %v", err)
+	}
+	return stock
+}
+
+func orderExists(t *testing.T, gormDB *gorm.DB, orderID uuid.UUID) bool {
+	t.Helper()
+	var count int64
+	if err := gormDB.Model(&models.Order{}).Where("id = ?", orderID).Count(&count).Error; err != nil {
+		t.Fatalf("Error consultando orden: %v", err)
+	}
+	return count > 0
+}
+
+func TestCreateWithStockReservation(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+	repo := NewOrderRepository(gormDB)
+
+	shirt := createTestProduct(t, gormDB, "Camisa de prueba", 5)
+	bag := createTestProduct(t, gormDB, "Bolso de prueba", 1)
+
+	order := newTestOrder(map[*models.Product]int{&shirt: 2, &bag: 1})
+	if err := repo.CreateWithStockReservation(order); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	if !order.StockReserved {
+		t.Errorf("StockReserved = false, se esperaba true")
+	}
+	if got := productStock(t, gormDB, shirt.ID); got != 3 {
+		t.Errorf("stock de %s = %d, se esperaba 3", shirt.Name, got)
+	}
+	if got := productStock(t, gormDB, bag.ID); got != 0 {
+		t.Errorf("stock de %s = %d, se esperaba 0", bag.Name, got)
+	}
+}
+
+func TestCreateWithStockReservationInsufficientStock(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+	repo := NewOrderRepository(gormDB)
+
+	bag := createTestProduct(t, gormDB, "Bolso de prueba", 1)
+
+	order := newTestOrder(map[*models.Product]int{&bag: 2})
+	err := repo.CreateWithStockReservation(order)
+
+	var shortage *InsufficientStockError
+	if !errors.As(err, &shortage) {
+		t.Fatalf("se esperaba *InsufficientStockError, se obtuvo %v", err)
+	}
+	want := StockShortage{ProductID: bag.ID, Name: bag.Name, Requested: 2, Available: 1}
+	if len(shortage.Items) != 1 || shortage.Items[0] != want {
+		t.Errorf("faltantes = %+v, se esperaba [%+v]", shortage.Items, want)
+	}
+	if !strings.Contains(err.Error(), "stock insuficiente") {
+		t.Errorf("el mensaje debe contener 'stock insuficiente' (409): %q", err.Error())
+	}
+
+	if got := productStock(t, gormDB, bag.ID); got != 1 {
+		t.Errorf("stock = %d, se esperaba 1 (sin cambios)", got)
+	}
+	if order.ID != uuid.Nil && orderExists(t, gormDB, order.ID) {
+		t.Errorf("la orden rechazada no debe guardarse")
+	}
+}
+
+func TestCreateWithStockReservationRollsBackAllItems(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+	repo := NewOrderRepository(gormDB)
+
+	// El primer producto (ID menor) sí alcanza y se descuenta antes de
+	// encontrar el faltante: la transacción debe revertir ese descuento
+	shirt := createTestProduct(t, gormDB, "Camisa de prueba", 5)
+	bag := createTestProduct(t, gormDB, "Bolso de prueba", 1)
+
+	order := newTestOrder(map[*models.Product]int{&shirt: 2, &bag: 3})
+	err := repo.CreateWithStockReservation(order)
+
+	var shortage *InsufficientStockError
+	if !errors.As(err, &shortage) {
+		t.Fatalf("se esperaba *InsufficientStockError, se obtuvo %v", err)
+	}
+	if len(shortage.Items) != 1 || shortage.Items[0].ProductID != bag.ID {
+		t.Errorf("faltantes = %+v, se esperaba solo %s", shortage.Items, bag.Name)
+	}
+
+	if got := productStock(t, gormDB, shirt.ID); got != 5 {
+		t.Errorf("stock de %s = %d, se esperaba 5 (descuento revertido)", shirt.Name, got)
+	}
+	if got := productStock(t, gormDB, bag.ID); got != 1 {
+		t.Errorf("stock de %s = %d, se esperaba 1", bag.Name, got)
+	}
+	if order.ID != uuid.Nil && orderExists(t, gormDB, order.ID) {
+		t.Errorf("la orden rechazada no debe guardarse")
+	}
+}
+
+func TestProductStockCheckConstraint(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+
+	bag := createTestProduct(t, gormDB, "Bolso de prueba", 1)
+
+	// Un descuento que no pase por ReserveStock tampoco puede dejar stock negativo
+	err := gormDB.Model(&models.Product{}).
+		Where("id = ?", bag.ID).
+		Update("stock", gorm.Expr("stock - ?", 2)).Error
+	if err == nil || !strings.Contains(err.Error(), "chk_products_stock_non_negative") {
+		t.Fatalf("se esperaba violación de chk_products_stock_non_negative, se obtuvo %v", err)
+	}
+	if got := productStock(t, gormDB, bag.ID); got != 1 {
+		t.Errorf("stock = %d, se esperaba 1", got)
+	}
+}
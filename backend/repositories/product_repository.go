@@ -0,0 +1,44 @@
+// backend/repositories/product_repository.go
+package repositories
+
+import (
+	"fmt"
+	"log"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ProductRepository define las consultas de productos que necesita el flujo de órdenes.
+// Al ser una interfaz, el servicio de órdenes puede probarse sin base de datos.
+type ProductRepository interface {
+	// GetByID obtiene un producto por su ID.
+	// Retorna error si el producto no existe o la consulta falla.
+	GetByID(id uint) (*models.Product, error)
+}
+
+// productRepository es la implementación concreta de ProductRepository con GORM.
+type productRepository struct {
+	db *gorm.DB
+}
+
+// NewProductRepository crea una nueva instancia del repositorio de productos.
+func NewProductRepository(db *gorm.DB) ProductRepository {
+	return &productRepository{db: db}
+}
+
+// GetByID obtiene un producto por su ID.
+func (r *productRepository) GetByID(id uint) (*models.Product, error) {
+	var product models.Product
+
+	if err := r.db.Where("id = ?", id).First(&product).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("producto no encontrado: ID %d", id)
+		}
+		log.Printf("Error al obtener producto %d: %v", id, err)
+		return nil, fmt.Errorf("error al obtener producto: %w", err)
+	}
+
+	return &product, nil
+}
@@ -0,0 +1,29 @@
+// backend/repositories/stock_errors.go
+package repositories
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StockShortage describe un producto sin unidades suficientes para una orden.
+type StockShortage struct {
+	ProductID uint   `json:"product_id"`
+	Name      string `json:"name"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
+
+// InsufficientStockError indica que uno o más productos no tienen stock suficiente.
+// El mensaje siempre contiene "stock insuficiente" para mapearlo a 409 Conflict.
+type InsufficientStockError struct {
+	Items []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		parts = append(parts, fmt.Sprintf("%s (disponible: %d, solicitado: %d)", item.Name, item.Available, item.Requested))
+	}
+	return "stock insuficiente para " + strings.Join(parts, ", ")
+}
@@ -4,6 +4,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"strings"
 	"time"
@@ -17,7 +18,8 @@ type Product struct {
 	SKU         string `gorm:"unique"`
 	Name        string
 	Description string
-	Price       float64
+	Price       int64 // Centavos (columna BIGINT)
+	Currency    string
 	Stock       int
 	ImageURL    string
 	Images      string `gorm:"type:jsonb;default:'[]'"`
@@ -172,7 +174,8 @@ func main() {
 			SKU:         sku,
 			Name:        p.Name,
 			Description: p.Desc,
-			Price:       p.Price,
+			Price:       int64(math.Round(p.Price * 100)),
+			Currency:    "GTQ",
 			Stock:       10,
 			ImageURL:    mainImageURL,
 			Images:      imagesJSON,
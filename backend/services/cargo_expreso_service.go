@@ -9,7 +9,10 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"sync"
 	"time"
+
+	"moda-organica/backend/models"
 )
 
 // ============================================
@@ -31,11 +34,11 @@ type CargoExpresoGuideRequest struct {
 	RecipientCity    string `json:"recipient_city"`
 
 	// Datos del envío
-	OrderID       string  `json:"order_id"`       // UUID de la orden como string
-	PackageType   string  `json:"package_type"`   // "sobre", "caja_pequeña", "caja_mediana"
-	Weight        float64 `json:"weight"`         // Peso en libras
-	DeclaredValue float64 `json:"declared_value"` // Valor declarado en Q
-	Notes         string  `json:"notes"`          // Notas especiales
+	OrderID       string       `json:"order_id"`       // UUID de la orden como string
+	PackageType   string       `json:"package_type"`   // "sobre", "caja_pequeña", "caja_mediana"
+	Weight        float64      `json:"weight"`         // Peso en libras
+	DeclaredValue models.Money `json:"declared_value"` // Valor declarado en Q
+	Notes         string       `json:"notes"`          // Notas especiales
 
 	// NUEVO: Tipo de entrega (home delivery o pickup)
 	DeliveryType string `json:"delivery_type"`           // "home_delivery" | "pickup_at_branch"
@@ -44,12 +47,12 @@ type CargoExpresoGuideRequest struct {
 
 // CargoExpresoGuideResponse representa la respuesta al crear una guía
 type CargoExpresoGuideResponse struct {
-	Success        bool    `json:"success"`
-	TrackingNumber string  `json:"tracking_number"`
-	GuideURL       string  `json:"guide_url"`      // URL del PDF de la guía
-	EstimatedDays  int     `json:"estimated_days"` // Días estimados de entrega
-	Cost           float64 `json:"cost"`           // Costo del envío
-	ErrorMessage   string  `json:"error_message,omitempty"`
+	Success        bool         `json:"success"`
+	TrackingNumber string       `json:"tracking_number"`
+	GuideURL       string       `json:"guide_url"`      // URL del PDF de la guía
+	EstimatedDays  int          `json:"estimated_days"` // Días estimados de entrega
+	Cost           models.Money `json:"cost"`           // Costo del envío
+	ErrorMessage   string       `json:"error_message,omitempty"`
 }
 
 // ============================================
@@ -60,14 +63,26 @@ type CargoExpresoGuideResponse struct {
 type CargoExpresoService interface {
 	CreateGuide(request CargoExpresoGuideRequest) (*CargoExpresoGuideResponse, error)
 	GetTrackingInfo(trackingNumber string) (*TrackingInfo, error)
+	CancelGuide(trackingNumber string) error
 }
 
 // TrackingInfo representa información de rastreo
 type TrackingInfo struct {
 	TrackingNumber string    `json:"tracking_number"`
-	Status         string    `json:"status"` // "pending", "in_transit", "delivered"
+	Status         string    `json:"status"` // Estado actual (ver models.ShipmentStatus)
 	LastUpdate     time.Time `json:"last_update"`
 	Location       string    `json:"location"`
+
+	// Events: Historial de la guía, del más antiguo al más reciente
+	Events []TrackingEvent `json:"events"`
+}
+
+// TrackingEvent es un evento del historial de la guía
+type TrackingEvent struct {
+	Status      string    `json:"status"`      // Ver models.ParseShipmentStatus
+	Description string    `json:"description"` // Ej: "Recibido en agencia"
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
 }
 
 // ============================================
@@ -76,6 +91,27 @@ type TrackingInfo struct {
 
 type mockCargoExpresoService struct{}
 
+// mockShipment es una guía simulada: su progreso depende del tiempo desde que se creó
+type mockShipment struct {
+	createdAt    time.Time
+	originCity   string
+	destCity     string
+	pickupBranch string
+}
+
+// mockShipments guarda las guías simuladas a nivel de paquete: cada servicio
+// crea su propia instancia del mock, pero todas deben ver las mismas guías
+var (
+	mockShipmentsMu sync.Mutex
+	mockShipments   = map[string]mockShipment{}
+)
+
+// mockTransitTime es lo que tarda una guía simulada en entregarse
+// (CARGO_EXPRESO_MOCK_TRANSIT_TIME, ej: "72h"; "10m" para probar el flujo completo)
+func mockTransitTime() time.Duration {
+	return durationFromEnv("CARGO_EXPRESO_MOCK_TRANSIT_TIME", 72*time.Hour)
+}
+
 // NewMockCargoExpresoService crea una instancia del servicio mock
 func NewMockCargoExpresoService() CargoExpresoService {
 	return &mockCargoExpresoService{}
@@ -92,27 +128,103 @@ func (s *mockCargoExpresoService) CreateGuide(request CargoExpresoGuideRequest)
 	// URL de guía fake (puedes crear un PDF mock o retornar una URL placeholder)
 	guideURL := fmt.Sprintf("https://storage.example.com/guides/%s.pdf", trackingNumber)
 
+	// Registrar la guía para simular su rastreo
+	shipment := mockShipment{
+		createdAt:  time.Now(),
+		originCity: request.SenderCity,
+		destCity:   request.RecipientCity,
+	}
+	if request.DeliveryType == "pickup_at_branch" {
+		shipment.pickupBranch = request.PickupBranch
+	}
+	mockShipmentsMu.Lock()
+	mockShipments[trackingNumber] = shipment
+	mockShipmentsMu.Unlock()
+
 	return &CargoExpresoGuideResponse{
 		Success:        true,
 		TrackingNumber: trackingNumber,
 		GuideURL:       guideURL,
 		EstimatedDays:  3, // 3-5 días típico
-		Cost:           36.00,
+		Cost:           models.NewMoney(3600),
 		ErrorMessage:   "",
 	}, nil
 }
 
-// GetTrackingInfo retorna información de rastreo simulada
+// GetTrackingInfo simula el avance de la guía según el tiempo desde que se
+// creó: recibida, en tránsito, en ruta (o en sucursal) y entregada. Las guías
+// que el mock no conoce (ej: creadas antes de reiniciar) empiezan a avanzar
+// desde la primera consulta.
 func (s *mockCargoExpresoService) GetTrackingInfo(trackingNumber string) (*TrackingInfo, error) {
+	if trackingNumber == "" {
+		return nil, fmt.Errorf("tracking number requerido para consultar el rastreo")
+	}
+
 	// Simular delay
 	time.Sleep(300 * time.Millisecond)
 
-	return &TrackingInfo{
-		TrackingNumber: trackingNumber,
-		Status:         "in_transit",
-		LastUpdate:     time.Now().Add(-2 * time.Hour),
-		Location:       "Centro de Distribución Ciudad de Guatemala",
-	}, nil
+	mockShipmentsMu.Lock()
+	shipment, ok := mockShipments[trackingNumber]
+	if !ok {
+		shipment = mockShipment{createdAt: time.Now()}
+		mockShipments[trackingNumber] = shipment
+	}
+	mockShipmentsMu.Unlock()
+
+	origin := shipment.originCity
+	if origin == "" {
+		origin = "Huehuetenango"
+	}
+	destination := shipment.destCity
+	if destination == "" {
+		destination = "destino"
+	}
+
+	lastMile := TrackingEvent{Status: "out_for_delivery", Description: "En ruta de entrega", Location: destination}
+	delivered := TrackingEvent{Status: "delivered", Description: "Entregado al destinatario", Location: destination}
+	if shipment.pickupBranch != "" {
+		lastMile = TrackingEvent{Status: "ready_for_pickup", Description: "Disponible en sucursal", Location: shipment.pickupBranch}
+		delivered = TrackingEvent{Status: "delivered", Description: "Entregado en sucursal", Location: shipment.pickupBranch}
+	}
+
+	// Cada paso ocurre en una fracción del tiempo de tránsito
+	steps := []struct {
+		at    float64
+		event TrackingEvent
+	}{
+		{0, TrackingEvent{Status: "pending", Description: "Guía generada", Location: origin}},
+		{0.1, TrackingEvent{Status: "picked_up", Description: "Recibido en agencia", Location: origin}},
+		{0.3, TrackingEvent{Status: "in_transit", Description: "En tránsito", Location: "Centro de Distribución Ciudad de Guatemala"}},
+		{0.75, lastMile},
+		{1, delivered},
+	}
+
+	transit := mockTransitTime()
+	now := time.Now()
+	info := &TrackingInfo{TrackingNumber: trackingNumber}
+	for _, step := range steps {
+		occurredAt := shipment.createdAt.Add(time.Duration(step.at * float64(transit))).Truncate(time.Second)
+		if occurredAt.After(now) {
+			break
+		}
+		event := step.event
+		event.OccurredAt = occurredAt
+		info.Events = append(info.Events, event)
+		info.Status, info.Location, info.LastUpdate = event.Status, event.Location, event.OccurredAt
+	}
+
+	return info, nil
+}
+
+// CancelGuide simula la anulación de una guía (para desarrollo)
+func (s *mockCargoExpresoService) CancelGuide(trackingNumber string) error {
+	if trackingNumber == "" {
+		return fmt.Errorf("tracking number requerido para anular la guía")
+	}
+	mockShipmentsMu.Lock()
+	delete(mockShipments, trackingNumber)
+	mockShipmentsMu.Unlock()
+	return nil
 }
 
 // generateMockTrackingNumber genera un tracking number fake pero realista
@@ -133,15 +245,21 @@ func generateMockTrackingNumber() string {
 // ============================================
 
 type realCargoExpresoService struct {
-	n8nWebhookURL string
-	apiKey        string
+	n8nWebhookURL         string
+	n8nCancelWebhookURL   string
+	n8nTrackingWebhookURL string
+	apiKey                string
 }
 
 // NewRealCargoExpresoService crea una instancia del servicio real (n8n)
-func NewRealCargoExpresoService(n8nURL, apiKey string) CargoExpresoService {
+// cancelURL es el workflow que anula guías y trackingURL el que consulta el
+// rastreo (vacíos si no están configurados)
+func NewRealCargoExpresoService(n8nURL, cancelURL, trackingURL, apiKey string) CargoExpresoService {
 	return &realCargoExpresoService{
-		n8nWebhookURL: n8nURL,
-		apiKey:        apiKey,
+		n8nWebhookURL:         n8nURL,
+		n8nCancelWebhookURL:   cancelURL,
+		n8nTrackingWebhookURL: trackingURL,
+		apiKey:                apiKey,
 	}
 }
 
@@ -191,10 +309,81 @@ func (s *realCargoExpresoService) CreateGuide(request CargoExpresoGuideRequest)
 	return &response, nil
 }
 
-// GetTrackingInfo obtiene info de tracking desde n8n
+// GetTrackingInfo llama al workflow de n8n que consulta el rastreo de la guía
+// en Cargo Expreso. Body: {"tracking_number": "..."}; respuesta: TrackingInfo.
 func (s *realCargoExpresoService) GetTrackingInfo(trackingNumber string) (*TrackingInfo, error) {
-	// TODO: Implementar cuando n8n tenga endpoint de tracking
-	return nil, fmt.Errorf("tracking info not implemented yet")
+	if s.n8nTrackingWebhookURL == "" {
+		return nil, fmt.Errorf("error al consultar rastreo de %s: N8N_CARGO_EXPRESO_TRACKING_WEBHOOK_URL no configurado", trackingNumber)
+	}
+
+	payload, err := json.Marshal(map[string]string{"tracking_number": trackingNumber})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", s.n8nTrackingWebhookURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar rastreo de %s: %w", trackingNumber, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error al consultar rastreo de %s: n8n returned %s (status %d)", trackingNumber, string(body), resp.StatusCode)
+	}
+
+	var info TrackingInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("error parsing n8n response: %w", err)
+	}
+	if info.TrackingNumber == "" {
+		info.TrackingNumber = trackingNumber
+	}
+	return &info, nil
+}
+
+// CancelGuide llama al workflow de n8n que anula la guía en Cargo Expreso
+func (s *realCargoExpresoService) CancelGuide(trackingNumber string) error {
+	if s.n8nCancelWebhookURL == "" {
+		return fmt.Errorf("error al anular guía %s: N8N_CARGO_EXPRESO_CANCEL_WEBHOOK_URL no configurado", trackingNumber)
+	}
+
+	payload, err := json.Marshal(map[string]string{"tracking_number": trackingNumber})
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", s.n8nCancelWebhookURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error al anular guía %s: %w", trackingNumber, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error al anular guía %s: n8n returned %s (status %d)", trackingNumber, string(body), resp.StatusCode)
+	}
+
+	return nil
 }
 
 // ============================================
@@ -214,6 +403,8 @@ func NewCargoExpresoService() CargoExpresoService {
 
 	// Modo real (requiere credenciales)
 	n8nURL := os.Getenv("N8N_CARGO_EXPRESO_WEBHOOK_URL")
+	cancelURL := os.Getenv("N8N_CARGO_EXPRESO_CANCEL_WEBHOOK_URL")
+	trackingURL := os.Getenv("N8N_CARGO_EXPRESO_TRACKING_WEBHOOK_URL")
 	apiKey := os.Getenv("N8N_API_KEY")
 
 	if n8nURL == "" {
@@ -222,5 +413,5 @@ func NewCargoExpresoService() CargoExpresoService {
 	}
 
 	fmt.Println("Cargo Expreso: Modo REAL activado (n8n)")
-	return NewRealCargoExpresoService(n8nURL, apiKey)
+	return NewRealCargoExpresoService(n8nURL, cancelURL, trackingURL, apiKey)
 }
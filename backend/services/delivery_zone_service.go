@@ -0,0 +1,499 @@
+// backend/services/delivery_zone_service.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// DeliveryZoneDTO crea o reemplaza una zona de entrega. En GeoJSON son las
+// properties de cada Feature (la geometría va en el Feature).
+type DeliveryZoneDTO struct {
+	Name string `json:"name" binding:"required,max=100"`
+
+	// Department / Municipality: Municipio que cubre la zona (opcional).
+	// Se guardan como código de departamento y nombre oficial del municipio.
+	Department   string `json:"department" binding:"max=50"`
+	Municipality string `json:"municipality" binding:"max=100"`
+
+	// Method: 'local_delivery' (default) | 'cargo_expreso'.
+	Method string `json:"method" binding:"omitempty,oneof=local_delivery cargo_expreso"`
+
+	Cost models.Money `json:"cost"`
+
+	// Geometry: Polygon o MultiPolygon GeoJSON ([lng, lat]).
+	Geometry models.ZoneGeometry `json:"geometry"`
+
+	// Active: default true.
+	Active *bool `json:"active"`
+}
+
+// Validate verifica nombre, método, costo, geometría y municipio (normaliza
+// departamento y municipio al catálogo geográfico).
+func (dto *DeliveryZoneDTO) Validate() error {
+	dto.Name = strings.TrimSpace(dto.Name)
+	if dto.Name == "" || len(dto.Name) > 100 {
+		return fmt.Errorf("validación: name es requerido (máximo 100 caracteres)")
+	}
+	switch dto.Method {
+	case "":
+		dto.Method = models.ShippingMethodLocal
+	case models.ShippingMethodLocal, models.ShippingMethodCargoExpreso:
+	default:
+		return fmt.Errorf("validación: method debe ser local_delivery o cargo_expreso")
+	}
+	if dto.Cost.IsNegative() {
+		return fmt.Errorf("validación: el costo no puede ser negativo")
+	}
+	if _, err := parseZoneGeometry(dto.Geometry); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(dto.Municipality) != "" {
+		dept, mun, err := ResolveLocation(dto.Department, dto.Municipality)
+		if err != nil {
+			return err
+		}
+		dto.Department, dto.Municipality = dept.Code, mun.Name
+	} else if strings.TrimSpace(dto.Department) != "" {
+		dept, ok := FindDepartment(dto.Department)
+		if !ok {
+			return fmt.Errorf("validación: departamento desconocido: %s", dto.Department)
+		}
+		dto.Department = dept.Code
+	}
+	return nil
+}
+
+// apply copia el DTO sobre la zona.
+func (dto *DeliveryZoneDTO) apply(zone *models.DeliveryZone) {
+	zone.Name = dto.Name
+	zone.Department = dto.Department
+	zone.Municipality = dto.Municipality
+	zone.Method = dto.Method
+	zone.Cost = dto.Cost
+	zone.Geometry = dto.Geometry
+	zone.Active = true
+	if dto.Active != nil {
+		zone.Active = *dto.Active
+	}
+}
+
+// DeliveryZoneFeature es una zona como Feature GeoJSON.
+type DeliveryZoneFeature struct {
+	Type       string              `json:"type"`
+	ID         uint                `json:"id,omitempty"`
+	Properties DeliveryZoneDTO     `json:"properties"`
+	Geometry   models.ZoneGeometry `json:"geometry"`
+}
+
+// DeliveryZoneCollection es un FeatureCollection GeoJSON de zonas
+// (formato de importación y exportación, editable en geojson.io o QGIS).
+type DeliveryZoneCollection struct {
+	Type     string                `json:"type"`
+	Features []DeliveryZoneFeature `json:"features"`
+}
+
+// ImportResult resume una importación de zonas.
+type ImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// ZoneLocation es el resultado de ubicar un punto en las zonas.
+type ZoneLocation struct {
+	Point GeoPoint             `json:"point"`
+	Zone  *models.DeliveryZone `json:"zone"`
+}
+
+// LocationMismatch es una orden cuyo pin cae fuera del municipio declarado.
+type LocationMismatch struct {
+	OrderID              uuid.UUID          `json:"order_id"`
+	OrderNumber          string             `json:"order_number"`
+	Status               models.OrderStatus `json:"status"`
+	CustomerName         string             `json:"customer_name"`
+	ShippingDepartment   string             `json:"shipping_department"`
+	ShippingMunicipality string             `json:"shipping_municipality"`
+	Pin                  GeoPoint           `json:"pin"`
+
+	// PinZone / PinMunicipality: Zona (y su municipio) donde cae el pin;
+	// vacíos si el pin no cae en ninguna zona.
+	PinZone         string `json:"pin_zone"`
+	PinMunicipality string `json:"pin_municipality"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LocationMismatchReport es el resultado de revisar los pines de las órdenes.
+type LocationMismatchReport struct {
+	// Checked: Órdenes verificables (su municipio tiene zonas o el pin cae en
+	// la zona de otro municipio).
+	Checked    int                `json:"checked"`
+	Mismatches []LocationMismatch `json:"mismatches"`
+
+	// Truncated: Se alcanzó el límite de resultados.
+	Truncated bool `json:"truncated"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// DeliveryZoneService administra las zonas de entrega por coordenadas. Cada
+// cambio invalida la caché del store de tarifas (las zonas se cargan con él).
+type DeliveryZoneService interface {
+	List(includeInactive bool) ([]models.DeliveryZone, error)
+	Get(id uint) (*models.DeliveryZone, error)
+	Create(dto DeliveryZoneDTO) (*models.DeliveryZone, error)
+	Update(id uint, dto DeliveryZoneDTO) (*models.DeliveryZone, error)
+	Delete(id uint) error
+
+	// Import crea o actualiza (por nombre) las zonas de un FeatureCollection.
+	// Es todo o nada: una zona inválida cancela la importación.
+	Import(collection DeliveryZoneCollection) (*ImportResult, error)
+
+	// Export retorna todas las zonas como FeatureCollection.
+	Export() (*DeliveryZoneCollection, error)
+
+	// Locate retorna la zona activa que contiene el punto (Zone nil si ninguna).
+	Locate(point GeoPoint) (*ZoneLocation, error)
+
+	// LocationMismatches lista las órdenes cuyo pin cae fuera del municipio
+	// declarado. Solo se pueden verificar los municipios que tienen zonas.
+	LocationMismatches(filter OrderFilter, limit int) (*LocationMismatchReport, error)
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type deliveryZoneService struct {
+	db    *gorm.DB
+	store ShippingRateStore
+}
+
+// NewDeliveryZoneService crea el servicio de zonas. store es el mismo que
+// usa QuoteShipping (ver SetShippingRateStore).
+func NewDeliveryZoneService(db *gorm.DB, store ShippingRateStore) DeliveryZoneService {
+	return &deliveryZoneService{db: db, store: store}
+}
+
+func (s *deliveryZoneService) List(includeInactive bool) ([]models.DeliveryZone, error) {
+	query := s.db.Model(&models.DeliveryZone{})
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+
+	var zones []models.DeliveryZone
+	if err := query.Order("department ASC, municipality ASC, name ASC").Find(&zones).Error; err != nil {
+		return nil, fmt.Errorf("error al obtener zonas: %w", err)
+	}
+	return zones, nil
+}
+
+func (s *deliveryZoneService) Get(id uint) (*models.DeliveryZone, error) {
+	var zone models.DeliveryZone
+	if err := s.db.First(&zone, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("zona no encontrada: %d", id)
+		}
+		return nil, fmt.Errorf("error al obtener zona: %w", err)
+	}
+	return &zone, nil
+}
+
+func (s *deliveryZoneService) Create(dto DeliveryZoneDTO) (*models.DeliveryZone, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	zone := &models.DeliveryZone{}
+	dto.apply(zone)
+	if err := createDeliveryZone(s.db, zone); err != nil {
+		return nil, err
+	}
+
+	s.store.Invalidate()
+	return zone, nil
+}
+
+func (s *deliveryZoneService) Update(id uint, dto DeliveryZoneDTO) (*models.DeliveryZone, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	zone, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	dto.apply(zone)
+	if err := s.db.Save(zone).Error; err != nil {
+		return nil, fmt.Errorf("error al actualizar zona: %w", err)
+	}
+
+	s.store.Invalidate()
+	return zone, nil
+}
+
+func (s *deliveryZoneService) Delete(id uint) error {
+	result := s.db.Delete(&models.DeliveryZone{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("error al eliminar zona: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("zona no encontrada: %d", id)
+	}
+
+	s.store.Invalidate()
+	return nil
+}
+
+func (s *deliveryZoneService) Import(collection DeliveryZoneCollection) (*ImportResult, error) {
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("validación: se espera un FeatureCollection GeoJSON")
+	}
+	if len(collection.Features) == 0 {
+		return nil, fmt.Errorf("validación: el FeatureCollection no tiene zonas")
+	}
+
+	dtos := make([]DeliveryZoneDTO, 0, len(collection.Features))
+	names := map[string]bool{}
+	for i, feature := range collection.Features {
+		dto := feature.Properties
+		dto.Geometry = feature.Geometry
+		if err := dto.Validate(); err != nil {
+			return nil, fmt.Errorf("%w (feature %d)", err, i)
+		}
+		if names[dto.Name] {
+			return nil, fmt.Errorf("validación: zona repetida en el archivo: %s", dto.Name)
+		}
+		names[dto.Name] = true
+		dtos = append(dtos, dto)
+	}
+
+	result := &ImportResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, dto := range dtos {
+			var zone models.DeliveryZone
+			err := tx.Where("name = ?", dto.Name).First(&zone).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				dto.apply(&zone)
+				if err := createDeliveryZone(tx, &zone); err != nil {
+					return err
+				}
+				result.Created++
+			case err != nil:
+				return fmt.Errorf("error al buscar zona %s: %w", dto.Name, err)
+			default:
+				dto.apply(&zone)
+				if err := tx.Save(&zone).Error; err != nil {
+					return fmt.Errorf("error al actualizar zona %s: %w", dto.Name, err)
+				}
+				result.Updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.store.Invalidate()
+	return result, nil
+}
+
+func (s *deliveryZoneService) Export() (*DeliveryZoneCollection, error) {
+	zones, err := s.List(true)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &DeliveryZoneCollection{Type: "FeatureCollection", Features: make([]DeliveryZoneFeature, 0, len(zones))}
+	for _, zone := range zones {
+		active := zone.Active
+		collection.Features = append(collection.Features, DeliveryZoneFeature{
+			Type: "Feature",
+			ID:   zone.ID,
+			Properties: DeliveryZoneDTO{
+				Name:         zone.Name,
+				Department:   zone.Department,
+				Municipality: zone.Municipality,
+				Method:       zone.Method,
+				Cost:         zone.Cost,
+				Active:       &active,
+			},
+			Geometry: zone.Geometry,
+		})
+	}
+	return collection, nil
+}
+
+func (s *deliveryZoneService) Locate(point GeoPoint) (*ZoneLocation, error) {
+	zones, err := s.geofenceZones()
+	if err != nil {
+		return nil, err
+	}
+
+	location := &ZoneLocation{Point: point}
+	if zone, ok := LocateZone(zones, point); ok {
+		location.Zone = &zone.Zone
+	}
+	return location, nil
+}
+
+// LocationMismatches revisa en lotes las órdenes con pin. Una orden no
+// coincide si su municipio tiene zonas y el pin no cae en ninguna de ellas,
+// o si el pin cae en la zona de otro municipio.
+func (s *deliveryZoneService) LocationMismatches(filter OrderFilter, limit int) (*LocationMismatchReport, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	zones, err := s.geofenceZones()
+	if err != nil {
+		return nil, err
+	}
+
+	// Zonas por municipio (nombre normalizado)
+	byMunicipality := map[string][]GeofenceZone{}
+	for _, zone := range zones {
+		if zone.Zone.Municipality != "" {
+			key := normalizeString(zone.Zone.Municipality)
+			byMunicipality[key] = append(byMunicipality[key], zone)
+		}
+	}
+
+	report := &LocationMismatchReport{Mismatches: []LocationMismatch{}}
+	var orders []models.Order
+	query := filter.Apply(s.db.Model(&models.Order{})).
+		Where("orders.delivery_lat IS NOT NULL AND orders.delivery_lng IS NOT NULL")
+	err = query.FindInBatches(&orders, 500, func(tx *gorm.DB, batch int) error {
+		for i := range orders {
+			order := &orders[i]
+			pin, err := NewGeoPoint(order.DeliveryLat, order.DeliveryLng)
+			if err != nil || pin == nil {
+				continue
+			}
+
+			municipality := order.ShippingMunicipality
+			if _, mun, err := ResolveLocation(order.ShippingDepartment, order.ShippingMunicipality); err == nil {
+				municipality = mun.Name
+			}
+
+			pinZone, inZone := LocateZone(zones, *pin)
+			declaredZones := byMunicipality[normalizeString(municipality)]
+			inDeclared := false
+			for _, zone := range declaredZones {
+				if zone.Contains(*pin) {
+					inDeclared = true
+					break
+				}
+			}
+
+			otherMunicipality := inZone && pinZone.Zone.Municipality != "" &&
+				normalizeString(pinZone.Zone.Municipality) != normalizeString(municipality)
+			if len(declaredZones) == 0 && !otherMunicipality {
+				continue // Municipio sin zonas: no se puede verificar
+			}
+			report.Checked++
+			if inDeclared {
+				continue
+			}
+
+			mismatch := LocationMismatch{
+				OrderID:              order.ID,
+				OrderNumber:          order.OrderNumber,
+				Status:               order.Status,
+				CustomerName:         order.CustomerName,
+				ShippingDepartment:   order.ShippingDepartment,
+				ShippingMunicipality: order.ShippingMunicipality,
+				Pin:                  *pin,
+				CreatedAt:            order.CreatedAt,
+			}
+			if inZone {
+				mismatch.PinZone = pinZone.Zone.Name
+				mismatch.PinMunicipality = pinZone.Zone.Municipality
+			}
+			report.Mismatches = append(report.Mismatches, mismatch)
+			if len(report.Mismatches) >= limit {
+				report.Truncated = true
+				return errStopBatches
+			}
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errStopBatches) {
+		return nil, fmt.Errorf("error al revisar órdenes: %w", err)
+	}
+	return report, nil
+}
+
+// errStopBatches corta FindInBatches al llegar al límite de resultados.
+var errStopBatches = errors.New("límite de resultados alcanzado")
+
+// geofenceZones carga las zonas activas con su geometría interpretada.
+func (s *deliveryZoneService) geofenceZones() ([]GeofenceZone, error) {
+	rules, err := s.store.Rules()
+	if err != nil {
+		return nil, err
+	}
+	return rules.Zones, nil
+}
+
+// createDeliveryZone inserta la zona. GORM omite los campos en cero con
+// default (Active=false quedaría en true), por eso se corrige después.
+func createDeliveryZone(db *gorm.DB, zone *models.DeliveryZone) error {
+	if err := db.Create(zone).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return fmt.Errorf("validación: ya existe una zona llamada %s", zone.Name)
+		}
+		return fmt.Errorf("error al crear zona: %w", err)
+	}
+	if !zone.Active {
+		if err := db.Model(zone).Update("active", false).Error; err != nil {
+			return fmt.Errorf("error al crear zona: %w", err)
+		}
+	}
+	return nil
+}
+
+// SeedDeliveryZones importa un archivo GeoJSON (DELIVERY_ZONES_GEOJSON) si la
+// tabla de zonas está vacía. Retorna la cantidad de zonas creadas.
+func SeedDeliveryZones(db *gorm.DB, service DeliveryZoneService, path string) (int, error) {
+	var count int64
+	if err := db.Model(&models.DeliveryZone{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("error al leer %s: %w", path, err)
+	}
+	var collection DeliveryZoneCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return 0, fmt.Errorf("error al interpretar %s: %w", path, err)
+	}
+
+	result, err := service.Import(collection)
+	if err != nil {
+		return 0, err
+	}
+	return result.Created, nil
+}
@@ -0,0 +1,105 @@
+// backend/services/email_service.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// ============================================
+// INTERFACE
+// ============================================
+
+// EmailService define el contrato para enviar emails transaccionales
+type EmailService interface {
+	// Send envía un email de texto plano
+	Send(to, subject, body string) error
+}
+
+// ============================================
+// MOCK IMPLEMENTATION
+// ============================================
+
+type mockEmailService struct{}
+
+// NewMockEmailService crea un servicio que solo escribe los emails en el log (desarrollo)
+func NewMockEmailService() EmailService {
+	return &mockEmailService{}
+}
+
+// Send escribe el email en el log
+func (s *mockEmailService) Send(to, subject, body string) error {
+	log.Printf("[EMAIL MOCK] Para: %s | Asunto: %s\n%s", to, subject, body)
+	return nil
+}
+
+// ============================================
+// SMTP IMPLEMENTATION
+// ============================================
+
+type smtpEmailService struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPEmailService crea un servicio que envía emails por SMTP
+func NewSMTPEmailService(host, port, username, password, from string) EmailService {
+	return &smtpEmailService{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send envía el email con autenticación PLAIN
+func (s *smtpEmailService) Send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("error enviando email: encabezados inválidos")
+	}
+
+	message := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	auth := smtp.PlainAuth("", s.username, s.password, s.host)
+	if err := smtp.SendMail(s.host+":"+s.port, auth, s.from, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("error enviando email a %s: %w", to, err)
+	}
+	return nil
+}
+
+// ============================================
+// FACTORY
+// ============================================
+
+// NewEmailService retorna la implementación correcta según configuración
+func NewEmailService() EmailService {
+	// EMAIL_MOCK=true (o sin SMTP_HOST) solo escribe los emails en el log
+	host := os.Getenv("SMTP_HOST")
+	if os.Getenv("EMAIL_MOCK") == "true" || host == "" {
+		fmt.Println("Email: Modo MOCK activado")
+		return NewMockEmailService()
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	fmt.Println("Email: Modo SMTP activado")
+	return NewSMTPEmailService(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("EMAIL_FROM"))
+}
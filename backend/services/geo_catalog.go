@@ -0,0 +1,175 @@
+// backend/services/geo_catalog.go
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// Tipos
+// ============================================================================
+
+// GeoDepartment es un departamento de Guatemala.
+type GeoDepartment struct {
+	// Code: Código ISO 3166-2:GT (ej: GT-13).
+	Code string `json:"code"`
+
+	// Name: Nombre oficial (ej: Huehuetenango).
+	Name string `json:"name"`
+
+	// Aliases: Otros nombres o códigos aceptados al buscar (ej: GT-HU, Huehue).
+	Aliases []string `json:"-"`
+
+	// Municipalities: Municipios en el orden del INE.
+	Municipalities []GeoMunicipality `json:"-"`
+}
+
+// GeoMunicipality es un municipio de Guatemala.
+type GeoMunicipality struct {
+	// Code: Código del INE (ej: 1302 = Chiantla, Huehuetenango).
+	Code string `json:"code"`
+
+	// DepartmentCode: Código ISO del departamento (ej: GT-13).
+	DepartmentCode string `json:"department_code"`
+
+	// Name: Nombre oficial (ej: Chiantla).
+	Name string `json:"name"`
+
+	// Aliases: Otros nombres aceptados al buscar (ej: Barillas).
+	Aliases []string `json:"-"`
+}
+
+func geoDepartment(code, name string, aliases []string, municipalities ...GeoMunicipality) GeoDepartment {
+	for i := range municipalities {
+		municipalities[i].DepartmentCode = code
+	}
+	return GeoDepartment{Code: code, Name: name, Aliases: aliases, Municipalities: municipalities}
+}
+
+func geoMunicipality(code, name string, aliases ...string) GeoMunicipality {
+	return GeoMunicipality{Code: code, Name: name, Aliases: aliases}
+}
+
+// ============================================================================
+// Índice
+// ============================================================================
+
+// geoIndex permite buscar departamentos y municipios por código, nombre o
+// alias, sin tildes ni mayúsculas (ver normalizeString).
+type geoIndex struct {
+	departments map[string]*GeoDepartment
+
+	// municipalitiesByCode: código INE -> municipio
+	municipalitiesByCode map[string]*GeoMunicipality
+
+	// municipalitiesByName: nombre o alias -> municipios con ese nombre
+	// (hay nombres repetidos entre departamentos, ej: San José, La Libertad)
+	municipalitiesByName map[string][]*GeoMunicipality
+}
+
+var guatemalaGeo = newGeoIndex(guatemalaDepartments)
+
+func newGeoIndex(departments []GeoDepartment) *geoIndex {
+	idx := &geoIndex{
+		departments:          map[string]*GeoDepartment{},
+		municipalitiesByCode: map[string]*GeoMunicipality{},
+		municipalitiesByName: map[string][]*GeoMunicipality{},
+	}
+
+	for i := range departments {
+		dept := &departments[i]
+		keys := append([]string{dept.Code, strings.TrimPrefix(dept.Code, "GT-"), dept.Name}, dept.Aliases...)
+		for _, key := range keys {
+			idx.departments[normalizeString(key)] = dept
+		}
+
+		for j := range dept.Municipalities {
+			mun := &dept.Municipalities[j]
+			idx.municipalitiesByCode[mun.Code] = mun
+
+			seen := map[string]bool{}
+			for _, name := range append([]string{mun.Name}, mun.Aliases...) {
+				key := normalizeString(name)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				idx.municipalitiesByName[key] = append(idx.municipalitiesByName[key], mun)
+			}
+		}
+	}
+	return idx
+}
+
+// municipality busca un municipio por código INE o nombre. departmentCode
+// vacío busca en todo el país.
+func (idx *geoIndex) municipality(departmentCode, input string) []*GeoMunicipality {
+	if mun, ok := idx.municipalitiesByCode[strings.TrimSpace(input)]; ok {
+		if departmentCode == "" || mun.DepartmentCode == departmentCode {
+			return []*GeoMunicipality{mun}
+		}
+		return nil
+	}
+
+	var found []*GeoMunicipality
+	for _, mun := range idx.municipalitiesByName[normalizeString(input)] {
+		if departmentCode == "" || mun.DepartmentCode == departmentCode {
+			found = append(found, mun)
+		}
+	}
+	return found
+}
+
+// ============================================================================
+// Funciones públicas
+// ============================================================================
+
+// GeoDepartments retorna los 22 departamentos en orden de código.
+func GeoDepartments() []GeoDepartment {
+	return guatemalaDepartments
+}
+
+// FindDepartment busca un departamento por código (GT-13, 13, GT-HU), nombre
+// o alias, sin tildes ni mayúsculas.
+func FindDepartment(input string) (*GeoDepartment, bool) {
+	dept, ok := guatemalaGeo.departments[normalizeString(input)]
+	return dept, ok
+}
+
+// ResolveLocation valida que el municipio pertenezca al departamento y retorna
+// sus registros del catálogo. El municipio se busca por código INE, nombre o
+// alias. Si el departamento viene vacío se deduce del municipio, siempre que
+// el nombre no se repita en otro departamento.
+func ResolveLocation(department, municipality string) (*GeoDepartment, *GeoMunicipality, error) {
+	if strings.TrimSpace(municipality) == "" {
+		return nil, nil, fmt.Errorf("validación: municipio no puede estar vacío")
+	}
+
+	if strings.TrimSpace(department) == "" {
+		found := guatemalaGeo.municipality("", municipality)
+		switch len(found) {
+		case 0:
+			return nil, nil, fmt.Errorf("validación: municipio desconocido: %s", municipality)
+		case 1:
+			dept, _ := FindDepartment(found[0].DepartmentCode)
+			return dept, found[0], nil
+		default:
+			return nil, nil, fmt.Errorf("validación: hay varios municipios llamados %s, indique el departamento", municipality)
+		}
+	}
+
+	dept, ok := FindDepartment(department)
+	if !ok {
+		return nil, nil, fmt.Errorf("validación: departamento desconocido: %s", department)
+	}
+
+	found := guatemalaGeo.municipality(dept.Code, municipality)
+	if len(found) == 0 {
+		if len(guatemalaGeo.municipality("", municipality)) > 0 {
+			return nil, nil, fmt.Errorf("validación: el municipio %s no pertenece al departamento %s", municipality, dept.Name)
+		}
+		return nil, nil, fmt.Errorf("validación: municipio desconocido: %s", municipality)
+	}
+	return dept, found[0], nil
+}
@@ -0,0 +1,394 @@
+// backend/services/geo_catalog_data.go
+package services
+
+// guatemalaDepartments es el catálogo de los 22 departamentos y 340 municipios
+// de Guatemala. Los departamentos usan el código ISO 3166-2:GT (GT-01 a GT-22)
+// y los municipios el código del INE (DDMM: departamento + municipio).
+// Los alias incluyen nombres oficiales largos, nombres de uso común y los
+// códigos ISO anteriores (GT-HU, ...).
+var guatemalaDepartments = []GeoDepartment{
+	geoDepartment("GT-01", "Guatemala", []string{"GT-GU"},
+		geoMunicipality("0101", "Guatemala", "Ciudad de Guatemala", "Guatemala Ciudad", "Ciudad Capital"),
+		geoMunicipality("0102", "Santa Catarina Pinula"),
+		geoMunicipality("0103", "San José Pinula"),
+		geoMunicipality("0104", "San José del Golfo"),
+		geoMunicipality("0105", "Palencia"),
+		geoMunicipality("0106", "Chinautla"),
+		geoMunicipality("0107", "San Pedro Ayampuc"),
+		geoMunicipality("0108", "Mixco"),
+		geoMunicipality("0109", "San Pedro Sacatepéquez"),
+		geoMunicipality("0110", "San Juan Sacatepéquez"),
+		geoMunicipality("0111", "San Raymundo", "San Raimundo"),
+		geoMunicipality("0112", "Chuarrancho"),
+		geoMunicipality("0113", "Fraijanes"),
+		geoMunicipality("0114", "Amatitlán"),
+		geoMunicipality("0115", "Villa Nueva"),
+		geoMunicipality("0116", "Villa Canales"),
+		geoMunicipality("0117", "San Miguel Petapa", "Petapa"),
+	),
+	geoDepartment("GT-02", "El Progreso", []string{"GT-PR", "Progreso"},
+		geoMunicipality("0201", "Guastatoya", "El Progreso Guastatoya"),
+		geoMunicipality("0202", "Morazán"),
+		geoMunicipality("0203", "San Agustín Acasaguastlán"),
+		geoMunicipality("0204", "San Cristóbal Acasaguastlán"),
+		geoMunicipality("0205", "El Jícaro"),
+		geoMunicipality("0206", "Sansare"),
+		geoMunicipality("0207", "Sanarate"),
+		geoMunicipality("0208", "San Antonio La Paz"),
+	),
+	geoDepartment("GT-03", "Sacatepéquez", []string{"GT-SA"},
+		geoMunicipality("0301", "Antigua Guatemala", "Antigua", "La Antigua Guatemala", "La Antigua"),
+		geoMunicipality("0302", "Jocotenango"),
+		geoMunicipality("0303", "Pastores"),
+		geoMunicipality("0304", "Sumpango"),
+		geoMunicipality("0305", "Santo Domingo Xenacoj"),
+		geoMunicipality("0306", "Santiago Sacatepéquez"),
+		geoMunicipality("0307", "San Bartolomé Milpas Altas"),
+		geoMunicipality("0308", "San Lucas Sacatepéquez"),
+		geoMunicipality("0309", "Santa Lucía Milpas Altas"),
+		geoMunicipality("0310", "Magdalena Milpas Altas"),
+		geoMunicipality("0311", "Santa María de Jesús"),
+		geoMunicipality("0312", "Ciudad Vieja"),
+		geoMunicipality("0313", "San Miguel Dueñas"),
+		geoMunicipality("0314", "Alotenango", "San Juan Alotenango"),
+		geoMunicipality("0315", "San Antonio Aguas Calientes"),
+		geoMunicipality("0316", "Santa Catarina Barahona"),
+	),
+	geoDepartment("GT-04", "Chimaltenango", []string{"GT-CM"},
+		geoMunicipality("0401", "Chimaltenango"),
+		geoMunicipality("0402", "San José Poaquil"),
+		geoMunicipality("0403", "San Martín Jilotepeque"),
+		geoMunicipality("0404", "San Juan Comalapa", "Comalapa"),
+		geoMunicipality("0405", "Santa Apolonia"),
+		geoMunicipality("0406", "Tecpán Guatemala", "Tecpán"),
+		geoMunicipality("0407", "Patzún"),
+		geoMunicipality("0408", "San Miguel Pochuta", "Pochuta"),
+		geoMunicipality("0409", "Patzicía"),
+		geoMunicipality("0410", "Santa Cruz Balanyá"),
+		geoMunicipality("0411", "Acatenango"),
+		geoMunicipality("0412", "San Pedro Yepocapa", "Yepocapa"),
+		geoMunicipality("0413", "San Andrés Itzapa"),
+		geoMunicipality("0414", "Parramos"),
+		geoMunicipality("0415", "Zaragoza"),
+		geoMunicipality("0416", "El Tejar"),
+	),
+	geoDepartment("GT-05", "Escuintla", []string{"GT-ES"},
+		geoMunicipality("0501", "Escuintla"),
+		geoMunicipality("0502", "Santa Lucía Cotzumalguapa"),
+		geoMunicipality("0503", "La Democracia"),
+		geoMunicipality("0504", "Siquinalá"),
+		geoMunicipality("0505", "Masagua"),
+		geoMunicipality("0506", "Tiquisate"),
+		geoMunicipality("0507", "La Gomera"),
+		geoMunicipality("0508", "Guanagazapa"),
+		geoMunicipality("0509", "San José", "Puerto San José"),
+		geoMunicipality("0510", "Iztapa"),
+		geoMunicipality("0511", "Palín"),
+		geoMunicipality("0512", "San Vicente Pacaya"),
+		geoMunicipality("0513", "Nueva Concepción"),
+		geoMunicipality("0514", "Sipacate"),
+	),
+	geoDepartment("GT-06", "Santa Rosa", []string{"GT-SR"},
+		geoMunicipality("0601", "Cuilapa"),
+		geoMunicipality("0602", "Barberena"),
+		geoMunicipality("0603", "Santa Rosa de Lima"),
+		geoMunicipality("0604", "Casillas"),
+		geoMunicipality("0605", "San Rafael Las Flores"),
+		geoMunicipality("0606", "Oratorio"),
+		geoMunicipality("0607", "San Juan Tecuaco"),
+		geoMunicipality("0608", "Chiquimulilla"),
+		geoMunicipality("0609", "Taxisco"),
+		geoMunicipality("0610", "Santa María Ixhuatán"),
+		geoMunicipality("0611", "Guazacapán"),
+		geoMunicipality("0612", "Santa Cruz Naranjo"),
+		geoMunicipality("0613", "Pueblo Nuevo Viñas"),
+		geoMunicipality("0614", "Nueva Santa Rosa"),
+	),
+	geoDepartment("GT-07", "Sololá", []string{"GT-SO"},
+		geoMunicipality("0701", "Sololá"),
+		geoMunicipality("0702", "San José Chacayá"),
+		geoMunicipality("0703", "Santa María Visitación"),
+		geoMunicipality("0704", "Santa Lucía Utatlán"),
+		geoMunicipality("0705", "Nahualá"),
+		geoMunicipality("0706", "Santa Catarina Ixtahuacán"),
+		geoMunicipality("0707", "Santa Clara La Laguna"),
+		geoMunicipality("0708", "Concepción"),
+		geoMunicipality("0709", "San Andrés Semetabaj"),
+		geoMunicipality("0710", "Panajachel"),
+		geoMunicipality("0711", "Santa Catarina Palopó"),
+		geoMunicipality("0712", "San Antonio Palopó"),
+		geoMunicipality("0713", "San Lucas Tolimán"),
+		geoMunicipality("0714", "Santa Cruz La Laguna"),
+		geoMunicipality("0715", "San Pablo La Laguna"),
+		geoMunicipality("0716", "San Marcos La Laguna"),
+		geoMunicipality("0717", "San Juan La Laguna"),
+		geoMunicipality("0718", "San Pedro La Laguna"),
+		geoMunicipality("0719", "Santiago Atitlán"),
+	),
+	geoDepartment("GT-08", "Totonicapán", []string{"GT-TO"},
+		geoMunicipality("0801", "Totonicapán"),
+		geoMunicipality("0802", "San Cristóbal Totonicapán"),
+		geoMunicipality("0803", "San Francisco El Alto"),
+		geoMunicipality("0804", "San Andrés Xecul"),
+		geoMunicipality("0805", "Momostenango"),
+		geoMunicipality("0806", "Santa María Chiquimula"),
+		geoMunicipality("0807", "Santa Lucía La Reforma"),
+		geoMunicipality("0808", "San Bartolo", "San Bartolo Aguas Calientes"),
+	),
+	geoDepartment("GT-09", "Quetzaltenango", []string{"GT-QZ", "Xela"},
+		geoMunicipality("0901", "Quetzaltenango", "Xela", "Xelajú"),
+		geoMunicipality("0902", "Salcajá"),
+		geoMunicipality("0903", "Olintepeque"),
+		geoMunicipality("0904", "San Carlos Sija"),
+		geoMunicipality("0905", "Sibilia"),
+		geoMunicipality("0906", "Cabricán"),
+		geoMunicipality("0907", "Cajolá"),
+		geoMunicipality("0908", "San Miguel Sigüilá"),
+		geoMunicipality("0909", "San Juan Ostuncalco", "Ostuncalco"),
+		geoMunicipality("0910", "San Mateo"),
+		geoMunicipality("0911", "Concepción Chiquirichapa"),
+		geoMunicipality("0912", "San Martín Sacatepéquez", "San Martín Chile Verde"),
+		geoMunicipality("0913", "Almolonga"),
+		geoMunicipality("0914", "Cantel"),
+		geoMunicipality("0915", "Huitán"),
+		geoMunicipality("0916", "Zunil"),
+		geoMunicipality("0917", "Colomba Costa Cuca", "Colomba"),
+		geoMunicipality("0918", "San Francisco La Unión"),
+		geoMunicipality("0919", "El Palmar"),
+		geoMunicipality("0920", "Coatepeque"),
+		geoMunicipality("0921", "Génova"),
+		geoMunicipality("0922", "Flores Costa Cuca"),
+		geoMunicipality("0923", "La Esperanza"),
+		geoMunicipality("0924", "Palestina de Los Altos"),
+	),
+	geoDepartment("GT-10", "Suchitepéquez", []string{"GT-SU"},
+		geoMunicipality("1001", "Mazatenango"),
+		geoMunicipality("1002", "Cuyotenango"),
+		geoMunicipality("1003", "San Francisco Zapotitlán"),
+		geoMunicipality("1004", "San Bernardino"),
+		geoMunicipality("1005", "San José El Ídolo"),
+		geoMunicipality("1006", "Santo Domingo Suchitepéquez"),
+		geoMunicipality("1007", "San Lorenzo"),
+		geoMunicipality("1008", "Samayac"),
+		geoMunicipality("1009", "San Pablo Jocopilas"),
+		geoMunicipality("1010", "San Antonio Suchitepéquez"),
+		geoMunicipality("1011", "San Miguel Panán"),
+		geoMunicipality("1012", "San Gabriel"),
+		geoMunicipality("1013", "Chicacao"),
+		geoMunicipality("1014", "Patulul"),
+		geoMunicipality("1015", "Santa Bárbara"),
+		geoMunicipality("1016", "San Juan Bautista"),
+		geoMunicipality("1017", "Santo Tomás La Unión"),
+		geoMunicipality("1018", "Zunilito"),
+		geoMunicipality("1019", "Pueblo Nuevo"),
+		geoMunicipality("1020", "Río Bravo"),
+		geoMunicipality("1021", "San José La Máquina"),
+	),
+	geoDepartment("GT-11", "Retalhuleu", []string{"GT-RE", "Reu"},
+		geoMunicipality("1101", "Retalhuleu", "Reu"),
+		geoMunicipality("1102", "San Sebastián"),
+		geoMunicipality("1103", "Santa Cruz Muluá"),
+		geoMunicipality("1104", "San Martín Zapotitlán"),
+		geoMunicipality("1105", "San Felipe"),
+		geoMunicipality("1106", "San Andrés Villa Seca"),
+		geoMunicipality("1107", "Champerico"),
+		geoMunicipality("1108", "Nuevo San Carlos"),
+		geoMunicipality("1109", "El Asintal"),
+	),
+	geoDepartment("GT-12", "San Marcos", []string{"GT-SM"},
+		geoMunicipality("1201", "San Marcos"),
+		geoMunicipality("1202", "San Pedro Sacatepéquez"),
+		geoMunicipality("1203", "San Antonio Sacatepéquez"),
+		geoMunicipality("1204", "Comitancillo"),
+		geoMunicipality("1205", "San Miguel Ixtahuacán"),
+		geoMunicipality("1206", "Concepción Tutuapa"),
+		geoMunicipality("1207", "Tacaná"),
+		geoMunicipality("1208", "Sibinal"),
+		geoMunicipality("1209", "Tajumulco"),
+		geoMunicipality("1210", "Tejutla"),
+		geoMunicipality("1211", "San Rafael Pie de la Cuesta"),
+		geoMunicipality("1212", "Nuevo Progreso"),
+		geoMunicipality("1213", "El Tumbador"),
+		geoMunicipality("1214", "El Rodeo"),
+		geoMunicipality("1215", "Malacatán"),
+		geoMunicipality("1216", "Catarina"),
+		geoMunicipality("1217", "Ayutla", "Tecún Umán"),
+		geoMunicipality("1218", "Ocós"),
+		geoMunicipality("1219", "San Pablo"),
+		geoMunicipality("1220", "El Quetzal"),
+		geoMunicipality("1221", "La Reforma"),
+		geoMunicipality("1222", "Pajapita"),
+		geoMunicipality("1223", "Ixchiguán"),
+		geoMunicipality("1224", "San José Ojetenam"),
+		geoMunicipality("1225", "San Cristóbal Cucho"),
+		geoMunicipality("1226", "Sipacapa"),
+		geoMunicipality("1227", "Esquipulas Palo Gordo"),
+		geoMunicipality("1228", "Río Blanco"),
+		geoMunicipality("1229", "San Lorenzo"),
+		geoMunicipality("1230", "La Blanca"),
+	),
+	geoDepartment("GT-13", "Huehuetenango", []string{"GT-HU", "Huehue"},
+		geoMunicipality("1301", "Huehuetenango", "Huehue"),
+		geoMunicipality("1302", "Chiantla"),
+		geoMunicipality("1303", "Malacatancito"),
+		geoMunicipality("1304", "Cuilco"),
+		geoMunicipality("1305", "Nentón"),
+		geoMunicipality("1306", "San Pedro Necta"),
+		geoMunicipality("1307", "Jacaltenango"),
+		geoMunicipality("1308", "Soloma", "San Pedro Soloma"),
+		geoMunicipality("1309", "San Ildefonso Ixtahuacán"),
+		geoMunicipality("1310", "Santa Bárbara"),
+		geoMunicipality("1311", "La Libertad"),
+		geoMunicipality("1312", "La Democracia"),
+		geoMunicipality("1313", "San Miguel Acatán"),
+		geoMunicipality("1314", "San Rafael La Independencia"),
+		geoMunicipality("1315", "Todos Santos Cuchumatán", "Todos Santos"),
+		geoMunicipality("1316", "San Juan Atitán"),
+		geoMunicipality("1317", "Santa Eulalia"),
+		geoMunicipality("1318", "San Mateo Ixtatán"),
+		geoMunicipality("1319", "Colotenango"),
+		geoMunicipality("1320", "San Sebastián Huehuetenango"),
+		geoMunicipality("1321", "Tectitán"),
+		geoMunicipality("1322", "Concepción Huista"),
+		geoMunicipality("1323", "San Juan Ixcoy"),
+		geoMunicipality("1324", "San Antonio Huista"),
+		geoMunicipality("1325", "San Sebastián Coatán"),
+		geoMunicipality("1326", "Santa Cruz Barillas", "Barillas"),
+		geoMunicipality("1327", "Aguacatán"),
+		geoMunicipality("1328", "San Rafael Petzal"),
+		geoMunicipality("1329", "San Gaspar Ixchil"),
+		geoMunicipality("1330", "Santiago Chimaltenango"),
+		geoMunicipality("1331", "Santa Ana Huista"),
+		geoMunicipality("1332", "Unión Cantinil"),
+		geoMunicipality("1333", "Petatán"),
+	),
+	geoDepartment("GT-14", "Quiché", []string{"GT-QC", "El Quiché"},
+		geoMunicipality("1401", "Santa Cruz del Quiché", "Quiché", "Santa Cruz Quiché"),
+		geoMunicipality("1402", "Chiché"),
+		geoMunicipality("1403", "Chinique"),
+		geoMunicipality("1404", "Zacualpa"),
+		geoMunicipality("1405", "Chajul", "San Gaspar Chajul"),
+		geoMunicipality("1406", "Chichicastenango", "Santo Tomás Chichicastenango"),
+		geoMunicipality("1407", "Patzité"),
+		geoMunicipality("1408", "San Antonio Ilotenango"),
+		geoMunicipality("1409", "San Pedro Jocopilas"),
+		geoMunicipality("1410", "Cunén"),
+		geoMunicipality("1411", "San Juan Cotzal", "Cotzal"),
+		geoMunicipality("1412", "Joyabaj"),
+		geoMunicipality("1413", "Nebaj", "Santa María Nebaj"),
+		geoMunicipality("1414", "San Andrés Sajcabajá"),
+		geoMunicipality("1415", "Uspantán", "San Miguel Uspantán"),
+		geoMunicipality("1416", "Sacapulas"),
+		geoMunicipality("1417", "San Bartolomé Jocotenango"),
+		geoMunicipality("1418", "Canillá"),
+		geoMunicipality("1419", "Chicamán"),
+		geoMunicipality("1420", "Ixcán", "Playa Grande"),
+		geoMunicipality("1421", "Pachalum"),
+	),
+	geoDepartment("GT-15", "Baja Verapaz", []string{"GT-BV"},
+		geoMunicipality("1501", "Salamá"),
+		geoMunicipality("1502", "San Miguel Chicaj"),
+		geoMunicipality("1503", "Rabinal"),
+		geoMunicipality("1504", "Cubulco"),
+		geoMunicipality("1505", "Granados"),
+		geoMunicipality("1506", "El Chol", "Santa Cruz El Chol"),
+		geoMunicipality("1507", "San Jerónimo"),
+		geoMunicipality("1508", "Purulhá"),
+	),
+	geoDepartment("GT-16", "Alta Verapaz", []string{"GT-AV"},
+		geoMunicipality("1601", "Cobán"),
+		geoMunicipality("1602", "Santa Cruz Verapaz"),
+		geoMunicipality("1603", "San Cristóbal Verapaz"),
+		geoMunicipality("1604", "Tactic"),
+		geoMunicipality("1605", "Tamahú"),
+		geoMunicipality("1606", "Tucurú", "San Miguel Tucurú"),
+		geoMunicipality("1607", "Panzós"),
+		geoMunicipality("1608", "Senahú"),
+		geoMunicipality("1609", "San Pedro Carchá", "Carchá"),
+		geoMunicipality("1610", "San Juan Chamelco", "Chamelco"),
+		geoMunicipality("1611", "Lanquín"),
+		geoMunicipality("1612", "Cahabón", "Santa María Cahabón"),
+		geoMunicipality("1613", "Chisec"),
+		geoMunicipality("1614", "Chahal"),
+		geoMunicipality("1615", "Fray Bartolomé de las Casas", "Fray Bartolomé"),
+		geoMunicipality("1616", "Santa Catalina La Tinta", "La Tinta"),
+		geoMunicipality("1617", "Raxruhá"),
+	),
+	geoDepartment("GT-17", "Petén", []string{"GT-PE", "El Petén"},
+		geoMunicipality("1701", "Flores"),
+		geoMunicipality("1702", "San José"),
+		geoMunicipality("1703", "San Benito"),
+		geoMunicipality("1704", "San Andrés"),
+		geoMunicipality("1705", "La Libertad"),
+		geoMunicipality("1706", "San Francisco"),
+		geoMunicipality("1707", "Santa Ana"),
+		geoMunicipality("1708", "Dolores"),
+		geoMunicipality("1709", "San Luis"),
+		geoMunicipality("1710", "Sayaxché"),
+		geoMunicipality("1711", "Melchor de Mencos"),
+		geoMunicipality("1712", "Poptún"),
+		geoMunicipality("1713", "Las Cruces"),
+		geoMunicipality("1714", "El Chal"),
+	),
+	geoDepartment("GT-18", "Izabal", []string{"GT-IZ"},
+		geoMunicipality("1801", "Puerto Barrios"),
+		geoMunicipality("1802", "Livingston"),
+		geoMunicipality("1803", "El Estor"),
+		geoMunicipality("1804", "Morales"),
+		geoMunicipality("1805", "Los Amates"),
+	),
+	geoDepartment("GT-19", "Zacapa", []string{"GT-ZA"},
+		geoMunicipality("1901", "Zacapa"),
+		geoMunicipality("1902", "Estanzuela"),
+		geoMunicipality("1903", "Río Hondo"),
+		geoMunicipality("1904", "Gualán"),
+		geoMunicipality("1905", "Teculután"),
+		geoMunicipality("1906", "Usumatlán"),
+		geoMunicipality("1907", "Cabañas"),
+		geoMunicipality("1908", "San Diego"),
+		geoMunicipality("1909", "La Unión"),
+		geoMunicipality("1910", "Huité"),
+		geoMunicipality("1911", "San Jorge"),
+	),
+	geoDepartment("GT-20", "Chiquimula", []string{"GT-CQ"},
+		geoMunicipality("2001", "Chiquimula"),
+		geoMunicipality("2002", "San José La Arada"),
+		geoMunicipality("2003", "San Juan Ermita"),
+		geoMunicipality("2004", "Jocotán"),
+		geoMunicipality("2005", "Camotán"),
+		geoMunicipality("2006", "Olopa"),
+		geoMunicipality("2007", "Esquipulas"),
+		geoMunicipality("2008", "Concepción Las Minas"),
+		geoMunicipality("2009", "Quezaltepeque"),
+		geoMunicipality("2010", "San Jacinto"),
+		geoMunicipality("2011", "Ipala"),
+	),
+	geoDepartment("GT-21", "Jalapa", []string{"GT-JA"},
+		geoMunicipality("2101", "Jalapa"),
+		geoMunicipality("2102", "San Pedro Pinula"),
+		geoMunicipality("2103", "San Luis Jilotepeque"),
+		geoMunicipality("2104", "San Manuel Chaparrón"),
+		geoMunicipality("2105", "San Carlos Alzatate"),
+		geoMunicipality("2106", "Monjas"),
+		geoMunicipality("2107", "Mataquescuintla"),
+	),
+	geoDepartment("GT-22", "Jutiapa", []string{"GT-JU"},
+		geoMunicipality("2201", "Jutiapa"),
+		geoMunicipality("2202", "El Progreso"),
+		geoMunicipality("2203", "Santa Catarina Mita"),
+		geoMunicipality("2204", "Agua Blanca"),
+		geoMunicipality("2205", "Asunción Mita"),
+		geoMunicipality("2206", "Yupiltepeque"),
+		geoMunicipality("2207", "Atescatempa"),
+		geoMunicipality("2208", "Jerez"),
+		geoMunicipality("2209", "El Adelanto"),
+		geoMunicipality("2210", "Zapotitlán"),
+		geoMunicipality("2211", "Comapa"),
+		geoMunicipality("2212", "Jalpatagua"),
+		geoMunicipality("2213", "Conguaco"),
+		geoMunicipality("2214", "Moyuta"),
+		geoMunicipality("2215", "Pasaco"),
+		geoMunicipality("2216", "San José Acatempa"),
+		geoMunicipality("2217", "Quesada"),
+	),
+}
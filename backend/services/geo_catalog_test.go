@@ -0,0 +1,120 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeoCatalogIsComplete(t *testing.T) {
+	departments := GeoDepartments()
+	if len(departments) != 22 {
+		t.Errorf("departamentos = %d, se esperaban 22", len(departments))
+	}
+
+	total := 0
+	codes := map[string]bool{}
+	for _, dept := range departments {
+		names := map[string]bool{}
+		for _, mun := range dept.Municipalities {
+			total++
+			if codes[mun.Code] {
+				t.Errorf("código INE repetido: %s", mun.Code)
+			}
+			codes[mun.Code] = true
+
+			// El código INE empieza con el número del departamento (GT-13 -> 13xx)
+			if mun.DepartmentCode != dept.Code || !strings.HasPrefix(mun.Code, strings.TrimPrefix(dept.Code, "GT-")) {
+				t.Errorf("municipio %s (%s) no corresponde a %s", mun.Name, mun.Code, dept.Code)
+			}
+
+			key := normalizeString(mun.Name)
+			if names[key] {
+				t.Errorf("municipio repetido en %s: %s", dept.Name, mun.Name)
+			}
+			names[key] = true
+		}
+	}
+	if total != 340 {
+		t.Errorf("municipios = %d, se esperaban 340", total)
+	}
+}
+
+func TestFindDepartment(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantCode string
+	}{
+		{"GT-13", "GT-13"},
+		{"13", "GT-13"},
+		{"GT-HU", "GT-13"},
+		{"huehue", "GT-13"},
+		{"  HUEHUETENANGO ", "GT-13"},
+		{"Sacatepequez", "GT-03"},
+		{"El Quiché", "GT-14"},
+		{"Xela", "GT-09"},
+		{"GT-23", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			dept, ok := FindDepartment(tt.input)
+			if tt.wantCode == "" {
+				if ok {
+					t.Fatalf("se esperaba no encontrado, se obtuvo %s", dept.Code)
+				}
+				return
+			}
+			if !ok || dept.Code != tt.wantCode {
+				t.Fatalf("FindDepartment(%q) = %+v (%v), se esperaba %s", tt.input, dept, ok, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	tests := []struct {
+		name             string
+		department       string
+		municipality     string
+		wantMunicipality string // código INE
+		wantErr          string
+	}{
+		{"nombre oficial", "GT-13", "Chiantla", "1302", ""},
+		{"sin tildes ni mayúsculas", "huehuetenango", "santa cruz barillas", "1326", ""},
+		{"alias del municipio", "GT-13", "Barillas", "1326", ""},
+		{"alias del departamento y del municipio", "Xela", "Xelajú", "0901", ""},
+		{"código INE", "GT-13", "1302", "1302", ""},
+		{"código INE de otro departamento", "GT-01", "1302", "", "no pertenece"},
+		{"nombre repetido con departamento", "GT-17", "San José", "1702", ""},
+		{"nombre repetido en el otro departamento", "Escuintla", "San José", "0509", ""},
+		{"nombre repetido sin departamento", "", "San José", "", "varios municipios"},
+		{"otro nombre repetido sin departamento", "", "La Libertad", "", "varios municipios"},
+		{"nombre único sin departamento", "", "Mixco", "0108", ""},
+		{"alias único sin departamento", "", "Puerto San José", "0509", ""},
+		{"municipio de otro departamento", "GT-13", "Mixco", "", "no pertenece"},
+		{"municipio desconocido", "GT-13", "Gotham", "", "municipio desconocido"},
+		{"municipio desconocido sin departamento", "", "Gotham", "", "municipio desconocido"},
+		{"departamento desconocido", "GT-99", "Chiantla", "", "departamento desconocido"},
+		{"municipio vacío", "GT-13", "  ", "", "no puede estar vacío"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dept, mun, err := ResolveLocation(tt.department, tt.municipality)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("se esperaba error con %q, se obtuvo %v", tt.wantErr, err)
+				}
+				if !strings.HasPrefix(err.Error(), "validación") {
+					t.Errorf("el error debe ser de validación: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if mun.Code != tt.wantMunicipality || mun.DepartmentCode != dept.Code {
+				t.Errorf("resultado = %s/%s (%s), se esperaba %s", dept.Code, mun.Name, mun.Code, tt.wantMunicipality)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// backend/services/geofence.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"moda-organica/backend/models"
+)
+
+// ============================================================================
+// Geometría
+// ============================================================================
+
+// GeoPoint es una coordenada de entrega.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// NewGeoPoint valida y arma un punto a partir de las coordenadas opcionales
+// de una orden. Retorna nil si no hay coordenadas.
+func NewGeoPoint(lat, lng *float64) (*GeoPoint, error) {
+	if lat == nil && lng == nil {
+		return nil, nil
+	}
+	if lat == nil || lng == nil {
+		return nil, fmt.Errorf("validación: delivery_lat y delivery_lng deben enviarse juntas")
+	}
+	if math.IsNaN(*lat) || *lat < -90 || *lat > 90 || math.IsNaN(*lng) || *lng < -180 || *lng > 180 {
+		return nil, fmt.Errorf("validación: coordenadas de entrega inválidas (%v, %v)", *lat, *lng)
+	}
+	return &GeoPoint{Lat: *lat, Lng: *lng}, nil
+}
+
+// ring es un anillo cerrado de posiciones GeoJSON [lng, lat].
+type ring [][2]float64
+
+// polygon es un anillo exterior seguido de sus huecos.
+type polygon []ring
+
+// contains aplica ray casting: el punto está en el anillo exterior y en
+// ninguno de los huecos.
+func (p polygon) contains(pt GeoPoint) bool {
+	if len(p) == 0 || !p[0].contains(pt) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.contains(pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// area retorna el área aproximada en grados² (solo para comparar zonas).
+func (p polygon) area() float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	area := p[0].area()
+	for _, hole := range p[1:] {
+		area -= hole.area()
+	}
+	return area
+}
+
+func (r ring) contains(pt GeoPoint) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > pt.Lat) != (yj > pt.Lat) && pt.Lng < (xj-xi)*(pt.Lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (r ring) area() float64 {
+	sum := 0.0
+	for i := 0; i+1 < len(r); i++ {
+		sum += r[i][0]*r[i+1][1] - r[i+1][0]*r[i][1]
+	}
+	return math.Abs(sum) / 2
+}
+
+// parseZoneGeometry interpreta y valida un Polygon o MultiPolygon GeoJSON.
+func parseZoneGeometry(geometry models.ZoneGeometry) ([]polygon, error) {
+	var polygons [][][][]float64
+	switch geometry.Type {
+	case "Polygon":
+		var single [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &single); err != nil {
+			return nil, fmt.Errorf("validación: coordenadas de Polygon inválidas: %v", err)
+		}
+		polygons = [][][][]float64{single}
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("validación: coordenadas de MultiPolygon inválidas: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("validación: la geometría debe ser Polygon o MultiPolygon, no '%s'", geometry.Type)
+	}
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("validación: la geometría no tiene polígonos")
+	}
+
+	parsed := make([]polygon, 0, len(polygons))
+	for _, rings := range polygons {
+		if len(rings) == 0 {
+			return nil, fmt.Errorf("validación: polígono sin anillo exterior")
+		}
+		poly := make(polygon, 0, len(rings))
+		for _, positions := range rings {
+			r, err := parseRing(positions)
+			if err != nil {
+				return nil, err
+			}
+			poly = append(poly, r)
+		}
+		parsed = append(parsed, poly)
+	}
+	return parsed, nil
+}
+
+func parseRing(positions [][]float64) (ring, error) {
+	if len(positions) < 4 {
+		return nil, fmt.Errorf("validación: cada anillo necesita al menos 4 posiciones")
+	}
+	r := make(ring, 0, len(positions))
+	for _, pos := range positions {
+		if len(pos) < 2 {
+			return nil, fmt.Errorf("validación: posición inválida %v (se espera [lng, lat])", pos)
+		}
+		lng, lat := pos[0], pos[1]
+		if lng < -180 || lng > 180 || lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("validación: posición fuera de rango %v (se espera [lng, lat])", pos)
+		}
+		r = append(r, [2]float64{lng, lat})
+	}
+	if r[0] != r[len(r)-1] {
+		return nil, fmt.Errorf("validación: el anillo debe cerrar (primera posición = última)")
+	}
+	return r, nil
+}
+
+// ============================================================================
+// Zonas
+// ============================================================================
+
+// GeofenceZone es una zona de entrega con su geometría ya interpretada.
+type GeofenceZone struct {
+	Zone     models.DeliveryZone
+	polygons []polygon
+	area     float64
+}
+
+// NewGeofenceZone interpreta la geometría de la zona.
+func NewGeofenceZone(zone models.DeliveryZone) (GeofenceZone, error) {
+	polygons, err := parseZoneGeometry(zone.Geometry)
+	if err != nil {
+		return GeofenceZone{}, err
+	}
+	area := 0.0
+	for _, p := range polygons {
+		area += p.area()
+	}
+	return GeofenceZone{Zone: zone, polygons: polygons, area: area}, nil
+}
+
+// Contains indica si el punto cae dentro de la zona.
+func (g GeofenceZone) Contains(pt GeoPoint) bool {
+	for _, p := range g.polygons {
+		if p.contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryZoneMatch es la zona de entrega aplicada a una cotización.
+type DeliveryZoneMatch struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Municipality string `json:"municipality,omitempty"`
+}
+
+// LocateZone retorna la zona que contiene el punto. Si varias zonas se
+// superponen gana la más pequeña (la más específica).
+func LocateZone(zones []GeofenceZone, pt GeoPoint) (*GeofenceZone, bool) {
+	var best *GeofenceZone
+	for i := range zones {
+		zone := &zones[i]
+		if !zone.Zone.Active || !zone.Contains(pt) {
+			continue
+		}
+		if best == nil || zone.area < best.area {
+			best = zone
+		}
+	}
+	return best, best != nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+)
+
+func TestRingContains(t *testing.T) {
+	square := ring{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	// Forma de L: el cuadrante superior derecho queda fuera
+	lShape := ring{{0, 0}, {10, 0}, {10, 5}, {5, 5}, {5, 10}, {0, 10}, {0, 0}}
+
+	tests := []struct {
+		name string
+		ring ring
+		pt   GeoPoint
+		want bool
+	}{
+		{"centro del cuadrado", square, GeoPoint{Lat: 5, Lng: 5}, true},
+		{"fuera del cuadrado", square, GeoPoint{Lat: 5, Lng: 15}, false},
+		{"debajo del cuadrado", square, GeoPoint{Lat: -1, Lng: 5}, false},
+		{"brazo inferior de la L", lShape, GeoPoint{Lat: 2, Lng: 8}, true},
+		{"brazo izquierdo de la L", lShape, GeoPoint{Lat: 8, Lng: 2}, true},
+		{"hueco cóncavo de la L", lShape, GeoPoint{Lat: 8, Lng: 8}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ring.contains(tt.pt); got != tt.want {
+				t.Errorf("contains(%+v) = %v, se esperaba %v", tt.pt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeofenceZoneContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry models.ZoneGeometry
+		pt       GeoPoint
+		want     bool
+	}{
+		{
+			"polígono con hueco: punto en el anillo",
+			models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[6,4],[6,6],[4,6],[4,4]]]`)},
+			GeoPoint{Lat: 2, Lng: 2}, true,
+		},
+		{
+			"polígono con hueco: punto en el hueco",
+			models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[6,4],[6,6],[4,6],[4,4]]]`)},
+			GeoPoint{Lat: 5, Lng: 5}, false,
+		},
+		{
+			"multipolígono: segundo polígono",
+			models.ZoneGeometry{Type: "MultiPolygon", Coordinates: json.RawMessage(`[[[[0,0],[1,0],[1,1],[0,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,6],[5,5]]]]`)},
+			GeoPoint{Lat: 5.5, Lng: 5.5}, true,
+		},
+		{
+			"multipolígono: entre los polígonos",
+			models.ZoneGeometry{Type: "MultiPolygon", Coordinates: json.RawMessage(`[[[[0,0],[1,0],[1,1],[0,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,6],[5,5]]]]`)},
+			GeoPoint{Lat: 3, Lng: 3}, false,
+		},
+		{
+			// GeoJSON usa [lng, lat]: la zona está en Huehuetenango, no en el océano
+			"orden lng/lat de GeoJSON",
+			models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`[[[-91.5,15.3],[-91.4,15.3],[-91.4,15.4],[-91.5,15.4],[-91.5,15.3]]]`)},
+			GeoPoint{Lat: 15.35, Lng: -91.45}, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := NewGeofenceZone(models.DeliveryZone{Name: tt.name, Geometry: tt.geometry, Active: true})
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := zone.Contains(tt.pt); got != tt.want {
+				t.Errorf("Contains(%+v) = %v, se esperaba %v", tt.pt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGeofenceZoneRejectsInvalidGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry models.ZoneGeometry
+		wantErr  string
+	}{
+		{"tipo no soportado", models.ZoneGeometry{Type: "Point", Coordinates: json.RawMessage(`[0,0]`)}, "Polygon o MultiPolygon"},
+		{"coordenadas mal formadas", models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`{"x":1}`)}, "coordenadas de Polygon"},
+		{"multipolígono vacío", models.ZoneGeometry{Type: "MultiPolygon", Coordinates: json.RawMessage(`[]`)}, "no tiene polígonos"},
+		{"anillo sin cerrar", models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`[[[0,0],[1,0],[1,1],[0,1]]]`)}, "debe cerrar"},
+		{"anillo con pocas posiciones", models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`[[[0,0],[1,0],[0,0]]]`)}, "al menos 4"},
+		{"lat/lng invertidas", models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(`[[[15,-91],[15,-92],[16,-92],[15,-91]]]`)}, "fuera de rango"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGeofenceZone(models.DeliveryZone{Name: tt.name, Geometry: tt.geometry})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("se esperaba error con %q, se obtuvo %v", tt.wantErr, err)
+			}
+			if !strings.HasPrefix(err.Error(), "validación") {
+				t.Errorf("el error debe ser de validación: %v", err)
+			}
+		})
+	}
+}
+
+func TestLocateZone(t *testing.T) {
+	city := squareZone(t, "Ciudad", "GT-13", "Huehuetenango", 0, 0, 10, 10)
+	center := squareZone(t, "Centro", "GT-13", "Huehuetenango", 4, 4, 6, 6)
+	inactive := squareZone(t, "Inactiva", "GT-13", "Huehuetenango", 4.5, 4.5, 5.5, 5.5)
+	inactive.Zone.Active = false
+	// El orden de la lista no debe importar: gana la zona más pequeña
+	zones := []GeofenceZone{center, city, inactive}
+
+	tests := []struct {
+		name     string
+		pt       GeoPoint
+		wantZone string
+	}{
+		{"zonas superpuestas: gana la más pequeña", GeoPoint{Lat: 5, Lng: 5}, "Centro"},
+		{"solo la zona grande", GeoPoint{Lat: 1, Lng: 1}, "Ciudad"},
+		{"fuera de toda zona", GeoPoint{Lat: 20, Lng: 20}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, ok := LocateZone(zones, tt.pt)
+			if tt.wantZone == "" {
+				if ok {
+					t.Fatalf("se esperaba sin zona, se obtuvo %s", zone.Zone.Name)
+				}
+				return
+			}
+			if !ok || zone.Zone.Name != tt.wantZone {
+				t.Fatalf("zona = %+v (%v), se esperaba %s", zone, ok, tt.wantZone)
+			}
+		})
+	}
+
+	if _, ok := LocateZone([]GeofenceZone{inactive}, GeoPoint{Lat: 5, Lng: 5}); ok {
+		t.Error("una zona inactiva no debe aplicar")
+	}
+}
@@ -0,0 +1,151 @@
+// backend/services/idempotency_store.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultIdempotencyRetention es el tiempo que se conserva una respuesta idempotente.
+	defaultIdempotencyRetention = 24 * time.Hour
+
+	// defaultIdempotencyLockTimeout es el tiempo máximo que una solicitud en
+	// proceso retiene su key. Debe superar la duración de la solicitud más lenta
+	// (crear la orden + iniciar el cobro con la pasarela).
+	defaultIdempotencyLockTimeout = 2 * time.Minute
+
+	// idempotencyPurgeInterval es cada cuánto se eliminan las keys vencidas.
+	idempotencyPurgeInterval = time.Hour
+)
+
+// IdempotencyStore persiste las keys de idempotencia y sus respuestas.
+type IdempotencyStore struct {
+	db          *gorm.DB
+	retention   time.Duration
+	lockTimeout time.Duration
+}
+
+// NewIdempotencyStore crea un store que conserva las respuestas durante retention.
+// Una solicitud en proceso retiene su key como máximo lockTimeout.
+func NewIdempotencyStore(db *gorm.DB, retention, lockTimeout time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{db: db, retention: retention, lockTimeout: lockTimeout}
+}
+
+// NewIdempotencyStoreFromEnv lee IDEMPOTENCY_KEY_RETENTION (ej: "24h") e
+// IDEMPOTENCY_LOCK_TIMEOUT (ej: "2m").
+func NewIdempotencyStoreFromEnv(db *gorm.DB) *IdempotencyStore {
+	return NewIdempotencyStore(db,
+		durationFromEnv("IDEMPOTENCY_KEY_RETENTION", defaultIdempotencyRetention),
+		durationFromEnv("IDEMPOTENCY_LOCK_TIMEOUT", defaultIdempotencyLockTimeout),
+	)
+}
+
+// Claim intenta reservar la key para procesar la solicitud.
+// Si la key es nueva retorna (nil, nil) y el llamador debe procesar la solicitud
+// y después llamar a Complete o Release. Si ya existe retorna el registro guardado.
+// Una key en proceso cuya reserva venció (el proceso original murió) se retoma
+// con el mismo body: también retorna (nil, nil).
+func (s *IdempotencyStore) Claim(key, endpoint, requestHash string) (*models.IdempotencyKey, error) {
+	now := time.Now()
+	lockedUntil := now.Add(s.lockTimeout)
+
+	// Una key vencida se puede reutilizar
+	if err := s.db.Where("key = ? AND endpoint = ? AND expires_at < ?", key, endpoint, now).
+		Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return nil, fmt.Errorf("error al limpiar key de idempotencia: %w", err)
+	}
+
+	record := models.IdempotencyKey{
+		Key:         key,
+		Endpoint:    endpoint,
+		RequestHash: requestHash,
+		LockedUntil: &lockedUntil,
+		ExpiresAt:   now.Add(s.retention),
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, fmt.Errorf("error al registrar key de idempotencia: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := s.db.First(&existing, "key = ? AND endpoint = ?", key, endpoint).Error; err != nil {
+		return nil, fmt.Errorf("error al obtener key de idempotencia: %w", err)
+	}
+
+	// Retomar la reserva vencida de una solicitud que nunca terminó. El UPDATE
+	// condicionado evita que dos reintentos simultáneos la retomen a la vez.
+	if !existing.Completed && existing.RequestHash == requestHash &&
+		(existing.LockedUntil == nil || existing.LockedUntil.Before(now)) {
+		takeover := s.db.Model(&models.IdempotencyKey{}).
+			Where("key = ? AND endpoint = ? AND completed = ? AND (locked_until IS NULL OR locked_until < ?)", key, endpoint, false, now).
+			Update("locked_until", lockedUntil)
+		if takeover.Error != nil {
+			return nil, fmt.Errorf("error al retomar key de idempotencia: %w", takeover.Error)
+		}
+		if takeover.RowsAffected == 1 {
+			log.Printf("Idempotency-Key %s (%s) retomada: la solicitud original no terminó", key, endpoint)
+			return nil, nil
+		}
+	}
+	return &existing, nil
+}
+
+// Complete guarda la respuesta de la solicitud para devolverla en los reintentos.
+func (s *IdempotencyStore) Complete(key, endpoint string, statusCode int, contentType string, body []byte) error {
+	return s.db.Model(&models.IdempotencyKey{}).
+		Where("key = ? AND endpoint = ?", key, endpoint).
+		Updates(map[string]interface{}{
+			"completed":     true,
+			"locked_until":  nil,
+			"status_code":   statusCode,
+			"content_type":  contentType,
+			"response_body": body,
+		}).Error
+}
+
+// Release libera la key sin guardar respuesta (ej: error 5xx antes de guardar
+// nada), para que el cliente pueda reintentar la solicitud con la misma key.
+func (s *IdempotencyStore) Release(key, endpoint string) error {
+	return s.db.Where("key = ? AND endpoint = ? AND completed = ?", key, endpoint, false).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// PurgeExpired elimina las keys vencidas. Retorna cuántas se eliminaron.
+func (s *IdempotencyStore) PurgeExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("error al eliminar keys de idempotencia vencidas: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Start elimina periódicamente las keys vencidas hasta que se cierre stop.
+// Debe llamarse en una goroutine.
+func (s *IdempotencyStore) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := s.PurgeExpired()
+			if err != nil {
+				log.Printf("Error al limpiar keys de idempotencia: %v", err)
+			} else if purged > 0 {
+				log.Printf("Keys de idempotencia vencidas eliminadas: %d", purged)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
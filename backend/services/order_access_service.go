@@ -0,0 +1,406 @@
+// backend/services/order_access_service.go
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultOrderLinkTTL es la vigencia de un enlace de consulta de orden.
+	defaultOrderLinkTTL = 72 * time.Hour
+
+	// maxOrderLinksPerHour limita los enlaces enviados por orden (evita spam al cliente).
+	maxOrderLinksPerHour = 5
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// OrderLookupDTO representa la solicitud de enlace de consulta de un invitado.
+type OrderLookupDTO struct {
+	// Email: Email con el que se hizo la orden.
+	Email string `json:"email" binding:"required,email"`
+
+	// OrderNumber: Número de la orden (MO-2026-000123). También se acepta el UUID.
+	OrderNumber string `json:"order_number" binding:"required"`
+}
+
+// OrderTrackingItem es una línea de la orden en la vista pública.
+type OrderTrackingItem struct {
+	ProductID   uint         `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	Quantity    int          `json:"quantity"`
+	Price       models.Money `json:"price"`
+	Subtotal    models.Money `json:"subtotal"`
+}
+
+// OrderTrackingEvent es un cambio de estado en la vista pública.
+// No incluye actor ni motivo: pueden contener datos internos.
+type OrderTrackingEvent struct {
+	FromStatus models.OrderStatus `json:"from_status,omitempty"`
+	ToStatus   models.OrderStatus `json:"to_status"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// OrderTrackingView es la vista de solo lectura que abre un enlace de consulta.
+// Solo expone lo que el cliente necesita para seguir su pedido.
+type OrderTrackingView struct {
+	OrderID              uuid.UUID            `json:"order_id"`
+	OrderNumber          string               `json:"order_number"`
+	Status               models.OrderStatus   `json:"status"`
+	CustomerName         string               `json:"customer_name"`
+	DeliveryType         string               `json:"delivery_type"`
+	PickupBranch         string               `json:"pickup_branch,omitempty"`
+	ShippingDepartment   string               `json:"shipping_department"`
+	ShippingMunicipality string               `json:"shipping_municipality"`
+	ShippingAddress      string               `json:"shipping_address"`
+	ShippingMethod       string               `json:"shipping_method"`
+	ShippingTracking     string               `json:"shipping_tracking,omitempty"`
+	CargoExpresoGuideURL string               `json:"cargo_expreso_guide_url,omitempty"`
+	Subtotal             models.Money         `json:"subtotal"`
+	ShippingCost         models.Money         `json:"shipping_cost"`
+	Total                models.Money         `json:"total"`
+	RefundedAmount       models.Money         `json:"refunded_amount"`
+	Currency             string               `json:"currency"`
+	PaidAt               *time.Time           `json:"paid_at,omitempty"`
+	CreatedAt            time.Time            `json:"created_at"`
+	Items                []OrderTrackingItem  `json:"items"`
+	Timeline             []OrderTrackingEvent `json:"timeline"`
+	LinkExpiresAt        time.Time            `json:"link_expires_at"`
+}
+
+// orderLinkPayload es el contenido firmado del token.
+type orderLinkPayload struct {
+	TokenID   uuid.UUID `json:"tid"`
+	OrderID   uuid.UUID `json:"oid"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// OrderLinkVerifier valida los enlaces de consulta firmados. Identifica al
+// invitado dueño de una orden al cancelarla o pedir una devolución.
+type OrderLinkVerifier interface {
+	// VerifyOrderLink retorna error si el token no es un enlace activo de la orden.
+	VerifyOrderLink(token string, orderID uuid.UUID) error
+}
+
+// OrderAccessService permite a los invitados consultar su orden con enlaces
+// mágicos: tokens firmados con HMAC, limitados a una orden, con vencimiento
+// y revocables (cada token tiene un registro en order_access_tokens).
+type OrderAccessService interface {
+	OrderLinkVerifier
+
+	// RequestLink envía el enlace de consulta si el email coincide con la orden.
+	// No indica si la orden existe: la respuesta al cliente es siempre la misma.
+	RequestLink(dto OrderLookupDTO) error
+
+	// ViewOrder valida el token y retorna la vista de solo lectura de su orden.
+	ViewOrder(token string) (*OrderTrackingView, error)
+
+	// RevokeLink invalida el token indicado.
+	RevokeLink(token string) error
+
+	// RevokeOrderLinks invalida todos los enlaces activos de una orden (admin).
+	RevokeOrderLinks(orderID uuid.UUID) (int64, error)
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type orderAccessService struct {
+	db          *gorm.DB
+	email       EmailService
+	secret      []byte
+	ttl         time.Duration
+	frontendURL string
+}
+
+// NewOrderAccessService crea el servicio de enlaces de consulta.
+// secret firma los tokens; ttl es la vigencia de cada enlace.
+func NewOrderAccessService(db *gorm.DB, email EmailService, secret []byte, ttl time.Duration, frontendURL string) OrderAccessService {
+	return &orderAccessService{
+		db:          db,
+		email:       email,
+		secret:      secret,
+		ttl:         ttl,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// NewOrderAccessServiceFromEnv lee ORDER_LINK_SECRET, ORDER_LINK_TTL y FRONTEND_URL.
+// Sin ORDER_LINK_SECRET se usa un secreto aleatorio: los enlaces dejan de
+// funcionar al reiniciar el servidor.
+func NewOrderAccessServiceFromEnv(db *gorm.DB, email EmailService) OrderAccessService {
+	secret := []byte(os.Getenv("ORDER_LINK_SECRET"))
+	if len(secret) == 0 {
+		log.Println("Advertencia: ORDER_LINK_SECRET no configurado, los enlaces de consulta no sobrevivirán un reinicio")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("No se pudo generar secreto para enlaces de consulta: %v", err)
+		}
+	}
+
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173" // Default para desarrollo
+	}
+
+	ttl := durationFromEnv("ORDER_LINK_TTL", defaultOrderLinkTTL)
+	return NewOrderAccessService(db, email, secret, ttl, frontendURL)
+}
+
+// RequestLink crea el token y lo envía al email de la orden.
+func (s *orderAccessService) RequestLink(dto OrderLookupDTO) error {
+	condition, ref := models.OrderRefCondition(dto.OrderNumber)
+
+	var order models.Order
+	if err := s.db.Select("id", "order_number", "customer_email", "customer_name").First(&order, condition, ref).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Printf("Consulta de orden inexistente: %q", dto.OrderNumber)
+			return nil
+		}
+		return fmt.Errorf("error al obtener orden: %w", err)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(dto.Email), order.CustomerEmail) {
+		log.Printf("Consulta de orden %s con email que no coincide", order.ID)
+		return nil
+	}
+
+	var recent int64
+	if err := s.db.Model(&models.OrderAccessToken{}).
+		Where("order_id = ? AND created_at > ?", order.ID, time.Now().Add(-time.Hour)).
+		Count(&recent).Error; err != nil {
+		return fmt.Errorf("error al consultar enlaces de la orden: %w", err)
+	}
+	if recent >= maxOrderLinksPerHour {
+		log.Printf("Límite de enlaces de consulta alcanzado para orden %s", order.ID)
+		return nil
+	}
+
+	record := models.OrderAccessToken{
+		OrderID:   order.ID,
+		Email:     order.CustomerEmail,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return fmt.Errorf("error al registrar enlace de consulta: %w", err)
+	}
+
+	token, err := s.sign(orderLinkPayload{
+		TokenID:   record.ID,
+		OrderID:   order.ID,
+		ExpiresAt: record.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/orders/track?token=%s", s.frontendURL, token)
+	body := fmt.Sprintf("Hola %s,\n\nPuedes ver el estado de tu pedido %s en el siguiente enlace:\n\n%s\n\n"+
+		"El enlace vence el %s. Si no solicitaste este correo, puedes ignorarlo.\n\nModa Orgánica",
+		order.CustomerName, order.Reference(), link, record.ExpiresAt.Format("02/01/2006 15:04"))
+
+	if err := s.email.Send(order.CustomerEmail, "Consulta tu pedido en Moda Orgánica", body); err != nil {
+		return err
+	}
+
+	log.Printf("Enlace de consulta %s enviado para orden %s", record.ID, order.ID)
+	return nil
+}
+
+// ViewOrder retorna la orden del token si la firma es válida y el enlace sigue activo.
+func (s *orderAccessService) ViewOrder(token string) (*OrderTrackingView, error) {
+	record, err := s.activeLink(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var order models.Order
+	if err := s.db.Preload("OrderItems").
+		Preload("StatusEvents", models.PreloadStatusEvents).
+		First(&order, "id = ?", record.OrderID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("orden no encontrada: %s", record.OrderID)
+		}
+		return nil, fmt.Errorf("error al obtener orden: %w", err)
+	}
+
+	if err := s.db.Model(record).Update("last_used_at", now).Error; err != nil {
+		log.Printf("No se pudo registrar uso del enlace %s: %v", record.ID, err)
+	}
+
+	view := &OrderTrackingView{
+		OrderID:              order.ID,
+		OrderNumber:          order.OrderNumber,
+		Status:               order.Status,
+		CustomerName:         order.CustomerName,
+		DeliveryType:         order.DeliveryType,
+		PickupBranch:         order.PickupBranch,
+		ShippingDepartment:   order.ShippingDepartment,
+		ShippingMunicipality: order.ShippingMunicipality,
+		ShippingAddress:      order.ShippingAddress,
+		ShippingMethod:       order.ShippingMethod,
+		ShippingTracking:     order.ShippingTracking,
+		CargoExpresoGuideURL: order.CargoExpresoGuideURL,
+		Subtotal:             order.Subtotal,
+		ShippingCost:         order.ShippingCost,
+		Total:                order.Total,
+		RefundedAmount:       order.RefundedAmount,
+		Currency:             order.Currency,
+		PaidAt:               order.PaidAt,
+		CreatedAt:            order.CreatedAt,
+		Items:                make([]OrderTrackingItem, 0, len(order.OrderItems)),
+		Timeline:             make([]OrderTrackingEvent, 0, len(order.StatusEvents)),
+		LinkExpiresAt:        record.ExpiresAt,
+	}
+	for _, item := range order.OrderItems {
+		view.Items = append(view.Items, OrderTrackingItem{
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			Price:       item.Price,
+			Subtotal:    item.GetSubtotal(),
+		})
+	}
+	for _, event := range order.StatusEvents {
+		view.Timeline = append(view.Timeline, OrderTrackingEvent{
+			FromStatus: event.FromStatus,
+			ToStatus:   event.ToStatus,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+
+	return view, nil
+}
+
+// VerifyOrderLink valida que el token sea un enlace activo de la orden.
+func (s *orderAccessService) VerifyOrderLink(token string, orderID uuid.UUID) error {
+	record, err := s.activeLink(token)
+	if err != nil {
+		return err
+	}
+	if record.OrderID != orderID {
+		return fmt.Errorf("enlace inválido")
+	}
+	return nil
+}
+
+// activeLink retorna el registro del token si la firma es válida y el enlace
+// no venció ni fue revocado.
+func (s *orderAccessService) activeLink(token string) (*models.OrderAccessToken, error) {
+	payload, err := s.verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Unix() >= payload.ExpiresAt {
+		return nil, fmt.Errorf("enlace vencido: solicita uno nuevo")
+	}
+
+	var record models.OrderAccessToken
+	if err := s.db.First(&record, "id = ? AND order_id = ?", payload.TokenID, payload.OrderID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("enlace inválido")
+		}
+		return nil, fmt.Errorf("error al verificar enlace: %w", err)
+	}
+	if !record.IsActive(now) {
+		return nil, fmt.Errorf("enlace vencido o revocado: solicita uno nuevo")
+	}
+	return &record, nil
+}
+
+// RevokeLink invalida el token. Un token vencido también se puede revocar.
+func (s *orderAccessService) RevokeLink(token string) error {
+	payload, err := s.verify(token)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Model(&models.OrderAccessToken{}).
+		Where("id = ? AND order_id = ? AND revoked_at IS NULL", payload.TokenID, payload.OrderID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("error al revocar enlace: %w", result.Error)
+	}
+
+	log.Printf("Enlace de consulta %s revocado (orden %s)", payload.TokenID, payload.OrderID)
+	return nil
+}
+
+// RevokeOrderLinks invalida todos los enlaces activos de la orden.
+func (s *orderAccessService) RevokeOrderLinks(orderID uuid.UUID) (int64, error) {
+	result := s.db.Model(&models.OrderAccessToken{}).
+		Where("order_id = ? AND revoked_at IS NULL AND expires_at > ?", orderID, time.Now()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("error al revocar enlaces de la orden: %w", result.Error)
+	}
+
+	log.Printf("Enlaces de consulta revocados para orden %s: %d", orderID, result.RowsAffected)
+	return result.RowsAffected, nil
+}
+
+// sign codifica el payload y le agrega la firma HMAC-SHA256: <payload>.<firma> en base64url.
+func (s *orderAccessService) sign(payload orderLinkPayload) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error al generar enlace: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+// verify comprueba la firma del token y retorna su payload (sin revisar vencimiento).
+func (s *orderAccessService) verify(token string) (*orderLinkPayload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("enlace inválido")
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.mac(encoded)) {
+		return nil, fmt.Errorf("enlace inválido")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("enlace inválido")
+	}
+
+	var payload orderLinkPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("enlace inválido")
+	}
+	return &payload, nil
+}
+
+// mac calcula la firma HMAC-SHA256 del payload codificado.
+func (s *orderAccessService) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
@@ -0,0 +1,178 @@
+// backend/services/order_access_service_test.go
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/testsupport"
+
+	"github.com/google/uuid"
+)
+
+func TestOrderLinkSignVerify(t *testing.T) {
+	service := &orderAccessService{secret: []byte("secreto-de-prueba")}
+	payload := orderLinkPayload{TokenID: uuid.New(), OrderID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	token, err := service.sign(payload)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	encoded, signature, _ := strings.Cut(token, ".")
+
+	// Otro payload firmado con el mismo secreto, para mezclar partes de tokens
+	other, err := service.sign(orderLinkPayload{TokenID: uuid.New(), OrderID: uuid.New(), ExpiresAt: payload.ExpiresAt})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	otherEncoded, _, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name    string
+		secret  string
+		token   string
+		wantErr bool
+	}{
+		{"token válido", "secreto-de-prueba", token, false},
+		{"otro secreto", "otro-secreto", token, true},
+		{"payload de otro token", "secreto-de-prueba", otherEncoded + "." + signature, true},
+		{"payload modificado", "secreto-de-prueba", base64.RawURLEncoding.EncodeToString([]byte(`{"tid":"x"}`)) + "." + signature, true},
+		{"firma truncada", "secreto-de-prueba", encoded + "." + signature[:len(signature)-2], true},
+		{"firma no base64", "secreto-de-prueba", encoded + ".!!", true},
+		{"sin firma", "secreto-de-prueba", encoded, true},
+		{"vacío", "secreto-de-prueba", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := &orderAccessService{secret: []byte(tt.secret)}
+			got, err := verifier.verify(tt.token)
+			if tt.wantErr {
+				if err == nil || err.Error() != "enlace inválido" {
+					t.Fatalf("se esperaba 'enlace inválido', se obtuvo %v (%+v)", err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if *got != payload {
+				t.Errorf("payload = %+v, se esperaba %+v", *got, payload)
+			}
+		})
+	}
+}
+
+func TestOrderLinkExpiredBeforeLookup(t *testing.T) {
+	// Sin base de datos: un token vencido se rechaza antes de consultarla
+	service := &orderAccessService{secret: []byte("secreto-de-prueba")}
+	orderID := uuid.New()
+	token, err := service.sign(orderLinkPayload{TokenID: uuid.New(), OrderID: orderID, ExpiresAt: time.Now().Add(-time.Second).Unix()})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if err := service.VerifyOrderLink(token, orderID); err == nil || !strings.Contains(err.Error(), "vencido") {
+		t.Fatalf("se esperaba enlace vencido, se obtuvo %v", err)
+	}
+}
+
+func TestOrderAccessTokenIsActive(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+	tests := []struct {
+		name  string
+		token models.OrderAccessToken
+		want  bool
+	}{
+		{"vigente", models.OrderAccessToken{ExpiresAt: now.Add(time.Hour)}, true},
+		{"vencido", models.OrderAccessToken{ExpiresAt: now.Add(-time.Second)}, false},
+		{"vence justo ahora", models.OrderAccessToken{ExpiresAt: now}, false},
+		{"revocado", models.OrderAccessToken{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.IsActive(now); got != tt.want {
+				t.Errorf("IsActive = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOrderLinkRevocation verifica contra Postgres que un enlace revocado,
+// vencido en su registro o de otra orden deje de funcionar.
+//
+// Uso: TEST_DATABASE_URL=... go test ./services -run TestOrderLinkRevocation
+func TestOrderLinkRevocation(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+	service := NewOrderAccessService(gormDB, nil, []byte("secreto-de-prueba"), time.Hour, "http://localhost:5173").(*orderAccessService)
+
+	order := models.Order{
+		Status:               models.StatusPending,
+		CustomerEmail:        "enlaces@example.com",
+		CustomerName:         "Prueba Enlaces",
+		CustomerPhone:        "55555555",
+		ShippingDepartment:   "GT-13",
+		ShippingMunicipality: "Huehuetenango",
+		ShippingAddress:      "4a calle 5-10 zona 1",
+		Subtotal:             models.NewMoney(10000),
+		ShippingCost:         models.NewMoney(0),
+		Total:                models.NewMoney(10000),
+	}
+	if err := gormDB.Create(&order).Error; err != nil {
+		t.Fatalf("Error creando orden de prueba: %v", err)
+	}
+	t.Cleanup(func() {
+		gormDB.Where("order_id = ?", order.ID).Delete(&models.OrderAccessToken{})
+		gormDB.Delete(&models.Order{}, "id = ?", order.ID)
+	})
+
+	// newLink registra un enlace con el vencimiento indicado y retorna su token
+	newLink := func(t *testing.T, expiresAt time.Time) string {
+		t.Helper()
+		record := models.OrderAccessToken{OrderID: order.ID, Email: order.CustomerEmail, ExpiresAt: expiresAt}
+		if err := gormDB.Create(&record).Error; err != nil {
+			t.Fatalf("Error creando enlace: %v", err)
+		}
+		// El payload vence después que el registro: debe mandar el registro
+		token, err := service.sign(orderLinkPayload{TokenID: record.ID, OrderID: order.ID, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+		return token
+	}
+
+	active := newLink(t, time.Now().Add(time.Hour))
+	if err := service.VerifyOrderLink(active, order.ID); err != nil {
+		t.Fatalf("enlace activo rechazado: %v", err)
+	}
+	if err := service.VerifyOrderLink(active, uuid.New()); err == nil {
+		t.Error("el enlace no debe servir para otra orden")
+	}
+
+	if err := service.RevokeLink(active); err != nil {
+		t.Fatalf("error al revocar: %v", err)
+	}
+	if err := service.VerifyOrderLink(active, order.ID); err == nil || !strings.Contains(err.Error(), "revocado") {
+		t.Errorf("enlace revocado aceptado: %v", err)
+	}
+
+	expired := newLink(t, time.Now().Add(-time.Minute))
+	if err := service.VerifyOrderLink(expired, order.ID); err == nil {
+		t.Error("enlace vencido en su registro aceptado")
+	}
+
+	first, second := newLink(t, time.Now().Add(time.Hour)), newLink(t, time.Now().Add(time.Hour))
+	revoked, err := service.RevokeOrderLinks(order.ID)
+	if err != nil {
+		t.Fatalf("error al revocar enlaces de la orden: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("enlaces revocados = %d, se esperaban 2 (los vencidos o ya revocados no cuentan)", revoked)
+	}
+	for _, token := range []string{first, second} {
+		if err := service.VerifyOrderLink(token, order.ID); err == nil {
+			t.Error("enlace aceptado después de revocar los de la orden")
+		}
+	}
+}
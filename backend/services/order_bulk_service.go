@@ -0,0 +1,374 @@
+// backend/services/order_bulk_service.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultBulkOrderWorkers es cuántas órdenes de un lote se procesan a la vez.
+	// Limita las llamadas simultáneas a Cargo Expreso y a la pasarela de pago.
+	defaultBulkOrderWorkers = 4
+
+	// maxBulkOrders es el máximo de órdenes por lote.
+	maxBulkOrders = 200
+)
+
+// BulkOrderAction es la acción que se aplica a cada orden del lote.
+type BulkOrderAction string
+
+const (
+	BulkActionUpdateStatus   BulkOrderAction = "update_status"   // Cambiar el estado (máquina de estados).
+	BulkActionGenerateGuides BulkOrderAction = "generate_guides" // Generar guías de Cargo Expreso.
+	BulkActionPackingSlips   BulkOrderAction = "packing_slips"   // Armar las hojas de empaque para imprimir.
+	BulkActionCancel         BulkOrderAction = "cancel"          // Cancelar (stock, sesión de pago, guía).
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// BulkOrderActionDTO representa una operación en lote sobre varias órdenes.
+type BulkOrderActionDTO struct {
+	// OrderIDs: Órdenes a procesar (los repetidos se procesan una sola vez).
+	OrderIDs []uuid.UUID `json:"order_ids" binding:"required,min=1"`
+
+	// Action: 'update_status' | 'generate_guides' | 'packing_slips' | 'cancel'.
+	Action BulkOrderAction `json:"action" binding:"required,oneof=update_status generate_guides packing_slips cancel"`
+
+	// Status: Estado nuevo (requerido con update_status).
+	Status models.OrderStatus `json:"status"`
+
+	// Reason: Motivo que queda en el timeline de cada orden (opcional).
+	Reason string `json:"reason"`
+}
+
+// PackingSlipItem es una línea de la hoja de empaque.
+type PackingSlipItem struct {
+	ProductID   uint   `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+}
+
+// PackingSlip contiene lo que se imprime para empacar y despachar una orden.
+type PackingSlip struct {
+	OrderNumber          string            `json:"order_number"`
+	CreatedAt            time.Time         `json:"created_at"`
+	CustomerName         string            `json:"customer_name"`
+	CustomerPhone        string            `json:"customer_phone"`
+	DeliveryType         string            `json:"delivery_type"`
+	PickupBranch         string            `json:"pickup_branch,omitempty"`
+	ShippingAddress      string            `json:"shipping_address"`
+	ShippingMunicipality string            `json:"shipping_municipality"`
+	ShippingDepartment   string            `json:"shipping_department"`
+	DeliveryNotes        string            `json:"delivery_notes,omitempty"`
+	ShippingMethod       string            `json:"shipping_method"`
+	ShippingTracking     string            `json:"shipping_tracking,omitempty"`
+	PaymentMethod        string            `json:"payment_method"`
+	Total                models.Money      `json:"total"`
+	AmountToCollect      models.Money      `json:"amount_to_collect"` // Cobro al entregar (contra entrega o saldo pendiente)
+	Items                []PackingSlipItem `json:"items"`
+}
+
+// BulkOrderItemResult es el resultado de la acción sobre una orden.
+type BulkOrderItemResult struct {
+	OrderID     uuid.UUID          `json:"order_id"`
+	OrderNumber string             `json:"order_number,omitempty"`
+	Success     bool               `json:"success"`
+	Error       string             `json:"error,omitempty"`
+	Status      models.OrderStatus `json:"status,omitempty"`
+
+	// Tracking y guía generados (generate_guides).
+	ShippingTracking     string `json:"shipping_tracking,omitempty"`
+	CargoExpresoGuideURL string `json:"cargo_expreso_guide_url,omitempty"`
+
+	// PackingSlip: Hoja de empaque (packing_slips).
+	PackingSlip *PackingSlip `json:"packing_slip,omitempty"`
+}
+
+// BulkOrderResult resume el lote: un resultado por orden, en el orden recibido.
+type BulkOrderResult struct {
+	Action    BulkOrderAction       `json:"action"`
+	Total     int                   `json:"total"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Results   []BulkOrderItemResult `json:"results"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// OrderBulkService aplica una acción a varias órdenes a la vez (despacho semanal).
+// Cada orden se procesa por separado: un error en una no detiene las demás.
+type OrderBulkService interface {
+	// Run valida la solicitud y procesa el lote con concurrencia limitada.
+	// Solo retorna error si la solicitud es inválida; los errores de cada
+	// orden van en su resultado.
+	Run(dto BulkOrderActionDTO, adminID string) (*BulkOrderResult, error)
+}
+
+// packingSlipStatuses son los estados en los que una orden se empaca o despacha.
+var packingSlipStatuses = map[models.OrderStatus]bool{
+	models.StatusPending:    true, // Solo contra entrega (se cobra al entregar)
+	models.StatusPaid:       true,
+	models.StatusProcessing: true,
+	models.StatusShipped:    true, // Reimpresión
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type orderBulkService struct {
+	db            *gorm.DB
+	orders        OrderService
+	cancellations OrderCancellationService
+	cargo         CargoExpresoService
+	workers       int
+}
+
+// NewOrderBulkService crea el servicio de operaciones en lote.
+// Los cambios de estado usan el mismo camino que PUT /admin/orders/:id/status
+// (OrderService → OrderRepository.UpdateStatus); workers limita la concurrencia.
+func NewOrderBulkService(db *gorm.DB, orders OrderService, cancellations OrderCancellationService, cargo CargoExpresoService, workers int) OrderBulkService {
+	if workers <= 0 {
+		workers = defaultBulkOrderWorkers
+	}
+	return &orderBulkService{
+		db:            db,
+		orders:        orders,
+		cancellations: cancellations,
+		cargo:         cargo,
+		workers:       workers,
+	}
+}
+
+// NewOrderBulkServiceFromEnv lee BULK_ORDER_WORKERS (ej: "4").
+func NewOrderBulkServiceFromEnv(db *gorm.DB, orders OrderService, cancellations OrderCancellationService, cargo CargoExpresoService) OrderBulkService {
+	workers := intFromEnv("BULK_ORDER_WORKERS", defaultBulkOrderWorkers)
+	return NewOrderBulkService(db, orders, cancellations, cargo, workers)
+}
+
+// Run procesa el lote con un pool de workers.
+func (s *orderBulkService) Run(dto BulkOrderActionDTO, adminID string) (*BulkOrderResult, error) {
+	action, err := s.actionFor(dto, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quitar repetidos conservando el orden recibido
+	seen := make(map[uuid.UUID]bool, len(dto.OrderIDs))
+	orderIDs := make([]uuid.UUID, 0, len(dto.OrderIDs))
+	for _, id := range dto.OrderIDs {
+		if !seen[id] {
+			seen[id] = true
+			orderIDs = append(orderIDs, id)
+		}
+	}
+	if len(orderIDs) > maxBulkOrders {
+		return nil, fmt.Errorf("validación: máximo %d órdenes por lote, recibidas %d", maxBulkOrders, len(orderIDs))
+	}
+
+	result := &BulkOrderResult{
+		Action:  dto.Action,
+		Total:   len(orderIDs),
+		Results: make([]BulkOrderItemResult, len(orderIDs)),
+	}
+
+	// Cada worker escribe solo en su posición: no se necesita lock
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.workers && w < len(orderIDs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item := BulkOrderItemResult{OrderID: orderIDs[i]}
+				if err := action(orderIDs[i], &item); err != nil {
+					item.Error = err.Error()
+				} else {
+					item.Success = true
+				}
+				result.Results[i] = item
+			}
+		}()
+	}
+	for i := range orderIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, item := range result.Results {
+		if item.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+
+	log.Printf("Lote '%s' de admin %s: %d órdenes, %d exitosas, %d con error",
+		dto.Action, adminID, result.Total, result.Succeeded, result.Failed)
+	return result, nil
+}
+
+// actionFor valida la solicitud y retorna la función que procesa una orden.
+func (s *orderBulkService) actionFor(dto BulkOrderActionDTO, adminID string) (func(uuid.UUID, *BulkOrderItemResult) error, error) {
+	switch dto.Action {
+	case BulkActionUpdateStatus:
+		if err := models.ValidateManualStatus(dto.Status); err != nil {
+			return nil, fmt.Errorf("validación: status inválido para update_status: %v", err)
+		}
+		if dto.Status == models.StatusCancelled {
+			// Cancelar tiene efectos (stock, sesión de pago, guía)
+			return func(id uuid.UUID, item *BulkOrderItemResult) error {
+				return s.cancel(id, dto.Reason, adminID, item)
+			}, nil
+		}
+		return func(id uuid.UUID, item *BulkOrderItemResult) error {
+			return s.updateStatus(id, dto.Status, dto.Reason, adminID, item)
+		}, nil
+
+	case BulkActionCancel:
+		return func(id uuid.UUID, item *BulkOrderItemResult) error {
+			return s.cancel(id, dto.Reason, adminID, item)
+		}, nil
+
+	case BulkActionGenerateGuides:
+		return func(id uuid.UUID, item *BulkOrderItemResult) error {
+			return s.generateGuide(id, adminID, item)
+		}, nil
+
+	case BulkActionPackingSlips:
+		return s.packingSlip, nil
+
+	default:
+		return nil, fmt.Errorf("validación: acción inválida: '%s'", dto.Action)
+	}
+}
+
+// updateStatus cambia el estado por el mismo camino que el endpoint individual.
+func (s *orderBulkService) updateStatus(id uuid.UUID, status models.OrderStatus, reason, adminID string, item *BulkOrderItemResult) error {
+	if reason == "" {
+		reason = "cambio de estado en lote"
+	}
+	if err := s.orders.UpdateOrderStatus(id, status, models.StatusChange{
+		ActorType: models.ActorAdmin,
+		ActorID:   adminID,
+		Reason:    reason,
+	}); err != nil {
+		return err
+	}
+
+	order, err := s.orders.GetOrderByID(id)
+	if err != nil {
+		return err
+	}
+	item.OrderNumber = order.OrderNumber
+	item.Status = order.Status
+	return nil
+}
+
+// cancel cancela la orden con todos sus efectos (OrderCancellationService).
+func (s *orderBulkService) cancel(id uuid.UUID, reason, adminID string, item *BulkOrderItemResult) error {
+	if reason == "" {
+		reason = "cancelada en lote"
+	}
+	result, err := s.cancellations.CancelByAdmin(id, reason, adminID)
+	if err != nil {
+		return err
+	}
+	item.OrderNumber = result.Order.OrderNumber
+	item.Status = result.Order.Status
+	return nil
+}
+
+// generateGuide genera la guía de Cargo Expreso de una orden pagada.
+func (s *orderBulkService) generateGuide(id uuid.UUID, adminID string, item *BulkOrderItemResult) error {
+	var order models.Order
+	if err := s.db.First(&order, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("orden no encontrada: %s", id)
+		}
+		return fmt.Errorf("error al obtener orden: %w", err)
+	}
+	item.OrderNumber = order.OrderNumber
+
+	response, err := GenerateOrderGuide(s.db, s.cargo, &order, models.StatusChange{
+		ActorType: models.ActorAdmin,
+		ActorID:   adminID,
+	})
+	if err != nil {
+		return err
+	}
+
+	item.Status = order.Status
+	item.ShippingTracking = response.TrackingNumber
+	item.CargoExpresoGuideURL = response.GuideURL
+	return nil
+}
+
+// packingSlip arma la hoja de empaque de la orden (solo lectura).
+func (s *orderBulkService) packingSlip(id uuid.UUID, item *BulkOrderItemResult) error {
+	var order models.Order
+	if err := s.db.Preload("OrderItems").First(&order, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("orden no encontrada: %s", id)
+		}
+		return fmt.Errorf("error al obtener orden: %w", err)
+	}
+	item.OrderNumber = order.OrderNumber
+	item.Status = order.Status
+
+	cashOnDelivery := PaymentMethod(order.PaymentMethod) == PaymentMethodCashOnDelivery
+	if !packingSlipStatuses[order.Status] || (order.Status == models.StatusPending && !cashOnDelivery) {
+		return fmt.Errorf("la orden en estado '%s' no está lista para despacho", order.Status)
+	}
+
+	// Por cobrar al entregar: el total si es contra entrega sin cobrar,
+	// o el saldo pendiente por ediciones posteriores al pago
+	toCollect := models.NewMoney(0)
+	if cashOnDelivery && order.PaidAt == nil {
+		toCollect = order.Total
+	} else if order.BalanceDue.IsPositive() {
+		toCollect = order.BalanceDue
+	}
+
+	slip := &PackingSlip{
+		OrderNumber:          order.Reference(),
+		CreatedAt:            order.CreatedAt,
+		CustomerName:         order.CustomerName,
+		CustomerPhone:        order.CustomerPhone,
+		DeliveryType:         order.DeliveryType,
+		PickupBranch:         order.PickupBranch,
+		ShippingAddress:      order.ShippingAddress,
+		ShippingMunicipality: order.ShippingMunicipality,
+		ShippingDepartment:   order.ShippingDepartment,
+		DeliveryNotes:        order.DeliveryNotes,
+		ShippingMethod:       order.ShippingMethod,
+		ShippingTracking:     order.ShippingTracking,
+		PaymentMethod:        order.PaymentMethod,
+		Total:                order.Total,
+		AmountToCollect:      toCollect,
+		Items:                make([]PackingSlipItem, 0, len(order.OrderItems)),
+	}
+	for _, orderItem := range order.OrderItems {
+		slip.Items = append(slip.Items, PackingSlipItem{
+			ProductID:   orderItem.ProductID,
+			ProductName: orderItem.ProductName,
+			Quantity:    orderItem.Quantity,
+		})
+	}
+
+	item.PackingSlip = slip
+	return nil
+}
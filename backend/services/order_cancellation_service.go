@@ -0,0 +1,326 @@
+// backend/services/order_cancellation_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultCancellationWindow es el tiempo que tiene el cliente para cancelar su orden.
+const defaultCancellationWindow = 24 * time.Hour
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// CancelOrderDTO representa la solicitud de cancelación de un cliente.
+type CancelOrderDTO struct {
+	// Token: Enlace de consulta firmado que recibió el cliente por email
+	// (ver OrderAccessService). Identifica al dueño de una orden de invitado.
+	Token string `json:"token"`
+
+	// Reason: Motivo de la cancelación (opcional).
+	Reason string `json:"reason"`
+}
+
+// CancelOrderResult resume los efectos de una cancelación.
+type CancelOrderResult struct {
+	// Order: Orden con su nuevo estado ('cancelled', o 'refunded' si ya estaba pagada).
+	Order *models.Order `json:"order"`
+
+	// Refund: Reembolso emitido si la orden ya estaba pagada.
+	Refund *models.Refund `json:"refund,omitempty"`
+
+	// ReleasedUnits: Unidades regresadas al inventario.
+	ReleasedUnits int `json:"released_units"`
+
+	// VoidedGuide: Tracking de la guía de Cargo Expreso anulada (si había).
+	VoidedGuide string `json:"voided_guide,omitempty"`
+
+	// GuideVoidError: La orden se canceló pero Cargo Expreso no anuló la guía;
+	// hay que anularla manualmente.
+	GuideVoidError string `json:"guide_void_error,omitempty"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// OrderCancellationService cancela órdenes con todos sus efectos: regresa el
+// stock, invalida la sesión de pago o reembolsa el pago, y anula la guía de
+// Cargo Expreso si ya se había generado.
+type OrderCancellationService interface {
+	// CancelByCustomer cancela la orden a solicitud del cliente, dentro del plazo
+	// configurado y antes de que se envíe. userID es el usuario autenticado
+	// (vacío para invitados, que se identifican con el enlace de consulta).
+	CancelByCustomer(orderID uuid.UUID, dto CancelOrderDTO, userID string) (*CancelOrderResult, error)
+
+	// CancelByAdmin cancela una orden que aún no se ha pagado.
+	// Las órdenes pagadas se cancelan con un reembolso (RefundService).
+	CancelByAdmin(orderID uuid.UUID, reason, adminID string) (*CancelOrderResult, error)
+}
+
+// customerCancellableStatuses son los estados en los que el cliente puede cancelar.
+// Una vez enviada (shipped), la orden solo puede devolverse.
+var customerCancellableStatuses = map[models.OrderStatus]bool{
+	models.StatusPending:    true,
+	models.StatusPaid:       true,
+	models.StatusProcessing: true,
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type orderCancellationService struct {
+	db       *gorm.DB
+	gateways *PaymentGateways
+	cargo    CargoExpresoService
+	links    OrderLinkVerifier
+	refunds  *refundService
+	window   time.Duration
+}
+
+// NewOrderCancellationService crea el servicio de cancelación.
+// links valida los enlaces de consulta con los que cancelan los invitados.
+// window es el tiempo, desde la creación de la orden, en el que el cliente puede cancelarla.
+func NewOrderCancellationService(db *gorm.DB, gateways *PaymentGateways, cargo CargoExpresoService, links OrderLinkVerifier, window time.Duration) OrderCancellationService {
+	return &orderCancellationService{
+		db:       db,
+		gateways: gateways,
+		cargo:    cargo,
+		links:    links,
+		refunds:  &refundService{db: db, gateways: gateways},
+		window:   window,
+	}
+}
+
+// NewOrderCancellationServiceFromEnv lee ORDER_CANCELLATION_WINDOW (ej: "24h", "2h").
+func NewOrderCancellationServiceFromEnv(db *gorm.DB, gateways *PaymentGateways, cargo CargoExpresoService, links OrderLinkVerifier) OrderCancellationService {
+	window := durationFromEnv("ORDER_CANCELLATION_WINDOW", defaultCancellationWindow)
+	return NewOrderCancellationService(db, gateways, cargo, links, window)
+}
+
+// CancelByCustomer verifica que quien cancela sea el dueño de la orden (ver verifyOrderOwner).
+func (s *orderCancellationService) CancelByCustomer(orderID uuid.UUID, dto CancelOrderDTO, userID string) (*CancelOrderResult, error) {
+	reason := "cancelada por el cliente"
+	if dto.Reason != "" {
+		reason += ": " + dto.Reason
+	}
+
+	return s.cancel(orderID, func(order *models.Order) (models.StatusChange, error) {
+		actorID, err := verifyOrderOwner(order, s.links, dto.Token, userID)
+		if err != nil {
+			return models.StatusChange{}, err
+		}
+
+		if !customerCancellableStatuses[order.Status] {
+			return models.StatusChange{}, fmt.Errorf("la orden en estado '%s' no se puede cancelar", order.Status)
+		}
+		if deadline := order.CreatedAt.Add(s.window); time.Now().After(deadline) {
+			return models.StatusChange{}, fmt.Errorf("la orden no se puede cancelar: el plazo de %s para cancelar venció", s.window)
+		}
+
+		return models.StatusChange{
+			ActorType: models.ActorCustomer,
+			ActorID:   actorID,
+			Reason:    reason,
+		}, nil
+	})
+}
+
+// CancelByAdmin cancela una orden no pagada con los mismos efectos que la del cliente.
+func (s *orderCancellationService) CancelByAdmin(orderID uuid.UUID, reason, adminID string) (*CancelOrderResult, error) {
+	return s.cancel(orderID, func(order *models.Order) (models.StatusChange, error) {
+		if isOrderPaid(order) {
+			return models.StatusChange{}, fmt.Errorf("la orden en estado '%s' ya fue pagada y no se puede cancelar: emite un reembolso", order.Status)
+		}
+		if !order.Status.CanTransitionTo(models.StatusCancelled) {
+			return models.StatusChange{}, fmt.Errorf("la orden en estado '%s' no se puede cancelar", order.Status)
+		}
+
+		return models.StatusChange{
+			ActorType: models.ActorAdmin,
+			ActorID:   adminID,
+			Reason:    reason,
+		}, nil
+	})
+}
+
+// cancel valida la solicitud con authorize y aplica los efectos de la
+// cancelación. Las llamadas externas no se hacen con la orden bloqueada:
+//  1. Sin bloqueo: authorize decide si la orden se puede cancelar.
+//  2. Orden sin pagar: se invalida la sesión de pago antes de cancelar, para
+//     que no se cobre una orden cancelada. Si después falla la transacción, la
+//     orden queda pendiente con la sesión vencida y el reconciliador la cancela.
+//  3. Transacción: se bloquea la orden, se vuelve a autorizar (pudo cambiar) y
+//     se reserva el reembolso total (orden pagada) o se regresa el stock y se
+//     cancela (orden sin pagar).
+//  4. Orden pagada: ya sin bloqueo se emite el reembolso en la pasarela (ver
+//     refundService.completeRefund).
+//  5. Con la cancelación ya guardada se anula la guía de Cargo Expreso. Si
+//     falla, la orden sigue cancelada y el resultado indica que hay que
+//     anular la guía manualmente. Si el reembolso quedó pendiente la guía
+//     también se anula: la orden se reembolsará y no debe enviarse.
+func (s *orderCancellationService) cancel(orderID uuid.UUID, authorize func(order *models.Order) (models.StatusChange, error)) (*CancelOrderResult, error) {
+	// 1. Validar la solicitud
+	var snapshot models.Order
+	if err := s.db.First(&snapshot, "id = ?", orderID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("orden no encontrada: %s", orderID)
+		}
+		return nil, fmt.Errorf("error al obtener orden: %w", err)
+	}
+	if _, err := authorize(&snapshot); err != nil {
+		return nil, err
+	}
+	wasPaid := isOrderPaid(&snapshot)
+
+	// 2. Invalidar la sesión de pago de una orden sin pagar
+	if !wasPaid && snapshot.StripeSessionID != "" {
+		gateway, err := s.gateways.Get(PaymentMethod(snapshot.PaymentMethod))
+		if err != nil {
+			return nil, err
+		}
+		if err := gateway.CancelCheckout(snapshot.StripeSessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Cancelar en la base de datos
+	result := &CancelOrderResult{}
+	var change models.StatusChange
+	var pending *models.Refund
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems").
+			First(&order, "id = ?", orderID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("orden no encontrada: %s", orderID)
+			}
+			return fmt.Errorf("error al obtener orden: %w", err)
+		}
+
+		var err error
+		if change, err = authorize(&order); err != nil {
+			return err
+		}
+		if isOrderPaid(&order) != wasPaid {
+			return fmt.Errorf("la orden cambió de estado mientras se cancelaba, intenta de nuevo")
+		}
+
+		// 3a. Orden pagada: reservar el reembolso total con reposición de stock
+		if wasPaid {
+			refund, err := s.refunds.reserveRefund(tx, &order, CreateRefundDTO{Reason: change.Reason}, change.ActorID, nil)
+			if err != nil {
+				return err
+			}
+			pending = refund
+			result.Order = &order
+			return nil
+		}
+
+		// 3b. Orden sin pagar: regresar el stock y cancelar
+		released, err := ReleaseOrderStock(tx, &order)
+		if err != nil {
+			return err
+		}
+		result.ReleasedUnits = released
+
+		if err := order.TransitionTo(tx, models.StatusCancelled, change); err != nil {
+			return fmt.Errorf("error al cancelar orden: %w", err)
+		}
+
+		result.Order = &order
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Emitir el reembolso de la orden pagada
+	if pending != nil {
+		refund, err := s.refunds.completeRefund(pending, change)
+		if err != nil {
+			if !errors.Is(err, ErrRefundRejected) {
+				s.voidGuide(result)
+			}
+			return nil, err
+		}
+		result.Refund = refund
+		if refund.Restocked {
+			for _, item := range refund.Items {
+				result.ReleasedUnits += item.Quantity
+			}
+		}
+		if err := s.db.First(result.Order, "id = ?", orderID).Error; err != nil {
+			return nil, fmt.Errorf("error al obtener orden: %w", err)
+		}
+	}
+
+	log.Printf("Orden %s cancelada por %s %s: estado %s, %d unidades regresadas al inventario",
+		result.Order.ID, change.ActorType, change.ActorID, result.Order.Status, result.ReleasedUnits)
+
+	// 5. Anular la guía de Cargo Expreso
+	s.voidGuide(result)
+	return result, nil
+}
+
+// voidGuide anula la guía de Cargo Expreso de la orden cancelada (si tiene) y
+// anota el resultado. Si falla, la guía se anula manualmente.
+func (s *orderCancellationService) voidGuide(result *CancelOrderResult) {
+	tracking := result.Order.ShippingTracking
+	if tracking == "" {
+		return
+	}
+
+	if err := s.cargo.CancelGuide(tracking); err != nil {
+		log.Printf("CRÍTICO: orden %s cancelada pero la guía %s sigue activa, anularla manualmente: %v",
+			result.Order.ID, tracking, err)
+		result.GuideVoidError = fmt.Sprintf("error al anular guía %s: %v", tracking, err)
+		return
+	}
+	result.VoidedGuide = tracking
+	log.Printf("Guía %s anulada (orden %s cancelada)", tracking, result.Order.ID)
+}
+
+// verifyOrderOwner verifica que quien hace la solicitud sea el dueño de la orden:
+// - Órdenes de una cuenta: el JWT debe ser del mismo usuario.
+// - Órdenes de invitado: token debe ser un enlace de consulta activo de la
+// orden (solo llega al email de la orden; conocer el email no basta).
+// Retorna el identificador del cliente para registrarlo como actor.
+func verifyOrderOwner(order *models.Order, links OrderLinkVerifier, token, userID string) (string, error) {
+	if order.UserID != nil {
+		if userID == "" || userID != order.UserID.String() {
+			return "", fmt.Errorf("no autorizado: inicia sesión con la cuenta que hizo la orden")
+		}
+		return userID, nil
+	}
+
+	if token == "" {
+		return "", fmt.Errorf("no autorizado: abre el enlace de consulta que enviamos al email de la orden")
+	}
+	if links == nil {
+		return "", fmt.Errorf("no autorizado: los enlaces de consulta no están disponibles")
+	}
+	if err := links.VerifyOrderLink(token, order.ID); err != nil {
+		return "", fmt.Errorf("no autorizado: %w", err)
+	}
+	return order.CustomerEmail, nil
+}
+
+// isOrderPaid indica si el pago de la orden ya se cobró.
+// Las órdenes contra entrega en 'processing' aún no se han cobrado.
+func isOrderPaid(order *models.Order) bool {
+	return order.PaidAt != nil || order.Status == models.StatusPaid
+}
@@ -0,0 +1,62 @@
+// backend/services/order_cancellation_service_test.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+)
+
+// fakeOrderLinks acepta solo el token válido de cada orden.
+type fakeOrderLinks map[uuid.UUID]string
+
+func (f fakeOrderLinks) VerifyOrderLink(token string, orderID uuid.UUID) error {
+	if f[orderID] != token {
+		return fmt.Errorf("enlace inválido")
+	}
+	return nil
+}
+
+func TestVerifyOrderOwner(t *testing.T) {
+	userID := uuid.New()
+	guestOrder := &models.Order{ID: uuid.New(), CustomerEmail: "cliente@example.com"}
+	accountOrder := &models.Order{ID: uuid.New(), UserID: &userID, CustomerEmail: "cuenta@example.com"}
+	links := fakeOrderLinks{guestOrder.ID: "token-valido"}
+
+	tests := []struct {
+		name      string
+		order     *models.Order
+		token     string
+		userID    string
+		wantActor string
+	}{
+		{"invitado con enlace de la orden", guestOrder, "token-valido", "", "cliente@example.com"},
+		{"invitado sin enlace", guestOrder, "", "", ""},
+		{"invitado con el email como token", guestOrder, "cliente@example.com", "", ""},
+		{"invitado con enlace de otra orden", accountOrder, "token-valido", "", ""},
+		{"cuenta con su sesión", accountOrder, "", userID.String(), userID.String()},
+		{"cuenta con otra sesión", accountOrder, "", uuid.NewString(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor, err := verifyOrderOwner(tt.order, links, tt.token, tt.userID)
+			if tt.wantActor == "" {
+				if err == nil || !strings.Contains(err.Error(), "no autorizado") {
+					t.Fatalf("se esperaba error 'no autorizado', se obtuvo actor=%q err=%v", actor, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if actor != tt.wantActor {
+				t.Errorf("actor = %q, se esperaba %q", actor, tt.wantActor)
+			}
+		})
+	}
+}
@@ -0,0 +1,555 @@
+// backend/services/order_edit_service.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// EditOrderItemDTO fija la cantidad final de un producto en la orden.
+// Quantity 0 quita el producto; un producto que no está en la orden se agrega.
+type EditOrderItemDTO struct {
+	// ProductID: Producto a agregar, modificar o quitar.
+	ProductID uint `json:"product_id" binding:"required"`
+
+	// Quantity: Cantidad final de unidades (0 para quitarlo).
+	Quantity int `json:"quantity" binding:"min=0"`
+}
+
+// PackageOverrideDTO ajusta a mano el paquete de Cargo Expreso de la orden.
+// Con Auto se descarta el ajuste y se vuelve a calcular con los productos.
+type PackageOverrideDTO struct {
+	// Type: 'sobre' | 'caja_pequeña' | 'caja_mediana'. Vacío = mantener.
+	Type string `json:"type"`
+
+	// WeightKg: Peso total del paquete en kg. 0 = mantener.
+	WeightKg float64 `json:"weight_kg" binding:"min=0"`
+
+	// Auto: Volver al paquete calculado con los productos.
+	Auto bool `json:"auto"`
+}
+
+// EditOrderDTO representa una edición de la orden hecha por un admin.
+// Solo se modifican los campos que vienen en la solicitud.
+type EditOrderDTO struct {
+	// Items: Cambios de cantidad por producto. Los productos no indicados no cambian.
+	Items []EditOrderItemDTO `json:"items" binding:"omitempty,dive"`
+
+	// --- Entrega ---
+	ShippingDepartment   *string `json:"shipping_department"`
+	ShippingMunicipality *string `json:"shipping_municipality"`
+	ShippingAddress      *string `json:"shipping_address"`
+	DeliveryType         *string `json:"delivery_type" binding:"omitempty,oneof=home_delivery pickup_at_branch"`
+	PickupBranch         *string `json:"pickup_branch"`
+	DeliveryNotes        *string `json:"delivery_notes"`
+
+	// Package: Ajuste manual del paquete (tipo y/o peso). Sin ajuste, el
+	// paquete se recalcula cuando cambian los items.
+	Package *PackageOverrideDTO `json:"package"`
+
+	// Reason: Motivo de la edición (requerido, queda en la bitácora).
+	Reason string `json:"reason" binding:"required"`
+}
+
+// EditOrderResult resume los efectos de una edición.
+type EditOrderResult struct {
+	// Order: Orden con los nuevos items y totales.
+	Order *models.Order `json:"order"`
+
+	// Edit: Registro de la bitácora con el detalle de los cambios.
+	Edit *models.OrderEdit `json:"edit"`
+
+	// VoidedGuide: Guía de Cargo Expreso anulada por el cambio de destino o de paquete (si había).
+	VoidedGuide string `json:"voided_guide,omitempty"`
+
+	// GuideVoidError: La edición se guardó pero Cargo Expreso no anuló la guía
+	// anterior; hay que anularla manualmente.
+	GuideVoidError string `json:"guide_void_error,omitempty"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// OrderEditService permite a un admin corregir una orden antes de enviarla:
+// items, cantidades y datos de entrega. Recalcula los totales, mueve el stock
+// en ambos sentidos y registra la edición en la bitácora (order_edits).
+type OrderEditService interface {
+	// EditOrder aplica la edición. Si la orden ya estaba cobrada, la diferencia
+	// de total queda como saldo pendiente (Order.BalanceDue).
+	EditOrder(orderID uuid.UUID, dto EditOrderDTO, adminID string) (*EditOrderResult, error)
+}
+
+// editableStatuses son los estados en los que la orden aún se puede editar.
+// Una vez enviada (shipped) solo puede devolverse; con reembolsos ya emitidos
+// los items dejan de coincidir con el libro de reembolsos.
+var editableStatuses = map[models.OrderStatus]bool{
+	models.StatusPending:    true,
+	models.StatusPaid:       true,
+	models.StatusProcessing: true,
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type orderEditService struct {
+	db       *gorm.DB
+	gateways *PaymentGateways
+	cargo    CargoExpresoService
+}
+
+// NewOrderEditService crea el servicio de edición de órdenes.
+func NewOrderEditService(db *gorm.DB, gateways *PaymentGateways, cargo CargoExpresoService) OrderEditService {
+	return &orderEditService{db: db, gateways: gateways, cargo: cargo}
+}
+
+// EditOrder bloquea la orden y aplica la edición en una sola transacción.
+// Si la edición deja sin efecto la guía de Cargo Expreso, la guía se anula
+// después de guardar: si la transacción falla, la guía sigue siendo válida.
+func (s *orderEditService) EditOrder(orderID uuid.UUID, dto EditOrderDTO, adminID string) (*EditOrderResult, error) {
+	result := &EditOrderResult{}
+	var staleGuide string
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 1. Bloquear la orden
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems").
+			First(&order, "id = ?", orderID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("orden no encontrada: %s", orderID)
+			}
+			return fmt.Errorf("error al obtener orden: %w", err)
+		}
+
+		if !editableStatuses[order.Status] {
+			return fmt.Errorf("la orden en estado '%s' ya no se puede editar", order.Status)
+		}
+
+		edit := &models.OrderEdit{
+			OrderID:       order.ID,
+			EditedBy:      adminID,
+			Reason:        dto.Reason,
+			PreviousTotal: order.Total,
+		}
+
+		// 2. Datos de entrega
+		destinationChanged, err := s.applyDeliveryChanges(&order, dto, edit)
+		if err != nil {
+			return err
+		}
+
+		// 3. Items y stock
+		changesBefore := len(edit.Changes)
+		if err := applyItemChanges(tx, &order, dto.Items, edit); err != nil {
+			return err
+		}
+		itemsChanged := len(edit.Changes) > changesBefore
+
+		// 3.1 Paquete: ajuste manual o recálculo con los nuevos items
+		packageChanged, err := applyPackageChanges(tx, &order, dto.Package, itemsChanged, edit)
+		if err != nil {
+			return err
+		}
+
+		if len(edit.Changes) == 0 {
+			return fmt.Errorf("validación: la edición no cambia nada")
+		}
+
+		// 4. Recalcular totales
+		subtotal := models.NewMoney(0)
+		for _, item := range order.OrderItems {
+			if subtotal, err = subtotal.Add(item.GetSubtotal()); err != nil {
+				return fmt.Errorf("validación: %w", err)
+			}
+		}
+		order.Subtotal = subtotal
+		if destinationChanged || packageChanged {
+			shipping, err := QuoteShipping(ShippingDestinationFor(&order))
+			if err != nil {
+				return err
+			}
+			order.ShippingCost = shipping.Cost
+			order.RequiresCourier = shipping.RequiresCourier
+			order.ShippingMethod = shipping.Method
+		}
+		if err := order.CalculateTotal(); err != nil {
+			return fmt.Errorf("validación: %w", err)
+		}
+		edit.NewTotal = order.Total
+
+		totalChanged := !order.Total.Equal(edit.PreviousTotal)
+		if totalChanged && order.Status == models.StatusPending && order.StripeSessionID != "" {
+			// La sesión de Stripe ya tiene el monto anterior y no se puede modificar
+			return fmt.Errorf("la orden tiene un pago con tarjeta en curso por %s: no se puede cambiar el total hasta que se pague", edit.PreviousTotal)
+		}
+
+		// 5. Saldo con el cliente: solo si la orden ya se cobró
+		if totalChanged && isOrderPaid(&order) {
+			if edit.BalanceChange, err = order.Total.Sub(edit.PreviousTotal); err != nil {
+				return err
+			}
+			if order.BalanceDue, err = order.BalanceDue.Add(edit.BalanceChange); err != nil {
+				return err
+			}
+		}
+
+		// 6. Quitar la guía si cambió el destino o el paquete: hay que generar una
+		// nueva (GenerateOrderGuide acepta órdenes en 'processing' sin guía).
+		// En Cargo Expreso se anula después de guardar (ver paso 8)
+		if (destinationChanged || packageChanged) && order.ShippingTracking != "" {
+			edit.Changes = append(edit.Changes, models.OrderEditChange{Field: "shipping_tracking", From: order.ShippingTracking})
+			staleGuide = order.ShippingTracking
+			order.ShippingTracking = ""
+			order.CargoExpresoGuideURL = ""
+		}
+
+		// 7. Guardar la orden y la bitácora
+		if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"shipping_department":     order.ShippingDepartment,
+			"shipping_municipality":   order.ShippingMunicipality,
+			"shipping_address":        order.ShippingAddress,
+			"delivery_type":           order.DeliveryType,
+			"pickup_branch":           order.PickupBranch,
+			"delivery_notes":          order.DeliveryNotes,
+			"shipping_method":         order.ShippingMethod,
+			"requires_courier":        order.RequiresCourier,
+			"shipping_tracking":       order.ShippingTracking,
+			"cargo_expreso_guide_url": order.CargoExpresoGuideURL,
+			"subtotal":                order.Subtotal,
+			"shipping_cost":           order.ShippingCost,
+			"total":                   order.Total,
+			"balance_due":             order.BalanceDue,
+			"package_type":            order.PackageType,
+			"package_weight_kg":       order.PackageWeightKg,
+			"package_overridden":      order.PackageOverridden,
+		}).Error; err != nil {
+			return fmt.Errorf("error al actualizar orden: %w", err)
+		}
+
+		if err := tx.Create(edit).Error; err != nil {
+			return fmt.Errorf("error al registrar edición: %w", err)
+		}
+
+		result.Order = &order
+		result.Edit = edit
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Orden %s editada por admin %s: %d cambios, total %s -> %s, saldo %s",
+		result.Order.ID, adminID, len(result.Edit.Changes), result.Edit.PreviousTotal, result.Edit.NewTotal, result.Order.BalanceDue)
+
+	// 8. Con la edición ya guardada, anular la guía anterior en Cargo Expreso
+	if staleGuide != "" {
+		if err := s.cargo.CancelGuide(staleGuide); err != nil {
+			log.Printf("CRÍTICO: orden %s editada pero la guía %s sigue activa, anularla manualmente: %v",
+				result.Order.ID, staleGuide, err)
+			result.GuideVoidError = fmt.Sprintf("error al anular guía %s: %v", staleGuide, err)
+		} else {
+			result.VoidedGuide = staleGuide
+		}
+	}
+	return result, nil
+}
+
+// applyDeliveryChanges aplica los cambios de entrega y valida el resultado con
+// las mismas reglas que la creación de la orden. Retorna si cambió el destino
+// (municipio, dirección o tipo de entrega).
+func (s *orderEditService) applyDeliveryChanges(order *models.Order, dto EditOrderDTO, edit *models.OrderEdit) (bool, error) {
+	set := func(field string, value *string, target *string) bool {
+		if value == nil || strings.TrimSpace(*value) == *target {
+			return false
+		}
+		edit.Changes = append(edit.Changes, models.OrderEditChange{Field: field, From: *target, To: strings.TrimSpace(*value)})
+		*target = strings.TrimSpace(*value)
+		return true
+	}
+
+	// El municipio (nuevo o actual) debe pertenecer al departamento (nuevo o actual)
+	if dto.ShippingDepartment != nil || dto.ShippingMunicipality != nil {
+		department, municipality := order.ShippingDepartment, order.ShippingMunicipality
+		if dto.ShippingDepartment != nil {
+			department = *dto.ShippingDepartment
+		}
+		if dto.ShippingMunicipality != nil {
+			municipality = *dto.ShippingMunicipality
+		}
+		dept, mun, err := ResolveLocation(department, municipality)
+		if err != nil {
+			return false, err
+		}
+		dto.ShippingDepartment, dto.ShippingMunicipality = stringPtr(dept.Code), stringPtr(mun.Name)
+	}
+
+	destinationChanged := false
+	destinationChanged = set("shipping_department", dto.ShippingDepartment, &order.ShippingDepartment) || destinationChanged
+	destinationChanged = set("shipping_municipality", dto.ShippingMunicipality, &order.ShippingMunicipality) || destinationChanged
+	destinationChanged = set("delivery_type", dto.DeliveryType, &order.DeliveryType) || destinationChanged
+	destinationChanged = set("pickup_branch", dto.PickupBranch, &order.PickupBranch) || destinationChanged
+	addressChanged := set("shipping_address", dto.ShippingAddress, &order.ShippingAddress)
+	destinationChanged = addressChanged || destinationChanged
+	set("delivery_notes", dto.DeliveryNotes, &order.DeliveryNotes)
+
+	if !destinationChanged {
+		return false, nil
+	}
+
+	if order.ShippingMunicipality == "" {
+		return false, fmt.Errorf("validación: shipping_municipality es requerido")
+	}
+
+	pickupAddress := strings.HasPrefix(order.ShippingAddress, "Recoger en sucursal: ")
+	if order.DeliveryType == "pickup_at_branch" {
+		if order.PickupBranch == "" {
+			return false, fmt.Errorf("validación: pickup_branch es requerido para entrega en sucursal")
+		}
+		if !addressChanged && (order.ShippingAddress == "" || pickupAddress) {
+			set("shipping_address", stringPtr("Recoger en sucursal: "+order.PickupBranch), &order.ShippingAddress)
+		}
+	} else {
+		if order.ShippingAddress == "" || (pickupAddress && !addressChanged) {
+			return false, fmt.Errorf("validación: shipping_address es requerido para entrega a domicilio")
+		}
+		set("pickup_branch", stringPtr(""), &order.PickupBranch)
+	}
+
+	if !s.gateways.IsAvailableFor(PaymentMethod(order.PaymentMethod), ShippingDestinationFor(order)) {
+		return false, fmt.Errorf("validación: la forma de pago '%s' no está disponible para %s", order.PaymentMethod, order.ShippingMunicipality)
+	}
+	return true, nil
+}
+
+// applyItemChanges agrega, modifica o quita items y mueve el stock de la
+// diferencia: reserva las unidades agregadas (falla con
+// *repositories.InsufficientStockError si no alcanzan) y regresa al
+// inventario las quitadas. Los items existentes conservan su precio snapshot;
+// los nuevos toman el precio vigente del producto.
+func applyItemChanges(tx *gorm.DB, order *models.Order, changes []EditOrderItemDTO, edit *models.OrderEdit) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	seen := map[uint]bool{}
+	reserve := map[uint]int{}
+	release := map[uint]int{}
+	items := order.OrderItems
+
+	for _, change := range changes {
+		if change.Quantity < 0 {
+			return fmt.Errorf("validación: quantity no puede ser negativo")
+		}
+		if seen[change.ProductID] {
+			return fmt.Errorf("validación: el producto %d aparece más de una vez", change.ProductID)
+		}
+		seen[change.ProductID] = true
+
+		index := -1
+		for i := range items {
+			if items[i].ProductID == change.ProductID {
+				index = i
+				break
+			}
+		}
+
+		current := 0
+		if index >= 0 {
+			current = items[index].Quantity
+		}
+		if change.Quantity == current {
+			continue
+		}
+
+		switch {
+		case index < 0:
+			// Producto nuevo en la orden
+			var product models.Product
+			if err := tx.First(&product, change.ProductID).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return fmt.Errorf("producto no encontrado: ID %d", change.ProductID)
+				}
+				return fmt.Errorf("error al obtener producto: %w", err)
+			}
+			if err := validateAddedProduct(order, &product); err != nil {
+				return err
+			}
+			item := models.OrderItem{
+				OrderID:     order.ID,
+				ProductID:   product.ID,
+				ProductName: product.Name,
+				Quantity:    change.Quantity,
+				Price:       product.Price,
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return fmt.Errorf("error al agregar item: %w", err)
+			}
+			items = append(items, item)
+		case change.Quantity == 0:
+			if err := tx.Delete(&models.OrderItem{}, "id = ?", items[index].ID).Error; err != nil {
+				return fmt.Errorf("error al quitar item: %w", err)
+			}
+			items = append(items[:index], items[index+1:]...)
+		default:
+			if err := tx.Model(&models.OrderItem{}).Where("id = ?", items[index].ID).
+				Update("quantity", change.Quantity).Error; err != nil {
+				return fmt.Errorf("error al actualizar item: %w", err)
+			}
+			items[index].Quantity = change.Quantity
+		}
+
+		if delta := change.Quantity - current; delta > 0 {
+			reserve[change.ProductID] = delta
+		} else {
+			release[change.ProductID] = -delta
+		}
+		edit.Changes = append(edit.Changes, models.OrderEditChange{
+			Field:     "item",
+			ProductID: change.ProductID,
+			From:      strconv.Itoa(current),
+			To:        strconv.Itoa(change.Quantity),
+		})
+	}
+
+	if len(items) == 0 {
+		return fmt.Errorf("validación: la orden debe conservar al menos un item")
+	}
+	order.OrderItems = items
+
+	// Órdenes creadas sin reserva de stock no mueven inventario
+	if !order.StockReserved {
+		return nil
+	}
+
+	if len(reserve) > 0 {
+		if err := repositories.ReserveStock(tx, reserve); err != nil {
+			return err
+		}
+	}
+	for productID, quantity := range release {
+		if err := tx.Model(&models.Product{}).
+			Where("id = ?", productID).
+			Update("stock", gorm.Expr("stock + ?", quantity)).Error; err != nil {
+			return fmt.Errorf("error al liberar stock del producto %d: %w", productID, err)
+		}
+	}
+	return nil
+}
+
+// validateAddedProduct verifica que un producto nuevo en la orden se pueda
+// vender: con precio de venta y en la misma moneda que la orden (el total se
+// suma en una sola moneda).
+func validateAddedProduct(order *models.Order, product *models.Product) error {
+	if !product.Price.IsPositive() {
+		return fmt.Errorf("validación: el producto %s no tiene precio de venta", product.Name)
+	}
+	currency := order.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if product.Price.CurrencyCode() != currency {
+		return fmt.Errorf("validación: el producto %s tiene precio en %s y la orden en %s",
+			product.Name, product.Price.CurrencyCode(), currency)
+	}
+	return nil
+}
+
+// applyPackageChanges aplica el ajuste manual del paquete o, si no hay ajuste
+// y cambiaron los items, lo vuelve a calcular (salvo que un admin lo haya
+// fijado antes). Retorna si cambió el tipo o el peso del paquete.
+func applyPackageChanges(tx *gorm.DB, order *models.Order, override *PackageOverrideDTO, itemsChanged bool, edit *models.OrderEdit) (bool, error) {
+	packageType, weightKg, overridden := order.PackageType, order.PackageWeightKg, order.PackageOverridden
+
+	switch {
+	case override != nil && override.Auto:
+		if override.Type != "" || override.WeightKg != 0 {
+			return false, fmt.Errorf("validación: package.auto no se puede combinar con type o weight_kg")
+		}
+		parcel, err := PackOrderItems(tx, order.OrderItems, nil)
+		if err != nil {
+			return false, err
+		}
+		packageType, weightKg, overridden = parcel.PackageType, parcel.WeightKg, false
+
+	case override != nil:
+		if override.Type == "" && override.WeightKg == 0 {
+			return false, fmt.Errorf("validación: package requiere type o weight_kg")
+		}
+		if override.WeightKg < 0 {
+			return false, fmt.Errorf("validación: package.weight_kg no puede ser negativo")
+		}
+		if override.Type != "" && !IsValidPackageType(override.Type) {
+			return false, fmt.Errorf("validación: tipo de paquete inválido '%s' (sobre, caja_pequeña o caja_mediana)", override.Type)
+		}
+		if packageType == "" || (itemsChanged && !overridden) {
+			// Lo que no se ajusta sale del paquete calculado con los items actuales
+			parcel, err := PackOrderItems(tx, order.OrderItems, nil)
+			if err != nil {
+				return false, err
+			}
+			packageType, weightKg = parcel.PackageType, parcel.WeightKg
+		}
+		if override.Type != "" {
+			packageType = override.Type
+		}
+		if override.WeightKg != 0 {
+			weightKg = override.WeightKg
+		}
+		overridden = true
+
+	case itemsChanged && !overridden:
+		parcel, err := PackOrderItems(tx, order.OrderItems, nil)
+		if err != nil {
+			return false, err
+		}
+		packageType, weightKg = parcel.PackageType, parcel.WeightKg
+
+	default:
+		return false, nil
+	}
+
+	changed := false
+	if packageType != order.PackageType {
+		edit.Changes = append(edit.Changes, models.OrderEditChange{Field: "package_type", From: order.PackageType, To: packageType})
+		order.PackageType = packageType
+		changed = true
+	}
+	if weightKg != order.PackageWeightKg {
+		edit.Changes = append(edit.Changes, models.OrderEditChange{
+			Field: "package_weight_kg",
+			From:  strconv.FormatFloat(order.PackageWeightKg, 'f', -1, 64),
+			To:    strconv.FormatFloat(weightKg, 'f', -1, 64),
+		})
+		order.PackageWeightKg = weightKg
+		changed = true
+	}
+	if overridden != order.PackageOverridden {
+		edit.Changes = append(edit.Changes, models.OrderEditChange{
+			Field: "package_overridden",
+			From:  strconv.FormatBool(order.PackageOverridden),
+			To:    strconv.FormatBool(overridden),
+		})
+		order.PackageOverridden = overridden
+	}
+	return changed, nil
+}
+
+// stringPtr retorna un puntero al string (para reutilizar los setters opcionales).
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+)
+
+func TestValidateAddedProduct(t *testing.T) {
+	order := &models.Order{Currency: models.DefaultCurrency}
+	tests := []struct {
+		name    string
+		price   models.Money
+		wantErr string
+	}{
+		{"precio válido", models.NewMoney(15000), ""},
+		{"sin precio", models.NewMoney(0), "no tiene precio"},
+		{"otra moneda", models.Money{Amount: 2000, Currency: "USD"}, "precio en USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAddedProduct(order, &models.Product{Name: "Blusa", Price: tt.price})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "validación") {
+				t.Fatalf("se esperaba error de validación con %q, se obtuvo %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// backend/services/order_export_service.go
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize es cuántas órdenes se leen de la BD por consulta al exportar.
+const exportBatchSize = 500
+
+// ExportFormat es el formato del archivo exportado.
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportXLSX ExportFormat = "xlsx"
+)
+
+// ExportRows indica si el archivo tiene una fila por orden o una por item.
+type ExportRows string
+
+const (
+	ExportRowsPerOrder ExportRows = "order"
+	ExportRowsPerItem  ExportRows = "item"
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// OrderExportRequest describe una exportación de órdenes.
+type OrderExportRequest struct {
+	// Filter: Mismos filtros que GET /admin/orders, más el rango de fechas.
+	Filter OrderFilter
+
+	// Format: 'csv' | 'xlsx'.
+	Format ExportFormat
+
+	// Rows: 'order' (default) | 'item'.
+	Rows ExportRows
+}
+
+// Validate verifica el formato, el tipo de filas y los filtros.
+func (r *OrderExportRequest) Validate() error {
+	switch r.Format {
+	case ExportCSV, ExportXLSX:
+	default:
+		return fmt.Errorf("validación: format debe ser csv o xlsx")
+	}
+	switch r.Rows {
+	case "":
+		r.Rows = ExportRowsPerOrder
+	case ExportRowsPerOrder, ExportRowsPerItem:
+	default:
+		return fmt.Errorf("validación: rows debe ser order o item")
+	}
+	return r.Filter.Validate()
+}
+
+// ContentType retorna el Content-Type HTTP del formato.
+func (f ExportFormat) ContentType() string {
+	if f == ExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// OrderExportService genera el reporte de órdenes para contabilidad.
+type OrderExportService interface {
+	// Export escribe el archivo en w a medida que lee las órdenes por lotes,
+	// sin cargarlas todas en memoria. No escribe nada en w hasta haber leído el
+	// primer lote: si falla la validación o la primera consulta, w queda intacto
+	// y todavía se puede responder con un error HTTP.
+	Export(request OrderExportRequest, w io.Writer) (int, error)
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type orderExportService struct {
+	db *gorm.DB
+}
+
+// NewOrderExportService crea el servicio de exportación.
+func NewOrderExportService(db *gorm.DB) OrderExportService {
+	return &orderExportService{db: db}
+}
+
+// rowWriter abstrae el formato del archivo (CSV o XLSX).
+type rowWriter interface {
+	WriteRow(cells []interface{}) error
+	Flush() error
+	Close() error
+}
+
+var orderExportHeader = []interface{}{
+	"Número de orden", "Fecha", "Estado", "Cliente", "Email", "Teléfono",
+	"Departamento", "Municipio", "Tipo de entrega", "Sucursal", "Método de envío",
+	"Guía", "Forma de pago", "Fecha de pago", "Subtotal", "Envío", "Total",
+	"Reembolsado", "Moneda",
+}
+
+var itemExportHeader = []interface{}{
+	"Número de orden", "Fecha", "Estado", "Cliente", "Email",
+	"Municipio", "Tipo de entrega", "Guía",
+	"Producto ID", "Producto", "Cantidad", "Precio unitario", "Total línea",
+	"Subtotal orden", "Envío orden", "Total orden",
+}
+
+// Export recorre las órdenes con paginación por (created_at, id) para que el
+// orden sea estable y cada lote sea una consulta indexada.
+// Retorna la cantidad de órdenes exportadas.
+func (s *orderExportService) Export(request OrderExportRequest, w io.Writer) (int, error) {
+	if err := request.Validate(); err != nil {
+		return 0, err
+	}
+
+	// Leer el primer lote antes de escribir el primer byte
+	batch, err := s.nextBatch(request, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := newRowWriter(request.Format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	header := orderExportHeader
+	if request.Rows == ExportRowsPerItem {
+		header = itemExportHeader
+	}
+	if err := out.WriteRow(header); err != nil {
+		return 0, fmt.Errorf("error al escribir exportación: %w", err)
+	}
+
+	exported := 0
+	for {
+		for i := range batch {
+			if err := writeOrderRows(out, &batch[i], request.Rows); err != nil {
+				return exported, fmt.Errorf("error al escribir exportación: %w", err)
+			}
+		}
+		exported += len(batch)
+
+		// Enviar lo escrito al cliente antes de leer el siguiente lote
+		if err := out.Flush(); err != nil {
+			return exported, fmt.Errorf("error al escribir exportación: %w", err)
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if len(batch) < exportBatchSize {
+			break
+		}
+		if batch, err = s.nextBatch(request, &batch[len(batch)-1]); err != nil {
+			return exported, err
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return exported, fmt.Errorf("error al escribir exportación: %w", err)
+	}
+
+	log.Printf("Exportación %s por %s: %d órdenes", request.Format, request.Rows, exported)
+	return exported, nil
+}
+
+// nextBatch lee el lote de órdenes que sigue a last (el primero si last es nil).
+func (s *orderExportService) nextBatch(request OrderExportRequest, last *models.Order) ([]models.Order, error) {
+	query := request.Filter.Apply(s.db.Model(&models.Order{}))
+	if request.Rows == ExportRowsPerItem {
+		query = query.Preload("OrderItems")
+	}
+	if last != nil {
+		query = query.Where("(orders.created_at, orders.id) > (?, ?)", last.CreatedAt, last.ID)
+	}
+
+	var batch []models.Order
+	if err := query.Order("orders.created_at ASC, orders.id ASC").
+		Limit(exportBatchSize).
+		Find(&batch).Error; err != nil {
+		return nil, fmt.Errorf("error al obtener órdenes: %w", err)
+	}
+	return batch, nil
+}
+
+// writeOrderRows escribe la fila de la orden o una fila por cada item.
+func writeOrderRows(out rowWriter, order *models.Order, rows ExportRows) error {
+	if rows == ExportRowsPerOrder {
+		return out.WriteRow([]interface{}{
+			order.Reference(), formatExportTime(&order.CreatedAt), string(order.Status),
+			order.CustomerName, order.CustomerEmail, order.CustomerPhone,
+			order.ShippingDepartment, order.ShippingMunicipality, order.DeliveryType,
+			order.PickupBranch, order.ShippingMethod, order.ShippingTracking,
+			order.PaymentMethod, formatExportTime(order.PaidAt),
+			order.Subtotal, order.ShippingCost, order.Total, order.RefundedAmount,
+			order.Currency,
+		})
+	}
+
+	for _, item := range order.OrderItems {
+		if err := out.WriteRow([]interface{}{
+			order.Reference(), formatExportTime(&order.CreatedAt), string(order.Status),
+			order.CustomerName, order.CustomerEmail,
+			order.ShippingMunicipality, order.DeliveryType, order.ShippingTracking,
+			item.ProductID, item.ProductName, item.Quantity, item.Price, item.GetSubtotal(),
+			order.Subtotal, order.ShippingCost, order.Total,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatExportTime formatea una fecha en hora de Guatemala para el reporte
+// (vacío si es nil), sin depender de la zona horaria del servidor.
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.In(analyticsLocation).Format("2006-01-02 15:04")
+}
+
+// newRowWriter crea el writer del formato pedido.
+func newRowWriter(format ExportFormat, w io.Writer) (rowWriter, error) {
+	if format == ExportXLSX {
+		return newXLSXWriter(w, "Órdenes")
+	}
+
+	// BOM UTF-8: Excel lo necesita para mostrar bien las tildes
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, fmt.Errorf("error al escribir exportación: %w", err)
+	}
+	return &csvRowWriter{csv: csv.NewWriter(w)}, nil
+}
+
+// csvRowWriter adapta encoding/csv a rowWriter.
+type csvRowWriter struct {
+	csv *csv.Writer
+}
+
+// WriteRow escribe la fila. Los textos de datos del cliente que Excel
+// interpretaría como fórmula se escapan (ver escapeCSVFormula).
+func (cw *csvRowWriter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case models.Money:
+			record[i] = v.Decimal()
+		case string:
+			record[i] = escapeCSVFormula(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return cw.csv.Write(record)
+}
+
+// escapeCSVFormula prefija con un apóstrofo los textos que empiezan con un
+// carácter que inicia una fórmula en Excel o LibreOffice (=, +, -, @, tab o
+// retorno de carro), para que se muestren como texto y no se ejecuten.
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// Flush envía las filas pendientes al writer.
+func (cw *csvRowWriter) Flush() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+// Close termina el archivo.
+func (cw *csvRowWriter) Close() error {
+	return cw.Flush()
+}
@@ -0,0 +1,52 @@
+// backend/services/order_export_service_test.go
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+)
+
+func TestCSVRowWriterEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	writer := &csvRowWriter{csv: csv.NewWriter(&buf)}
+
+	cells := []interface{}{
+		"=HYPERLINK(\"http://example.com\")", "+50255555555", "-2+3", "@SUM(A1)",
+		"\t=1+1", "\r=1+1", "Ana López", "", models.NewMoney(-1500), 3,
+	}
+	if err := writer.WriteRow(cells); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	record, err := csv.NewReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("error leyendo CSV: %v", err)
+	}
+	want := []string{
+		"'=HYPERLINK(\"http://example.com\")", "'+50255555555", "'-2+3", "'@SUM(A1)",
+		"'\t=1+1", "'\r=1+1", "Ana López", "", "-15.00", "3",
+	}
+	for i := range want {
+		if record[i] != want[i] {
+			t.Errorf("celda %d = %q, se esperaba %q", i, record[i], want[i])
+		}
+	}
+}
+
+func TestFormatExportTimeUsesGuatemalaTime(t *testing.T) {
+	// 02:30 UTC es 20:30 del día anterior en Guatemala (UTC-6)
+	at := time.Date(2026, 3, 2, 2, 30, 0, 0, time.UTC)
+	if got, want := formatExportTime(&at), "2026-03-01 20:30"; got != want {
+		t.Errorf("formatExportTime = %q, se esperaba %q", got, want)
+	}
+	if got := formatExportTime(nil); got != "" {
+		t.Errorf("formatExportTime(nil) = %q, se esperaba vacío", got)
+	}
+}
@@ -0,0 +1,204 @@
+// backend/services/order_filter.go
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderFilter son los filtros de los listados de órdenes del admin
+// (GET /admin/orders y la exportación). Los campos vacíos no filtran.
+type OrderFilter struct {
+	// Status: Estado exacto de la orden.
+	Status models.OrderStatus
+
+	// Municipality: Municipio de envío exacto.
+	Municipality string
+
+	// OrderNumber: Número de orden completo o parcial ("MO-2026-000123", "123").
+	OrderNumber string
+
+	// From / To: Rango de fechas de creación. To es exclusivo.
+	From *time.Time
+	To   *time.Time
+
+	// MinTotal / MaxTotal: Rango del total de la orden (inclusivo).
+	MinTotal *models.Money
+	MaxTotal *models.Money
+
+	// DeliveryType: 'home_delivery' | 'pickup_at_branch'.
+	DeliveryType string
+
+	// ShippingMethod: Método de envío exacto ('local', 'cargo_expreso', ...).
+	ShippingMethod string
+
+	// RequiresCourier: Solo órdenes que requieren (true) o no (false) Cargo Expreso.
+	RequiresCourier *bool
+
+	// HasTracking: Solo órdenes con (true) o sin (false) número de guía.
+	HasTracking *bool
+
+	// Search: Texto libre sobre nombre, email y teléfono del cliente,
+	// número de guía y número de orden.
+	Search string
+}
+
+// ParseOrderDateRange interpreta las fechas "from" y "to" (YYYY-MM-DD) de un listado.
+// "to" incluye el día completo: se convierte en el inicio del día siguiente.
+// Los días son de la hora de Guatemala, igual que en la analítica de ventas.
+func ParseOrderDateRange(from, to string) (*time.Time, *time.Time, error) {
+	var start, end *time.Time
+	if from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, analyticsLocation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("validación: from debe tener formato YYYY-MM-DD")
+		}
+		start = &parsed
+	}
+	if to != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", to, analyticsLocation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("validación: to debe tener formato YYYY-MM-DD")
+		}
+		parsed = parsed.AddDate(0, 0, 1)
+		end = &parsed
+	}
+	if start != nil && end != nil && !start.Before(*end) {
+		return nil, nil, fmt.Errorf("validación: from debe ser anterior o igual a to")
+	}
+	return start, end, nil
+}
+
+// Validate verifica los valores del filtro.
+func (f OrderFilter) Validate() error {
+	if f.Status != "" && !f.Status.IsValid() {
+		return fmt.Errorf("validación: estado de orden inválido: %s", f.Status)
+	}
+	switch f.DeliveryType {
+	case "", "home_delivery", "pickup_at_branch":
+	default:
+		return fmt.Errorf("validación: delivery_type debe ser home_delivery o pickup_at_branch")
+	}
+	if f.MinTotal != nil && f.MinTotal.IsNegative() || f.MaxTotal != nil && f.MaxTotal.IsNegative() {
+		return fmt.Errorf("validación: los montos no pueden ser negativos")
+	}
+	if f.MinTotal != nil && f.MaxTotal != nil && f.MinTotal.GreaterThan(*f.MaxTotal) {
+		return fmt.Errorf("validación: min_total debe ser menor o igual a max_total")
+	}
+	if len(f.Search) > 100 {
+		return fmt.Errorf("validación: la búsqueda no puede tener más de 100 caracteres")
+	}
+	return nil
+}
+
+// Apply agrega las condiciones del filtro a una consulta sobre orders.
+func (f OrderFilter) Apply(query *gorm.DB) *gorm.DB {
+	if f.Status != "" {
+		query = query.Where("orders.status = ?", f.Status)
+	}
+	if f.Municipality != "" {
+		query = query.Where("orders.shipping_municipality = ?", f.Municipality)
+	}
+	if number := strings.TrimSpace(f.OrderNumber); number != "" {
+		query = query.Where("orders.order_number ILIKE ?", "%"+escapeLike(strings.ToUpper(number))+"%")
+	}
+	if f.From != nil {
+		query = query.Where("orders.created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("orders.created_at < ?", *f.To)
+	}
+	if f.MinTotal != nil {
+		query = query.Where("orders.total >= ?", *f.MinTotal)
+	}
+	if f.MaxTotal != nil {
+		query = query.Where("orders.total <= ?", *f.MaxTotal)
+	}
+	if f.DeliveryType != "" {
+		query = query.Where("orders.delivery_type = ?", f.DeliveryType)
+	}
+	if f.ShippingMethod != "" {
+		query = query.Where("orders.shipping_method = ?", f.ShippingMethod)
+	}
+	if f.RequiresCourier != nil {
+		query = query.Where("orders.requires_courier = ?", *f.RequiresCourier)
+	}
+	if f.HasTracking != nil {
+		if *f.HasTracking {
+			query = query.Where("COALESCE(orders.shipping_tracking, '') <> ''")
+		} else {
+			query = query.Where("COALESCE(orders.shipping_tracking, '') = ''")
+		}
+	}
+	if search := strings.TrimSpace(f.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		query = query.Where(
+			"orders.customer_name ILIKE ? OR orders.customer_email ILIKE ? OR orders.customer_phone ILIKE ? "+
+				"OR orders.shipping_tracking ILIKE ? OR orders.order_number ILIKE ?",
+			pattern, pattern, pattern, pattern, pattern,
+		)
+	}
+	return query
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto tal cual.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ============================================================================
+// Paginación por cursor
+// ============================================================================
+
+// OrderCursor es la posición de la última orden de una página del listado del
+// admin, ordenado por created_at DESC, id DESC. A diferencia del offset, no se
+// salta ni repite órdenes cuando entran órdenes nuevas entre una página y otra.
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// NewOrderCursor crea el cursor que apunta a la orden.
+func NewOrderCursor(order *models.Order) OrderCursor {
+	return OrderCursor{CreatedAt: order.CreatedAt, ID: order.ID}
+}
+
+// Encode retorna el cursor como texto opaco para la respuesta (next_cursor).
+func (c OrderCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseOrderCursor interpreta un cursor generado por Encode.
+func ParseOrderCursor(s string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("validación: cursor inválido")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("validación: cursor inválido")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("validación: cursor inválido")
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("validación: cursor inválido")
+	}
+	return &OrderCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// After agrega a la consulta la condición para traer las órdenes que siguen
+// al cursor en orden created_at DESC, id DESC.
+func (c OrderCursor) After(query *gorm.DB) *gorm.DB {
+	return query.Where("(orders.created_at, orders.id) < (?, ?)", c.CreatedAt, c.ID)
+}
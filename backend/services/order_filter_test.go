@@ -0,0 +1,37 @@
+// backend/services/order_filter_test.go
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOrderDateRangeUsesGuatemalaTime(t *testing.T) {
+	from, to, err := ParseOrderDateRange("2026-03-01", "2026-03-31")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	wantFrom := time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2026, 4, 1, 6, 0, 0, 0, time.UTC)
+	if !from.Equal(wantFrom) {
+		t.Errorf("from = %s, se esperaba %s", from.UTC(), wantFrom)
+	}
+	if !to.Equal(wantTo) {
+		t.Errorf("to = %s, se esperaba %s", to.UTC(), wantTo)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"MO-2026":   "MO-2026",
+		"100%":      `100\%`,
+		"ana_lopez": `ana\_lopez`,
+		`c:\ruta`:   `c:\\ruta`,
+	}
+	for input, want := range tests {
+		if got := escapeLike(input); got != want {
+			t.Errorf("escapeLike(%q) = %q, se esperaba %q", input, got, want)
+		}
+	}
+}
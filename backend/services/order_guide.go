@@ -0,0 +1,134 @@
+// backend/services/order_guide.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GenerateOrderGuide genera la guía de Cargo Expreso de una orden pagada y la
+// pasa a 'processing' guardando el tracking. actor indica quién la genera
+// (webhook de Stripe, admin en lote); el motivo lo completa esta función.
+//
+// También acepta órdenes pagadas que ya están en 'processing' sin guía: la
+// edición de destino o paquete anula la guía anterior sin regresar la orden a
+// 'paid', y la guía nueva se guarda sin cambiar de estado.
+//
+// La orden se vuelve a leer con bloqueo antes de guardar: si mientras se
+// generaba la guía otra solicitud ya guardó una, se retorna error y la guía
+// nueva se anula.
+func GenerateOrderGuide(db *gorm.DB, cargo CargoExpresoService, order *models.Order, actor models.StatusChange) (*CargoExpresoGuideResponse, error) {
+	if err := checkGuideable(order); err != nil {
+		return nil, err
+	}
+
+	// Datos del remitente (Moda Orgánica) desde .env
+	senderName := os.Getenv("CARGO_EXPRESO_SENDER_NAME")
+	senderPhone := os.Getenv("CARGO_EXPRESO_SENDER_PHONE")
+	senderAddress := os.Getenv("CARGO_EXPRESO_SENDER_ADDRESS")
+	senderCity := os.Getenv("CARGO_EXPRESO_SENDER_CITY")
+	if senderName == "" || senderPhone == "" || senderAddress == "" {
+		return nil, fmt.Errorf("error al generar guía: datos del remitente (CARGO_EXPRESO_SENDER_*) incompletos")
+	}
+
+	parcel, err := OrderPackage(db, order)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar guía: %w", err)
+	}
+
+	response, err := cargo.CreateGuide(CargoExpresoGuideRequest{
+		// Remitente (Moda Orgánica)
+		SenderName:    senderName,
+		SenderPhone:   senderPhone,
+		SenderAddress: senderAddress,
+		SenderCity:    senderCity,
+
+		// Destinatario (Cliente)
+		RecipientName:    order.CustomerName,
+		RecipientPhone:   order.CustomerPhone,
+		RecipientAddress: order.ShippingAddress, // Vacío si es pickup
+		RecipientCity:    order.ShippingMunicipality,
+
+		// Datos del envío
+		OrderID:       order.ID.String(),
+		PackageType:   parcel.PackageType,
+		Weight:        parcel.WeightLb(),
+		DeclaredValue: order.Total,
+		Notes:         fmt.Sprintf("Orden numero %s - Moda Organica", order.Reference()),
+
+		DeliveryType: order.DeliveryType,
+		PickupBranch: order.PickupBranch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al generar guía: %w", err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("error al generar guía: Cargo Expreso respondió: %s", response.ErrorMessage)
+	}
+
+	// Guardar el tracking y pasar de paid a processing
+	actor.Reason = "guía de Cargo Expreso generada: " + response.TrackingNumber
+	actor.Fields = map[string]interface{}{
+		"shipping_tracking":       response.TrackingNumber,
+		"cargo_expreso_guide_url": response.GuideURL,
+		// Guía nueva: el rastreo empieza de cero
+		"tracking_next_check_at": nil,
+		"tracking_last_event_at": nil,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var current models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "id = ?", order.ID).Error; err != nil {
+			return fmt.Errorf("error al obtener orden: %w", err)
+		}
+		if err := checkGuideable(&current); err != nil {
+			return err
+		}
+		if current.Status == models.StatusProcessing {
+			// Guía de reemplazo: el estado no cambia, solo se registra en el timeline
+			if err := tx.Model(&models.Order{}).Where("id = ?", current.ID).Updates(actor.Fields).Error; err != nil {
+				return fmt.Errorf("error al guardar la guía: %w", err)
+			}
+			if err := models.RecordStatusEvent(tx, current.ID, current.Status, current.Status, actor); err != nil {
+				return err
+			}
+		} else if err := current.TransitionTo(tx, models.StatusProcessing, actor); err != nil {
+			return err
+		}
+		current.ShippingTracking = response.TrackingNumber
+		current.CargoExpresoGuideURL = response.GuideURL
+		*order = current
+		return nil
+	})
+	if err != nil {
+		// La guía ya se creó en Cargo Expreso pero no quedó en la orden: anularla
+		if cancelErr := cargo.CancelGuide(response.TrackingNumber); cancelErr != nil {
+			log.Printf("No se pudo anular la guía %s sin usar: %v", response.TrackingNumber, cancelErr)
+		}
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// checkGuideable verifica que la orden esté lista para generar su guía: pagada,
+// o en 'processing' ya cobrada y sin guía (la anterior se anuló al editarla).
+func checkGuideable(order *models.Order) error {
+	switch {
+	case !order.RequiresCourier:
+		return fmt.Errorf("la orden %s no requiere Cargo Expreso (entrega local)", order.Reference())
+	case order.ShippingTracking != "":
+		return fmt.Errorf("la orden %s ya tiene guía %s", order.Reference(), order.ShippingTracking)
+	case order.Status == models.StatusProcessing && order.PaidAt != nil:
+		return nil
+	case order.Status != models.StatusPaid:
+		return fmt.Errorf("la orden %s en estado '%s' no se puede enviar: debe estar pagada", order.Reference(), order.Status)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+)
+
+func TestCheckGuideable(t *testing.T) {
+	paidAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		order   models.Order
+		wantErr string
+	}{
+		{"pagada sin guía", models.Order{Status: models.StatusPaid, RequiresCourier: true, PaidAt: &paidAt}, ""},
+		{"en proceso con la guía anulada al editar", models.Order{Status: models.StatusProcessing, RequiresCourier: true, PaidAt: &paidAt}, ""},
+		{"en proceso con guía", models.Order{Status: models.StatusProcessing, RequiresCourier: true, PaidAt: &paidAt, ShippingTracking: "CE-1"}, "ya tiene guía"},
+		{"en proceso sin cobrar (contra entrega)", models.Order{Status: models.StatusProcessing, RequiresCourier: true}, "debe estar pagada"},
+		{"pendiente de pago", models.Order{Status: models.StatusPending, RequiresCourier: true}, "debe estar pagada"},
+		{"entrega local", models.Order{Status: models.StatusPaid, PaidAt: &paidAt}, "no requiere Cargo Expreso"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGuideable(&tt.order)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("se esperaba error con %q, se obtuvo %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// backend/services/order_reconciler.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultPendingOrderTTL es el tiempo que una orden puede quedar pendiente de pago.
+	// Coincide con la expiración por defecto de una Checkout Session de Stripe.
+	defaultPendingOrderTTL = 24 * time.Hour
+
+	// defaultReconcileInterval es cada cuánto corre el reconciliador en segundo plano.
+	defaultReconcileInterval = 15 * time.Minute
+
+	// reconcileBatchSize limita cuántas órdenes se procesan por corrida.
+	reconcileBatchSize = 100
+
+	// pendingRefundGrace es cuánto espera el reconciliador antes de retomar un
+	// reembolso pendiente, para no competir con la solicitud que lo creó. Las
+	// idempotency keys de Stripe duran 24 horas: el intervalo debe ser menor.
+	pendingRefundGrace = 5 * time.Minute
+)
+
+// ReconcileResult resume una corrida del reconciliador de órdenes pendientes.
+type ReconcileResult struct {
+	// Cutoff: Se consideraron vencidas las órdenes creadas antes de este momento.
+	Cutoff time.Time `json:"cutoff"`
+
+	// Cancelled: Órdenes canceladas en esta corrida.
+	Cancelled []uuid.UUID `json:"cancelled"`
+
+	// ReleasedUnits: Unidades regresadas al inventario.
+	ReleasedUnits int `json:"released_units"`
+
+	// Errors: Órdenes que no se pudieron cancelar (se reintentan en la siguiente corrida).
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// OrderReconciler cancela las órdenes que quedaron pendientes de pago más
+// tiempo del permitido (el cliente abandonó la página de Stripe) y regresa
+// al inventario el stock que tenían reservado. En cada corrida también
+// completa los reembolsos que quedaron pendientes.
+type OrderReconciler struct {
+	db       *gorm.DB
+	gateways *PaymentGateways
+	refunds  *refundService
+	ttl      time.Duration
+	interval time.Duration
+}
+
+// NewOrderReconciler crea un reconciliador con el TTL y el intervalo indicados.
+func NewOrderReconciler(db *gorm.DB, gateways *PaymentGateways, ttl, interval time.Duration) *OrderReconciler {
+	return &OrderReconciler{
+		db:       db,
+		gateways: gateways,
+		refunds:  &refundService{db: db, gateways: gateways},
+		ttl:      ttl,
+		interval: interval,
+	}
+}
+
+// NewOrderReconcilerFromEnv lee PENDING_ORDER_RECONCILE_INTERVAL (formato de
+// time.ParseDuration, ej: "15m"). ttl es el de PendingOrderTTLFromEnv, el
+// mismo con el que vencen las sesiones de pago.
+func NewOrderReconcilerFromEnv(db *gorm.DB, gateways *PaymentGateways, ttl time.Duration) *OrderReconciler {
+	interval := durationFromEnv("PENDING_ORDER_RECONCILE_INTERVAL", defaultReconcileInterval)
+	return NewOrderReconciler(db, gateways, ttl, interval)
+}
+
+// PendingOrderTTLFromEnv lee PENDING_ORDER_TTL (ej: "24h", "2h"): el tiempo que
+// una orden puede quedar pendiente de pago.
+func PendingOrderTTLFromEnv() time.Duration {
+	return durationFromEnv("PENDING_ORDER_TTL", defaultPendingOrderTTL)
+}
+
+// TTL retorna el tiempo máximo que una orden puede quedar pendiente.
+func (r *OrderReconciler) TTL() time.Duration {
+	return r.ttl
+}
+
+// Start corre el reconciliador periódicamente hasta que se cierre stop.
+// Debe llamarse en una goroutine.
+func (r *OrderReconciler) Start(stop <-chan struct{}) {
+	log.Printf("Reconciliador de órdenes pendientes iniciado (TTL %s, cada %s)", r.ttl, r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.ExpireStaleOrders(r.ttl); err != nil {
+				log.Printf("Error en reconciliador de órdenes pendientes: %v", err)
+			}
+			if completed, err := r.refunds.ResumePendingRefunds(pendingRefundGrace); err != nil {
+				log.Printf("Error al retomar reembolsos pendientes: %v", err)
+			} else if completed > 0 {
+				log.Printf("Reconciliador: %d reembolsos pendientes completados", completed)
+			}
+		case <-stop:
+			log.Println("Reconciliador de órdenes pendientes detenido")
+			return
+		}
+	}
+}
+
+// ExpireStaleOrders cancela las órdenes pendientes de pago creadas hace más de olderThan.
+// Las órdenes con pago contra entrega no vencen: se cobran al entregarlas.
+func (r *OrderReconciler) ExpireStaleOrders(olderThan time.Duration) (*ReconcileResult, error) {
+	result := &ReconcileResult{
+		Cutoff:    time.Now().Add(-olderThan),
+		Cancelled: []uuid.UUID{},
+		Errors:    map[string]string{},
+	}
+
+	var candidates []uuid.UUID
+	if err := r.db.Model(&models.Order{}).
+		Where("status = ? AND created_at < ? AND payment_method <> ?",
+			models.StatusPending, result.Cutoff, PaymentMethodCashOnDelivery).
+		Order("created_at ASC").
+		Limit(reconcileBatchSize).
+		Pluck("id", &candidates).Error; err != nil {
+		return nil, fmt.Errorf("error al buscar órdenes pendientes vencidas: %w", err)
+	}
+
+	for _, orderID := range candidates {
+		released, cancelled, err := r.expireOrder(orderID, result.Cutoff)
+		if err != nil {
+			log.Printf("No se pudo expirar la orden %s: %v", orderID, err)
+			result.Errors[orderID.String()] = err.Error()
+			continue
+		}
+		if cancelled {
+			result.Cancelled = append(result.Cancelled, orderID)
+			result.ReleasedUnits += released
+		}
+	}
+
+	if len(result.Cancelled) > 0 || len(result.Errors) > 0 {
+		log.Printf("Reconciliador: %d órdenes canceladas, %d unidades liberadas, %d errores",
+			len(result.Cancelled), result.ReleasedUnits, len(result.Errors))
+	}
+
+	return result, nil
+}
+
+// expireOrder cancela una orden vencida y libera su stock. Igual que
+// OrderCancellationService, la sesión de pago se invalida antes de cancelar:
+// si el cliente la pagó justo antes, Stripe no la deja expirar y la orden
+// queda pendiente hasta que llegue el webhook de pago. Después, con la fila
+// bloqueada, se vuelve a verificar el estado por si el webhook llegó mientras
+// tanto. Retorna las unidades liberadas.
+func (r *OrderReconciler) expireOrder(orderID uuid.UUID, cutoff time.Time) (int, bool, error) {
+	// 1. Invalidar la sesión de pago
+	var snapshot models.Order
+	if err := r.db.First(&snapshot, "id = ?", orderID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if snapshot.Status != models.StatusPending || !snapshot.CreatedAt.Before(cutoff) {
+		return 0, false, nil
+	}
+
+	if snapshot.StripeSessionID != "" && r.gateways != nil {
+		gateway, err := r.gateways.Get(PaymentMethod(snapshot.PaymentMethod))
+		if err != nil {
+			return 0, false, err
+		}
+		if err := gateway.CancelCheckout(snapshot.StripeSessionID); err != nil {
+			return 0, false, fmt.Errorf("no se pudo invalidar la sesión de pago, se reintenta en la siguiente corrida: %w", err)
+		}
+	}
+
+	// 2. Cancelar y liberar el stock
+	var order models.Order
+	released := 0
+	cancelled := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("OrderItems").
+			First(&order, "id = ?", orderID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// Bloqueada por otra transacción o eliminada: se revisa en la siguiente corrida
+				return nil
+			}
+			return err
+		}
+
+		if order.Status != models.StatusPending || !order.CreatedAt.Before(cutoff) {
+			return nil
+		}
+
+		units, err := ReleaseOrderStock(tx, &order)
+		if err != nil {
+			return err
+		}
+		released = units
+
+		if err := order.TransitionTo(tx, models.StatusCancelled, models.StatusChange{
+			ActorType: models.ActorSystem,
+			ActorID:   "order_reconciler",
+			Reason:    fmt.Sprintf("pendiente de pago por más de %s", r.ttl),
+		}); err != nil {
+			return fmt.Errorf("error al cancelar orden: %w", err)
+		}
+
+		cancelled = true
+		return nil
+	})
+	if err != nil || !cancelled {
+		return 0, false, err
+	}
+
+	log.Printf("Orden %s cancelada: pendiente de pago desde %s (más de %s), %d unidades regresadas al inventario",
+		order.ID, order.CreatedAt.Format(time.RFC3339), time.Since(order.CreatedAt).Round(time.Minute), released)
+
+	return released, true, nil
+}
+
+// ReleaseOrderStock regresa al inventario las unidades reservadas por la orden
+// y la marca sin stock reservado. Debe llamarse dentro de una transacción.
+// Retorna las unidades liberadas (0 si la orden no tenía stock reservado).
+func ReleaseOrderStock(tx *gorm.DB, order *models.Order) (int, error) {
+	if !order.StockReserved {
+		return 0, nil
+	}
+
+	items := order.OrderItems
+	if len(items) == 0 {
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return 0, fmt.Errorf("error al obtener items de la orden: %w", err)
+		}
+	}
+
+	released := 0
+	for _, item := range items {
+		if err := tx.Model(&models.Product{}).
+			Where("id = ?", item.ProductID).
+			Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+			return 0, fmt.Errorf("error al liberar stock del producto %d: %w", item.ProductID, err)
+		}
+		released += item.Quantity
+	}
+
+	if err := tx.Model(order).Update("stock_reserved", false).Error; err != nil {
+		return 0, fmt.Errorf("error al actualizar reserva de stock: %w", err)
+	}
+	order.StockReserved = false
+
+	return released, nil
+}
+
+// durationFromEnv lee una duración de una variable de entorno o usa el valor por defecto.
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		log.Printf("Advertencia: %s inválido (%q), usando %s", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// intFromEnv lee un entero positivo de una variable de entorno o usa el valor por defecto.
+func intFromEnv(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		log.Printf("Advertencia: %s inválido (%q), usando %d", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
@@ -9,7 +9,6 @@ import (
 	"moda-organica/backend/repositories"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 // ============================================================================
@@ -41,14 +40,50 @@ type CreateOrderDTO struct {
 	// CustomerPhone: Número de teléfono del cliente (requerido).
 	CustomerPhone string `json:"customer_phone" binding:"required"`
 
-	// ShippingAddress: Dirección completa de envío (requerida).
-	ShippingAddress string `json:"shipping_address" binding:"required"`
+	// ShippingDepartment: Departamento de envío (código, nombre o alias; ej: "GT-13").
+	// Se guarda como código del catálogo geográfico.
+	ShippingDepartment string `json:"shipping_department"`
+
+	// ShippingAddress: Dirección completa de envío (requerida para entrega a domicilio).
+	ShippingAddress string `json:"shipping_address"`
 
 	// ShippingMunicipality: Municipio de envío (requerido para calcular costo).
+	// Acepta código INE, nombre o alias; se guarda con el nombre oficial y debe
+	// pertenecer a ShippingDepartment.
 	ShippingMunicipality string `json:"shipping_municipality" binding:"required"`
 
+	// DeliveryType: 'home_delivery' (default) | 'pickup_at_branch'.
+	DeliveryType string `json:"delivery_type" binding:"omitempty,oneof=home_delivery pickup_at_branch"`
+
+	// PickupBranch: Sucursal de Cargo Expreso (requerida si DeliveryType es pickup_at_branch).
+	PickupBranch string `json:"pickup_branch"`
+
+	// DeliveryNotes: Instrucciones de entrega.
+	DeliveryNotes string `json:"delivery_notes"`
+
+	// DeliveryLat / DeliveryLng: Coordenadas de entrega (opcionales). Si caen en
+	// una zona de entrega, la zona define el método y costo del envío.
+	DeliveryLat *float64 `json:"delivery_lat"`
+	DeliveryLng *float64 `json:"delivery_lng"`
+
+	// PaymentMethod: 'card' (default) | 'cash_on_delivery'.
+	PaymentMethod PaymentMethod `json:"payment_method" binding:"omitempty,oneof=card cash_on_delivery"`
+
 	// Items: Lista de items a comprar (requerida, mínimo 1).
 	Items []OrderItemDTO `json:"items" binding:"required,min=1"`
+
+	// ClientPrices: Montos que vio el cliente. Si se indican y no coinciden con
+	// la cotización del servidor, la orden se rechaza con PriceMismatchError.
+	ClientPrices *ClientPrices `json:"-"`
+}
+
+// CreateOrderResult es el resultado del pipeline de creación de órdenes.
+type CreateOrderResult struct {
+	// Order: Orden creada con sus items.
+	Order *models.Order
+
+	// Quote: Cotización del servidor con la que se creó la orden.
+	Quote *CartQuote
 }
 
 // ============================================================================
@@ -58,10 +93,10 @@ type CreateOrderDTO struct {
 // OrderService define la interfaz para la lógica de negocio de órdenes.
 // Encapsula las reglas de negocio y la orquestación entre repositorios.
 type OrderService interface {
-	// CreateOrder crea una nueva orden con todos sus items.
-	// Valida productos, stock, calcula costos y crea snapshots.
-	// Retorna la orden creada o error si algo falla.
-	CreateOrder(dto CreateOrderDTO) (*models.Order, error)
+	// CreateOrder es el único pipeline de creación de órdenes: valida la solicitud,
+	// cotiza con precios del servidor, reserva stock, crea los snapshots de los
+	// items y guarda la orden. Todos los endpoints que crean órdenes lo usan.
+	CreateOrder(dto CreateOrderDTO) (*CreateOrderResult, error)
 
 	// GetOrderByID obtiene una orden por su ID.
 	// Retorna error si la orden no existe.
@@ -71,13 +106,14 @@ type OrderService interface {
 	// Retorna lista de órdenes o error si falla.
 	GetUserOrders(userID uuid.UUID) ([]models.Order, error)
 
-	// UpdateOrderStatus actualiza el estado de una orden.
+	// UpdateOrderStatus actualiza el estado de una orden (cambio manual del admin:
+	// no asigna paid ni los estados de reembolso, ver models.ValidateManualStatus).
 	// Retorna error si la orden no existe o la actualización falla.
-	UpdateOrderStatus(orderID uuid.UUID, status models.OrderStatus) error
+	UpdateOrderStatus(orderID uuid.UUID, status models.OrderStatus, change models.StatusChange) error
 
 	// CalculateShippingCost calcula el costo de envío para un municipio.
 	// Retorna el costo o error si el municipio es inválido.
-	CalculateShippingCost(municipality string) (float64, error)
+	CalculateShippingCost(municipality string) (models.Money, error)
 }
 
 // ============================================================================
@@ -87,16 +123,17 @@ type OrderService interface {
 // orderService implementa la interfaz OrderService.
 // Contiene la lógica de negocio y orquesta las operaciones de los repositorios.
 type orderService struct {
-	orderRepo repositories.OrderRepository
-	db        *gorm.DB
+	orderRepo   repositories.OrderRepository
+	productRepo repositories.ProductRepository
 }
 
 // NewOrderService crea una nueva instancia del servicio de órdenes.
-// Recibe el repositorio de órdenes y la conexión a la base de datos.
-func NewOrderService(orderRepo repositories.OrderRepository, db *gorm.DB) OrderService {
+// Recibe los repositorios de órdenes y productos (interfaces, para poder
+// probar el servicio con implementaciones en memoria).
+func NewOrderService(orderRepo repositories.OrderRepository, productRepo repositories.ProductRepository) OrderService {
 	return &orderService{
-		orderRepo: orderRepo,
-		db:        db,
+		orderRepo:   orderRepo,
+		productRepo: productRepo,
 	}
 }
 
@@ -104,15 +141,70 @@ func NewOrderService(orderRepo repositories.OrderRepository, db *gorm.DB) OrderS
 // CREATE
 // ============================================================================
 
-// CreateOrder crea una nueva orden con todos los datos del DTO.
-// Valida productos, reduce stock, calcula costos y crea snapshots.
-func (s *orderService) CreateOrder(dto CreateOrderDTO) (*models.Order, error) {
-	// Validar que la orden tenga al menos un item
-	if len(dto.Items) == 0 {
-		return nil, fmt.Errorf("no se puede crear una orden sin items")
+// CreateOrder ejecuta el pipeline de creación de órdenes:
+//  1. Validar la solicitud
+//  2. Cotizar con precios y envío del servidor (y comparar con lo que vio el cliente);
+//     el pago contra entrega solo se acepta si el envío es local
+//  3. Verificar stock disponible
+//  4. Crear los snapshots de los items
+//  5. Guardar la orden reservando stock en una sola transacción
+func (s *orderService) CreateOrder(dto CreateOrderDTO) (*CreateOrderResult, error) {
+	// 1. Validar la solicitud
+	if err := normalizeCreateOrderDTO(&dto); err != nil {
+		return nil, err
+	}
+
+	cartItems := make([]models.CartItem, 0, len(dto.Items))
+	for _, item := range dto.Items {
+		cartItems = append(cartItems, models.CartItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	// 2. Cotizar con datos del servidor
+	pin, err := NewGeoPoint(dto.DeliveryLat, dto.DeliveryLng)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := QuoteCart(s.productRepo, cartItems, ShippingDestination{
+		Department:   dto.ShippingDepartment,
+		Municipality: dto.ShippingMunicipality,
+		DeliveryType: dto.DeliveryType,
+		Pin:          pin,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// El pago contra entrega solo aplica a entregas locales (sin courier). Se
+	// valida aquí para que ningún endpoint pueda saltarse la regla.
+	if dto.PaymentMethod == PaymentMethodCashOnDelivery && quote.Shipping.RequiresCourier {
+		return nil, fmt.Errorf("validación: la forma de pago '%s' no está disponible para %s", dto.PaymentMethod, dto.ShippingMunicipality)
+	}
+
+	if dto.ClientPrices != nil {
+		if diffs := quote.Compare(dto.ClientPrices); len(diffs) > 0 {
+			return nil, &PriceMismatchError{Discrepancies: diffs, Quote: quote}
+		}
+	}
+
+	// 3. Verificar stock (el repositorio lo vuelve a verificar de forma atómica al reservar)
+	var shortages []repositories.StockShortage
+	for _, item := range quote.Items {
+		if item.Product.Stock < item.Quantity {
+			shortages = append(shortages, repositories.StockShortage{
+				ProductID: item.Product.ID,
+				Name:      item.Product.Name,
+				Requested: item.Quantity,
+				Available: item.Product.Stock,
+			})
+		}
+	}
+	if len(shortages) > 0 {
+		err := &repositories.InsufficientStockError{Items: shortages}
+		log.Printf("Orden rechazada: %v", err)
+		return nil, err
 	}
 
-	// Crear la orden desde el DTO
+	// 4. Construir la orden con los snapshots de los items
 	order := &models.Order{
 		ID:                   uuid.New(),
 		UserID:               dto.UserID,
@@ -120,106 +212,108 @@ func (s *orderService) CreateOrder(dto CreateOrderDTO) (*models.Order, error) {
 		CustomerEmail:        dto.CustomerEmail,
 		CustomerName:         dto.CustomerName,
 		CustomerPhone:        dto.CustomerPhone,
-		ShippingAddress:      dto.ShippingAddress,
+		ShippingDepartment:   dto.ShippingDepartment,
 		ShippingMunicipality: dto.ShippingMunicipality,
-		OrderItems:           []models.OrderItem{},
+		ShippingAddress:      dto.ShippingAddress,
+		DeliveryType:         dto.DeliveryType,
+		PickupBranch:         dto.PickupBranch,
+		DeliveryNotes:        dto.DeliveryNotes,
+		DeliveryLat:          dto.DeliveryLat,
+		DeliveryLng:          dto.DeliveryLng,
+		Subtotal:             quote.Subtotal,
+		ShippingCost:         quote.ShippingCost,
+		Total:                quote.Total,
+		Currency:             quote.Total.CurrencyCode(),
+		ShippingMethod:       quote.Shipping.Method,
+		RequiresCourier:      quote.Shipping.RequiresCourier,
+		PackageType:          quote.Package.PackageType,
+		PackageWeightKg:      quote.Package.WeightKg,
+		PaymentMethod:        string(dto.PaymentMethod),
+		OrderItems:           quote.OrderItems(),
+	}
+	for i := range order.OrderItems {
+		order.OrderItems[i].ID = uuid.New()
+		order.OrderItems[i].OrderID = order.ID
 	}
 
-	// Usar una transacción para asegurar consistencia
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			log.Printf("Panic en CreateOrder: %v", r)
-		}
-	}()
-
-	// Procesar cada item del pedido
-	subtotal := 0.0
-	for _, itemDTO := range dto.Items {
-		// Obtener el producto
-		var product models.Product
-		if err := tx.Where("id = ?", itemDTO.ProductID).First(&product).Error; err != nil {
-			tx.Rollback()
-			if err == gorm.ErrRecordNotFound {
-				log.Printf("Producto no encontrado: %d", itemDTO.ProductID)
-				return nil, fmt.Errorf("producto no encontrado: ID %d", itemDTO.ProductID)
-			}
-			log.Printf("Error al obtener producto: %v", err)
-			return nil, fmt.Errorf("error al obtener producto: %w", err)
-		}
+	// 5. Guardar la orden y reservar stock
+	if err := s.orderRepo.CreateWithStockReservation(order); err != nil {
+		log.Printf("Error al crear orden: %v", err)
+		return nil, err
+	}
 
-		// Validar stock disponible
-		if product.Stock < itemDTO.Quantity {
-			tx.Rollback()
-			log.Printf("Stock insuficiente para producto %d. Disponible: %d, Solicitado: %d",
-				itemDTO.ProductID, product.Stock, itemDTO.Quantity)
-			return nil, fmt.Errorf("stock insuficiente para %s. Disponible: %d", product.Name, product.Stock)
-		}
+	log.Printf("Orden %s creada: %d items, total %s, pago %s", order.ID, len(order.OrderItems), order.Total, order.PaymentMethod)
 
-		// Crear snapshot del item con datos actuales del producto
-		orderItem := models.OrderItem{
-			ID:          uuid.New(),
-			OrderID:     order.ID,
-			ProductID:   product.ID,
-			ProductName: product.Name,
-			Quantity:    itemDTO.Quantity,
-			Price:       product.Price,
-		}
+	return &CreateOrderResult{Order: order, Quote: quote}, nil
+}
 
-		// Validar el item
-		if err := orderItem.Validate(); err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("validación de item fallida: %w", err)
+// normalizeCreateOrderDTO valida la solicitud, aplica valores por defecto y
+// agrupa los items repetidos del mismo producto.
+func normalizeCreateOrderDTO(dto *CreateOrderDTO) error {
+	if len(dto.Items) == 0 {
+		return fmt.Errorf("no se puede crear una orden sin items")
+	}
+	if dto.ShippingMunicipality == "" {
+		return fmt.Errorf("validación: shipping_municipality es requerido")
+	}
+	department, municipality, err := ResolveLocation(dto.ShippingDepartment, dto.ShippingMunicipality)
+	if err != nil {
+		return err
+	}
+	dto.ShippingDepartment, dto.ShippingMunicipality = department.Code, municipality.Name
+
+	// Agrupar items del mismo producto para validar el stock por el total de unidades
+	merged := make([]OrderItemDTO, 0, len(dto.Items))
+	positions := map[uint]int{}
+	for _, item := range dto.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("validación de item fallida: quantity debe ser mayor a 0")
 		}
-
-		// Agregar item a la orden
-		order.OrderItems = append(order.OrderItems, orderItem)
-		subtotal += orderItem.GetSubtotal()
-
-		// Reducir stock del producto
-		if err := tx.Model(&models.Product{}).
-			Where("id = ?", itemDTO.ProductID).
-			Update("stock", gorm.Expr("stock - ?", itemDTO.Quantity)).Error; err != nil {
-			tx.Rollback()
-			log.Printf("Error al reducir stock: %v", err)
-			return nil, fmt.Errorf("error al actualizar stock: %w", err)
+		if i, ok := positions[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
 		}
+		positions[item.ProductID] = len(merged)
+		merged = append(merged, item)
 	}
-
-	// Calcular costo de envío
-	shippingCost := CalculateShippingCost(dto.ShippingMunicipality)
-
-	// Actualizar totales en la orden
-	order.Subtotal = subtotal
-	order.ShippingCost = shippingCost
-	order.CalculateTotal()
-
-	// Crear la orden en la base de datos
-	if err := tx.Create(order).Error; err != nil {
-		tx.Rollback()
-		log.Printf("Error al crear orden: %v", err)
-		return nil, fmt.Errorf("error al crear orden: %w", err)
+	dto.Items = merged
+
+	switch dto.DeliveryType {
+	case "":
+		dto.DeliveryType = "home_delivery"
+	case "home_delivery", "pickup_at_branch":
+	default:
+		return fmt.Errorf("validación: delivery_type inválido: %s", dto.DeliveryType)
 	}
 
-	// Crear los items de la orden
-	for _, item := range order.OrderItems {
-		item.OrderID = order.ID
-		if err := tx.Create(&item).Error; err != nil {
-			tx.Rollback()
-			log.Printf("Error al crear item de orden: %v", err)
-			return nil, fmt.Errorf("error al crear item de orden: %w", err)
+	if dto.DeliveryType == "pickup_at_branch" {
+		if dto.PickupBranch == "" {
+			return fmt.Errorf("validación: pickup_branch es requerido para entrega en sucursal")
+		}
+		if dto.ShippingAddress == "" {
+			dto.ShippingAddress = "Recoger en sucursal: " + dto.PickupBranch
 		}
+	} else if dto.ShippingAddress == "" {
+		return fmt.Errorf("validación: shipping_address es requerido para entrega a domicilio")
 	}
 
-	// Confirmar la transacción
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("Error al confirmar transacción CreateOrder: %v", err)
-		return nil, fmt.Errorf("error al confirmar transacción: %w", err)
+	switch dto.PaymentMethod {
+	case "":
+		dto.PaymentMethod = PaymentMethodCard
+	case PaymentMethodCard, PaymentMethodCashOnDelivery:
+	default:
+		return fmt.Errorf("validación: payment_method inválido: %s", dto.PaymentMethod)
 	}
 
-	// Obtener la orden completa del repositorio para retornarla
-	return s.orderRepo.GetByID(order.ID)
+	return nil
+}
+
+// ShippingMethodFor determina el método de envío según si requiere courier.
+func ShippingMethodFor(requiresCourier bool) string {
+	if requiresCourier {
+		return models.ShippingMethodCargoExpreso
+	}
+	return models.ShippingMethodLocal
 }
 
 // ============================================================================
@@ -251,22 +345,16 @@ func (s *orderService) GetUserOrders(userID uuid.UUID) ([]models.Order, error) {
 // ============================================================================
 
 // UpdateOrderStatus actualiza el estado de una orden.
-func (s *orderService) UpdateOrderStatus(orderID uuid.UUID, status models.OrderStatus) error {
-	// Validar que el estado sea válido
-	validStatuses := map[models.OrderStatus]bool{
-		models.StatusPending:   true,
-		models.StatusPaid:      true,
-		models.StatusShipped:   true,
-		models.StatusCancelled: true,
-	}
-
-	if !validStatuses[status] {
-		log.Printf("Estado de orden inválido: %s", status)
-		return fmt.Errorf("estado de orden inválido: %s", status)
+func (s *orderService) UpdateOrderStatus(orderID uuid.UUID, status models.OrderStatus, change models.StatusChange) error {
+	// Validar que el estado exista y se pueda asignar a mano (la transición la
+	// valida el repositorio)
+	if err := models.ValidateManualStatus(status); err != nil {
+		log.Printf("Cambio de estado rechazado para orden %s: %v", orderID, err)
+		return err
 	}
 
 	// Actualizar el estado
-	if err := s.orderRepo.UpdateStatus(orderID, status); err != nil {
+	if err := s.orderRepo.UpdateStatus(orderID, status, change); err != nil {
 		log.Printf("Error al actualizar estado de orden %s: %v", orderID, err)
 		return err
 	}
@@ -280,13 +368,15 @@ func (s *orderService) UpdateOrderStatus(orderID uuid.UUID, status models.OrderS
 // ============================================================================
 
 // CalculateShippingCost calcula el costo de envío para un municipio.
-// Utiliza la función del servicio de cálculo de envío.
-func (s *orderService) CalculateShippingCost(municipality string) (float64, error) {
+// Utiliza la tabla de tarifas vigente (entrega a domicilio).
+func (s *orderService) CalculateShippingCost(municipality string) (models.Money, error) {
 	if municipality == "" {
-		return 0, fmt.Errorf("municipio no puede estar vacío")
+		return models.Money{}, fmt.Errorf("municipio no puede estar vacío")
 	}
 
-	// Llamar a la función pública de cálculo de envío
-	cost := CalculateShippingCost(municipality)
-	return cost, nil
+	shipping, err := QuoteShipping(ShippingDestination{Municipality: municipality})
+	if err != nil {
+		return models.Money{}, err
+	}
+	return shipping.Cost, nil
 }
@@ -0,0 +1,126 @@
+// backend/services/order_service_test.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+)
+
+// memoryProductRepository es un ProductRepository en memoria para pruebas.
+type memoryProductRepository map[uint]models.Product
+
+func (r memoryProductRepository) GetByID(id uint) (*models.Product, error) {
+	product, ok := r[id]
+	if !ok {
+		return nil, fmt.Errorf("producto no encontrado: ID %d", id)
+	}
+	return &product, nil
+}
+
+// memoryOrderRepository es un OrderRepository en memoria para pruebas.
+type memoryOrderRepository struct {
+	created []*models.Order
+}
+
+func (r *memoryOrderRepository) Create(order *models.Order) error {
+	r.created = append(r.created, order)
+	return nil
+}
+
+func (r *memoryOrderRepository) CreateWithStockReservation(order *models.Order) error {
+	order.StockReserved = true
+	return r.Create(order)
+}
+
+func (r *memoryOrderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
+	for _, order := range r.created {
+		if order.ID == id {
+			return order, nil
+		}
+	}
+	return nil, fmt.Errorf("orden no encontrada: %s", id)
+}
+
+func (r *memoryOrderRepository) GetByUserID(userID uuid.UUID) ([]models.Order, error) {
+	return nil, nil
+}
+
+func (r *memoryOrderRepository) GetAll(page, pageSize int) ([]models.Order, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *memoryOrderRepository) Update(order *models.Order) error {
+	return nil
+}
+
+func (r *memoryOrderRepository) UpdateStatus(orderID uuid.UUID, status models.OrderStatus, change models.StatusChange) error {
+	return nil
+}
+
+func (r *memoryOrderRepository) Delete(id uuid.UUID) error {
+	return nil
+}
+
+func newTestOrderDTO(department, municipality string, method PaymentMethod) CreateOrderDTO {
+	return CreateOrderDTO{
+		CustomerEmail:        "cliente@example.com",
+		CustomerName:         "Cliente de Prueba",
+		CustomerPhone:        "55555555",
+		ShippingDepartment:   department,
+		ShippingMunicipality: municipality,
+		ShippingAddress:      "4a calle 5-10 zona 1",
+		PaymentMethod:        method,
+		Items:                []OrderItemDTO{{ProductID: 1, Quantity: 1}},
+	}
+}
+
+func TestCreateOrderCashOnDeliveryRequiresLocalDelivery(t *testing.T) {
+	products := memoryProductRepository{
+		1: {ID: 1, Name: "Mochila", Price: models.NewMoney(25000), Stock: 10},
+	}
+
+	tests := []struct {
+		name         string
+		department   string
+		municipality string
+		method       PaymentMethod
+		wantErr      bool
+	}{
+		{"contra entrega local", "GT-13", "Huehuetenango", PaymentMethodCashOnDelivery, false},
+		{"contra entrega fuera de la zona local", "GT-01", "Guatemala", PaymentMethodCashOnDelivery, true},
+		{"contra entrega en otro municipio de Huehuetenango", "GT-13", "Malacatancito", PaymentMethodCashOnDelivery, true},
+		{"tarjeta fuera de la zona local", "GT-01", "Guatemala", PaymentMethodCard, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := &memoryOrderRepository{}
+			service := NewOrderService(orders, products)
+
+			result, err := service.CreateOrder(newTestOrderDTO(tt.department, tt.municipality, tt.method))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba rechazo, se creó la orden %s", result.Order.ID)
+				}
+				if !strings.Contains(err.Error(), "validación") {
+					t.Errorf("el error debe ser de validación (400), se obtuvo: %v", err)
+				}
+				if len(orders.created) != 0 {
+					t.Errorf("no debe guardarse ninguna orden, se guardaron %d", len(orders.created))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if result.Order.PaymentMethod != string(tt.method) {
+				t.Errorf("payment_method = %s, se esperaba %s", result.Order.PaymentMethod, tt.method)
+			}
+		})
+	}
+}
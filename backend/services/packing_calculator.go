@@ -0,0 +1,206 @@
+// backend/services/packing_calculator.go
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// Tipos de paquete
+// ============================================================================
+
+// Tipos de paquete que acepta Cargo Expreso (ver CargoExpresoGuideRequest).
+const (
+	PackageEnvelope  = "sobre"
+	PackageSmallBox  = "caja_pequeña"
+	PackageMediumBox = "caja_mediana"
+)
+
+// defaultItemWeightKg es el peso que se asume por unidad cuando el producto
+// no tiene peso registrado (una pieza de ropa o accesorio empacado).
+const defaultItemWeightKg = 0.3
+
+// poundsPerKg convierte kg a libras (Cargo Expreso recibe el peso en libras).
+const poundsPerKg = 2.20462
+
+// PackageSpec son las medidas interiores y el peso máximo de un tipo de paquete.
+type PackageSpec struct {
+	Type        string  `json:"type"`
+	LengthCm    float64 `json:"length_cm"`
+	WidthCm     float64 `json:"width_cm"`
+	HeightCm    float64 `json:"height_cm"`
+	MaxWeightKg float64 `json:"max_weight_kg"`
+}
+
+// packageSpecs va del paquete más pequeño al más grande: se elige el primero
+// en el que cabe el pedido.
+var packageSpecs = []PackageSpec{
+	{Type: PackageEnvelope, LengthCm: 35, WidthCm: 25, HeightCm: 3, MaxWeightKg: 0.5},
+	{Type: PackageSmallBox, LengthCm: 30, WidthCm: 20, HeightCm: 15, MaxWeightKg: 5},
+	{Type: PackageMediumBox, LengthCm: 50, WidthCm: 40, HeightCm: 30, MaxWeightKg: 20},
+}
+
+// IsValidPackageType indica si el tipo de paquete es uno de los de Cargo Expreso.
+func IsValidPackageType(packageType string) bool {
+	for _, spec := range packageSpecs {
+		if spec.Type == packageType {
+			return true
+		}
+	}
+	return false
+}
+
+// dimensions retorna las medidas ordenadas de mayor a menor, para comparar
+// sin importar la orientación.
+func dimensions(length, width, height float64) [3]float64 {
+	dims := []float64{length, width, height}
+	sort.Sort(sort.Reverse(sort.Float64Slice(dims)))
+	return [3]float64{dims[0], dims[1], dims[2]}
+}
+
+func (s PackageSpec) volume() float64 {
+	return s.LengthCm * s.WidthCm * s.HeightCm
+}
+
+// fitsItem indica si una unidad cabe en el paquete en alguna orientación.
+func (s PackageSpec) fitsItem(dims [3]float64) bool {
+	box := dimensions(s.LengthCm, s.WidthCm, s.HeightCm)
+	return dims[0] <= box[0] && dims[1] <= box[1] && dims[2] <= box[2]
+}
+
+// ============================================================================
+// Calculador de empaque
+// ============================================================================
+
+// PackingItem es un producto del pedido con su cantidad.
+type PackingItem struct {
+	Product  models.Product
+	Quantity int
+}
+
+// PackingResult es el paquete calculado para un pedido.
+type PackingResult struct {
+	// PackageType: 'sobre' | 'caja_pequeña' | 'caja_mediana'.
+	PackageType string `json:"package_type"`
+
+	// WeightKg: Peso total (suma de los productos, con el peso por defecto
+	// para los que no tienen peso registrado).
+	WeightKg float64 `json:"weight_kg"`
+
+	// VolumeCm3: Volumen total de los productos con dimensiones registradas.
+	VolumeCm3 float64 `json:"volume_cm3"`
+
+	// EstimatedItems: Unidades sin peso registrado (se usó el peso por defecto).
+	EstimatedItems int `json:"estimated_items,omitempty"`
+
+	// Oversize: El pedido no cabe en ningún paquete estándar; se usa el más
+	// grande y conviene revisarlo antes de generar la guía.
+	Oversize bool `json:"oversize,omitempty"`
+}
+
+// WeightLb retorna el peso en libras, redondeado a 2 decimales.
+func (r PackingResult) WeightLb() float64 {
+	return KgToLb(r.WeightKg)
+}
+
+// KgToLb convierte kg a libras, redondeado a 2 decimales.
+func KgToLb(kg float64) float64 {
+	return math.Round(kg*poundsPerKg*100) / 100
+}
+
+// PackItems suma el peso de los productos y elige el paquete más pequeño en
+// el que caben: cada unidad debe caber en alguna orientación, el volumen total
+// no debe pasar el del paquete y el peso no debe pasar su máximo. Las
+// dimensiones desconocidas (0) no se verifican.
+func PackItems(items []PackingItem) PackingResult {
+	var result PackingResult
+	var largest [3]float64
+
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		weight := item.Product.WeightKg
+		if weight <= 0 {
+			weight = defaultItemWeightKg
+			result.EstimatedItems += item.Quantity
+		}
+		result.WeightKg += weight * float64(item.Quantity)
+
+		dims := dimensions(item.Product.LengthCm, item.Product.WidthCm, item.Product.HeightCm)
+		if dims[2] > 0 {
+			result.VolumeCm3 += dims[0] * dims[1] * dims[2] * float64(item.Quantity)
+		}
+		for i := range largest {
+			largest[i] = math.Max(largest[i], dims[i])
+		}
+	}
+	result.WeightKg = math.Round(result.WeightKg*1000) / 1000
+
+	for _, spec := range packageSpecs {
+		if result.WeightKg <= spec.MaxWeightKg && result.VolumeCm3 <= spec.volume() && spec.fitsItem(largest) {
+			result.PackageType = spec.Type
+			return result
+		}
+	}
+
+	result.PackageType = packageSpecs[len(packageSpecs)-1].Type
+	result.Oversize = true
+	return result
+}
+
+// PackOrderItems calcula el paquete de los items de una orden con el peso y
+// las dimensiones actuales de sus productos. quantities permite empacar solo
+// una parte (ej: los items de una devolución); nil = todos los items.
+func PackOrderItems(db *gorm.DB, items []models.OrderItem, quantities map[uuid.UUID]int) (PackingResult, error) {
+	productIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	var products []models.Product
+	if len(productIDs) > 0 {
+		if err := db.Select("id", "name", "weight_kg", "length_cm", "width_cm", "height_cm").
+			Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+			return PackingResult{}, fmt.Errorf("error al obtener productos para el empaque: %w", err)
+		}
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	packing := make([]PackingItem, 0, len(items))
+	for _, item := range items {
+		quantity := item.Quantity
+		if quantities != nil {
+			quantity = quantities[item.ID]
+		}
+		// Un producto borrado se empaca con el peso por defecto
+		packing = append(packing, PackingItem{Product: byID[item.ProductID], Quantity: quantity})
+	}
+	return PackItems(packing), nil
+}
+
+// OrderPackage retorna el paquete guardado en la orden. Las órdenes anteriores
+// al calculador de empaque no lo tienen: se calcula con sus items.
+func OrderPackage(db *gorm.DB, order *models.Order) (PackingResult, error) {
+	if order.PackageType != "" {
+		return PackingResult{PackageType: order.PackageType, WeightKg: order.PackageWeightKg}, nil
+	}
+
+	items := order.OrderItems
+	if len(items) == 0 {
+		if err := db.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return PackingResult{}, fmt.Errorf("error al obtener items de la orden: %w", err)
+		}
+	}
+	return PackOrderItems(db, items, nil)
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"moda-organica/backend/models"
+)
+
+// packedProduct arma un producto con peso (kg) y dimensiones (cm) de empaque.
+func packedProduct(weightKg, lengthCm, widthCm, heightCm float64) models.Product {
+	return models.Product{WeightKg: weightKg, LengthCm: lengthCm, WidthCm: widthCm, HeightCm: heightCm}
+}
+
+func TestPackItems(t *testing.T) {
+	tests := []struct {
+		name          string
+		items         []PackingItem
+		wantPackage   string
+		wantWeightKg  float64
+		wantEstimated int
+		wantOversize  bool
+	}{
+		{"pedido vacío", nil, PackageEnvelope, 0, 0, false},
+		{"pulsera sin datos: peso por defecto", []PackingItem{{models.Product{}, 1}}, PackageEnvelope, 0.3, 1, false},
+		{"dos piezas sin peso pasan el máximo del sobre", []PackingItem{{models.Product{}, 2}}, PackageSmallBox, 0.6, 2, false},
+		{"pesos decimales se suman sin error de redondeo", []PackingItem{{packedProduct(0.1, 10, 10, 1), 3}}, PackageEnvelope, 0.3, 0, false},
+		{"pieza gruesa no cabe en el sobre", []PackingItem{{packedProduct(0.2, 20, 15, 5), 1}}, PackageSmallBox, 0.2, 0, false},
+		{"pieza larga cabe rotada en la caja mediana", []PackingItem{{packedProduct(0.5, 5, 40, 10), 1}}, PackageMediumBox, 0.5, 0, false},
+		{"el volumen total pasa la caja pequeña", []PackingItem{{packedProduct(0.1, 10, 10, 10), 10}}, PackageMediumBox, 1, 0, false},
+		{"mezcla de productos con y sin peso", []PackingItem{{packedProduct(1.5, 20, 15, 10), 1}, {models.Product{}, 2}}, PackageSmallBox, 2.1, 2, false},
+		{"cantidades en cero o negativas se ignoran", []PackingItem{{packedProduct(30, 60, 60, 60), 0}, {packedProduct(30, 60, 60, 60), -1}}, PackageEnvelope, 0, 0, false},
+		{"muy pesado: caja mediana marcada", []PackingItem{{packedProduct(12.5, 30, 20, 10), 2}}, PackageMediumBox, 25, 0, true},
+		{"no cabe en ningún paquete", []PackingItem{{packedProduct(2, 80, 10, 10), 1}}, PackageMediumBox, 2, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PackItems(tt.items)
+			if got.PackageType != tt.wantPackage {
+				t.Errorf("PackageType = %s, se esperaba %s", got.PackageType, tt.wantPackage)
+			}
+			if got.WeightKg != tt.wantWeightKg {
+				t.Errorf("WeightKg = %v, se esperaba %v", got.WeightKg, tt.wantWeightKg)
+			}
+			if got.EstimatedItems != tt.wantEstimated {
+				t.Errorf("EstimatedItems = %d, se esperaba %d", got.EstimatedItems, tt.wantEstimated)
+			}
+			if got.Oversize != tt.wantOversize {
+				t.Errorf("Oversize = %v, se esperaba %v", got.Oversize, tt.wantOversize)
+			}
+		})
+	}
+}
+
+func TestKgToLb(t *testing.T) {
+	tests := []struct {
+		kg   float64
+		want float64
+	}{
+		{0, 0},
+		{0.3, 0.66},
+		{1, 2.2},
+		{2.5, 5.51},
+	}
+	for _, tt := range tests {
+		if got := KgToLb(tt.kg); got != tt.want {
+			t.Errorf("KgToLb(%v) = %v, se esperaba %v", tt.kg, got, tt.want)
+		}
+	}
+}
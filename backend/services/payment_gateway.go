@@ -0,0 +1,488 @@
+// backend/services/payment_gateway.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/checkout/session"
+	"github.com/stripe/stripe-go/v78/refund"
+)
+
+// ============================================
+// STRUCTURES
+// ============================================
+
+// PaymentMethod identifica la forma de pago elegida por el cliente
+type PaymentMethod string
+
+const (
+	PaymentMethodCard           PaymentMethod = "card"             // Tarjeta vía pasarela en línea (Stripe)
+	PaymentMethodCashOnDelivery PaymentMethod = "cash_on_delivery" // Efectivo contra entrega (solo entregas locales)
+)
+
+// CheckoutRequest representa los datos necesarios para iniciar el cobro de una orden
+type CheckoutRequest struct {
+	Order      *models.Order // Orden ya creada (pendiente)
+	Quote      *CartQuote    // Cotización calculada en el servidor
+	SuccessURL string        // URL a la que vuelve el cliente tras pagar
+	CancelURL  string        // URL a la que vuelve el cliente si cancela
+	ExpiresAt  time.Time     // Vencimiento de la sesión (cero = el de la pasarela)
+}
+
+// CheckoutResponse representa el resultado de iniciar el cobro
+type CheckoutResponse struct {
+	Provider    string // "stripe" | "cash_on_delivery" | "fake"
+	SessionID   string // Identificador de la sesión en la pasarela (vacío si no aplica)
+	RedirectURL string // URL a la que se redirige al cliente
+}
+
+// GatewayRefundRequest representa los datos necesarios para reembolsar un pago
+type GatewayRefundRequest struct {
+	PaymentIntentID string       // Pago original en la pasarela
+	Amount          models.Money // Monto a reembolsar
+	Reason          string       // Motivo (se guarda como metadata)
+	OrderID         string       // UUID de la orden como string
+	OrderNumber     string       // Número legible de la orden (MO-2026-000123)
+	IdempotencyKey  string       // Evita reembolsos duplicados ante reintentos
+}
+
+// GatewayRefundResponse representa la respuesta de la pasarela
+type GatewayRefundResponse struct {
+	Provider string // "stripe" | "cash_on_delivery" | "fake"
+	RefundID string // Identificador del reembolso en la pasarela
+	Status   string // "succeeded", "pending", "manual", ...
+}
+
+// ErrRefundRejected indica que la pasarela rechazó el reembolso: no se emitió
+// y repetir la misma solicitud no cambia el resultado. Cualquier otro error de
+// Refund es ambiguo (el reembolso pudo emitirse) y se reintenta con la misma
+// idempotency key.
+var ErrRefundRejected = errors.New("la pasarela rechazó el reembolso")
+
+// ============================================
+// INTERFACES
+// ============================================
+
+// RefundGateway define el contrato para emitir reembolsos
+type RefundGateway interface {
+	Refund(request GatewayRefundRequest) (*GatewayRefundResponse, error)
+}
+
+// PaymentGateway define el contrato de una forma de pago
+type PaymentGateway interface {
+	RefundGateway
+
+	// Method retorna la forma de pago que implementa
+	Method() PaymentMethod
+
+	// CreateCheckout inicia el cobro de la orden
+	CreateCheckout(request CheckoutRequest) (*CheckoutResponse, error)
+
+	// CancelCheckout invalida una sesión de cobro abierta (si aplica)
+	CancelCheckout(sessionID string) error
+}
+
+// ============================================
+// STRIPE IMPLEMENTATION
+// ============================================
+
+type stripePaymentGateway struct {
+	secretKey string
+}
+
+// NewStripePaymentGateway crea una instancia que cobra con Stripe Checkout
+func NewStripePaymentGateway(secretKey string) PaymentGateway {
+	return &stripePaymentGateway{secretKey: secretKey}
+}
+
+// Method retorna PaymentMethodCard
+func (g *stripePaymentGateway) Method() PaymentMethod {
+	return PaymentMethodCard
+}
+
+// CreateCheckout crea una Stripe Checkout Session con los montos de la cotización
+func (g *stripePaymentGateway) CreateCheckout(request CheckoutRequest) (*CheckoutResponse, error) {
+	stripe.Key = g.secretKey
+	order := request.Order
+
+	// Line items a partir de la cotización del servidor
+	var lineItems []*stripe.CheckoutSessionLineItemParams
+	for _, item := range request.Quote.Items {
+		productData := &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+			Name:        stripe.String(item.Product.Name),
+			Description: stripe.String(fmt.Sprintf("Producto ID: %d", item.Product.ID)),
+		}
+		if item.Product.ImageURL != "" {
+			productData.Images = []*string{stripe.String(item.Product.ImageURL)}
+		}
+
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency:    stripe.String(stripeCurrency(item.UnitPrice)), // Quetzales guatemaltecos
+				ProductData: productData,
+				UnitAmount:  stripe.Int64(item.UnitPrice.Cents()),
+			},
+			Quantity: stripe.Int64(int64(item.Quantity)),
+		})
+	}
+
+	// Agregar shipping como line item si es mayor a 0
+	if request.Quote.ShippingCost.IsPositive() {
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String(stripeCurrency(request.Quote.ShippingCost)),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name:        stripe.String("Envío a " + order.ShippingMunicipality),
+					Description: stripe.String("Costo de envío"),
+				},
+				UnitAmount: stripe.Int64(request.Quote.ShippingCost.Cents()),
+			},
+			Quantity: stripe.Int64(1),
+		})
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		PaymentMethodTypes: stripe.StringSlice([]string{
+			"card", // Tarjetas de crédito/débito
+		}),
+		LineItems:  lineItems,
+		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL: stripe.String(request.SuccessURL),
+		CancelURL:  stripe.String(request.CancelURL),
+
+		// Metadata para vincular con nuestra orden. order_number es el que se
+		// ve en el dashboard de Stripe; los webhooks buscan la orden por order_id.
+		ClientReferenceID: stripe.String(order.Reference()),
+		Metadata: map[string]string{
+			"order_number":   order.OrderNumber,
+			"order_id":       order.ID.String(),
+			"customer_email": order.CustomerEmail,
+			"customer_name":  order.CustomerName,
+		},
+
+		// El PaymentIntent también lleva el order_id para poder asociar
+		// eventos como payment_intent.payment_failed con la orden
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			Description: stripe.String("Orden " + order.Reference() + " - Moda Orgánica"),
+			Metadata: map[string]string{
+				"order_number": order.OrderNumber,
+				"order_id":     order.ID.String(),
+			},
+		},
+
+		// Información del cliente
+		CustomerEmail: stripe.String(order.CustomerEmail),
+
+		// Permitir códigos promocionales (opcional)
+		AllowPromotionCodes: stripe.Bool(false),
+
+		// Configuración de facturación
+		BillingAddressCollection: stripe.String("auto"),
+	}
+
+	// La sesión vence junto con la orden pendiente (ver OrderReconciler)
+	if !request.ExpiresAt.IsZero() {
+		params.ExpiresAt = stripe.Int64(stripeSessionExpiry(request.ExpiresAt, time.Now()).Unix())
+	}
+
+	sess, err := session.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("error creando sesión de pago con Stripe: %w", err)
+	}
+
+	return &CheckoutResponse{
+		Provider:    "stripe",
+		SessionID:   sess.ID,
+		RedirectURL: sess.URL,
+	}, nil
+}
+
+// CancelCheckout expira la Checkout Session para que no pueda pagarse
+func (g *stripePaymentGateway) CancelCheckout(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	stripe.Key = g.secretKey
+	if _, err := session.Expire(sessionID, nil); err != nil {
+		// Stripe no deja expirar una sesión que ya venció, pero tampoco se puede pagar
+		if sess, getErr := session.Get(sessionID, nil); getErr == nil && sess.Status == stripe.CheckoutSessionStatusExpired {
+			return nil
+		}
+		return fmt.Errorf("error expirando sesión de Stripe %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// stripeSessionExpiry ajusta el vencimiento pedido al rango que acepta Stripe
+// (entre 30 minutos y 24 horas desde la creación). Si el TTL de la orden es
+// más corto, el reconciliador expira la sesión antes de cancelarla; si es
+// más largo, la sesión vence primero y la orden se cancela después.
+func stripeSessionExpiry(expiresAt, now time.Time) time.Time {
+	// Margen para que la solicitud no quede fuera de rango al llegar a Stripe
+	earliest := now.Add(31 * time.Minute)
+	latest := now.Add(24*time.Hour - time.Minute)
+
+	if expiresAt.Before(earliest) {
+		return earliest
+	}
+	if expiresAt.After(latest) {
+		return latest
+	}
+	return expiresAt
+}
+
+// Refund crea un reembolso sobre el PaymentIntent original
+func (g *stripePaymentGateway) Refund(request GatewayRefundRequest) (*GatewayRefundResponse, error) {
+	if request.PaymentIntentID == "" {
+		return nil, fmt.Errorf("%w: la orden no tiene un pago de Stripe asociado", ErrRefundRejected)
+	}
+
+	stripe.Key = g.secretKey
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(request.PaymentIntentID),
+		Amount:        stripe.Int64(request.Amount.Cents()),
+	}
+	params.AddMetadata("order_number", request.OrderNumber)
+	params.AddMetadata("order_id", request.OrderID)
+	params.AddMetadata("reason", request.Reason)
+	if request.IdempotencyKey != "" {
+		params.SetIdempotencyKey(request.IdempotencyKey)
+	}
+
+	r, err := refund.New(params)
+	if err != nil {
+		return nil, stripeRefundError(err)
+	}
+
+	return &GatewayRefundResponse{
+		Provider: "stripe",
+		RefundID: r.ID,
+		Status:   string(r.Status),
+	}, nil
+}
+
+// stripeRefundError clasifica un error de refund.New. Stripe responde 4xx
+// cuando no crea el reembolso (saldo insuficiente, pago ya reembolsado, ...);
+// 409 (la misma idempotency key sigue en proceso), 429, 5xx y los errores de
+// red no garantizan que no se haya emitido.
+func stripeRefundError(err error) error {
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) {
+		status := stripeErr.HTTPStatusCode
+		if status >= 400 && status < 500 && status != 409 && status != 429 {
+			return fmt.Errorf("%w: %v", ErrRefundRejected, err)
+		}
+	}
+	return fmt.Errorf("error creando reembolso en Stripe: %w", err)
+}
+
+// stripeCurrency retorna el código de moneda en el formato de Stripe ("gtq")
+func stripeCurrency(amount models.Money) string {
+	return strings.ToLower(amount.CurrencyCode())
+}
+
+// ============================================
+// CASH ON DELIVERY IMPLEMENTATION
+// ============================================
+
+type cashOnDeliveryGateway struct{}
+
+// NewCashOnDeliveryGateway crea la forma de pago contra entrega
+func NewCashOnDeliveryGateway() PaymentGateway {
+	return &cashOnDeliveryGateway{}
+}
+
+// Method retorna PaymentMethodCashOnDelivery
+func (g *cashOnDeliveryGateway) Method() PaymentMethod {
+	return PaymentMethodCashOnDelivery
+}
+
+// CreateCheckout no cobra en línea: el cliente paga al recibir el pedido.
+// Se redirige directamente a la página de confirmación.
+func (g *cashOnDeliveryGateway) CreateCheckout(request CheckoutRequest) (*CheckoutResponse, error) {
+	return &CheckoutResponse{
+		Provider:    "cash_on_delivery",
+		RedirectURL: request.SuccessURL,
+	}, nil
+}
+
+// CancelCheckout no tiene sesión que invalidar
+func (g *cashOnDeliveryGateway) CancelCheckout(sessionID string) error {
+	return nil
+}
+
+// Refund registra un reembolso manual (el efectivo se devuelve en persona)
+func (g *cashOnDeliveryGateway) Refund(request GatewayRefundRequest) (*GatewayRefundResponse, error) {
+	return &GatewayRefundResponse{
+		Provider: "cash_on_delivery",
+		RefundID: "cod-" + uuid.New().String(),
+		Status:   "manual",
+	}, nil
+}
+
+// ============================================
+// FAKE IMPLEMENTATION
+// ============================================
+
+// FakePaymentGateway simula cobros con tarjeta de forma determinista
+// (desarrollo y pruebas). Guarda cada solicitud para poder inspeccionarla.
+// Como Stripe, un reembolso repetido con la misma idempotency key retorna
+// el reembolso original sin emitir otro.
+type FakePaymentGateway struct {
+	mu           sync.Mutex
+	Checkouts    []CheckoutRequest
+	Refunds      []GatewayRefundRequest
+	CancelledIDs []string
+	refundIDs    map[string]string
+	// Err: si no es nil, todas las operaciones fallan con este error.
+	Err error
+}
+
+// NewFakePaymentGateway crea una pasarela simulada
+func NewFakePaymentGateway() *FakePaymentGateway {
+	return &FakePaymentGateway{}
+}
+
+// Method retorna PaymentMethodCard
+func (g *FakePaymentGateway) Method() PaymentMethod {
+	return PaymentMethodCard
+}
+
+// CreateCheckout retorna una sesión secuencial (cs_fake_000001, ...)
+func (g *FakePaymentGateway) CreateCheckout(request CheckoutRequest) (*CheckoutResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Err != nil {
+		return nil, g.Err
+	}
+
+	g.Checkouts = append(g.Checkouts, request)
+	return &CheckoutResponse{
+		Provider:    "fake",
+		SessionID:   fmt.Sprintf("cs_fake_%06d", len(g.Checkouts)),
+		RedirectURL: request.SuccessURL,
+	}, nil
+}
+
+// CancelCheckout registra la sesión cancelada
+func (g *FakePaymentGateway) CancelCheckout(sessionID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Err != nil {
+		return g.Err
+	}
+
+	g.CancelledIDs = append(g.CancelledIDs, sessionID)
+	return nil
+}
+
+// Refund registra la solicitud y retorna un ID secuencial (re_fake_000001, ...)
+func (g *FakePaymentGateway) Refund(request GatewayRefundRequest) (*GatewayRefundResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Err != nil {
+		return nil, g.Err
+	}
+
+	if id, ok := g.refundIDs[request.IdempotencyKey]; ok && request.IdempotencyKey != "" {
+		return &GatewayRefundResponse{Provider: "fake", RefundID: id, Status: "succeeded"}, nil
+	}
+
+	g.Refunds = append(g.Refunds, request)
+	id := fmt.Sprintf("re_fake_%06d", len(g.Refunds))
+	if request.IdempotencyKey != "" {
+		if g.refundIDs == nil {
+			g.refundIDs = map[string]string{}
+		}
+		g.refundIDs[request.IdempotencyKey] = id
+	}
+	return &GatewayRefundResponse{
+		Provider: "fake",
+		RefundID: id,
+		Status:   "succeeded",
+	}, nil
+}
+
+// ============================================
+// REGISTRY
+// ============================================
+
+// PaymentGateways agrupa las formas de pago disponibles
+type PaymentGateways struct {
+	gateways map[PaymentMethod]PaymentGateway
+}
+
+// NewPaymentGateways registra las pasarelas recibidas (una por forma de pago)
+func NewPaymentGateways(gateways ...PaymentGateway) *PaymentGateways {
+	registry := &PaymentGateways{gateways: make(map[PaymentMethod]PaymentGateway, len(gateways))}
+	for _, gateway := range gateways {
+		registry.gateways[gateway.Method()] = gateway
+	}
+	return registry
+}
+
+// Get retorna la pasarela de una forma de pago
+func (p *PaymentGateways) Get(method PaymentMethod) (PaymentGateway, error) {
+	gateway, ok := p.gateways[method]
+	if !ok {
+		return nil, fmt.Errorf("forma de pago no disponible: %s", method)
+	}
+	return gateway, nil
+}
+
+// AvailableFor retorna las formas de pago válidas para un destino.
+// El pago contra entrega solo aplica a entregas locales (Huehuetenango, Chiantla,
+// o el pin dentro de una zona de entrega local).
+func (p *PaymentGateways) AvailableFor(dest ShippingDestination) []PaymentMethod {
+	methods := []PaymentMethod{}
+	if _, ok := p.gateways[PaymentMethodCard]; ok {
+		methods = append(methods, PaymentMethodCard)
+	}
+	if _, ok := p.gateways[PaymentMethodCashOnDelivery]; ok && IsLocalDelivery(dest) {
+		methods = append(methods, PaymentMethodCashOnDelivery)
+	}
+	return methods
+}
+
+// IsAvailableFor indica si una forma de pago aplica para el destino
+func (p *PaymentGateways) IsAvailableFor(method PaymentMethod, dest ShippingDestination) bool {
+	for _, available := range p.AvailableFor(dest) {
+		if available == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================
+// FACTORY
+// ============================================
+
+// NewPaymentGatewaysFromEnv retorna las pasarelas según configuración
+func NewPaymentGatewaysFromEnv() *PaymentGateways {
+	// STRIPE_MOCK=true usa la pasarela simulada (desarrollo local)
+	var card PaymentGateway
+	if os.Getenv("STRIPE_MOCK") == "true" {
+		log.Println("Pagos con tarjeta: Modo MOCK activado")
+		card = NewFakePaymentGateway()
+	} else {
+		card = NewStripePaymentGateway(os.Getenv("STRIPE_SECRET_KEY"))
+	}
+
+	return NewPaymentGateways(card, NewCashOnDeliveryGateway())
+}
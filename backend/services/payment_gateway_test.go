@@ -0,0 +1,307 @@
+// backend/services/payment_gateway_test.go
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v78"
+)
+
+func TestPaymentGatewaysAvailableFor(t *testing.T) {
+	withCOD := NewPaymentGateways(NewFakePaymentGateway(), NewCashOnDeliveryGateway())
+	cardOnly := NewPaymentGateways(NewFakePaymentGateway())
+
+	tests := []struct {
+		name     string
+		gateways *PaymentGateways
+		dest     ShippingDestination
+		want     []PaymentMethod
+	}{
+		{"Huehuetenango es entrega local", withCOD,
+			ShippingDestination{Department: "GT-13", Municipality: "Huehuetenango"},
+			[]PaymentMethod{PaymentMethodCard, PaymentMethodCashOnDelivery}},
+		{"Chiantla es entrega local", withCOD,
+			ShippingDestination{Department: "GT-13", Municipality: "Chiantla"},
+			[]PaymentMethod{PaymentMethodCard, PaymentMethodCashOnDelivery}},
+		{"Guatemala va por Cargo Expreso", withCOD,
+			ShippingDestination{Department: "GT-01", Municipality: "Guatemala"},
+			[]PaymentMethod{PaymentMethodCard}},
+		{"otro municipio de Huehuetenango va por Cargo Expreso", withCOD,
+			ShippingDestination{Department: "GT-13", Municipality: "Malacatancito"},
+			[]PaymentMethod{PaymentMethodCard}},
+		{"sin pasarela contra entrega registrada", cardOnly,
+			ShippingDestination{Department: "GT-13", Municipality: "Huehuetenango"},
+			[]PaymentMethod{PaymentMethodCard}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.gateways.AvailableFor(tt.dest)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("AvailableFor = %v, se esperaba %v", got, tt.want)
+			}
+
+			wantCOD := len(tt.want) == 2
+			if got := tt.gateways.IsAvailableFor(PaymentMethodCashOnDelivery, tt.dest); got != wantCOD {
+				t.Errorf("IsAvailableFor(cash_on_delivery) = %t, se esperaba %t", got, wantCOD)
+			}
+			if !tt.gateways.IsAvailableFor(PaymentMethodCard, tt.dest) {
+				t.Errorf("IsAvailableFor(card) = false, se esperaba true")
+			}
+		})
+	}
+}
+
+func newTestCheckoutRequest() CheckoutRequest {
+	return CheckoutRequest{
+		Order: &models.Order{
+			ID:                   uuid.New(),
+			OrderNumber:          "MO-2026-000001",
+			CustomerEmail:        "cliente@example.com",
+			CustomerName:         "Cliente de Prueba",
+			ShippingMunicipality: "Guatemala",
+		},
+		Quote: &CartQuote{
+			Items: []QuotedItem{{
+				Product:   models.Product{ID: 1, Name: "Mochila"},
+				Quantity:  2,
+				UnitPrice: models.NewMoney(25000),
+				LineTotal: models.NewMoney(50000),
+			}},
+			Subtotal:     models.NewMoney(50000),
+			ShippingCost: models.NewMoney(3500),
+			Total:        models.NewMoney(53500),
+		},
+		SuccessURL: "http://localhost:5173/checkout/success",
+		CancelURL:  "http://localhost:5173/checkout/cancel",
+		ExpiresAt:  time.Now().Add(2 * time.Hour),
+	}
+}
+
+// useStripeServer dirige las llamadas de stripe-go al servidor de prueba
+// durante el test.
+func useStripeServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	previous := stripe.GetBackend(stripe.APIBackend)
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(server.URL),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	}))
+	t.Cleanup(func() {
+		stripe.SetBackend(stripe.APIBackend, previous)
+		server.Close()
+	})
+}
+
+func TestPaymentGatewayCreateCheckout(t *testing.T) {
+	tests := []struct {
+		name         string
+		gateway      func(t *testing.T) PaymentGateway
+		wantErr      string
+		wantProvider string
+		wantSession  string
+		wantRedirect string
+	}{
+		{
+			name: "stripe crea la sesión con la cotización del servidor",
+			gateway: func(t *testing.T) PaymentGateway {
+				useStripeServer(t, func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v1/checkout/sessions" {
+						t.Errorf("ruta = %s, se esperaba /v1/checkout/sessions", r.URL.Path)
+					}
+					if err := r.ParseForm(); err != nil {
+						// El handler corre en otra goroutine: t.Fatalf no detiene la prueba aquí
+						t.Errorf("error leyendo el formulario: %v", err)
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					want := map[string]string{
+						"line_items[0][price_data][unit_amount]": "25000",
+						"line_items[0][price_data][currency]":    "gtq",
+						"line_items[0][quantity]":                "2",
+						"line_items[1][price_data][unit_amount]": "3500",
+						"metadata[order_number]":                 "MO-2026-000001",
+						"customer_email":                         "cliente@example.com",
+						"success_url":                            "http://localhost:5173/checkout/success",
+					}
+					for key, value := range want {
+						if got := r.PostForm.Get(key); got != value {
+							t.Errorf("%s = %q, se esperaba %q", key, got, value)
+						}
+					}
+					if r.PostForm.Get("payment_intent_data[metadata][order_id]") == "" {
+						t.Errorf("el PaymentIntent debe llevar el order_id en la metadata")
+					}
+					if r.PostForm.Get("expires_at") == "" {
+						t.Errorf("la sesión debe vencer junto con la orden pendiente (expires_at)")
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"id":"cs_test_123","object":"checkout.session","url":"https://checkout.stripe.com/c/pay/cs_test_123"}`))
+				})
+				return NewStripePaymentGateway("sk_test_123")
+			},
+			wantProvider: "stripe",
+			wantSession:  "cs_test_123",
+			wantRedirect: "https://checkout.stripe.com/c/pay/cs_test_123",
+		},
+		{
+			name: "stripe rechaza la sesión",
+			gateway: func(t *testing.T) PaymentGateway {
+				useStripeServer(t, func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"Invalid currency"}}`))
+				})
+				return NewStripePaymentGateway("sk_test_123")
+			},
+			wantErr: "error creando sesión de pago con Stripe",
+		},
+		{
+			name:         "contra entrega redirige a la confirmación sin sesión",
+			gateway:      func(t *testing.T) PaymentGateway { return NewCashOnDeliveryGateway() },
+			wantProvider: "cash_on_delivery",
+			wantRedirect: "http://localhost:5173/checkout/success",
+		},
+		{
+			name:         "pasarela simulada",
+			gateway:      func(t *testing.T) PaymentGateway { return NewFakePaymentGateway() },
+			wantProvider: "fake",
+			wantSession:  "cs_fake_000001",
+			wantRedirect: "http://localhost:5173/checkout/success",
+		},
+		{
+			name: "pasarela simulada con error",
+			gateway: func(t *testing.T) PaymentGateway {
+				fake := NewFakePaymentGateway()
+				fake.Err = errors.New("pasarela caída")
+				return fake
+			},
+			wantErr: "pasarela caída",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.gateway(t).CreateCheckout(newTestCheckoutRequest())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("se esperaba error %q, se obtuvo %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if response.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, se esperaba %q", response.Provider, tt.wantProvider)
+			}
+			if response.SessionID != tt.wantSession {
+				t.Errorf("SessionID = %q, se esperaba %q", response.SessionID, tt.wantSession)
+			}
+			if response.RedirectURL != tt.wantRedirect {
+				t.Errorf("RedirectURL = %q, se esperaba %q", response.RedirectURL, tt.wantRedirect)
+			}
+		})
+	}
+}
+
+func TestFakePaymentGatewayRecordsCheckouts(t *testing.T) {
+	fake := NewFakePaymentGateway()
+	request := newTestCheckoutRequest()
+
+	for i := 0; i < 2; i++ {
+		if _, err := fake.CreateCheckout(request); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	if len(fake.Checkouts) != 2 || fake.Checkouts[1].Order.ID != request.Order.ID {
+		t.Fatalf("Checkouts = %d, se esperaban 2 solicitudes de la orden %s", len(fake.Checkouts), request.Order.ID)
+	}
+}
+
+func TestStripeRefundError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{"saldo insuficiente (400)", &stripe.Error{HTTPStatusCode: http.StatusBadRequest, Code: stripe.ErrorCodeChargeAlreadyRefunded}, true},
+		{"pago no encontrado (404)", &stripe.Error{HTTPStatusCode: http.StatusNotFound}, true},
+		{"misma key en proceso (409)", &stripe.Error{HTTPStatusCode: http.StatusConflict}, false},
+		{"límite de solicitudes (429)", &stripe.Error{HTTPStatusCode: http.StatusTooManyRequests}, false},
+		{"error de Stripe (500)", &stripe.Error{HTTPStatusCode: http.StatusInternalServerError}, false},
+		{"error de red", errors.New("connection reset by peer"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripeRefundError(tt.err)
+			if rejected := errors.Is(got, ErrRefundRejected); rejected != tt.rejected {
+				t.Fatalf("errors.Is(ErrRefundRejected) = %t, se esperaba %t (%v)", rejected, tt.rejected, got)
+			}
+		})
+	}
+}
+
+func TestFakePaymentGatewayRefundIsIdempotent(t *testing.T) {
+	fake := NewFakePaymentGateway()
+	request := GatewayRefundRequest{
+		PaymentIntentID: "pi_fake",
+		Amount:          models.NewMoney(100),
+		IdempotencyKey:  "refund-" + uuid.New().String(),
+	}
+
+	first, err := fake.Refund(request)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	retry, err := fake.Refund(request)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if retry.RefundID != first.RefundID || len(fake.Refunds) != 1 {
+		t.Fatalf("reintento = %s (%d reembolsos), se esperaba %s (1 reembolso)", retry.RefundID, len(fake.Refunds), first.RefundID)
+	}
+
+	request.IdempotencyKey = "refund-" + uuid.New().String()
+	other, err := fake.Refund(request)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if other.RefundID == first.RefundID {
+		t.Fatalf("otra key retornó el mismo reembolso %s", other.RefundID)
+	}
+}
+
+func TestStripeSessionExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      time.Time
+	}{
+		{"dentro del rango de Stripe", now.Add(2 * time.Hour), now.Add(2 * time.Hour)},
+		{"TTL menor a 30 minutos", now.Add(10 * time.Minute), now.Add(31 * time.Minute)},
+		{"TTL mayor a 24 horas", now.Add(48 * time.Hour), now.Add(24*time.Hour - time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripeSessionExpiry(tt.expiresAt, now); !got.Equal(tt.want) {
+				t.Fatalf("stripeSessionExpiry = %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
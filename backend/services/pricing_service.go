@@ -0,0 +1,175 @@
+// backend/services/pricing_service.go
+package services
+
+import (
+	"fmt"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
+)
+
+// QuotedItem representa un item del carrito con precio recalculado desde la BD.
+type QuotedItem struct {
+	Product  models.Product `json:"-"`
+	Quantity int            `json:"quantity"`
+	// UnitPrice: precio unitario vigente en la base de datos.
+	UnitPrice models.Money `json:"unit_price"`
+	// LineTotal: UnitPrice * Quantity.
+	LineTotal models.Money `json:"line_total"`
+}
+
+// CartQuote es la cotización del carrito calculada en el servidor.
+type CartQuote struct {
+	Items        []QuotedItem `json:"items"`
+	Subtotal     models.Money `json:"subtotal"`
+	ShippingCost models.Money `json:"shipping_cost"`
+	Total        models.Money `json:"total"`
+	// Shipping: Tarifa aplicada (método y si requiere courier).
+	Shipping *ShippingQuote `json:"shipping,omitempty"`
+	// Package: Paquete calculado con los productos; su peso define la tarifa.
+	Package *PackingResult `json:"package,omitempty"`
+}
+
+// PriceDiscrepancy describe una diferencia entre lo que mostró el cliente
+// y lo que calcula el servidor.
+type PriceDiscrepancy struct {
+	// Field: "price", "subtotal", "shipping_cost" o "total".
+	Field     string       `json:"field"`
+	ProductID uint         `json:"product_id,omitempty"`
+	Client    models.Money `json:"client"`
+	Server    models.Money `json:"server"`
+}
+
+// ClientPrices son los montos que el cliente vio antes de confirmar la compra.
+// Solo se usan para detectar diferencias con la cotización del servidor.
+type ClientPrices struct {
+	// Items: Precio unitario mostrado por producto.
+	Items        map[uint]models.Money
+	Subtotal     models.Money
+	ShippingCost models.Money
+	Total        models.Money
+}
+
+// PriceMismatchError indica que los precios mostrados al cliente ya no coinciden
+// con los del servidor. Incluye la cotización vigente para mostrarla de nuevo.
+type PriceMismatchError struct {
+	Discrepancies []PriceDiscrepancy
+	Quote         *CartQuote
+}
+
+func (e *PriceMismatchError) Error() string {
+	return fmt.Sprintf("los precios del carrito cambiaron (%d diferencias)", len(e.Discrepancies))
+}
+
+// QuoteCart carga cada producto del carrito, recalcula precios, subtotal,
+// paquete, costo de envío y total usando únicamente datos del servidor. El
+// peso del paquete reemplaza destination.WeightKg.
+func QuoteCart(products repositories.ProductRepository, items []models.CartItem, destination ShippingDestination) (*CartQuote, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no se puede crear una orden sin items")
+	}
+
+	quote := &CartQuote{
+		Items:    make([]QuotedItem, 0, len(items)),
+		Subtotal: models.NewMoney(0),
+	}
+
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("validación de item fallida: quantity debe ser mayor a 0")
+		}
+
+		product, err := products.GetByID(item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		lineTotal := product.Price.Mul(item.Quantity)
+		quote.Items = append(quote.Items, QuotedItem{
+			Product:   *product,
+			Quantity:  item.Quantity,
+			UnitPrice: product.Price,
+			LineTotal: lineTotal,
+		})
+		if quote.Subtotal, err = quote.Subtotal.Add(lineTotal); err != nil {
+			return nil, fmt.Errorf("validación de item fallida: %w", err)
+		}
+	}
+
+	packing := make([]PackingItem, 0, len(quote.Items))
+	for _, item := range quote.Items {
+		packing = append(packing, PackingItem{Product: item.Product, Quantity: item.Quantity})
+	}
+	parcel := PackItems(packing)
+	quote.Package = &parcel
+	destination.WeightKg = parcel.WeightKg
+
+	shipping, err := QuoteShipping(destination)
+	if err != nil {
+		return nil, err
+	}
+	quote.Shipping = shipping
+	quote.ShippingCost = shipping.Cost
+	if quote.Total, err = quote.Subtotal.Add(quote.ShippingCost); err != nil {
+		return nil, fmt.Errorf("validación: el envío se cotizó en otra moneda: %w", err)
+	}
+
+	return quote, nil
+}
+
+// OrderItems construye los snapshots de OrderItem a partir de la cotización.
+func (q *CartQuote) OrderItems() []models.OrderItem {
+	orderItems := make([]models.OrderItem, 0, len(q.Items))
+	for _, item := range q.Items {
+		orderItems = append(orderItems, models.OrderItem{
+			ProductID:   item.Product.ID,
+			ProductName: item.Product.Name,
+			Quantity:    item.Quantity,
+			Price:       item.UnitPrice,
+		})
+	}
+	return orderItems
+}
+
+// CompareUnitPrice registra una discrepancia si el precio mostrado al cliente
+// no coincide con el precio vigente del producto.
+func (q *CartQuote) CompareUnitPrice(productID uint, clientPrice models.Money) []PriceDiscrepancy {
+	var diffs []PriceDiscrepancy
+	for _, item := range q.Items {
+		if item.Product.ID == productID && !item.UnitPrice.Equal(clientPrice) {
+			diffs = append(diffs, PriceDiscrepancy{
+				Field:     "price",
+				ProductID: productID,
+				Client:    clientPrice,
+				Server:    item.UnitPrice,
+			})
+		}
+	}
+	return diffs
+}
+
+// CompareTotals compara subtotal, envío y total mostrados al cliente con la cotización.
+func (q *CartQuote) CompareTotals(subtotal, shippingCost, total models.Money) []PriceDiscrepancy {
+	var diffs []PriceDiscrepancy
+	if !q.Subtotal.Equal(subtotal) {
+		diffs = append(diffs, PriceDiscrepancy{Field: "subtotal", Client: subtotal, Server: q.Subtotal})
+	}
+	if !q.ShippingCost.Equal(shippingCost) {
+		diffs = append(diffs, PriceDiscrepancy{Field: "shipping_cost", Client: shippingCost, Server: q.ShippingCost})
+	}
+	if !q.Total.Equal(total) {
+		diffs = append(diffs, PriceDiscrepancy{Field: "total", Client: total, Server: q.Total})
+	}
+	return diffs
+}
+
+// Compare retorna todas las diferencias entre lo que vio el cliente y la cotización.
+func (q *CartQuote) Compare(client *ClientPrices) []PriceDiscrepancy {
+	var diffs []PriceDiscrepancy
+	for _, item := range q.Items {
+		if price, ok := client.Items[item.Product.ID]; ok {
+			diffs = append(diffs, q.CompareUnitPrice(item.Product.ID, price)...)
+		}
+	}
+	return append(diffs, q.CompareTotals(client.Subtotal, client.ShippingCost, client.Total)...)
+}
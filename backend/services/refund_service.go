@@ -0,0 +1,541 @@
+// backend/services/refund_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// RefundItemDTO indica cuántas unidades de un OrderItem se reembolsan.
+type RefundItemDTO struct {
+	// OrderItemID: Item de la orden a reembolsar.
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+
+	// Quantity: Unidades a reembolsar (mínimo 1).
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+// CreateRefundDTO representa la solicitud de reembolso de un admin.
+// Si Items está vacío se reembolsa todo lo pendiente de la orden, incluido el envío.
+type CreateRefundDTO struct {
+	// Items: Unidades a reembolsar (vacío = reembolso total).
+	Items []RefundItemDTO `json:"items"`
+
+	// IncludeShipping: Reembolsa también el costo de envío en un reembolso parcial.
+	IncludeShipping bool `json:"include_shipping"`
+
+	// Restock: Regresa las unidades al inventario (default true).
+	Restock *bool `json:"restock"`
+
+	// Reason: Motivo del reembolso.
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// RefundService define la lógica de negocio de reembolsos.
+type RefundService interface {
+	// RefundOrder emite un reembolso total o parcial, actualiza el inventario,
+	// registra el reembolso en el libro y cambia el estado de la orden.
+	RefundOrder(orderID uuid.UUID, dto CreateRefundDTO, adminID string) (*models.Refund, error)
+
+	// ResumePendingRefunds completa los reembolsos que quedaron pendientes
+	// hace más de olderThan. Retorna cuántos se completaron.
+	ResumePendingRefunds(olderThan time.Duration) (int, error)
+
+	// CompletePendingRefund emite en la pasarela un reembolso ya reservado
+	// (ej: con ReserveLatePaymentRefund) y lo aplica.
+	CompletePendingRefund(refundID uuid.UUID, actor models.StatusChange) (*models.Refund, error)
+}
+
+// refundableStatuses son los estados en los que una orden puede reembolsarse.
+var refundableStatuses = map[models.OrderStatus]bool{
+	models.StatusPaid:              true,
+	models.StatusProcessing:        true,
+	models.StatusShipped:           true,
+	models.StatusDelivered:         true,
+	models.StatusPartiallyRefunded: true,
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type refundService struct {
+	db       *gorm.DB
+	gateways *PaymentGateways
+}
+
+// NewRefundService crea una nueva instancia del servicio de reembolsos.
+// El reembolso se emite con la pasarela de la forma de pago de cada orden.
+func NewRefundService(db *gorm.DB, gateways *PaymentGateways) RefundService {
+	return &refundService{
+		db:       db,
+		gateways: gateways,
+	}
+}
+
+// RefundOrder reserva el reembolso con la orden bloqueada, de modo que dos
+// reembolsos simultáneos no puedan superar el monto pagado, y lo emite en la
+// pasarela ya sin el bloqueo (ver completeRefund).
+func (s *refundService) RefundOrder(orderID uuid.UUID, dto CreateRefundDTO, adminID string) (*models.Refund, error) {
+	actor := models.StatusChange{
+		ActorType: models.ActorAdmin,
+		ActorID:   adminID,
+	}
+
+	var pending *models.Refund
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 1. Bloquear la orden
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems").
+			First(&order, "id = ?", orderID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("orden no encontrada: %s", orderID)
+			}
+			return fmt.Errorf("error al obtener orden: %w", err)
+		}
+
+		var err error
+		pending, err = s.reserveRefund(tx, &order, dto, actor.ActorID, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeRefund(pending, actor)
+}
+
+// ResumePendingRefunds completa los reembolsos 'pending' creados hace más de
+// olderThan: la pasarela no respondió o el reembolso se emitió pero no se
+// aplicó a la orden. Retorna cuántos se completaron.
+func (s *refundService) ResumePendingRefunds(olderThan time.Duration) (int, error) {
+	var pending []models.Refund
+	if err := s.db.Preload("Items").
+		Where("status = ? AND created_at < ?", models.RefundPending, time.Now().Add(-olderThan)).
+		Order("created_at ASC").
+		Limit(reconcileBatchSize).
+		Find(&pending).Error; err != nil {
+		return 0, fmt.Errorf("error al buscar reembolsos pendientes: %w", err)
+	}
+
+	completed := 0
+	for i := range pending {
+		if _, err := s.completeRefund(&pending[i], models.StatusChange{
+			ActorType: models.ActorSystem,
+			ActorID:   "refund_reconciler",
+		}); err != nil {
+			log.Printf("No se pudo completar el reembolso pendiente %s: %v", pending[i].ID, err)
+			continue
+		}
+		completed++
+	}
+	return completed, nil
+}
+
+// CompletePendingRefund busca el reembolso 'pending' y lo completa (ver completeRefund).
+func (s *refundService) CompletePendingRefund(refundID uuid.UUID, actor models.StatusChange) (*models.Refund, error) {
+	var refundRecord models.Refund
+	if err := s.db.Preload("Items").
+		First(&refundRecord, "id = ? AND status = ?", refundID, models.RefundPending).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("reembolso pendiente no encontrado: %s", refundID)
+		}
+		return nil, fmt.Errorf("error al obtener reembolso: %w", err)
+	}
+	return s.completeRefund(&refundRecord, actor)
+}
+
+// ReserveLatePaymentRefund registra 'pending', dentro de tx, el reembolso total
+// de un pago que la pasarela confirmó con la orden ya cancelada (ej: el cliente
+// pagó justo cuando la orden venció). La orden sigue cancelada y guarda el
+// pago recibido para poder reembolsarlo; el reembolso se emite después con
+// CompletePendingRefund (o ResumePendingRefunds si esa llamada falla).
+func ReserveLatePaymentRefund(tx *gorm.DB, order *models.Order, amount models.Money, paymentIntentID, sessionID string) (*models.Refund, error) {
+	if order.Status != models.StatusCancelled {
+		return nil, fmt.Errorf("la orden en estado '%s' no está cancelada", order.Status)
+	}
+	if paymentIntentID == "" {
+		return nil, fmt.Errorf("la sesión %s no tiene un pago asociado que reembolsar", sessionID)
+	}
+	if !amount.IsPositive() {
+		amount = order.Total
+	}
+
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"payment_intent_id": paymentIntentID,
+		"stripe_session_id": sessionID,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("error al registrar pago de la orden cancelada: %w", err)
+	}
+
+	refundRecord := &models.Refund{
+		OrderID:   order.ID,
+		Status:    models.RefundPending,
+		Amount:    amount,
+		Reason:    fmt.Sprintf("pago recibido con la orden cancelada (sesión %s)", sessionID),
+		CreatedBy: string(models.ActorStripeWebhook),
+	}
+	if err := tx.Create(refundRecord).Error; err != nil {
+		return nil, fmt.Errorf("error al registrar reembolso: %w", err)
+	}
+
+	if err := models.RecordStatusEvent(tx, order.ID, order.Status, order.Status, models.StatusChange{
+		ActorType: models.ActorStripeWebhook,
+		ActorID:   sessionID,
+		Reason:    fmt.Sprintf("pago de %s recibido con la orden cancelada, se reembolsa completo", amount),
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Orden %s cancelada recibió un pago de %s (sesión %s): reembolso %s reservado",
+		order.ID, amount, sessionID, refundRecord.ID)
+	return refundRecord, nil
+}
+
+// reserveRefund valida el reembolso sobre una orden ya bloqueada (con
+// OrderItems precargados) y lo registra 'pending' dentro de tx, reservando sus
+// unidades y su monto. returnID indica la devolución que resuelve (si aplica).
+// Si la orden ya tiene un reembolso pendiente del mismo solicitante y la
+// misma devolución (un intento anterior no terminó) se retorna ese en lugar de
+// crear otro: completarlo con su misma idempotency key nunca reembolsa dos
+// veces. Un pendiente de otra solicitud bloquea nuevos reembolsos hasta que
+// ResumePendingRefunds lo complete.
+func (s *refundService) reserveRefund(tx *gorm.DB, order *models.Order, dto CreateRefundDTO, createdBy string, returnID *uuid.UUID) (*models.Refund, error) {
+	restock := dto.Restock == nil || *dto.Restock
+
+	if !refundableStatuses[order.Status] {
+		return nil, fmt.Errorf("la orden en estado '%s' no se puede reembolsar", order.Status)
+	}
+
+	var existing []models.Refund
+	if err := tx.Preload("Items").
+		Where("order_id = ? AND status = ?", order.ID, models.RefundPending).
+		Limit(1).
+		Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("error al consultar reembolsos pendientes: %w", err)
+	}
+	if len(existing) > 0 {
+		pending := &existing[0]
+		if pending.CreatedBy != createdBy || !sameReturnRequest(pending.ReturnRequestID, returnID) {
+			return nil, fmt.Errorf("validación: la orden tiene un reembolso pendiente de %s (%s) que se completa automáticamente, intenta más tarde",
+				pending.Amount, pending.ID)
+		}
+		log.Printf("Orden %s: se retoma el reembolso pendiente %s (%s)", order.ID, pending.ID, pending.Amount)
+		return pending, nil
+	}
+
+	// 2. Unidades ya reembolsadas (o reservadas) por item
+	refundedQty, err := refundedQuantities(tx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shippingRefunded models.Money
+	if err := tx.Model(&models.Refund{}).
+		Where("order_id = ?", order.ID).
+		Select("COALESCE(SUM(shipping_amount), 0)").
+		Row().Scan(&shippingRefunded); err != nil {
+		return nil, fmt.Errorf("error al consultar reembolsos de envío: %w", err)
+	}
+
+	// 3. Construir las líneas del reembolso
+	refundRecord := &models.Refund{
+		OrderID:         order.ID,
+		Status:          models.RefundPending,
+		Amount:          models.NewMoney(0),
+		Reason:          dto.Reason,
+		Restocked:       restock,
+		CreatedBy:       createdBy,
+		ReturnRequestID: returnID,
+	}
+
+	fullRefund := len(dto.Items) == 0
+	requested := map[uuid.UUID]int{}
+	if fullRefund {
+		for _, item := range order.OrderItems {
+			if remaining := item.Quantity - refundedQty[item.ID]; remaining > 0 {
+				requested[item.ID] = remaining
+			}
+		}
+	} else {
+		for _, item := range dto.Items {
+			requested[item.OrderItemID] += item.Quantity
+		}
+	}
+
+	for _, item := range order.OrderItems {
+		qty, ok := requested[item.ID]
+		if !ok {
+			continue
+		}
+		delete(requested, item.ID)
+
+		if remaining := item.Quantity - refundedQty[item.ID]; qty > remaining {
+			return nil, fmt.Errorf("validación: solo quedan %d unidades reembolsables de %s", remaining, item.ProductName)
+		}
+
+		amount := item.Price.Mul(qty)
+		refundRecord.Items = append(refundRecord.Items, models.RefundItem{
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			Quantity:    qty,
+			Amount:      amount,
+		})
+		if refundRecord.Amount, err = refundRecord.Amount.Add(amount); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(requested) > 0 {
+		return nil, fmt.Errorf("validación: items no pertenecen a la orden %s", order.ID)
+	}
+
+	if (fullRefund || dto.IncludeShipping) && order.ShippingCost.GreaterThan(shippingRefunded) {
+		if refundRecord.ShippingAmount, err = order.ShippingCost.Sub(shippingRefunded); err != nil {
+			return nil, err
+		}
+		if refundRecord.Amount, err = refundRecord.Amount.Add(refundRecord.ShippingAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	if !refundRecord.Amount.IsPositive() {
+		return nil, fmt.Errorf("validación: no hay montos pendientes de reembolso")
+	}
+	balance, err := order.Total.Sub(order.RefundedAmount)
+	if err != nil {
+		return nil, err
+	}
+	if refundRecord.Amount.GreaterThan(balance) {
+		return nil, fmt.Errorf("validación: el reembolso (%s) supera el saldo pagado (%s)",
+			refundRecord.Amount, balance)
+	}
+
+	// 4. Reservar el reembolso en el libro (su ID será la idempotency key)
+	if err := tx.Create(refundRecord).Error; err != nil {
+		return nil, fmt.Errorf("error al registrar reembolso: %w", err)
+	}
+	return refundRecord, nil
+}
+
+// completeRefund emite en la pasarela un reembolso 'pending' y lo aplica a la
+// orden. Se llama sin transacción abierta, para no bloquear la orden durante
+// la llamada externa:
+//  1. La pasarela recibe el ID del reembolso como idempotency key: si un
+//     intento anterior ya lo emitió, retorna ese mismo reembolso.
+//  2. Si la pasarela lo rechaza (ErrRefundRejected) se borra la reserva. Con
+//     cualquier otro error el reembolso queda 'pending' y se completa al
+//     reintentar la solicitud o con ResumePendingRefunds.
+//  3. Con la orden bloqueada se marca 'succeeded', se repone el stock, se
+//     actualiza la orden y, si resuelve una devolución, se cierra la devolución.
+func (s *refundService) completeRefund(refundRecord *models.Refund, actor models.StatusChange) (*models.Refund, error) {
+	var order models.Order
+	if err := s.db.First(&order, "id = ?", refundRecord.OrderID).Error; err != nil {
+		return nil, fmt.Errorf("error al obtener orden: %w", err)
+	}
+
+	// 1. Llamar a la pasarela
+	method := PaymentMethod(order.PaymentMethod)
+	if method == "" {
+		method = PaymentMethodCard
+	}
+	gateway, err := s.gateways.Get(method)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := gateway.Refund(GatewayRefundRequest{
+		PaymentIntentID: order.PaymentIntentID,
+		Amount:          refundRecord.Amount,
+		Reason:          refundRecord.Reason,
+		OrderID:         order.ID.String(),
+		OrderNumber:     order.OrderNumber,
+		IdempotencyKey:  "refund-" + refundRecord.ID.String(),
+	})
+	if err != nil {
+		// 2. Rechazado: liberar la reserva. Ambiguo: queda pendiente
+		if errors.Is(err, ErrRefundRejected) {
+			if discardErr := s.discardRefund(refundRecord.ID); discardErr != nil {
+				log.Printf("No se pudo borrar el reembolso rechazado %s: %v", refundRecord.ID, discardErr)
+			}
+			return nil, err
+		}
+		log.Printf("Reembolso %s de la orden %s queda pendiente: %v", refundRecord.ID, order.ID, err)
+		return nil, fmt.Errorf("el reembolso %s quedó pendiente por un error de la pasarela, se completa al reintentar: %w",
+			refundRecord.ID, err)
+	}
+
+	// 3. Aplicar el reembolso a la orden
+	if err := s.finalizeRefund(refundRecord, response, actor); err != nil {
+		log.Printf("CRÍTICO: reembolso %s emitido en %s pero no aplicado a la orden %s (queda pendiente): %v",
+			response.RefundID, response.Provider, order.ID, err)
+		return nil, fmt.Errorf("reembolso %s emitido pero no registrado, se completa al reintentar: %w", response.RefundID, err)
+	}
+
+	log.Printf("Reembolso %s (%s) emitido sobre orden %s", refundRecord.ProviderRefundID, refundRecord.Amount, order.ID)
+	return refundRecord, nil
+}
+
+// finalizeRefund marca 'succeeded' un reembolso ya emitido en la pasarela y
+// aplica sus efectos en una transacción. Si otro intento ya lo aplicó, solo
+// actualiza refundRecord.
+func (s *refundService) finalizeRefund(refundRecord *models.Refund, response *GatewayRefundResponse, actor models.StatusChange) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Mismo orden de bloqueo que ResolveReturn: devolución, orden, reembolso
+		var request models.ReturnRequest
+		if refundRecord.ReturnRequestID != nil {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&request, "id = ?", *refundRecord.ReturnRequestID).Error; err != nil {
+				return fmt.Errorf("error al obtener devolución: %w", err)
+			}
+		}
+
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, "id = ?", refundRecord.OrderID).Error; err != nil {
+			return fmt.Errorf("error al obtener orden: %w", err)
+		}
+
+		var current models.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "id = ?", refundRecord.ID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("reembolso no encontrado: %s", refundRecord.ID)
+			}
+			return fmt.Errorf("error al obtener reembolso: %w", err)
+		}
+		if current.Status != models.RefundPending {
+			refundRecord.Status = current.Status
+			refundRecord.Provider = current.Provider
+			refundRecord.ProviderRefundID = current.ProviderRefundID
+			return nil
+		}
+
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"status":             models.RefundSucceeded,
+			"provider":           response.Provider,
+			"provider_refund_id": response.RefundID,
+		}).Error; err != nil {
+			return fmt.Errorf("error al registrar reembolso: %w", err)
+		}
+
+		// Regresar unidades al inventario
+		if refundRecord.Restocked {
+			for _, item := range refundRecord.Items {
+				if err := tx.Model(&models.Product{}).
+					Where("id = ?", item.ProductID).
+					Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+					return fmt.Errorf("error al reponer stock: %w", err)
+				}
+			}
+		}
+
+		change := models.StatusChange{
+			ActorType: actor.ActorType,
+			ActorID:   actor.ActorID,
+			Reason:    fmt.Sprintf("reembolso %s (%s): %s", response.RefundID, refundRecord.Amount, refundRecord.Reason),
+		}
+
+		if order.Status == models.StatusCancelled {
+			// Pago recibido con la orden ya cancelada (ReserveLatePaymentRefund):
+			// la orden nunca se cobró, solo se registra la devolución
+			if err := models.RecordStatusEvent(tx, order.ID, order.Status, order.Status, change); err != nil {
+				return err
+			}
+		} else {
+			// Actualizar monto reembolsado y estado de la orden
+			refundedAmount, err := order.RefundedAmount.Add(refundRecord.Amount)
+			if err != nil {
+				return err
+			}
+			status := models.StatusPartiallyRefunded
+			if !order.Total.GreaterThan(refundedAmount) {
+				status = models.StatusRefunded
+			}
+
+			change.Fields = map[string]interface{}{
+				"refunded_amount": refundedAmount,
+			}
+			if err := order.TransitionTo(tx, status, change); err != nil {
+				return fmt.Errorf("error al actualizar orden: %w", err)
+			}
+		}
+
+		// Cerrar la devolución que resuelve este reembolso
+		if refundRecord.ReturnRequestID != nil {
+			if err := request.TransitionTo(tx, models.ReturnRefunded, map[string]interface{}{
+				"refund_id":   refundRecord.ID,
+				"resolved_at": time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+
+		refundRecord.Status = models.RefundSucceeded
+		refundRecord.Provider = response.Provider
+		refundRecord.ProviderRefundID = response.RefundID
+		return nil
+	})
+}
+
+// sameReturnRequest indica si dos referencias opcionales a una devolución coinciden.
+func sameReturnRequest(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// discardRefund borra la reserva de un reembolso que la pasarela rechazó.
+func (s *refundService) discardRefund(refundID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND status = ?", refundID, models.RefundPending).Delete(&models.Refund{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return tx.Where("refund_id = ?", refundID).Delete(&models.RefundItem{}).Error
+	})
+}
+
+// refundedQuantities suma las unidades ya reembolsadas por cada OrderItem de la orden.
+func refundedQuantities(tx *gorm.DB, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+
+	if err := tx.Model(&models.RefundItem{}).
+		Select("refund_items.order_item_id, SUM(refund_items.quantity) AS quantity").
+		Joins("JOIN refunds ON refunds.id = refund_items.refund_id").
+		Where("refunds.order_id = ?", orderID).
+		Group("refund_items.order_item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("error al consultar unidades reembolsadas: %w", err)
+	}
+
+	quantities := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		quantities[row.OrderItemID] = row.Quantity
+	}
+	return quantities, nil
+}
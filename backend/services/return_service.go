@@ -0,0 +1,739 @@
+// backend/services/return_service.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultReturnWindow es el plazo, desde la entrega, para solicitar una devolución.
+const defaultReturnWindow = 30 * 24 * time.Hour
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// ReturnItemDTO indica cuántas unidades de un OrderItem se devuelven y por qué.
+type ReturnItemDTO struct {
+	// OrderItemID: Item de la orden a devolver.
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+
+	// Quantity: Unidades a devolver (mínimo 1).
+	Quantity int `json:"quantity" binding:"required,min=1"`
+
+	// Reason: Motivo ('size_too_small', 'size_too_large', 'defective', ...).
+	Reason models.ReturnReason `json:"reason" binding:"required"`
+
+	// ExchangeProductID: Producto que se quiere a cambio (opcional, ej: otra talla).
+	ExchangeProductID *uint `json:"exchange_product_id"`
+}
+
+// CreateReturnDTO representa la solicitud de devolución de un cliente.
+type CreateReturnDTO struct {
+	// Token: Enlace de consulta firmado que recibió el cliente por email
+	// (ver OrderAccessService). Identifica al dueño de una orden de invitado.
+	Token string `json:"token"`
+
+	// Notes: Comentarios del cliente.
+	Notes string `json:"notes"`
+
+	// Items: Prendas a devolver (mínimo 1).
+	Items []ReturnItemDTO `json:"items" binding:"required,min=1,dive"`
+}
+
+// ResolveReturnDTO indica cómo se resuelve una devolución ya recibida.
+type ResolveReturnDTO struct {
+	// Resolution: 'refund' (reembolso) | 'exchange' (orden de reemplazo).
+	Resolution string `json:"resolution" binding:"required,oneof=refund exchange"`
+
+	// Notes: Comentarios del admin.
+	Notes string `json:"notes"`
+}
+
+// ReturnFilter filtra el listado de devoluciones del panel de admin.
+type ReturnFilter struct {
+	Status  models.ReturnStatus
+	OrderID *uuid.UUID
+	Limit   int
+	Offset  int
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// ReturnService define el flujo de devoluciones y cambios (RMA):
+// requested -> approved (guía de retorno) -> received (restock) -> refunded | exchanged.
+// Una devolución se puede rechazar mientras no se haya recibido.
+type ReturnService interface {
+	// RequestReturn registra la solicitud del cliente sobre prendas de una orden entregada.
+	RequestReturn(orderID uuid.UUID, dto CreateReturnDTO, userID string) (*models.ReturnRequest, error)
+
+	// ListReturns lista devoluciones con filtros. Retorna también el total sin paginar.
+	ListReturns(filter ReturnFilter) ([]models.ReturnRequest, int64, error)
+
+	// GetReturn obtiene una devolución con sus items.
+	GetReturn(returnID uuid.UUID) (*models.ReturnRequest, error)
+
+	// ApproveReturn aprueba la devolución y genera la guía de retorno con Cargo Expreso.
+	ApproveReturn(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error)
+
+	// RejectReturn rechaza la devolución.
+	RejectReturn(returnID uuid.UUID, reason, adminID string) (*models.ReturnRequest, error)
+
+	// ReceiveReturn marca las prendas como recibidas y las regresa al inventario.
+	ReceiveReturn(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error)
+
+	// ResolveReturn reembolsa las prendas o crea la orden de reemplazo.
+	ResolveReturn(returnID uuid.UUID, dto ResolveReturnDTO, adminID string) (*models.ReturnRequest, error)
+}
+
+// returnableStatuses son los estados de orden en los que se puede solicitar una devolución.
+var returnableStatuses = map[models.OrderStatus]bool{
+	models.StatusDelivered:         true,
+	models.StatusPartiallyRefunded: true,
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type returnService struct {
+	db      *gorm.DB
+	cargo   CargoExpresoService
+	links   OrderLinkVerifier
+	refunds *refundService
+	window  time.Duration
+}
+
+// NewReturnService crea el servicio de devoluciones.
+// links valida los enlaces de consulta con los que se identifican los invitados.
+// window es el plazo, desde la entrega, para solicitar una devolución.
+func NewReturnService(db *gorm.DB, gateways *PaymentGateways, cargo CargoExpresoService, links OrderLinkVerifier, window time.Duration) ReturnService {
+	return &returnService{
+		db:      db,
+		cargo:   cargo,
+		links:   links,
+		refunds: &refundService{db: db, gateways: gateways},
+		window:  window,
+	}
+}
+
+// NewReturnServiceFromEnv lee RETURN_REQUEST_WINDOW (ej: "720h" = 30 días).
+func NewReturnServiceFromEnv(db *gorm.DB, gateways *PaymentGateways, cargo CargoExpresoService, links OrderLinkVerifier) ReturnService {
+	window := durationFromEnv("RETURN_REQUEST_WINDOW", defaultReturnWindow)
+	return NewReturnService(db, gateways, cargo, links, window)
+}
+
+// ============================================================================
+// CLIENTE
+// ============================================================================
+
+// RequestReturn valida que la orden sea del cliente, que esté entregada y dentro
+// del plazo, y que no se devuelvan más unidades de las compradas.
+func (s *returnService) RequestReturn(orderID uuid.UUID, dto CreateReturnDTO, userID string) (*models.ReturnRequest, error) {
+	if len(dto.Items) == 0 {
+		return nil, fmt.Errorf("validación: la devolución debe incluir al menos un item")
+	}
+
+	var result *models.ReturnRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 1. Bloquear la orden (evita dos solicitudes simultáneas sobre las mismas prendas)
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems").
+			First(&order, "id = ?", orderID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("orden no encontrada: %s", orderID)
+			}
+			return fmt.Errorf("error al obtener orden: %w", err)
+		}
+
+		actorID, err := verifyOrderOwner(&order, s.links, dto.Token, userID)
+		if err != nil {
+			return err
+		}
+
+		if !returnableStatuses[order.Status] {
+			return fmt.Errorf("la orden en estado '%s' no se puede devolver", order.Status)
+		}
+
+		deliveredAt, err := orderDeliveredAt(tx, &order)
+		if err != nil {
+			return err
+		}
+		if time.Now().After(deliveredAt.Add(s.window)) {
+			return fmt.Errorf("la orden no se puede devolver: el plazo de %s desde la entrega venció", s.window)
+		}
+
+		// 2. Unidades disponibles para devolver por item
+		available, err := returnableQuantities(tx, &order)
+		if err != nil {
+			return err
+		}
+
+		itemsByID := make(map[uuid.UUID]models.OrderItem, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			itemsByID[item.ID] = item
+		}
+
+		request := &models.ReturnRequest{
+			OrderID:       order.ID,
+			Status:        models.ReturnRequested,
+			CustomerNotes: dto.Notes,
+			RequestedBy:   actorID,
+		}
+
+		requested := map[uuid.UUID]int{}
+		for _, line := range dto.Items {
+			item, ok := itemsByID[line.OrderItemID]
+			if !ok {
+				return fmt.Errorf("validación: el item %s no pertenece a la orden %s", line.OrderItemID, order.ID)
+			}
+			if !line.Reason.IsValid() {
+				return fmt.Errorf("validación: motivo de devolución inválido: %s", line.Reason)
+			}
+
+			requested[item.ID] += line.Quantity
+			if requested[item.ID] > available[item.ID] {
+				return fmt.Errorf("validación: solo quedan %d unidades de %s que se puedan devolver", available[item.ID], item.ProductName)
+			}
+
+			if line.ExchangeProductID != nil {
+				var count int64
+				if err := tx.Model(&models.Product{}).Where("id = ?", *line.ExchangeProductID).Count(&count).Error; err != nil {
+					return fmt.Errorf("error al verificar producto de cambio: %w", err)
+				}
+				if count == 0 {
+					return fmt.Errorf("producto no encontrado: ID %d", *line.ExchangeProductID)
+				}
+			}
+
+			request.Items = append(request.Items, models.ReturnItem{
+				OrderItemID:       item.ID,
+				ProductID:         item.ProductID,
+				ProductName:       item.ProductName,
+				Quantity:          line.Quantity,
+				Reason:            line.Reason,
+				ExchangeProductID: line.ExchangeProductID,
+			})
+		}
+
+		if err := tx.Create(request).Error; err != nil {
+			return fmt.Errorf("error al registrar devolución: %w", err)
+		}
+
+		result = request
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Devolución %s solicitada sobre orden %s (%d items)", result.ID, result.OrderID, len(result.Items))
+	return result, nil
+}
+
+// ============================================================================
+// ADMIN
+// ============================================================================
+
+// ListReturns lista devoluciones, las más recientes primero.
+func (s *returnService) ListReturns(filter ReturnFilter) ([]models.ReturnRequest, int64, error) {
+	query := s.db.Model(&models.ReturnRequest{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.OrderID != nil {
+		query = query.Where("order_id = ?", *filter.OrderID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error al contar devoluciones: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	var returns []models.ReturnRequest
+	if err := query.Preload("Items").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(filter.Offset).
+		Find(&returns).Error; err != nil {
+		return nil, 0, fmt.Errorf("error al obtener devoluciones: %w", err)
+	}
+
+	return returns, total, nil
+}
+
+// GetReturn obtiene una devolución con sus items.
+func (s *returnService) GetReturn(returnID uuid.UUID) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	if err := s.db.Preload("Items").First(&request, "id = ?", returnID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("devolución no encontrada: %s", returnID)
+		}
+		return nil, fmt.Errorf("error al obtener devolución: %w", err)
+	}
+	return &request, nil
+}
+
+// ApproveReturn genera una guía de Cargo Expreso del cliente hacia la tienda.
+// Si la guía no se puede generar, la devolución sigue en 'requested'.
+// La guía se genera sin bloquear la devolución ni la orden (igual que
+// GenerateOrderGuide): si después no se puede guardar, se anula.
+func (s *returnService) ApproveReturn(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error) {
+	// 1. Validar y generar la guía fuera de la transacción
+	request, order, err := s.load(s.db, returnID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ValidateReturnTransition(request.Status, models.ReturnApproved); err != nil {
+		return nil, err
+	}
+
+	guide, err := s.createReturnGuide(request, order)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Guardar la guía; la devolución se vuelve a validar con bloqueo por si
+	// otra solicitud la aprobó o rechazó mientras se generaba la guía
+	err = s.transact(returnID, func(tx *gorm.DB, request *models.ReturnRequest, order *models.Order) error {
+		if err := models.ValidateReturnTransition(request.Status, models.ReturnApproved); err != nil {
+			return err
+		}
+		return request.TransitionTo(tx, models.ReturnApproved, map[string]interface{}{
+			"return_tracking":  guide.TrackingNumber,
+			"return_guide_url": guide.GuideURL,
+			"admin_notes":      notes,
+		})
+	})
+	if err != nil {
+		// La guía ya se creó en Cargo Expreso pero no quedó en la devolución: anularla
+		if cancelErr := s.cargo.CancelGuide(guide.TrackingNumber); cancelErr != nil {
+			log.Printf("No se pudo anular la guía de retorno %s sin usar: %v", guide.TrackingNumber, cancelErr)
+		}
+		return nil, err
+	}
+
+	log.Printf("Devolución %s aprobada por %s, guía de retorno %s", returnID, adminID, guide.TrackingNumber)
+	return s.GetReturn(returnID)
+}
+
+// RejectReturn rechaza la devolución (ej: prenda usada o fuera de política).
+func (s *returnService) RejectReturn(returnID uuid.UUID, reason, adminID string) (*models.ReturnRequest, error) {
+	return s.update(returnID, func(tx *gorm.DB, request *models.ReturnRequest, order *models.Order) error {
+		log.Printf("Devolución %s rechazada por %s: %s", request.ID, adminID, reason)
+		return request.TransitionTo(tx, models.ReturnRejected, map[string]interface{}{
+			"admin_notes": reason,
+			"resolved_at": time.Now(),
+		})
+	})
+}
+
+// ReceiveReturn regresa al inventario las prendas devueltas.
+func (s *returnService) ReceiveReturn(returnID uuid.UUID, notes, adminID string) (*models.ReturnRequest, error) {
+	return s.update(returnID, func(tx *gorm.DB, request *models.ReturnRequest, order *models.Order) error {
+		fields := map[string]interface{}{"received_at": time.Now()}
+		if notes != "" {
+			fields["admin_notes"] = notes
+		}
+		if err := request.TransitionTo(tx, models.ReturnReceived, fields); err != nil {
+			return err
+		}
+
+		units := 0
+		for _, item := range request.Items {
+			if err := tx.Model(&models.Product{}).
+				Where("id = ?", item.ProductID).
+				Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+				return fmt.Errorf("error al reponer stock del producto %d: %w", item.ProductID, err)
+			}
+			units += item.Quantity
+		}
+
+		log.Printf("Devolución %s recibida por %s: %d unidades regresadas al inventario", request.ID, adminID, units)
+		return nil
+	})
+}
+
+// ResolveReturn cierra una devolución recibida:
+//   - refund: reembolsa las prendas devueltas (sin envío; el stock ya se repuso
+//     al recibirlas). El reembolso se reserva con la devolución bloqueada y se
+//     emite después en la pasarela; la devolución pasa a 'refunded' cuando la
+//     pasarela lo confirma (si queda pendiente, al completarse).
+//   - exchange: crea una orden de reemplazo ya pagada con los productos de cambio
+//     (o los mismos productos si el cliente no indicó otro), reservando su stock.
+//     Si el producto de cambio tiene otro precio, la diferencia queda como saldo
+//     de la orden de reemplazo (balance_due) para cobrarla o reembolsarla.
+//     Si va por Cargo Expreso, su guía se genera al guardar el cambio; si la
+//     guía falla, la orden queda 'paid' y el admin la genera con la acción en
+//     lote generate_guides.
+func (s *returnService) ResolveReturn(returnID uuid.UUID, dto ResolveReturnDTO, adminID string) (*models.ReturnRequest, error) {
+	actor := models.StatusChange{
+		ActorType: models.ActorAdmin,
+		ActorID:   adminID,
+	}
+
+	var replacement *models.Order
+	var pending *models.Refund
+	err := s.transact(returnID, func(tx *gorm.DB, request *models.ReturnRequest, order *models.Order) error {
+		if dto.Resolution == "refund" {
+			if err := models.ValidateReturnTransition(request.Status, models.ReturnRefunded); err != nil {
+				return err
+			}
+
+			restock := false
+			refundDTO := CreateRefundDTO{
+				Restock: &restock,
+				Reason:  fmt.Sprintf("devolución %s", request.ID),
+			}
+			for _, item := range request.Items {
+				refundDTO.Items = append(refundDTO.Items, RefundItemDTO{OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+			}
+
+			refund, err := s.refunds.reserveRefund(tx, order, refundDTO, adminID, &request.ID)
+			if err != nil {
+				return err
+			}
+			pending = refund
+
+			if dto.Notes != "" {
+				if err := tx.Model(&models.ReturnRequest{}).
+					Where("id = ?", request.ID).
+					Update("admin_notes", dto.Notes).Error; err != nil {
+					return fmt.Errorf("error al actualizar devolución: %w", err)
+				}
+			}
+			return nil
+		}
+
+		if err := models.ValidateReturnTransition(request.Status, models.ReturnExchanged); err != nil {
+			return err
+		}
+
+		var refunding int64
+		if err := tx.Model(&models.Refund{}).
+			Where("return_request_id = ? AND status = ?", request.ID, models.RefundPending).
+			Count(&refunding).Error; err != nil {
+			return fmt.Errorf("error al consultar reembolsos pendientes: %w", err)
+		}
+		if refunding > 0 {
+			return fmt.Errorf("validación: la devolución tiene un reembolso pendiente, no se puede resolver como cambio")
+		}
+
+		created, err := s.createReplacementOrder(tx, request, order)
+		if err != nil {
+			return err
+		}
+		replacement = created
+
+		fields := map[string]interface{}{
+			"resolved_at":          time.Now(),
+			"replacement_order_id": created.ID,
+		}
+		if dto.Notes != "" {
+			fields["admin_notes"] = dto.Notes
+		}
+		request.ReplacementOrderID = &created.ID
+		log.Printf("Devolución %s: orden de reemplazo %s creada por %s", request.ID, created.ID, adminID)
+		return request.TransitionTo(tx, models.ReturnExchanged, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Con la devolución liberada, emitir el reembolso (cierra la devolución)
+	if pending != nil {
+		if _, err := s.refunds.completeRefund(pending, actor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Con el cambio ya guardado, generar la guía de la orden de reemplazo
+	if replacement != nil && replacement.RequiresCourier {
+		guide, err := GenerateOrderGuide(s.db, s.cargo, replacement, actor)
+		if err != nil {
+			log.Printf("No se pudo generar la guía de la orden de reemplazo %s (generarla con generate_guides): %v",
+				replacement.ID, err)
+		} else {
+			log.Printf("Guía %s generada para la orden de reemplazo %s", guide.TrackingNumber, replacement.ID)
+		}
+	}
+	return s.GetReturn(returnID)
+}
+
+// update bloquea la devolución y su orden, aplica apply y retorna la devolución actualizada.
+func (s *returnService) update(returnID uuid.UUID, apply func(tx *gorm.DB, request *models.ReturnRequest, order *models.Order) error) (*models.ReturnRequest, error) {
+	if err := s.transact(returnID, apply); err != nil {
+		return nil, err
+	}
+	return s.GetReturn(returnID)
+}
+
+// transact bloquea la devolución y su orden y aplica apply en una transacción.
+func (s *returnService) transact(returnID uuid.UUID, apply func(tx *gorm.DB, request *models.ReturnRequest, order *models.Order) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		request, order, err := s.load(tx, returnID, true)
+		if err != nil {
+			return err
+		}
+		return apply(tx, request, order)
+	})
+}
+
+// load obtiene la devolución con sus items y su orden con sus items.
+// Con lock, ambas filas se bloquean (FOR UPDATE) hasta el fin de la transacción.
+func (s *returnService) load(db *gorm.DB, returnID uuid.UUID, lock bool) (*models.ReturnRequest, *models.Order, error) {
+	query := func() *gorm.DB {
+		if lock {
+			return db.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		return db
+	}
+
+	var request models.ReturnRequest
+	if err := query().Preload("Items").First(&request, "id = ?", returnID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, fmt.Errorf("devolución no encontrada: %s", returnID)
+		}
+		return nil, nil, fmt.Errorf("error al obtener devolución: %w", err)
+	}
+
+	var order models.Order
+	if err := query().Preload("OrderItems").First(&order, "id = ?", request.OrderID).Error; err != nil {
+		return nil, nil, fmt.Errorf("error al obtener orden de la devolución: %w", err)
+	}
+	return &request, &order, nil
+}
+
+// createReturnGuide genera la guía de retorno: el cliente envía, la tienda recibe.
+func (s *returnService) createReturnGuide(request *models.ReturnRequest, order *models.Order) (*CargoExpresoGuideResponse, error) {
+	storeName := os.Getenv("CARGO_EXPRESO_SENDER_NAME")
+	storePhone := os.Getenv("CARGO_EXPRESO_SENDER_PHONE")
+	storeAddress := os.Getenv("CARGO_EXPRESO_SENDER_ADDRESS")
+	storeCity := os.Getenv("CARGO_EXPRESO_SENDER_CITY")
+	if storeName == "" || storePhone == "" || storeAddress == "" {
+		return nil, fmt.Errorf("error al generar guía de retorno: datos de la tienda (CARGO_EXPRESO_SENDER_*) incompletos")
+	}
+
+	prices := make(map[uuid.UUID]models.Money, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		prices[item.ID] = item.Price
+	}
+	declared := models.NewMoney(0)
+	quantities := make(map[uuid.UUID]int, len(request.Items))
+	for _, item := range request.Items {
+		var err error
+		if declared, err = declared.Add(prices[item.OrderItemID].Mul(item.Quantity)); err != nil {
+			return nil, fmt.Errorf("error al generar guía de retorno: %w", err)
+		}
+		quantities[item.OrderItemID] += item.Quantity
+	}
+
+	// El paquete de retorno lleva solo las prendas devueltas
+	parcel, err := PackOrderItems(s.db, order.OrderItems, quantities)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar guía de retorno: %w", err)
+	}
+
+	response, err := s.cargo.CreateGuide(CargoExpresoGuideRequest{
+		// Remitente: el cliente que devuelve las prendas
+		SenderName:    order.CustomerName,
+		SenderPhone:   order.CustomerPhone,
+		SenderAddress: order.ShippingAddress,
+		SenderCity:    order.ShippingMunicipality,
+
+		// Destinatario: la tienda
+		RecipientName:    storeName,
+		RecipientPhone:   storePhone,
+		RecipientAddress: storeAddress,
+		RecipientCity:    storeCity,
+
+		OrderID:       order.ID.String(),
+		PackageType:   parcel.PackageType,
+		Weight:        parcel.WeightLb(),
+		DeclaredValue: declared,
+		Notes:         fmt.Sprintf("Devolucion %s de la orden %s - Moda Organica", request.ID, order.Reference()),
+		DeliveryType:  "home_delivery",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al generar guía de retorno: %w", err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("error al generar guía de retorno: %s", response.ErrorMessage)
+	}
+
+	return response, nil
+}
+
+// createReplacementOrder crea la orden del cambio con los datos de envío de la
+// orden original. Se crea como pagada y sin costo: el cliente ya pagó las prendas.
+func (s *returnService) createReplacementOrder(tx *gorm.DB, request *models.ReturnRequest, original *models.Order) (*models.Order, error) {
+	now := time.Now()
+	replacement := &models.Order{
+		ID:                   uuid.New(),
+		UserID:               original.UserID,
+		Status:               models.StatusPaid,
+		CustomerEmail:        original.CustomerEmail,
+		CustomerName:         original.CustomerName,
+		CustomerPhone:        original.CustomerPhone,
+		ShippingDepartment:   original.ShippingDepartment,
+		ShippingMunicipality: original.ShippingMunicipality,
+		ShippingAddress:      original.ShippingAddress,
+		DeliveryType:         original.DeliveryType,
+		PickupBranch:         original.PickupBranch,
+		DeliveryNotes:        original.DeliveryNotes,
+		DeliveryLat:          original.DeliveryLat,
+		DeliveryLng:          original.DeliveryLng,
+		ShippingMethod:       original.ShippingMethod,
+		RequiresCourier:      original.RequiresCourier,
+		Subtotal:             models.NewMoney(0),
+		ShippingCost:         models.NewMoney(0),
+		Total:                models.NewMoney(0),
+		Currency:             original.Currency,
+		PaymentMethod:        original.PaymentMethod,
+		PaidAt:               &now,
+		ReplacesOrderID:      &original.ID,
+	}
+
+	exchangePrices := make(map[uint]models.Money)
+	for _, item := range request.Items {
+		productID := item.ProductID
+		if item.ExchangeProductID != nil {
+			productID = *item.ExchangeProductID
+		}
+
+		var product models.Product
+		if err := tx.Select("id", "name", "price").First(&product, "id = ?", productID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, fmt.Errorf("producto no encontrado: ID %d", productID)
+			}
+			return nil, fmt.Errorf("error al obtener producto: %w", err)
+		}
+		exchangePrices[product.ID] = product.Price
+
+		replacement.OrderItems = append(replacement.OrderItems, models.OrderItem{
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Quantity:    item.Quantity,
+			Price:       models.NewMoney(0),
+		})
+	}
+
+	// El cambio por un producto de otro precio no es gratis: la diferencia queda
+	// como saldo de la orden de reemplazo (se cobra al entregar o se reembolsa)
+	balance, err := exchangeBalance(request.Items, original.OrderItems, exchangePrices)
+	if err != nil {
+		return nil, err
+	}
+	replacement.BalanceDue = balance
+
+	// Misma reserva atómica de stock que las órdenes normales
+	if err := repositories.NewOrderRepository(tx).CreateWithStockReservation(replacement); err != nil {
+		return nil, err
+	}
+	if !balance.IsZero() {
+		log.Printf("Orden de reemplazo %s: diferencia de precio por cambio de producto %s", replacement.ID, balance)
+	}
+
+	return replacement, nil
+}
+
+// exchangeBalance calcula la diferencia entre el precio actual de los productos
+// de cambio y lo que el cliente pagó por las prendas devueltas. Positivo: el
+// cliente debe pagarla. Los items sin producto de cambio no generan diferencia.
+func exchangeBalance(items []models.ReturnItem, paidItems []models.OrderItem, prices map[uint]models.Money) (models.Money, error) {
+	paid := make(map[uuid.UUID]models.Money, len(paidItems))
+	for _, item := range paidItems {
+		paid[item.ID] = item.Price
+	}
+
+	balance := models.NewMoney(0)
+	for _, item := range items {
+		if item.ExchangeProductID == nil || *item.ExchangeProductID == item.ProductID {
+			continue
+		}
+		paidPrice, ok := paid[item.OrderItemID]
+		if !ok {
+			return models.Money{}, fmt.Errorf("item de orden no encontrado: %s", item.OrderItemID)
+		}
+		price, ok := prices[*item.ExchangeProductID]
+		if !ok {
+			return models.Money{}, fmt.Errorf("producto no encontrado: ID %d", *item.ExchangeProductID)
+		}
+		difference, err := price.Sub(paidPrice)
+		if err != nil {
+			return models.Money{}, fmt.Errorf("validación: el producto de cambio %d tiene otra moneda: %w", *item.ExchangeProductID, err)
+		}
+		if balance, err = balance.Add(difference.Mul(item.Quantity)); err != nil {
+			return models.Money{}, err
+		}
+	}
+	return balance, nil
+}
+
+// orderDeliveredAt retorna cuándo se entregó la orden según su timeline.
+// Si no hay evento de entrega (órdenes antiguas), usa la fecha de creación.
+func orderDeliveredAt(tx *gorm.DB, order *models.Order) (time.Time, error) {
+	var event models.OrderStatusEvent
+	err := tx.Where("order_id = ? AND to_status = ?", order.ID, models.StatusDelivered).
+		Order("created_at DESC").
+		First(&event).Error
+	if err == gorm.ErrRecordNotFound {
+		return order.CreatedAt, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error al consultar entrega de la orden: %w", err)
+	}
+	return event.CreatedAt, nil
+}
+
+// returnableQuantities calcula cuántas unidades de cada item aún se pueden devolver:
+// lo comprado menos lo reembolsado y lo incluido en devoluciones abiertas o cambiadas.
+// Las devoluciones reembolsadas ya cuentan como reembolso; las rechazadas no cuentan.
+func returnableQuantities(tx *gorm.DB, order *models.Order) (map[uuid.UUID]int, error) {
+	refunded, err := refundedQuantities(tx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	if err := tx.Model(&models.ReturnItem{}).
+		Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ? AND return_requests.status NOT IN ?", order.ID,
+			[]models.ReturnStatus{models.ReturnRejected, models.ReturnRefunded}).
+		Group("return_items.order_item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("error al consultar unidades en devolución: %w", err)
+	}
+
+	returned := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		returned[row.OrderItemID] = row.Quantity
+	}
+
+	available := make(map[uuid.UUID]int, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		available[item.ID] = item.Quantity - refunded[item.ID] - returned[item.ID]
+	}
+	return available, nil
+}
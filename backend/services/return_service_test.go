@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestExchangeBalance(t *testing.T) {
+	blouse := models.OrderItem{ID: uuid.New(), ProductID: 1, Price: models.NewMoney(15000)}
+	skirt := models.OrderItem{ID: uuid.New(), ProductID: 2, Price: models.NewMoney(20000)}
+	paid := []models.OrderItem{blouse, skirt}
+	prices := map[uint]models.Money{
+		1: models.NewMoney(15000),
+		3: models.NewMoney(25000), // más cara
+		4: models.NewMoney(12000), // más barata
+	}
+	exchange := func(id uint) *uint { return &id }
+
+	tests := []struct {
+		name    string
+		items   []models.ReturnItem
+		want    int64
+		wantErr string
+	}{
+		{"misma prenda (otra talla)", []models.ReturnItem{{OrderItemID: blouse.ID, ProductID: 1, Quantity: 1}}, 0, ""},
+		{"cambio por el mismo producto", []models.ReturnItem{{OrderItemID: blouse.ID, ProductID: 1, ExchangeProductID: exchange(1), Quantity: 1}}, 0, ""},
+		{"cambio por uno más caro", []models.ReturnItem{{OrderItemID: blouse.ID, ProductID: 1, ExchangeProductID: exchange(3), Quantity: 2}}, 20000, ""},
+		{"cambio por uno más barato", []models.ReturnItem{{OrderItemID: skirt.ID, ProductID: 2, ExchangeProductID: exchange(4), Quantity: 1}}, -8000, ""},
+		{"diferencias que se compensan", []models.ReturnItem{
+			{OrderItemID: blouse.ID, ProductID: 1, ExchangeProductID: exchange(3), Quantity: 1},
+			{OrderItemID: skirt.ID, ProductID: 2, ExchangeProductID: exchange(4), Quantity: 1},
+		}, 2000, ""},
+		{"producto de cambio sin precio", []models.ReturnItem{{OrderItemID: blouse.ID, ProductID: 1, ExchangeProductID: exchange(9), Quantity: 1}}, 0, "producto no encontrado"},
+		{"item ajeno a la orden", []models.ReturnItem{{OrderItemID: uuid.New(), ProductID: 1, ExchangeProductID: exchange(3), Quantity: 1}}, 0, "item de orden no encontrado"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exchangeBalance(tt.items, paid, prices)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("se esperaba error con %q, se obtuvo %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got.Amount != tt.want {
+				t.Fatalf("saldo = %d centavos, se esperaba %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
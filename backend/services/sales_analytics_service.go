@@ -0,0 +1,345 @@
+// backend/services/sales_analytics_service.go
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// analyticsTimeZone es la zona horaria de los reportes: un "día" de ventas es
+// un día en Guatemala, no en UTC.
+const analyticsTimeZone = models.StoreTimeZone
+
+// analyticsLocation es analyticsTimeZone cargada.
+var analyticsLocation = models.StoreLocation
+
+// maxAnalyticsBuckets limita los puntos de una serie de tiempo.
+const maxAnalyticsBuckets = 750
+
+// Granularity es el tamaño de cada punto de una serie de tiempo.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week" // Semanas ISO (empiezan el lunes)
+	GranularityMonth Granularity = "month"
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// AnalyticsPeriod es un rango de fechas de reporte. To es exclusivo.
+type AnalyticsPeriod struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// ParseAnalyticsPeriod interpreta "from" y "to" (YYYY-MM-DD, hora de Guatemala).
+// "to" incluye el día completo. Sin fechas, el período son los últimos 30 días
+// incluyendo hoy.
+func ParseAnalyticsPeriod(from, to string) (AnalyticsPeriod, error) {
+	now := time.Now().In(analyticsLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, analyticsLocation)
+
+	period := AnalyticsPeriod{To: today.AddDate(0, 0, 1)}
+	if to != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", to, analyticsLocation)
+		if err != nil {
+			return period, fmt.Errorf("validación: to debe tener formato YYYY-MM-DD")
+		}
+		period.To = parsed.AddDate(0, 0, 1)
+	}
+
+	period.From = period.To.AddDate(0, 0, -30)
+	if from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, analyticsLocation)
+		if err != nil {
+			return period, fmt.Errorf("validación: from debe tener formato YYYY-MM-DD")
+		}
+		period.From = parsed
+	}
+
+	if !period.From.Before(period.To) {
+		return period, fmt.Errorf("validación: from debe ser anterior o igual a to")
+	}
+	return period, nil
+}
+
+// Previous retorna el período anterior de la misma duración (para comparar).
+func (p AnalyticsPeriod) Previous() AnalyticsPeriod {
+	return AnalyticsPeriod{From: p.From.Add(-p.To.Sub(p.From)), To: p.From}
+}
+
+// ChannelSales son las ventas de un canal de envío.
+type ChannelSales struct {
+	Orders  int64        `json:"orders"`
+	Revenue models.Money `json:"revenue"`
+}
+
+// SalesSummary son los totales de un período.
+type SalesSummary struct {
+	Period            AnalyticsPeriod `json:"period"`
+	Orders            int64           `json:"orders"`
+	Revenue           models.Money    `json:"revenue"`
+	AverageOrderValue models.Money    `json:"average_order_value"`
+
+	// Local: Entregas locales y recogidas en sucursal. CargoExpreso: envíos por courier.
+	Local        ChannelSales `json:"local"`
+	CargoExpreso ChannelSales `json:"cargo_expreso"`
+}
+
+// SalesComparison compara un período con el anterior de la misma duración.
+// Los porcentajes son nil cuando el período anterior no tuvo ventas.
+type SalesComparison struct {
+	Current                    SalesSummary `json:"current"`
+	Previous                   SalesSummary `json:"previous"`
+	OrdersChangePct            *float64     `json:"orders_change_pct"`
+	RevenueChangePct           *float64     `json:"revenue_change_pct"`
+	AverageOrderValueChangePct *float64     `json:"average_order_value_change_pct"`
+}
+
+// SalesPoint es un punto de la serie de tiempo. Period es el primer día del
+// día/semana/mes en hora de Guatemala (YYYY-MM-DD).
+type SalesPoint struct {
+	Period  string       `json:"period"`
+	Orders  int64        `json:"orders"`
+	Revenue models.Money `json:"revenue"`
+}
+
+// ProductSales son las ventas de un producto. Revenue es bruto (precio por
+// cantidad), sin descontar reembolsos parciales.
+type ProductSales struct {
+	ProductID   uint         `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	Units       int64        `json:"units"`
+	Orders      int64        `json:"orders"`
+	Revenue     models.Money `json:"revenue"`
+}
+
+// RegionSales son las ventas de un departamento o municipio.
+type RegionSales struct {
+	Department        string       `json:"department"`
+	Municipality      string       `json:"municipality,omitempty"`
+	Orders            int64        `json:"orders"`
+	Revenue           models.Money `json:"revenue"`
+	AverageOrderValue models.Money `json:"average_order_value"`
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// SalesAnalyticsService calcula las métricas de ventas del dashboard del admin.
+// Todo se agrega en SQL; ningún método carga órdenes en memoria.
+// Cuentan como venta las órdenes cobradas (models.RevenueCondition), igual que
+// en las estadísticas de órdenes; el revenue es neto de reembolsos.
+type SalesAnalyticsService interface {
+	// Summary retorna los totales del período comparados con el período anterior.
+	Summary(period AnalyticsPeriod) (*SalesComparison, error)
+
+	// TimeSeries retorna órdenes y revenue por día, semana o mes, incluyendo
+	// los intervalos sin ventas.
+	TimeSeries(period AnalyticsPeriod, granularity Granularity) ([]SalesPoint, error)
+
+	// TopProducts retorna los productos más vendidos por "units" o "revenue".
+	TopProducts(period AnalyticsPeriod, sortBy string, limit int) ([]ProductSales, error)
+
+	// SalesByRegion agrupa las ventas por "department" o "municipality".
+	SalesByRegion(period AnalyticsPeriod, groupBy string) ([]RegionSales, error)
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type salesAnalyticsService struct {
+	db *gorm.DB
+}
+
+// NewSalesAnalyticsService crea el servicio de analítica de ventas.
+func NewSalesAnalyticsService(db *gorm.DB) SalesAnalyticsService {
+	return &salesAnalyticsService{db: db}
+}
+
+// Summary calcula el período actual y el anterior con una consulta cada uno.
+func (s *salesAnalyticsService) Summary(period AnalyticsPeriod) (*SalesComparison, error) {
+	current, err := s.summary(period)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.summary(period.Previous())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SalesComparison{
+		Current:                    *current,
+		Previous:                   *previous,
+		OrdersChangePct:            changePct(float64(previous.Orders), float64(current.Orders)),
+		RevenueChangePct:           changePct(float64(previous.Revenue.Cents()), float64(current.Revenue.Cents())),
+		AverageOrderValueChangePct: changePct(float64(previous.AverageOrderValue.Cents()), float64(current.AverageOrderValue.Cents())),
+	}, nil
+}
+
+func (s *salesAnalyticsService) summary(period AnalyticsPeriod) (*SalesSummary, error) {
+	var row struct {
+		Orders            int64
+		Revenue           models.Money
+		AverageOrderValue models.Money
+		CourierOrders     int64
+		CourierRevenue    models.Money
+		NonCourierOrders  int64
+		NonCourierRevenue models.Money
+	}
+
+	err := s.db.Raw(`
+		SELECT COUNT(*) AS orders,
+		       COALESCE(SUM(total - refunded_amount), 0) AS revenue,
+		       COALESCE(ROUND(AVG(total - refunded_amount)), 0) AS average_order_value,
+		       COUNT(*) FILTER (WHERE requires_courier) AS courier_orders,
+		       COALESCE(SUM(total - refunded_amount) FILTER (WHERE requires_courier), 0) AS courier_revenue,
+		       COUNT(*) FILTER (WHERE NOT requires_courier) AS non_courier_orders,
+		       COALESCE(SUM(total - refunded_amount) FILTER (WHERE NOT requires_courier), 0) AS non_courier_revenue
+		FROM orders
+		WHERE `+models.RevenueCondition+` AND created_at >= ? AND created_at < ?`,
+		period.From, period.To,
+	).Scan(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al calcular resumen de ventas: %w", err)
+	}
+
+	return &SalesSummary{
+		Period:            period,
+		Orders:            row.Orders,
+		Revenue:           row.Revenue,
+		AverageOrderValue: row.AverageOrderValue,
+		Local:             ChannelSales{Orders: row.NonCourierOrders, Revenue: row.NonCourierRevenue},
+		CargoExpreso:      ChannelSales{Orders: row.CourierOrders, Revenue: row.CourierRevenue},
+	}, nil
+}
+
+// TimeSeries genera los intervalos con generate_series y les une las órdenes,
+// así los días sin ventas aparecen con cero.
+func (s *salesAnalyticsService) TimeSeries(period AnalyticsPeriod, granularity Granularity) ([]SalesPoint, error) {
+	days := int(period.To.Sub(period.From).Hours() / 24)
+	var buckets int
+	switch granularity {
+	case GranularityDay:
+		buckets = days
+	case GranularityWeek:
+		buckets = days / 7
+	case GranularityMonth:
+		buckets = days / 28
+	default:
+		return nil, fmt.Errorf("validación: granularity debe ser day, week o month")
+	}
+	if buckets > maxAnalyticsBuckets {
+		return nil, fmt.Errorf("validación: el período es demasiado largo para granularity=%s", granularity)
+	}
+
+	// Límites del período en hora local (timestamp sin zona), como los buckets
+	const localLayout = "2006-01-02 15:04:05.999"
+	start := period.From.In(analyticsLocation).Format(localLayout)
+	end := period.To.Add(-time.Millisecond).In(analyticsLocation).Format(localLayout)
+
+	// granularity, la zona horaria y la condición de venta son constantes validadas: se pueden interpolar
+	query := fmt.Sprintf(`
+		SELECT to_char(b.bucket, 'YYYY-MM-DD') AS period,
+		       COUNT(o.id) AS orders,
+		       COALESCE(SUM(o.total - o.refunded_amount), 0) AS revenue
+		FROM generate_series(date_trunc('%[1]s', ?::timestamp), ?::timestamp, interval '1 %[1]s') AS b(bucket)
+		LEFT JOIN orders o
+		       ON date_trunc('%[1]s', o.created_at AT TIME ZONE '%[2]s') = b.bucket
+		      AND o.%[3]s AND o.created_at >= ? AND o.created_at < ?
+		GROUP BY b.bucket
+		ORDER BY b.bucket`, granularity, analyticsTimeZone, models.RevenueCondition)
+
+	var points []SalesPoint
+	if err := s.db.Raw(query, start, end, period.From, period.To).Scan(&points).Error; err != nil {
+		return nil, fmt.Errorf("error al calcular serie de ventas: %w", err)
+	}
+	return points, nil
+}
+
+// TopProducts agrega order_items de las órdenes vendidas del período.
+func (s *salesAnalyticsService) TopProducts(period AnalyticsPeriod, sortBy string, limit int) ([]ProductSales, error) {
+	orderBy := "units DESC, revenue DESC"
+	switch sortBy {
+	case "", "units":
+	case "revenue":
+		orderBy = "revenue DESC, units DESC"
+	default:
+		return nil, fmt.Errorf("validación: sort debe ser units o revenue")
+	}
+	if limit <= 0 || limit > 100 {
+		return nil, fmt.Errorf("validación: limit debe estar entre 1 y 100")
+	}
+
+	var products []ProductSales
+	err := s.db.Raw(`
+		SELECT oi.product_id,
+		       MAX(oi.product_name) AS product_name,
+		       SUM(oi.quantity) AS units,
+		       COUNT(DISTINCT oi.order_id) AS orders,
+		       COALESCE(SUM(oi.price * oi.quantity), 0) AS revenue
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.`+models.RevenueCondition+` AND o.created_at >= ? AND o.created_at < ?
+		GROUP BY oi.product_id
+		ORDER BY `+orderBy+`
+		LIMIT ?`,
+		period.From, period.To, limit,
+	).Scan(&products).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al calcular productos más vendidos: %w", err)
+	}
+	return products, nil
+}
+
+// SalesByRegion agrupa por shipping_department (y shipping_municipality).
+func (s *salesAnalyticsService) SalesByRegion(period AnalyticsPeriod, groupBy string) ([]RegionSales, error) {
+	var columns string
+	switch groupBy {
+	case "", "department":
+		columns = "shipping_department AS department"
+		groupBy = "shipping_department"
+	case "municipality":
+		columns = "shipping_department AS department, shipping_municipality AS municipality"
+		groupBy = "shipping_department, shipping_municipality"
+	default:
+		return nil, fmt.Errorf("validación: group_by debe ser department o municipality")
+	}
+
+	var regions []RegionSales
+	err := s.db.Raw(`
+		SELECT `+columns+`,
+		       COUNT(*) AS orders,
+		       COALESCE(SUM(total - refunded_amount), 0) AS revenue,
+		       COALESCE(ROUND(AVG(total - refunded_amount)), 0) AS average_order_value
+		FROM orders
+		WHERE `+models.RevenueCondition+` AND created_at >= ? AND created_at < ?
+		GROUP BY `+groupBy+`
+		ORDER BY revenue DESC`,
+		period.From, period.To,
+	).Scan(&regions).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al calcular ventas por región: %w", err)
+	}
+	return regions, nil
+}
+
+// changePct retorna la variación porcentual de previous a current con un
+// decimal (nil si previous es cero).
+func changePct(previous, current float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	pct := math.Round((current-previous)/previous*1000) / 10
+	return &pct
+}
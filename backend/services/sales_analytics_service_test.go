@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// gtMidnight retorna la medianoche en Guatemala (06:00 UTC) del día indicado.
+func gtMidnight(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 6, 0, 0, 0, time.UTC)
+}
+
+func TestParseAnalyticsPeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  string
+	}{
+		{"mes completo", "2026-03-01", "2026-03-31", gtMidnight(2026, 3, 1), gtMidnight(2026, 4, 1), ""},
+		{"un solo día", "2026-03-15", "2026-03-15", gtMidnight(2026, 3, 15), gtMidnight(2026, 3, 16), ""},
+		{"fin de año", "2025-12-31", "2025-12-31", gtMidnight(2025, 12, 31), gtMidnight(2026, 1, 1), ""},
+		{"solo to: 30 días hasta to", "", "2026-03-31", gtMidnight(2026, 3, 2), gtMidnight(2026, 4, 1), ""},
+		{"from después de to", "2026-03-31", "2026-03-01", time.Time{}, time.Time{}, "anterior o igual"},
+		{"from con otro formato", "01/03/2026", "2026-03-31", time.Time{}, time.Time{}, "from debe tener formato"},
+		{"to con hora", "2026-03-01", "2026-03-31T10:00:00", time.Time{}, time.Time{}, "to debe tener formato"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, err := ParseAnalyticsPeriod(tt.from, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasPrefix(err.Error(), "validación") {
+					t.Fatalf("se esperaba error de validación con %q, se obtuvo %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if !period.From.Equal(tt.wantFrom) || !period.To.Equal(tt.wantTo) {
+				t.Errorf("período = %s - %s, se esperaba %s - %s", period.From.UTC(), period.To.UTC(), tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestParseAnalyticsPeriodDefaultsToLast30Days(t *testing.T) {
+	period, err := ParseAnalyticsPeriod("", "")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	now := time.Now().In(analyticsLocation)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, analyticsLocation).AddDate(0, 0, 1)
+	if !period.To.Equal(tomorrow) {
+		t.Errorf("to = %s, se esperaba el fin de hoy en Guatemala (%s)", period.To, tomorrow)
+	}
+	if !period.From.Equal(tomorrow.AddDate(0, 0, -30)) {
+		t.Errorf("from = %s, se esperaban 30 días antes de to", period.From)
+	}
+}
+
+func TestAnalyticsPeriodPrevious(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   AnalyticsPeriod
+		wantFrom time.Time
+	}{
+		{"un día", AnalyticsPeriod{From: gtMidnight(2026, 3, 15), To: gtMidnight(2026, 3, 16)}, gtMidnight(2026, 3, 14)},
+		{"marzo (31 días)", AnalyticsPeriod{From: gtMidnight(2026, 3, 1), To: gtMidnight(2026, 4, 1)}, gtMidnight(2026, 1, 29)},
+		{"cruza el año", AnalyticsPeriod{From: gtMidnight(2026, 1, 1), To: gtMidnight(2026, 1, 8)}, gtMidnight(2025, 12, 25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := tt.period.Previous()
+			// El período anterior termina donde empieza el actual, sin traslaparse
+			if !previous.To.Equal(tt.period.From) {
+				t.Errorf("to = %s, se esperaba %s", previous.To.UTC(), tt.period.From.UTC())
+			}
+			if !previous.From.Equal(tt.wantFrom) {
+				t.Errorf("from = %s, se esperaba %s", previous.From.UTC(), tt.wantFrom)
+			}
+			if previous.To.Sub(previous.From) != tt.period.To.Sub(tt.period.From) {
+				t.Errorf("duración = %s, se esperaba %s", previous.To.Sub(previous.From), tt.period.To.Sub(tt.period.From))
+			}
+		})
+	}
+}
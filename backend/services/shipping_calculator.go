@@ -1,23 +1,71 @@
 package services
 
 import (
+	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"moda-organica/backend/models"
+
 	"golang.org/x/text/runes"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
 )
 
-// ShippingRules define las reglas de negocio para cálculo de envío
+// ShippingRules es la tabla de tarifas de envío vigente (ver models.ShippingRate)
+// y las zonas de entrega por coordenadas (ver models.DeliveryZone)
 type ShippingRules struct {
-	LocalZones []string // Municipios con entrega local gratuita
-	Costs      struct {
-		Local        float64 // Entrega local GRATIS (Huehue/Chiantla)
-		CargoExpreso float64 // Cargo Expreso (resto del país)
+	Rates []models.ShippingRate
+	Zones []GeofenceZone
+}
+
+// ShippingDestination es el destino (y paquete) a cotizar
+type ShippingDestination struct {
+	Department   string  // Código del departamento (ej: GT-13), opcional
+	Municipality string  // Municipio de entrega (nombre o código INE)
+	DeliveryType string  // 'home_delivery' (default) | 'pickup_at_branch'
+	WeightKg     float64 // Peso del paquete; 0 si no se conoce
+
+	// Pin: Coordenadas de entrega, opcionales. Con zonas configuradas definen
+	// si aplica la entrega local dentro del municipio declarado.
+	Pin *GeoPoint
+}
+
+// ShippingQuote es el resultado de cotizar un envío
+type ShippingQuote struct {
+	Cost            models.Money `json:"cost"`
+	Method          string       `json:"method"`
+	RequiresCourier bool         `json:"requires_courier"`
+	RateID          uint         `json:"rate_id,omitempty"`
+	RateName        string       `json:"rate_name,omitempty"`
+
+	// Zone: Zona de entrega donde cae el pin (nil si se cotizó por municipio).
+	Zone *DeliveryZoneMatch `json:"zone,omitempty"`
+}
+
+// ShippingDestinationFor arma el destino de envío de una orden. Las
+// coordenadas inválidas se ignoran (se cotiza por municipio).
+func ShippingDestinationFor(order *models.Order) ShippingDestination {
+	pin, _ := NewGeoPoint(order.DeliveryLat, order.DeliveryLng)
+	return ShippingDestination{
+		Department:   order.ShippingDepartment,
+		Municipality: order.ShippingMunicipality,
+		DeliveryType: order.DeliveryType,
+		WeightKg:     order.PackageWeightKg,
+		Pin:          pin,
 	}
 }
 
+// usesGeofence indica si el destino se cotiza por zonas: entrega a domicilio,
+// con pin y con zonas configuradas. La recogida en sucursal no depende del pin.
+func (sc *shippingCalculator) usesGeofence(dest ShippingDestination) bool {
+	return dest.Pin != nil && dest.DeliveryType != "pickup_at_branch" && len(sc.rules.Zones) > 0
+}
+
 type shippingCalculator struct {
 	rules ShippingRules
 }
@@ -35,57 +83,277 @@ func normalizeString(s string) string {
 	return result
 }
 
-// Calculate determina el costo de envío para un destino dado
-func (sc *shippingCalculator) Calculate(destinationCity string) float64 {
-	normalized := normalizeString(destinationCity)
+// canonicalDestination resuelve el destino contra el catálogo geográfico
+// (código de departamento y nombre oficial del municipio). Si no se puede
+// resolver se deja como viene y solo coincide con tarifas del mismo nombre.
+func canonicalDestination(dest ShippingDestination) ShippingDestination {
+	if dept, mun, err := ResolveLocation(dest.Department, dest.Municipality); err == nil {
+		dest.Department, dest.Municipality = dept.Code, mun.Name
+	}
+	return dest
+}
+
+// sameDepartment compara departamentos por código del catálogo, o por nombre
+// si alguno no está en el catálogo.
+func sameDepartment(a, b string) bool {
+	deptA, okA := FindDepartment(a)
+	deptB, okB := FindDepartment(b)
+	if okA && okB {
+		return deptA.Code == deptB.Code
+	}
+	return normalizeString(a) == normalizeString(b)
+}
+
+// Match busca la tarifa vigente más específica para el destino:
+// municipio > departamento > todo el país. Entre tarifas igual de específicas
+// gana la de vigencia más reciente. El municipio debe coincidir completo
+// (sin tildes ni mayúsculas): "Huehuetenango" no coincide con "Aldea Huehuetenango".
+// Si el destino se cotiza por zonas (ver usesGeofence) y el pin no cayó en
+// ninguna, se descartan las tarifas de entrega local.
+func (sc *shippingCalculator) Match(dest ShippingDestination, now time.Time) (*models.ShippingRate, error) {
+	geofenced := sc.usesGeofence(dest)
+	dest = canonicalDestination(dest)
+	municipality := normalizeString(dest.Municipality)
+
+	var best *models.ShippingRate
+	bestScore := -1
+	for i := range sc.rules.Rates {
+		rate := &sc.rules.Rates[i]
+		if !rate.IsValidAt(now) || !rate.CoversWeight(dest.WeightKg) {
+			continue
+		}
+		if geofenced && !rate.RequiresCourier() {
+			continue
+		}
+
+		score := 0
+		if rate.Department != "" {
+			if !sameDepartment(rate.Department, dest.Department) {
+				continue
+			}
+			score++
+		}
+		if rate.Municipality != "" {
+			if normalizeString(rate.Municipality) != municipality {
+				continue
+			}
+			score += 2
+		}
+
+		if score > bestScore || (score == bestScore && rate.ValidFrom.After(best.ValidFrom)) {
+			best, bestScore = rate, score
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("validación: no hay tarifa de envío para %s", dest.Municipality)
+	}
+	return best, nil
+}
 
-	// Verificar si es zona local (Huehue/Chiantla) -> GRATIS
-	for _, zone := range sc.rules.LocalZones {
-		normalizedZone := normalizeString(zone)
-		if strings.Contains(normalized, normalizedZone) {
-			return sc.rules.Costs.Local // Q0.00
+// Calculate determina el costo de envío para un destino dado. Si el pin cae
+// en una zona de entrega se usa el método y costo de la zona. Un pin en una
+// zona de otro municipio se rechaza: el pin no puede cambiar el destino
+// declarado (ej: envío a Guatemala con el pin en Huehuetenango).
+func (sc *shippingCalculator) Calculate(dest ShippingDestination, now time.Time) (*ShippingQuote, error) {
+	if sc.usesGeofence(dest) {
+		if zone, ok := LocateZone(sc.rules.Zones, *dest.Pin); ok {
+			if !zoneCoversDestination(zone.Zone, dest) {
+				return nil, fmt.Errorf("validación: la ubicación marcada en el mapa está en %s, no en %s; corrige el pin o el municipio",
+					zone.Zone.Municipality, dest.Municipality)
+			}
+			return &ShippingQuote{
+				Cost:            zone.Zone.Cost,
+				Method:          zone.Zone.Method,
+				RequiresCourier: zone.Zone.RequiresCourier(),
+				Zone:            &DeliveryZoneMatch{ID: zone.Zone.ID, Name: zone.Zone.Name, Municipality: zone.Zone.Municipality},
+			}, nil
 		}
 	}
 
-	// Resto del país: Cargo Expreso
-	return sc.rules.Costs.CargoExpreso // Q36.00
+	rate, err := sc.Match(dest, now)
+	if err != nil {
+		return nil, err
+	}
+
+	surcharge := rate.HomeDeliverySurcharge
+	if dest.DeliveryType == "pickup_at_branch" {
+		surcharge = rate.PickupSurcharge
+	}
+	cost, err := rate.BaseCost.Add(surcharge)
+	if err != nil {
+		return nil, fmt.Errorf("tarifa %d inválida: %w", rate.ID, err)
+	}
+
+	return &ShippingQuote{
+		Cost:            cost,
+		Method:          rate.Method,
+		RequiresCourier: rate.RequiresCourier(),
+		RateID:          rate.ID,
+		RateName:        rate.Name,
+	}, nil
+}
+
+// zoneCoversDestination indica si la zona pertenece al municipio declarado.
+// Las zonas sin municipio aceptan cualquier destino.
+func zoneCoversDestination(zone models.DeliveryZone, dest ShippingDestination) bool {
+	if zone.Municipality == "" {
+		return true
+	}
+	dest = canonicalDestination(dest)
+	if zone.Department != "" && !sameDepartment(zone.Department, dest.Department) {
+		return false
+	}
+	return normalizeString(zone.Municipality) == normalizeString(dest.Municipality)
+}
+
+// ============================================================================
+// Store de tarifas
+// ============================================================================
+
+// ShippingRateStore entrega la tabla de tarifas vigente
+type ShippingRateStore interface {
+	Rules() (ShippingRules, error)
+
+	// Invalidate descarta la caché (se llama después de modificar tarifas)
+	Invalidate()
+}
+
+// staticShippingRateStore sirve tarifas fijas (sin BD)
+type staticShippingRateStore struct {
+	rules ShippingRules
 }
 
-// CalculateShippingCost - Función pública (usada por controllers)
-func CalculateShippingCost(destinationCity string) float64 {
-	rules := ShippingRules{
-		LocalZones: []string{"chiantla", "huehuetenango"},
-		Costs: struct {
-			Local        float64
-			CargoExpreso float64
-		}{
-			Local:        0.00,  // GRATIS para Huehue/Chiantla
-			CargoExpreso: 36.00, // Cargo Expreso nacional
-		},
+func (s staticShippingRateStore) Rules() (ShippingRules, error) { return s.rules, nil }
+func (s staticShippingRateStore) Invalidate()                   {}
+
+// dbShippingRateStore lee las tarifas y zonas de la BD y las guarda en caché por ttl.
+// Con varias instancias, un cambio de tarifa se ve en todas en a lo sumo ttl.
+type dbShippingRateStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu       sync.Mutex
+	rules    ShippingRules
+	loadedAt time.Time
+}
+
+// NewShippingRateStore crea el store de tarifas respaldado por la BD
+func NewShippingRateStore(db *gorm.DB, ttl time.Duration) ShippingRateStore {
+	return &dbShippingRateStore{db: db, ttl: ttl}
+}
+
+// NewShippingRateStoreFromEnv crea el store con SHIPPING_RATES_CACHE_TTL (default 5m)
+func NewShippingRateStoreFromEnv(db *gorm.DB) ShippingRateStore {
+	return NewShippingRateStore(db, durationFromEnv("SHIPPING_RATES_CACHE_TTL", 5*time.Minute))
+}
+
+// Rules retorna las tarifas en caché o las recarga si vencieron.
+// Si la BD falla se siguen usando las últimas tarifas cargadas.
+func (s *dbShippingRateStore) Rules() (ShippingRules, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < s.ttl {
+		return s.rules, nil
+	}
+
+	rules, err := s.load()
+	if err != nil {
+		if s.loadedAt.IsZero() {
+			return ShippingRules{}, fmt.Errorf("error al cargar tarifas de envío: %w", err)
+		}
+		log.Printf("Error recargando tarifas de envío, se usan las anteriores: %v", err)
+		return s.rules, nil
 	}
 
-	calculator := newShippingCalculator(rules)
-	return calculator.Calculate(destinationCity)
+	s.rules = rules
+	s.loadedAt = time.Now()
+	return s.rules, nil
 }
 
-// RequiresCargoExpreso - Determina si un envío necesita Cargo Expreso
-func RequiresCargoExpreso(destinationCity string) bool {
-	normalized := normalizeString(destinationCity)
-	localZones := []string{"chiantla", "huehuetenango"}
+// load lee las tarifas vigentes y las zonas activas. Una zona con geometría
+// inválida se omite (y se registra) para no bloquear el checkout.
+func (s *dbShippingRateStore) load() (ShippingRules, error) {
+	var rates []models.ShippingRate
+	err := s.db.Where("active = ? AND (valid_until IS NULL OR valid_until > ?)", true, time.Now()).
+		Order("id ASC").
+		Find(&rates).Error
+	if err != nil {
+		return ShippingRules{}, err
+	}
+
+	var zones []models.DeliveryZone
+	if err := s.db.Where("active = ?", true).Order("id ASC").Find(&zones).Error; err != nil {
+		return ShippingRules{}, err
+	}
 
-	// Si es zona local, NO requiere Cargo Expreso
-	for _, zone := range localZones {
-		normalizedZone := normalizeString(zone)
-		if strings.Contains(normalized, normalizedZone) {
-			return false
+	rules := ShippingRules{Rates: rates}
+	for _, zone := range zones {
+		geofence, err := NewGeofenceZone(zone)
+		if err != nil {
+			log.Printf("Zona de entrega %d (%s) omitida: %v", zone.ID, zone.Name, err)
+			continue
 		}
+		rules.Zones = append(rules.Zones, geofence)
 	}
+	return rules, nil
+}
+
+// Invalidate fuerza la recarga en la próxima consulta
+func (s *dbShippingRateStore) Invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// shippingRates es el store usado por QuoteShipping. Sin BD usa las tarifas por defecto.
+var (
+	shippingRatesMu sync.RWMutex
+	shippingRates   ShippingRateStore = staticShippingRateStore{rules: ShippingRules{Rates: models.DefaultShippingRates()}}
+)
+
+// SetShippingRateStore define el store de tarifas (se llama al iniciar con BD)
+func SetShippingRateStore(store ShippingRateStore) {
+	shippingRatesMu.Lock()
+	shippingRates = store
+	shippingRatesMu.Unlock()
+}
 
-	// Resto del país SI requiere Cargo Expreso
-	return true
+func currentShippingRates() ShippingRateStore {
+	shippingRatesMu.RLock()
+	defer shippingRatesMu.RUnlock()
+	return shippingRates
+}
+
+// ============================================================================
+// Funciones públicas
+// ============================================================================
+
+// QuoteShipping cotiza el envío a un destino con la tabla de tarifas vigente
+func QuoteShipping(dest ShippingDestination) (*ShippingQuote, error) {
+	if strings.TrimSpace(dest.Municipality) == "" {
+		return nil, fmt.Errorf("validación: municipio no puede estar vacío")
+	}
+
+	rules, err := currentShippingRates().Rules()
+	if err != nil {
+		return nil, err
+	}
+	return newShippingCalculator(rules).Calculate(dest, time.Now())
+}
+
+// RequiresCargoExpreso - Determina si un envío necesita Cargo Expreso.
+// Si no hay tarifa para el destino se asume que sí (no es entrega local).
+func RequiresCargoExpreso(dest ShippingDestination) bool {
+	quote, err := QuoteShipping(dest)
+	if err != nil {
+		return true
+	}
+	return quote.RequiresCourier
 }
 
 // IsLocalDelivery - Verifica si el destino es zona local
-func IsLocalDelivery(destinationCity string) bool {
-	return !RequiresCargoExpreso(destinationCity)
+func IsLocalDelivery(dest ShippingDestination) bool {
+	return !RequiresCargoExpreso(dest)
 }
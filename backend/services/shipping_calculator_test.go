@@ -0,0 +1,73 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+)
+
+// squareZone arma una zona cuadrada de entrega local para las pruebas.
+func squareZone(t *testing.T, name, department, municipality string, minLng, minLat, maxLng, maxLat float64) GeofenceZone {
+	t.Helper()
+	coordinates := fmt.Sprintf("[[[%[1]v,%[2]v],[%[3]v,%[2]v],[%[3]v,%[4]v],[%[1]v,%[4]v],[%[1]v,%[2]v]]]",
+		minLng, minLat, maxLng, maxLat)
+	zone, err := NewGeofenceZone(models.DeliveryZone{
+		Name:         name,
+		Department:   department,
+		Municipality: municipality,
+		Method:       models.ShippingMethodLocal,
+		Cost:         models.NewMoney(1500),
+		Geometry:     models.ZoneGeometry{Type: "Polygon", Coordinates: json.RawMessage(coordinates)},
+		Active:       true,
+	})
+	if err != nil {
+		t.Fatalf("zona inválida: %v", err)
+	}
+	return zone
+}
+
+func TestShippingCalculatorPinMustMatchMunicipality(t *testing.T) {
+	calculator := newShippingCalculator(ShippingRules{
+		Rates: models.DefaultShippingRates(),
+		Zones: []GeofenceZone{squareZone(t, "Huehuetenango centro", "GT-13", "Huehuetenango", -91.5, 15.3, -91.4, 15.4)},
+	})
+	inZone := &GeoPoint{Lat: 15.35, Lng: -91.45}
+	outside := &GeoPoint{Lat: 14.6, Lng: -90.5}
+
+	tests := []struct {
+		name        string
+		dest        ShippingDestination
+		wantErr     string
+		wantCourier bool
+		wantZone    bool
+	}{
+		{"pin en la zona del municipio declarado", ShippingDestination{Department: "GT-13", Municipality: "Huehuetenango", Pin: inZone}, "", false, true},
+		{"pin en la zona de otro municipio", ShippingDestination{Department: "GT-01", Municipality: "Guatemala", Pin: inZone}, "validación", false, false},
+		{"pin fuera de toda zona", ShippingDestination{Department: "GT-01", Municipality: "Guatemala", Pin: outside}, "", true, false},
+		{"recogida en sucursal ignora el pin", ShippingDestination{Department: "GT-01", Municipality: "Guatemala", DeliveryType: "pickup_at_branch", Pin: inZone}, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := calculator.Calculate(tt.dest, time.Now())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("se esperaba error con %q, se obtuvo %v (cotización %+v)", tt.wantErr, err, quote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if quote.RequiresCourier != tt.wantCourier {
+				t.Errorf("RequiresCourier = %v, se esperaba %v", quote.RequiresCourier, tt.wantCourier)
+			}
+			if (quote.Zone != nil) != tt.wantZone {
+				t.Errorf("Zone = %+v, se esperaba zona: %v", quote.Zone, tt.wantZone)
+			}
+		})
+	}
+}
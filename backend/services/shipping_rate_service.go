@@ -0,0 +1,244 @@
+// backend/services/shipping_rate_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// DTOs
+// ============================================================================
+
+// ShippingRateDTO crea o reemplaza una tarifa de envío.
+type ShippingRateDTO struct {
+	Name string `json:"name" binding:"required,max=100"`
+
+	// Department / Municipality: Vacíos = aplica a todo el país / departamento.
+	// Se guardan como código de departamento y nombre oficial del municipio.
+	Department   string `json:"department" binding:"max=50"`
+	Municipality string `json:"municipality" binding:"max=100"`
+
+	// Method: 'local_delivery' | 'cargo_expreso'.
+	Method string `json:"method" binding:"required,oneof=local_delivery cargo_expreso"`
+
+	// MinWeightKg / MaxWeightKg: Rango de peso [min, max). 0 en max = sin límite.
+	MinWeightKg float64 `json:"min_weight_kg" binding:"min=0"`
+	MaxWeightKg float64 `json:"max_weight_kg" binding:"min=0"`
+
+	BaseCost              models.Money `json:"base_cost"`
+	HomeDeliverySurcharge models.Money `json:"home_delivery_surcharge"`
+	PickupSurcharge       models.Money `json:"pickup_surcharge"`
+
+	// ValidFrom: Inicio de la vigencia (default: ahora, o la actual al editar).
+	// ValidUntil: fin exclusivo, opcional.
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+
+	// Active: default true.
+	Active *bool `json:"active"`
+}
+
+// Validate verifica montos, pesos, fechas y que el destino exista en el
+// catálogo geográfico (normaliza departamento y municipio).
+func (dto *ShippingRateDTO) Validate() error {
+	if dto.BaseCost.IsNegative() || dto.HomeDeliverySurcharge.IsNegative() || dto.PickupSurcharge.IsNegative() {
+		return fmt.Errorf("validación: los costos no pueden ser negativos")
+	}
+	if dto.MaxWeightKg != 0 && dto.MaxWeightKg <= dto.MinWeightKg {
+		return fmt.Errorf("validación: max_weight_kg debe ser mayor que min_weight_kg")
+	}
+	if dto.ValidFrom != nil && dto.ValidUntil != nil && !dto.ValidUntil.After(*dto.ValidFrom) {
+		return fmt.Errorf("validación: valid_until debe ser posterior a valid_from")
+	}
+
+	if strings.TrimSpace(dto.Municipality) != "" {
+		dept, mun, err := ResolveLocation(dto.Department, dto.Municipality)
+		if err != nil {
+			return err
+		}
+		dto.Department, dto.Municipality = dept.Code, mun.Name
+	} else if strings.TrimSpace(dto.Department) != "" {
+		dept, ok := FindDepartment(dto.Department)
+		if !ok {
+			return fmt.Errorf("validación: departamento desconocido: %s", dto.Department)
+		}
+		dto.Department = dept.Code
+	}
+	return nil
+}
+
+// apply copia el DTO sobre la tarifa.
+func (dto *ShippingRateDTO) apply(rate *models.ShippingRate) {
+	rate.Name = strings.TrimSpace(dto.Name)
+	rate.Department = strings.TrimSpace(dto.Department)
+	rate.Municipality = strings.TrimSpace(dto.Municipality)
+	rate.Method = dto.Method
+	rate.MinWeightKg = dto.MinWeightKg
+	rate.MaxWeightKg = dto.MaxWeightKg
+	rate.BaseCost = dto.BaseCost
+	rate.HomeDeliverySurcharge = dto.HomeDeliverySurcharge
+	rate.PickupSurcharge = dto.PickupSurcharge
+	rate.ValidUntil = dto.ValidUntil
+
+	if dto.ValidFrom != nil {
+		rate.ValidFrom = *dto.ValidFrom
+	} else if rate.ValidFrom.IsZero() {
+		rate.ValidFrom = time.Now()
+	}
+	rate.Active = true
+	if dto.Active != nil {
+		rate.Active = *dto.Active
+	}
+}
+
+// ShippingRateListFilter filtra el listado de tarifas del admin.
+type ShippingRateListFilter struct {
+	Department   string
+	Municipality string
+
+	// IncludeInactive: Incluir tarifas desactivadas o vencidas.
+	IncludeInactive bool
+}
+
+// ============================================================================
+// Service Interface
+// ============================================================================
+
+// ShippingRateService administra la tabla de tarifas de envío. Cada cambio
+// invalida la caché del store para que el checkout use la tarifa nueva sin
+// reiniciar el servidor.
+type ShippingRateService interface {
+	List(filter ShippingRateListFilter) ([]models.ShippingRate, error)
+	Get(id uint) (*models.ShippingRate, error)
+	Create(dto ShippingRateDTO) (*models.ShippingRate, error)
+	Update(id uint, dto ShippingRateDTO) (*models.ShippingRate, error)
+	Delete(id uint) error
+
+	// Quote cotiza un destino con las tarifas vigentes (para probar cambios).
+	Quote(dest ShippingDestination) (*ShippingQuote, error)
+}
+
+// ============================================================================
+// Implementation
+// ============================================================================
+
+type shippingRateService struct {
+	db    *gorm.DB
+	store ShippingRateStore
+}
+
+// NewShippingRateService crea el servicio de tarifas. store es el mismo que
+// usa QuoteShipping (ver SetShippingRateStore).
+func NewShippingRateService(db *gorm.DB, store ShippingRateStore) ShippingRateService {
+	return &shippingRateService{db: db, store: store}
+}
+
+func (s *shippingRateService) List(filter ShippingRateListFilter) ([]models.ShippingRate, error) {
+	query := s.db.Model(&models.ShippingRate{})
+	if filter.Department != "" {
+		if dept, ok := FindDepartment(filter.Department); ok {
+			filter.Department = dept.Code
+		}
+		query = query.Where("department = ?", filter.Department)
+	}
+	if filter.Municipality != "" {
+		query = query.Where("municipality ILIKE ?", filter.Municipality)
+	}
+	if !filter.IncludeInactive {
+		query = query.Where("active = ? AND (valid_until IS NULL OR valid_until > ?)", true, time.Now())
+	}
+
+	var rates []models.ShippingRate
+	if err := query.Order("department ASC, municipality ASC, min_weight_kg ASC, valid_from DESC").Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("error al obtener tarifas: %w", err)
+	}
+	return rates, nil
+}
+
+func (s *shippingRateService) Get(id uint) (*models.ShippingRate, error) {
+	var rate models.ShippingRate
+	if err := s.db.First(&rate, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("tarifa no encontrada: %d", id)
+		}
+		return nil, fmt.Errorf("error al obtener tarifa: %w", err)
+	}
+	return &rate, nil
+}
+
+func (s *shippingRateService) Create(dto ShippingRateDTO) (*models.ShippingRate, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	rate := &models.ShippingRate{}
+	dto.apply(rate)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rate).Error; err != nil {
+			return err
+		}
+		// GORM omite los campos en cero con default: Active=false quedaría en true
+		if !rate.Active {
+			return tx.Model(rate).Update("active", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al crear tarifa: %w", err)
+	}
+
+	s.store.Invalidate()
+	return rate, nil
+}
+
+func (s *shippingRateService) Update(id uint, dto ShippingRateDTO) (*models.ShippingRate, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	rate, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	dto.apply(rate)
+	if err := s.db.Save(rate).Error; err != nil {
+		return nil, fmt.Errorf("error al actualizar tarifa: %w", err)
+	}
+
+	s.store.Invalidate()
+	return rate, nil
+}
+
+// Delete borra la tarifa. Las órdenes guardan su costo de envío, así que
+// borrar una tarifa no cambia órdenes existentes.
+func (s *shippingRateService) Delete(id uint) error {
+	result := s.db.Delete(&models.ShippingRate{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("error al eliminar tarifa: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("tarifa no encontrada: %d", id)
+	}
+
+	s.store.Invalidate()
+	return nil
+}
+
+func (s *shippingRateService) Quote(dest ShippingDestination) (*ShippingQuote, error) {
+	if strings.TrimSpace(dest.Municipality) == "" {
+		return nil, fmt.Errorf("validación: municipio no puede estar vacío")
+	}
+
+	rules, err := s.store.Rules()
+	if err != nil {
+		return nil, err
+	}
+	return newShippingCalculator(rules).Calculate(dest, time.Now())
+}
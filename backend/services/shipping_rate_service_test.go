@@ -0,0 +1,39 @@
+// backend/services/shipping_rate_service_test.go
+package services
+
+import (
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/testsupport"
+)
+
+func TestShippingRateServiceCreateKeepsActiveFlag(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+	service := NewShippingRateService(gormDB, NewShippingRateStore(gormDB, time.Minute))
+
+	for _, active := range []bool{true, false} {
+		active := active
+		rate, err := service.Create(ShippingRateDTO{
+			Name:         "Tarifa de prueba",
+			Department:   "GT-13",
+			Municipality: "Huehuetenango",
+			Method:       models.ShippingMethodLocal,
+			BaseCost:     models.NewMoney(2500),
+			Active:       &active,
+		})
+		if err != nil {
+			t.Fatalf("error inesperado (active=%t): %v", active, err)
+		}
+		t.Cleanup(func() { gormDB.Delete(&models.ShippingRate{}, rate.ID) })
+
+		var saved models.ShippingRate
+		if err := gormDB.First(&saved, rate.ID).Error; err != nil {
+			t.Fatalf("error leyendo tarifa: %v", err)
+		}
+		if saved.Active != active {
+			t.Errorf("active guardado = %t, se esperaba %t", saved.Active, active)
+		}
+	}
+}
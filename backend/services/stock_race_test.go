@@ -0,0 +1,121 @@
+// backend/services/stock_race_test.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"moda-organica/backend/models"
+	"moda-organica/backend/repositories"
+	"moda-organica/backend/testsupport"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// stockRaceOrders es el número de órdenes simultáneas contra la última unidad.
+const stockRaceOrders = 50
+
+// TestCreateOrderStockRace crea un producto con una sola unidad y lanza muchas
+// órdenes en paralelo contra él. Solo una debe crearse, el resto debe fallar
+// con InsufficientStockError y el stock debe quedar en 0.
+//
+// Uso: TEST_DATABASE_URL=... go test ./services -run StockRace
+func TestCreateOrderStockRace(t *testing.T) {
+	gormDB := testsupport.OpenDB(t)
+
+	product := models.Product{
+		SKU:   "STOCK-RACE-" + uuid.NewString()[:8],
+		Name:  "Producto prueba de concurrencia",
+		Price: models.NewMoney(10000),
+		Stock: 1,
+	}
+	if err := gormDB.Create(&product).Error; err != nil {
+		t.Fatalf("Error creando producto de prueba: %v", err)
+	}
+	t.Cleanup(func() { cleanupStockRace(t, gormDB, product.ID) })
+
+	orderService := NewOrderService(
+		repositories.NewOrderRepository(gormDB),
+		repositories.NewProductRepository(gormDB),
+	)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		created   int
+		shortages int
+		failures  []error
+	)
+
+	start := make(chan struct{})
+	for i := 0; i < stockRaceOrders; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			<-start
+
+			dto := newTestOrderDTO("GT-13", "Huehuetenango", PaymentMethodCard)
+			dto.CustomerEmail = fmt.Sprintf("stock-race-%d@example.com", n)
+			dto.Items = []OrderItemDTO{{ProductID: product.ID, Quantity: 1}}
+			_, err := orderService.CreateOrder(dto)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var shortage *repositories.InsufficientStockError
+			switch {
+			case err == nil:
+				created++
+			case errors.As(err, &shortage):
+				shortages++
+			default:
+				failures = append(failures, err)
+			}
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Errorf("error inesperado: %v", err)
+	}
+
+	var stock int
+	if err := gormDB.Model(&models.Product{}).Select("stock").Where("id = ?", product.ID).Scan(&stock).Error; err != nil {
+		t.Fatalf("Error leyendo stock final: %v", err)
+	}
+	if created != 1 || stock != 0 {
+		t.Fatalf("sobreventa: %d órdenes creadas, %d sin stock, stock final %d (se esperaba 1 orden y stock 0)",
+			created, shortages, stock)
+	}
+}
+
+// cleanupStockRace elimina el producto de prueba y las órdenes creadas contra él.
+func cleanupStockRace(t *testing.T, gormDB *gorm.DB, productID uint) {
+	var orderIDs []uuid.UUID
+	if err := gormDB.Model(&models.OrderItem{}).Where("product_id = ?", productID).
+		Distinct().Pluck("order_id", &orderIDs).Error; err != nil {
+		t.Errorf("Error obteniendo órdenes de prueba: %v", err)
+	}
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if len(orderIDs) > 0 {
+			if err := tx.Where("order_id IN ?", orderIDs).Delete(&models.OrderStatusEvent{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("order_id IN ?", orderIDs).Delete(&models.OrderItem{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", orderIDs).Delete(&models.Order{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Product{}, productID).Error
+	})
+	if err != nil {
+		t.Errorf("Error limpiando datos de prueba: %v", err)
+	}
+}
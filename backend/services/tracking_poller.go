@@ -0,0 +1,364 @@
+// backend/services/tracking_poller.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultTrackingPollInterval es cada cuánto corre el poller y cada cuánto
+	// se consulta una guía con movimiento reciente.
+	defaultTrackingPollInterval = time.Hour
+
+	// defaultTrackingMaxBackoff es el máximo entre consultas de una guía sin movimiento.
+	defaultTrackingMaxBackoff = 24 * time.Hour
+
+	// trackingBatchSize limita cuántas guías se consultan por corrida.
+	trackingBatchSize = 100
+)
+
+// trackedStatuses son los estados de orden cuya guía se sigue consultando.
+// Una orden con reembolso parcial ya no avanza sola: la revisa el admin.
+var trackedStatuses = []models.OrderStatus{models.StatusProcessing, models.StatusShipped}
+
+// TrackingPollResult resume una corrida del poller de rastreo.
+type TrackingPollResult struct {
+	// Checked: Guías consultadas.
+	Checked int `json:"checked"`
+
+	// NewEvents: Eventos de rastreo nuevos guardados en shipment_events.
+	NewEvents int `json:"new_events"`
+
+	// Shipped / Delivered: Órdenes que pasaron a 'shipped' o 'delivered'.
+	Shipped   []uuid.UUID `json:"shipped"`
+	Delivered []uuid.UUID `json:"delivered"`
+
+	// Errors: Guías que no se pudieron consultar (se reintentan en la siguiente corrida).
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// TrackingPoller consulta el rastreo de las guías de Cargo Expreso activas,
+// guarda cada evento en shipment_events y avanza las órdenes a 'shipped' y
+// 'delivered'. Las guías sin movimiento se consultan cada vez con menos
+// frecuencia (ver nextCheckDelay).
+type TrackingPoller struct {
+	db         *gorm.DB
+	cargo      CargoExpresoService
+	interval   time.Duration
+	maxBackoff time.Duration
+}
+
+// NewTrackingPoller crea un poller con el intervalo y el backoff máximo indicados.
+func NewTrackingPoller(db *gorm.DB, cargo CargoExpresoService, interval, maxBackoff time.Duration) *TrackingPoller {
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+	return &TrackingPoller{
+		db:         db,
+		cargo:      cargo,
+		interval:   interval,
+		maxBackoff: maxBackoff,
+	}
+}
+
+// NewTrackingPollerFromEnv lee TRACKING_POLL_INTERVAL y TRACKING_MAX_BACKOFF
+// (formato de time.ParseDuration, ej: "1h", "24h").
+func NewTrackingPollerFromEnv(db *gorm.DB, cargo CargoExpresoService) *TrackingPoller {
+	interval := durationFromEnv("TRACKING_POLL_INTERVAL", defaultTrackingPollInterval)
+	maxBackoff := durationFromEnv("TRACKING_MAX_BACKOFF", defaultTrackingMaxBackoff)
+	return NewTrackingPoller(db, cargo, interval, maxBackoff)
+}
+
+// Start corre el poller al iniciar y luego periódicamente hasta que se cierre
+// stop. Debe llamarse en una goroutine.
+func (p *TrackingPoller) Start(stop <-chan struct{}) {
+	log.Printf("Poller de rastreo de Cargo Expreso iniciado (cada %s, backoff máximo %s)", p.interval, p.maxBackoff)
+
+	// Primera pasada inmediata: no esperar un intervalo completo tras un reinicio
+	if _, err := p.PollDue(); err != nil {
+		log.Printf("Error en poller de rastreo: %v", err)
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := p.PollDue(); err != nil {
+				log.Printf("Error en poller de rastreo: %v", err)
+			}
+		case <-stop:
+			log.Println("Poller de rastreo de Cargo Expreso detenido")
+			return
+		}
+	}
+}
+
+// PollDue consulta las guías activas cuya próxima consulta ya venció.
+func (p *TrackingPoller) PollDue() (*TrackingPollResult, error) {
+	result := newTrackingPollResult()
+
+	var candidates []uuid.UUID
+	if err := p.db.Model(&models.Order{}).
+		Where("shipping_tracking <> '' AND status IN ? AND (tracking_next_check_at IS NULL OR tracking_next_check_at <= ?)",
+			trackedStatuses, time.Now()).
+		Order("tracking_next_check_at ASC NULLS FIRST").
+		Limit(trackingBatchSize).
+		Pluck("id", &candidates).Error; err != nil {
+		return nil, fmt.Errorf("error al buscar guías para rastrear: %w", err)
+	}
+
+	for _, orderID := range candidates {
+		if err := p.poll(orderID, result); err != nil {
+			log.Printf("No se pudo rastrear la guía de la orden %s: %v", orderID, err)
+		}
+	}
+
+	if result.NewEvents > 0 || len(result.Errors) > 0 {
+		log.Printf("Poller de rastreo: %d guías, %d eventos nuevos, %d enviadas, %d entregadas, %d errores",
+			result.Checked, result.NewEvents, len(result.Shipped), len(result.Delivered), len(result.Errors))
+	}
+
+	return result, nil
+}
+
+// PollOrder consulta la guía de una orden sin esperar su próxima consulta.
+func (p *TrackingPoller) PollOrder(orderID uuid.UUID) (*TrackingPollResult, error) {
+	var order models.Order
+	if err := p.db.Select("id", "status", "shipping_tracking").First(&order, "id = ?", orderID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("orden no encontrada: %s", orderID)
+		}
+		return nil, fmt.Errorf("error al obtener orden: %w", err)
+	}
+	if order.ShippingTracking == "" {
+		return nil, fmt.Errorf("validación: la orden %s no tiene guía de Cargo Expreso", orderID)
+	}
+
+	result := newTrackingPollResult()
+	if err := p.poll(orderID, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func newTrackingPollResult() *TrackingPollResult {
+	return &TrackingPollResult{
+		Shipped:   []uuid.UUID{},
+		Delivered: []uuid.UUID{},
+		Errors:    map[string]string{},
+	}
+}
+
+// poll consulta la guía de la orden y acumula el resultado.
+func (p *TrackingPoller) poll(orderID uuid.UUID, result *TrackingPollResult) error {
+	newEvents, status, err := p.pollOrder(orderID)
+	if err != nil {
+		result.Errors[orderID.String()] = err.Error()
+		return err
+	}
+
+	result.Checked++
+	result.NewEvents += newEvents
+	switch status {
+	case models.StatusShipped:
+		result.Shipped = append(result.Shipped, orderID)
+	case models.StatusDelivered:
+		result.Delivered = append(result.Delivered, orderID)
+	}
+	return nil
+}
+
+// pollOrder consulta la transportista fuera de la transacción y después, con
+// la orden bloqueada, guarda los eventos nuevos, avanza el estado y programa
+// la próxima consulta. Retorna los eventos nuevos y el estado al que pasó la
+// orden (vacío si no cambió).
+func (p *TrackingPoller) pollOrder(orderID uuid.UUID) (int, models.OrderStatus, error) {
+	var tracking string
+	if err := p.db.Model(&models.Order{}).Where("id = ?", orderID).
+		Pluck("shipping_tracking", &tracking).Error; err != nil {
+		return 0, "", fmt.Errorf("error al obtener guía: %w", err)
+	}
+
+	info, infoErr := p.cargo.GetTrackingInfo(tracking)
+	if infoErr != nil {
+		// Reintentar en el siguiente intervalo, sin martillar a la transportista
+		next := time.Now().Add(p.interval)
+		if err := p.db.Model(&models.Order{}).Where("id = ?", orderID).
+			UpdateColumn("tracking_next_check_at", next).Error; err != nil {
+			log.Printf("No se pudo programar el rastreo de la orden %s: %v", orderID, err)
+		}
+		return 0, "", infoErr
+	}
+
+	events := shipmentEvents(orderID, tracking, info)
+	newEvents := 0
+	var advancedTo models.OrderStatus
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			First(&order, "id = ?", orderID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// Bloqueada por otra transacción o eliminada: se revisa en la siguiente corrida
+				return nil
+			}
+			return err
+		}
+		if order.ShippingTracking != tracking {
+			// La guía se anuló o reemplazó mientras se consultaba
+			return nil
+		}
+
+		if len(events) > 0 {
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&events)
+			if created.Error != nil {
+				return fmt.Errorf("error al guardar eventos de rastreo: %w", created.Error)
+			}
+			newEvents = int(created.RowsAffected)
+		}
+
+		// Avanzar la orden según el evento con más progreso
+		latest := latestShipmentEvent(events)
+		if latest != nil {
+			target := latest.Status.OrderStatus()
+			if target != "" && target != order.Status && order.Status.CanTransitionTo(target) && isTrackedStatus(order.Status) {
+				if err := order.TransitionTo(tx, target, models.StatusChange{
+					ActorType: models.ActorCarrier,
+					ActorID:   "tracking_poller",
+					Reason:    trackingReason(tracking, latest),
+				}); err != nil {
+					return err
+				}
+				advancedTo = target
+			}
+		}
+
+		// Programar la próxima consulta según el tiempo sin movimiento
+		updates := map[string]interface{}{}
+		if lastEventAt := lastShipmentEventAt(events); lastEventAt != nil {
+			updates["tracking_last_event_at"] = *lastEventAt
+			order.TrackingLastEventAt = lastEventAt
+		}
+		quiet := time.Duration(0)
+		if order.TrackingLastEventAt != nil {
+			quiet = time.Since(*order.TrackingLastEventAt)
+		}
+		updates["tracking_next_check_at"] = time.Now().Add(p.nextCheckDelay(quiet))
+		if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).UpdateColumns(updates).Error; err != nil {
+			return fmt.Errorf("error al programar rastreo: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	if advancedTo != "" {
+		log.Printf("Orden %s pasó a '%s' por el rastreo de la guía %s", orderID, advancedTo, tracking)
+	}
+	return newEvents, advancedTo, nil
+}
+
+// nextCheckDelay aleja las consultas de una guía sin movimiento: la mitad del
+// tiempo que lleva quieta, entre interval y maxBackoff. Una guía que acaba de
+// moverse se vuelve a consultar en el siguiente intervalo.
+func (p *TrackingPoller) nextCheckDelay(quiet time.Duration) time.Duration {
+	delay := quiet / 2
+	if delay < p.interval {
+		delay = p.interval
+	}
+	if delay > p.maxBackoff {
+		delay = p.maxBackoff
+	}
+	return delay
+}
+
+// shipmentEvents convierte el rastreo de la transportista en eventos a guardar.
+// Si la respuesta no trae historial se usa el estado actual. Los estados que
+// no se reconocen se ignoran.
+func shipmentEvents(orderID uuid.UUID, tracking string, info *TrackingInfo) []models.ShipmentEvent {
+	raw := info.Events
+	if len(raw) == 0 && info.Status != "" {
+		raw = []TrackingEvent{{Status: info.Status, Location: info.Location, OccurredAt: info.LastUpdate}}
+	}
+
+	events := make([]models.ShipmentEvent, 0, len(raw))
+	for _, event := range raw {
+		status, ok := models.ParseShipmentStatus(event.Status)
+		if !ok {
+			log.Printf("Estado de rastreo desconocido para la guía %s: %q", tracking, event.Status)
+			continue
+		}
+		if event.OccurredAt.IsZero() {
+			continue
+		}
+		events = append(events, models.ShipmentEvent{
+			OrderID:        orderID,
+			TrackingNumber: tracking,
+			Status:         status,
+			Description:    strings.TrimSpace(event.Description),
+			Location:       strings.TrimSpace(event.Location),
+			OccurredAt:     event.OccurredAt.UTC(),
+		})
+	}
+	return events
+}
+
+// latestShipmentEvent retorna el evento con más progreso (a igual progreso, el más reciente).
+func latestShipmentEvent(events []models.ShipmentEvent) *models.ShipmentEvent {
+	var latest *models.ShipmentEvent
+	for i := range events {
+		event := &events[i]
+		if event.Status.Progress() < 0 {
+			continue
+		}
+		if latest == nil || event.Status.Progress() > latest.Status.Progress() ||
+			(event.Status.Progress() == latest.Status.Progress() && event.OccurredAt.After(latest.OccurredAt)) {
+			latest = event
+		}
+	}
+	return latest
+}
+
+// lastShipmentEventAt retorna el momento del evento más reciente (nil si no hay).
+func lastShipmentEventAt(events []models.ShipmentEvent) *time.Time {
+	var last *time.Time
+	for i := range events {
+		if last == nil || events[i].OccurredAt.After(*last) {
+			last = &events[i].OccurredAt
+		}
+	}
+	return last
+}
+
+func isTrackedStatus(status models.OrderStatus) bool {
+	for _, tracked := range trackedStatuses {
+		if status == tracked {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingReason arma el motivo del cambio de estado para el timeline.
+func trackingReason(tracking string, event *models.ShipmentEvent) string {
+	reason := fmt.Sprintf("Cargo Expreso %s: %s", tracking, event.Status)
+	if event.Description != "" {
+		reason = fmt.Sprintf("Cargo Expreso %s: %s", tracking, event.Description)
+	}
+	if event.Location != "" {
+		reason += " (" + event.Location + ")"
+	}
+	return reason
+}
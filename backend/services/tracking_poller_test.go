@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"moda-organica/backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestLatestShipmentEvent(t *testing.T) {
+	base := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	event := func(status models.ShipmentStatus, hours int) models.ShipmentEvent {
+		return models.ShipmentEvent{Status: status, OccurredAt: base.Add(time.Duration(hours) * time.Hour)}
+	}
+
+	tests := []struct {
+		name       string
+		events     []models.ShipmentEvent
+		wantStatus models.ShipmentStatus
+		wantHours  int
+	}{
+		{"sin eventos", nil, "", 0},
+		{"solo incidencias", []models.ShipmentEvent{event(models.ShipmentException, 1)}, "", 0},
+		{"en orden", []models.ShipmentEvent{event(models.ShipmentPickedUp, 1), event(models.ShipmentInTransit, 5)}, models.ShipmentInTransit, 5},
+		// La transportista puede reportar el historial desordenado
+		{"desordenados", []models.ShipmentEvent{event(models.ShipmentDelivered, 30), event(models.ShipmentPickedUp, 1), event(models.ShipmentInTransit, 5)}, models.ShipmentDelivered, 30},
+		{"un evento tardío con menos progreso no retrocede", []models.ShipmentEvent{event(models.ShipmentOutForDelivery, 20), event(models.ShipmentInTransit, 25)}, models.ShipmentOutForDelivery, 20},
+		{"una incidencia no reemplaza el progreso", []models.ShipmentEvent{event(models.ShipmentInTransit, 5), event(models.ShipmentException, 8)}, models.ShipmentInTransit, 5},
+		{"a igual progreso gana el más reciente", []models.ShipmentEvent{event(models.ShipmentInTransit, 9), event(models.ShipmentInTransit, 3)}, models.ShipmentInTransit, 9},
+		{"en ruta y en sucursal tienen el mismo progreso", []models.ShipmentEvent{event(models.ShipmentOutForDelivery, 10), event(models.ShipmentReadyForPickup, 12)}, models.ShipmentReadyForPickup, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latest := latestShipmentEvent(tt.events)
+			if tt.wantStatus == "" {
+				if latest != nil {
+					t.Fatalf("se esperaba nil, se obtuvo %+v", latest)
+				}
+				return
+			}
+			if latest == nil {
+				t.Fatalf("se esperaba %s, se obtuvo nil", tt.wantStatus)
+			}
+			if latest.Status != tt.wantStatus || !latest.OccurredAt.Equal(base.Add(time.Duration(tt.wantHours)*time.Hour)) {
+				t.Errorf("evento = %s a las +%s, se esperaba %s a las +%dh", latest.Status, latest.OccurredAt.Sub(base), tt.wantStatus, tt.wantHours)
+			}
+		})
+	}
+}
+
+func TestNextCheckDelay(t *testing.T) {
+	poller := NewTrackingPoller(nil, nil, time.Hour, 24*time.Hour)
+
+	tests := []struct {
+		name  string
+		quiet time.Duration
+		want  time.Duration
+	}{
+		{"recién movida", 0, time.Hour},
+		{"menos de dos intervalos quieta", 90 * time.Minute, time.Hour},
+		{"la mitad del tiempo quieta", 10 * time.Hour, 5 * time.Hour},
+		{"justo en el máximo", 48 * time.Hour, 24 * time.Hour},
+		{"semanas sin movimiento", 14 * 24 * time.Hour, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := poller.nextCheckDelay(tt.quiet); got != tt.want {
+				t.Errorf("nextCheckDelay(%s) = %s, se esperaba %s", tt.quiet, got, tt.want)
+			}
+		})
+	}
+
+	// Un backoff máximo menor que el intervalo se sube al intervalo
+	clamped := NewTrackingPoller(nil, nil, time.Hour, time.Minute)
+	if got := clamped.nextCheckDelay(48 * time.Hour); got != time.Hour {
+		t.Errorf("nextCheckDelay con backoff menor al intervalo = %s, se esperaba 1h", got)
+	}
+}
+
+func TestShipmentEvents(t *testing.T) {
+	orderID := uuid.New()
+	occurred := time.Date(2026, 3, 10, 9, 0, 0, 0, time.FixedZone("CST", -6*60*60))
+
+	events := shipmentEvents(orderID, "CE123", &TrackingInfo{
+		Status: "En tránsito",
+		Events: []TrackingEvent{
+			{Status: "Recibido en agencia", Description: " Recibido en agencia Huehuetenango ", OccurredAt: occurred},
+			{Status: "estado nuevo", OccurredAt: occurred.Add(time.Hour)},
+			{Status: "EN TRÁNSITO", Location: "Guatemala", OccurredAt: occurred.Add(2 * time.Hour)},
+		},
+	})
+	if len(events) != 2 {
+		t.Fatalf("eventos = %d, se esperaban 2 (el estado desconocido se ignora)", len(events))
+	}
+	if events[0].Status != models.ShipmentPickedUp || events[0].Description != "Recibido en agencia Huehuetenango" || events[0].OccurredAt.Location() != time.UTC {
+		t.Errorf("primer evento = %+v", events[0])
+	}
+	if events[1].Status != models.ShipmentInTransit || events[1].OrderID != orderID || events[1].TrackingNumber != "CE123" {
+		t.Errorf("segundo evento = %+v", events[1])
+	}
+
+	// Sin historial se usa el estado actual
+	current := shipmentEvents(orderID, "CE123", &TrackingInfo{Status: "entregado", LastUpdate: occurred})
+	if len(current) != 1 || current[0].Status != models.ShipmentDelivered {
+		t.Errorf("eventos sin historial = %+v, se esperaba uno 'delivered'", current)
+	}
+}
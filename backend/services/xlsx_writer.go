@@ -0,0 +1,119 @@
+// backend/services/xlsx_writer.go
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"moda-organica/backend/models"
+)
+
+// xlsxWriter escribe un libro de Excel (.xlsx) de una sola hoja fila por fila.
+// Un .xlsx es un zip de archivos XML: las partes fijas se escriben al inicio y
+// la hoja se va escribiendo al final del zip, así el archivo completo nunca
+// está en memoria. Los textos van como "inline strings" (sin tabla de strings
+// compartidos) y los montos como números.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// xlsxStaticParts son las partes del paquete que no dependen de los datos.
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+// newXLSXWriter escribe las partes fijas y abre la hoja sheetName.
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+
+	for _, part := range xlsxStaticParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("error al escribir xlsx: %w", err)
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, fmt.Errorf("error al escribir xlsx: %w", err)
+		}
+	}
+
+	workbook, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return nil, fmt.Errorf("error al escribir xlsx: %w", err)
+	}
+	fmt.Fprint(workbook, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`)
+	xml.EscapeText(workbook, []byte(sheetName))
+	fmt.Fprint(workbook, `" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("error al escribir xlsx: %w", err)
+	}
+	xw := &xlsxWriter{zip: zw, sheet: bufio.NewWriter(sheet)}
+	xw.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return xw, nil
+}
+
+// WriteRow agrega una fila. Los models.Money e int se escriben como números;
+// el resto como texto.
+func (xw *xlsxWriter) WriteRow(cells []interface{}) error {
+	xw.rows++
+	fmt.Fprintf(xw.sheet, `<row r="%d">`, xw.rows)
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case models.Money:
+			fmt.Fprintf(xw.sheet, `<c><v>%s</v></c>`, v.Decimal())
+		case int:
+			fmt.Fprintf(xw.sheet, `<c><v>%s</v></c>`, strconv.Itoa(v))
+		case uint:
+			fmt.Fprintf(xw.sheet, `<c><v>%d</v></c>`, v)
+		default:
+			xw.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(xw.sheet, []byte(fmt.Sprint(v)))
+			xw.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Flush envía al writer lo que está en el buffer de la hoja.
+func (xw *xlsxWriter) Flush() error {
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Flush()
+}
+
+// Close cierra la hoja y el zip.
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := xw.sheet.Flush(); err != nil {
+		return fmt.Errorf("error al escribir xlsx: %w", err)
+	}
+	return xw.zip.Close()
+}
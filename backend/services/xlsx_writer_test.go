@@ -0,0 +1,84 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"moda-organica/backend/models"
+)
+
+// readZipPart retorna el contenido de una parte del paquete.
+func readZipPart(t *testing.T, archive *zip.Reader, name string) string {
+	t.Helper()
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("falta la parte %s: %v", name, err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("error leyendo %s: %v", name, err)
+	}
+	return string(content)
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	xw, err := newXLSXWriter(&buf, "Órdenes & pagos")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	rows := [][]interface{}{
+		{"Número de orden", "Total", "Cantidad"},
+		{"MO-2026-000001", models.NewMoney(25050), 2},
+		{"<script> & \"comillas\"", models.NewMoney(-1000), uint(7)},
+	}
+	for _, row := range rows {
+		if err := xw.WriteRow(row); err != nil {
+			t.Fatalf("error escribiendo fila: %v", err)
+		}
+		if err := xw.Flush(); err != nil {
+			t.Fatalf("error en Flush: %v", err)
+		}
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatalf("error en Close: %v", err)
+	}
+
+	// El zip debe tener directorio central (se lee completo) y las partes del paquete
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("el archivo no es un zip válido: %v", err)
+	}
+	for _, part := range xlsxStaticParts {
+		readZipPart(t, archive, part.name)
+	}
+
+	workbook := readZipPart(t, archive, "xl/workbook.xml")
+	if !strings.Contains(workbook, `name="Órdenes &amp; pagos"`) {
+		t.Errorf("el nombre de la hoja no está escapado: %s", workbook)
+	}
+
+	sheet := readZipPart(t, archive, "xl/worksheets/sheet1.xml")
+	if err := xml.Unmarshal([]byte(sheet), new(interface{})); err != nil {
+		t.Fatalf("la hoja no es XML válido: %v\n%s", err, sheet)
+	}
+	for _, want := range []string{
+		`<row r="1">`, `<row r="3">`,
+		`<t xml:space="preserve">MO-2026-000001</t>`,
+		`<c><v>250.50</v></c>`, `<c><v>2</v></c>`,
+		`<c><v>-10.00</v></c>`, `<c><v>7</v></c>`,
+		`&lt;script&gt; &amp; &#34;comillas&#34;`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("la hoja no contiene %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `<row r="4">`) {
+		t.Errorf("la hoja tiene más filas de las escritas")
+	}
+}
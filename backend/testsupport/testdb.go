@@ -0,0 +1,37 @@
+// backend/testsupport/testdb.go
+// Package testsupport reúne utilidades compartidas por las pruebas de varios paquetes.
+package testsupport
+
+import (
+	"os"
+	"testing"
+
+	"moda-organica/backend/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenDB abre la base de datos de pruebas de TEST_DATABASE_URL y la migra
+// con los mismos modelos que main.go. Sin la variable, la prueba se omite.
+// Nunca apuntar a producción: las pruebas crean y borran datos.
+func OpenDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL no configurada: se omite la prueba contra Postgres")
+	}
+
+	gormDB, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Error conectando a la base de datos: %v", err)
+	}
+	if err := models.Migrate(gormDB); err != nil {
+		t.Fatalf("Error al migrar modelos: %v", err)
+	}
+	return gormDB
+}
@@ -69,17 +69,21 @@ export const brand = {
 	}
 };
 
+// Compara nombres de municipio completos, sin tildes ni mayúsculas
+// (igual que el backend: "Aldea Huehuetenango" no es entrega local)
+function normalizeMunicipality(municipality) {
+	return (municipality || '').normalize('NFD').replace(/[\u0300-\u036f]/g, '').toLowerCase().trim();
+}
+
 export function getShippingCost(municipality) {
-	const { localZones, costs } = brand.businessRules.shipping;
-	const normalizedMun = municipality?.toLowerCase() || '';
-	const isLocal = localZones.some(zone => normalizedMun.includes(zone.toLowerCase()));
-	return isLocal ? costs.local : costs.national;
+	const { costs } = brand.businessRules.shipping;
+	return isLocalDelivery(municipality) ? costs.local : costs.national;
 }
 
 export function isLocalDelivery(municipality) {
 	const { localZones } = brand.businessRules.shipping;
-	const normalizedMun = municipality?.toLowerCase() || '';
-	return localZones.some(zone => normalizedMun.includes(zone.toLowerCase()));
+	const normalizedMun = normalizeMunicipality(municipality);
+	return localZones.some(zone => normalizedMun === normalizeMunicipality(zone));
 }
 
 export function requiresCargoExpreso(municipality) {
@@ -112,11 +112,11 @@ test.describe('LocationSelector Component', () => {
     // Seleccionar Guatemala y un municipio
     await deptSelect.selectOption('GT-01');
     await page.waitForTimeout(300);
-    await munSelect.selectOption('GT-01-01'); // Guatemala city
+    await munSelect.selectOption('0101'); // Guatemala city
 
     // Verificar que se seleccionó
     const value1 = await munSelect.inputValue();
-    expect(value1).toBe('GT-01-01');
+    expect(value1).toBe('0101');
 
     // Cambiar a otro departamento
     await deptSelect.selectOption('GT-13'); // Huehuetenango
@@ -140,7 +140,7 @@ test.describe('LocationSelector Component', () => {
     await page.waitForTimeout(300);
 
     // Select Huehuetenango city (the special zone)
-    await munSelect.selectOption('GT-13-01');
+    await munSelect.selectOption('1301');
     await page.waitForTimeout(500);
 
     // Buscar el badge de envío local
@@ -164,7 +164,7 @@ test.describe('LocationSelector Component', () => {
     await page.waitForTimeout(300);
 
     // Select Chiantla
-    await munSelect.selectOption('GT-13-02');
+    await munSelect.selectOption('1302');
     await page.waitForTimeout(500);
 
     // Buscar badge
@@ -186,7 +186,7 @@ test.describe('LocationSelector Component', () => {
     await page.waitForTimeout(300);
 
     // Select un municipio diferente (no especial)
-    await munSelect.selectOption('GT-13-03'); // La Libertad
+    await munSelect.selectOption('1303'); // Malacatancito
     await page.waitForTimeout(300);
 
     // Verificar que NO aparece el badge
@@ -202,7 +202,7 @@ test.describe('LocationSelector Component', () => {
     // Seleccionar ubicación
     await deptSelect.selectOption('GT-01');
     await page.waitForTimeout(300);
-    await munSelect.selectOption('GT-01-01');
+    await munSelect.selectOption('0101');
     await page.waitForTimeout(300);
 
     // Intentar blur sin contenido
@@ -258,7 +258,7 @@ test.describe('LocationSelector Component', () => {
     // Seleccionar ubicación
     await deptSelect.selectOption('GT-01');
     await page.waitForTimeout(300);
-    await munSelect.selectOption('GT-01-01');
+    await munSelect.selectOption('0101');
     await page.waitForTimeout(300);
 
     // Ingresar dirección
@@ -286,7 +286,7 @@ test.describe('LocationSelector Component', () => {
     // Seleccionar ubicación completa
     await deptSelect.selectOption('GT-13'); // Huehuetenango
     await page.waitForTimeout(300);
-    await munSelect.selectOption('GT-13-01'); // Huehuetenango city
+    await munSelect.selectOption('1301'); // Huehuetenango city
     await page.waitForTimeout(300);
     await addrInput.fill('Calle Independencia 456, Apto 2, zona 1');
     await page.waitForTimeout(300);
@@ -297,7 +297,7 @@ test.describe('LocationSelector Component', () => {
     const addrValue = await addrInput.inputValue();
 
     expect(deptValue).toBe('GT-13');
-    expect(munValue).toBe('GT-13-01');
+    expect(munValue).toBe('1301');
     expect(addrValue).toBe('Calle Independencia 456, Apto 2, zona 1');
 
     // Verificar badge
@@ -416,7 +416,7 @@ test.describe('LocationSelector Component', () => {
 
     await deptSelect.selectOption('GT-01');
     await page.waitForTimeout(300);
-    await munSelect.selectOption('GT-01-01');
+    await munSelect.selectOption('0101');
     await page.waitForTimeout(300);
     await addrInput.fill('Calle 6 Avenida, zona 1, Guatemala');
     await page.waitForTimeout(300);
@@ -426,7 +426,7 @@ test.describe('LocationSelector Component', () => {
     expect(await nameInput.inputValue()).toBe('Juan García López');
     expect(await phoneInput.inputValue()).toBe('78901234');
     expect(await deptSelect.inputValue()).toBe('GT-01');
-    expect(await munSelect.inputValue()).toBe('GT-01-01');
+    expect(await munSelect.inputValue()).toBe('0101');
     expect(await addrInput.inputValue()).toContain('Calle 6 Avenida');
   });
 
@@ -449,7 +449,7 @@ test.describe('LocationSelector Component', () => {
     // Interactuar
     await deptSelect.selectOption('GT-13');
     await page.waitForTimeout(300);
-    await munSelect.selectOption('GT-13-01');
+    await munSelect.selectOption('1301');
     await page.waitForTimeout(500);
 
     // Badge debe aparecer